@@ -2,7 +2,10 @@ export interface User {
 	id: string;
 	username: string;
 	email?: string;
+	email_verified?: boolean;
 	AccessToken?: string;
+	mfa_required?: boolean;
+	mfa_token?: string;
 }
 
 export interface LoginCredentials {
@@ -10,6 +13,12 @@ export interface LoginCredentials {
 	password: string;
 }
 
+export interface MFALoginCredentials {
+	mfa_token: string;
+	code?: string;
+	recovery_code?: string;
+}
+
 export interface SignupCredentials extends LoginCredentials {
 	username: string;
 }
@@ -10,14 +10,18 @@ export interface Room {
 	topic_source?: string;
 	creator_username?: string;
 	participants: number;
+	visibility?: RoomVisibility;
 }
 
+export type RoomVisibility = 'public' | 'unlisted' | 'invite_only';
+
 export interface RoomPermission {
 	role: string;
 	can_manage_room: boolean;
 	can_manage_channels: boolean;
 	can_moderate: boolean;
 	can_post: boolean;
+	can_mention_everyone: boolean;
 	is_muted: boolean;
 	is_banned: boolean;
 }
@@ -26,6 +30,7 @@ export interface RoomMember {
 	user_id: string;
 	username: string;
 	role: string;
+	invited_by?: string;
 	created_at: string;
 }
 
@@ -37,6 +42,7 @@ export interface RoomChannel {
 	kind: string;
 	position: number;
 	is_private: boolean;
+	slow_mode_seconds?: number;
 }
 
 export interface RoomCategory {
@@ -129,12 +135,22 @@ export interface UpdateMemberRoleRequest {
 	can_manage_channels?: boolean;
 	can_moderate?: boolean;
 	can_post?: boolean;
+	can_mention_everyone?: boolean;
 	ban?: boolean;
 }
 
+export interface UserStatus {
+	text?: string;
+	emoji?: string;
+	expires_at?: string;
+}
+
 export interface PresenceUser {
 	user_id?: string;
 	username: string;
+	display_name?: string;
+	avatar_url?: string;
+	status?: UserStatus;
 }
 
 export interface TypingEvent {
@@ -146,7 +162,7 @@ export interface TypingEvent {
 }
 
 export interface WebSocketEvent {
-	type: 'history' | 'message.created' | 'typing' | 'presence' | 'notification';
+	type: 'history' | 'message.created' | 'typing' | 'presence' | 'notification' | 'profile' | 'error';
 	message?: Message;
 	messages?: Message[];
 	typing?: TypingEvent;
@@ -155,4 +171,6 @@ export interface WebSocketEvent {
 		online_users: PresenceUser[];
 	};
 	notification?: NotificationItem;
+	profile?: PresenceUser;
+	error?: string;
 }
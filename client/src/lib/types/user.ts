@@ -1,3 +1,5 @@
+import type { UserStatus } from './room';
+
 export interface User {
 	id: string;
 	email: string;
@@ -24,6 +26,11 @@ export interface Achievement {
 export interface UserProfile {
 	user_id: string;
 	username: string;
+	display_name?: string;
+	bio?: string;
+	pronouns?: string;
+	avatar_url?: string;
+	status?: UserStatus;
 	total_messages: number;
 	total_upvotes: number;
 	daily_streak: number;
@@ -36,3 +43,9 @@ export interface ActivityData {
 	date: string;
 	count: number;
 }
+
+export interface BlockedUser {
+	user_id: string;
+	username: string;
+	created_at: string;
+}
@@ -0,0 +1,33 @@
+-- +goose Up
+
+-- +goose StatementBegin
+-- room_id and message_id are SET NULL rather than CASCADE so that rows outlive
+-- expired rooms long enough for their blobs to be removed from storage.
+CREATE TABLE IF NOT EXISTS attachments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    room_id UUID REFERENCES rooms(id) ON DELETE SET NULL,
+    message_id UUID REFERENCES messages(id) ON DELETE SET NULL,
+    uploader_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    filename TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    storage_key TEXT NOT NULL,
+    thumbnail_key TEXT,
+    width INT,
+    height INT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_room_id ON attachments(room_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_message_id ON attachments(message_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_orphaned ON attachments(created_at) WHERE room_id IS NULL OR message_id IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_attachments_orphaned;
+DROP INDEX IF EXISTS idx_attachments_message_id;
+DROP INDEX IF EXISTS idx_attachments_room_id;
+DROP TABLE IF EXISTS attachments;
+-- +goose StatementEnd
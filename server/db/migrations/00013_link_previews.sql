@@ -0,0 +1,22 @@
+-- +goose Up
+
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS link_previews (
+    url TEXT PRIMARY KEY,
+    ok BOOLEAN NOT NULL DEFAULT FALSE,
+    title TEXT,
+    description TEXT,
+    image_url TEXT,
+    site_name TEXT,
+    fetched_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_link_previews_fetched_at ON link_previews(fetched_at);
+-- +goose StatementEnd
+
+-- +goose Down
+
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_link_previews_fetched_at;
+DROP TABLE IF EXISTS link_previews;
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+
+-- +goose StatementBegin
+-- Custom emoji are stored as ":name:", which does not fit in the original column.
+ALTER TABLE message_reactions ALTER COLUMN emoji TYPE VARCHAR(64);
+
+CREATE INDEX IF NOT EXISTS idx_message_reactions_message_id ON message_reactions(message_id);
+
+CREATE TABLE IF NOT EXISTS room_emojis (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    room_id UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    name VARCHAR(32) NOT NULL,
+    attachment_id UUID NOT NULL REFERENCES attachments(id) ON DELETE CASCADE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE(room_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_room_emojis_attachment_id ON room_emojis(attachment_id);
+-- +goose StatementEnd
+
+-- +goose Down
+
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_room_emojis_attachment_id;
+DROP TABLE IF EXISTS room_emojis;
+DROP INDEX IF EXISTS idx_message_reactions_message_id;
+DELETE FROM message_reactions WHERE LENGTH(emoji) > 10;
+ALTER TABLE message_reactions ALTER COLUMN emoji TYPE VARCHAR(10);
+-- +goose StatementEnd
@@ -0,0 +1,39 @@
+-- +goose Up
+
+-- +goose StatementBegin
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS reply_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS last_reply_at TIMESTAMP;
+
+UPDATE messages AS parent
+SET reply_count = replies.count,
+    last_reply_at = replies.last_reply_at
+FROM (
+    SELECT parent_message_id, COUNT(*) AS count, MAX(created_at) AS last_reply_at
+    FROM messages
+    WHERE parent_message_id IS NOT NULL
+    GROUP BY parent_message_id
+) AS replies
+WHERE parent.id = replies.parent_message_id;
+
+CREATE INDEX IF NOT EXISTS idx_messages_thread ON messages(parent_message_id, created_at, id)
+    WHERE parent_message_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS thread_followers (
+    message_id UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (message_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_thread_followers_user_id ON thread_followers(user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_thread_followers_user_id;
+DROP TABLE IF EXISTS thread_followers;
+DROP INDEX IF EXISTS idx_messages_thread;
+ALTER TABLE messages DROP COLUMN IF EXISTS last_reply_at;
+ALTER TABLE messages DROP COLUMN IF EXISTS reply_count;
+-- +goose StatementEnd
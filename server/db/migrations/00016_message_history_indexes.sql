@@ -0,0 +1,14 @@
+-- +goose Up
+
+-- +goose StatementBegin
+-- Keyset pagination walks messages by (created_at, id) within a room or channel.
+CREATE INDEX IF NOT EXISTS idx_messages_room_history ON messages(room_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_messages_channel_history ON messages(room_id, channel_id, created_at, id);
+-- +goose StatementEnd
+
+-- +goose Down
+
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_messages_channel_history;
+DROP INDEX IF EXISTS idx_messages_room_history;
+-- +goose StatementEnd
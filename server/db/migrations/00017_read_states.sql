@@ -0,0 +1,27 @@
+-- +goose Up
+
+-- +goose StatementBegin
+-- One read pointer per user and channel. The pointer is the (created_at, id)
+-- of the newest message the user has seen, so it keeps working after that
+-- message is deleted.
+CREATE TABLE IF NOT EXISTS channel_read_states (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    channel_id UUID NOT NULL REFERENCES room_channels(id) ON DELETE CASCADE,
+    room_id UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    last_read_message_id UUID NOT NULL,
+    last_read_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, channel_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_channel_read_states_channel ON channel_read_states(channel_id, last_read_at);
+CREATE INDEX IF NOT EXISTS idx_notifications_user_unread ON notifications(user_id) WHERE is_read = FALSE;
+-- +goose StatementEnd
+
+-- +goose Down
+
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_notifications_user_unread;
+DROP INDEX IF EXISTS idx_channel_read_states_channel;
+DROP TABLE IF EXISTS channel_read_states;
+-- +goose StatementEnd
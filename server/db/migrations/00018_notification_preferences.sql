@@ -0,0 +1,29 @@
+-- +goose Up
+
+-- +goose StatementBegin
+-- A row with room_id NULL holds the user's default; room rows override it.
+-- A NULL level on a room row inherits the default level.
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    room_id UUID REFERENCES rooms(id) ON DELETE CASCADE,
+    level TEXT CHECK (level IN ('all', 'mentions', 'none')),
+    muted_until TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notification_preferences_default
+    ON notification_preferences(user_id) WHERE room_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notification_preferences_room
+    ON notification_preferences(user_id, room_id) WHERE room_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_created ON notifications(user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_notifications_created_at ON notifications(created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_notifications_created_at;
+DROP INDEX IF EXISTS idx_notifications_user_created;
+DROP TABLE IF EXISTS notification_preferences;
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+
+-- +goose StatementBegin
+-- Gates @here, @channel/@room and role mentions, which notify many members at once.
+ALTER TABLE room_members ADD COLUMN IF NOT EXISTS can_mention_everyone BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE room_members
+SET can_mention_everyone = TRUE
+WHERE role = 'owner' OR can_manage_room OR can_moderate;
+-- +goose StatementEnd
+
+-- +goose Down
+
+-- +goose StatementBegin
+ALTER TABLE room_members DROP COLUMN IF EXISTS can_mention_everyone;
+-- +goose StatementEnd
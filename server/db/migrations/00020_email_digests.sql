@@ -0,0 +1,19 @@
+-- +goose Up
+
+-- +goose StatementBegin
+-- email_digest is only read from a user's default row (room_id NULL).
+ALTER TABLE notification_preferences ADD COLUMN IF NOT EXISTS email_digest BOOLEAN NOT NULL DEFAULT TRUE;
+
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS emailed_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_notifications_digest_pending
+    ON notifications(created_at) WHERE is_read = FALSE AND emailed_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_notifications_digest_pending;
+ALTER TABLE notifications DROP COLUMN IF EXISTS emailed_at;
+ALTER TABLE notification_preferences DROP COLUMN IF EXISTS email_digest;
+-- +goose StatementEnd
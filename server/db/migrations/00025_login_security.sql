@@ -0,0 +1,28 @@
+-- +goose Up
+
+-- +goose StatementBegin
+-- Consecutive failed sign-ins since the last successful one. Past a
+-- threshold each failure locks the account for exponentially longer.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS failed_login_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS login_locked_until TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS login_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    method TEXT NOT NULL,
+    outcome TEXT NOT NULL CHECK (outcome IN ('success', 'mfa_required', 'invalid_password', 'invalid_code', 'locked')),
+    ip_address TEXT,
+    user_agent TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_events_user ON login_events(user_id, created_at DESC, id DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+
+-- +goose StatementBegin
+DROP TABLE IF EXISTS login_events;
+ALTER TABLE users DROP COLUMN IF EXISTS login_locked_until;
+ALTER TABLE users DROP COLUMN IF EXISTS failed_login_attempts;
+-- +goose StatementEnd
@@ -0,0 +1,57 @@
+-- +goose Up
+
+-- +goose StatementBegin
+-- Deleting a user keeps their messages and rooms for everyone else, and
+-- removes the records that only describe the user.
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS messages_user_id_fkey;
+ALTER TABLE messages ADD CONSTRAINT messages_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE rooms DROP CONSTRAINT IF EXISTS rooms_creator_id_fkey;
+ALTER TABLE rooms ADD CONSTRAINT rooms_creator_id_fkey
+    FOREIGN KEY (creator_id) REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE daily_checkins DROP CONSTRAINT IF EXISTS daily_checkins_user_id_fkey;
+ALTER TABLE daily_checkins ADD CONSTRAINT daily_checkins_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE upvotes DROP CONSTRAINT IF EXISTS upvotes_from_user_id_fkey;
+ALTER TABLE upvotes ADD CONSTRAINT upvotes_from_user_id_fkey
+    FOREIGN KEY (from_user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE upvotes DROP CONSTRAINT IF EXISTS upvotes_to_user_id_fkey;
+ALTER TABLE upvotes ADD CONSTRAINT upvotes_to_user_id_fkey
+    FOREIGN KEY (to_user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE user_achievements DROP CONSTRAINT IF EXISTS user_achievements_user_id_fkey;
+ALTER TABLE user_achievements ADD CONSTRAINT user_achievements_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+-- +goose StatementEnd
+
+-- +goose Down
+
+-- +goose StatementBegin
+ALTER TABLE user_achievements DROP CONSTRAINT IF EXISTS user_achievements_user_id_fkey;
+ALTER TABLE user_achievements ADD CONSTRAINT user_achievements_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id);
+
+ALTER TABLE upvotes DROP CONSTRAINT IF EXISTS upvotes_to_user_id_fkey;
+ALTER TABLE upvotes ADD CONSTRAINT upvotes_to_user_id_fkey
+    FOREIGN KEY (to_user_id) REFERENCES users(id);
+
+ALTER TABLE upvotes DROP CONSTRAINT IF EXISTS upvotes_from_user_id_fkey;
+ALTER TABLE upvotes ADD CONSTRAINT upvotes_from_user_id_fkey
+    FOREIGN KEY (from_user_id) REFERENCES users(id);
+
+ALTER TABLE daily_checkins DROP CONSTRAINT IF EXISTS daily_checkins_user_id_fkey;
+ALTER TABLE daily_checkins ADD CONSTRAINT daily_checkins_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id);
+
+ALTER TABLE rooms DROP CONSTRAINT IF EXISTS rooms_creator_id_fkey;
+ALTER TABLE rooms ADD CONSTRAINT rooms_creator_id_fkey
+    FOREIGN KEY (creator_id) REFERENCES users(id);
+
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS messages_user_id_fkey;
+ALTER TABLE messages ADD CONSTRAINT messages_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id);
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN IF NOT EXISTS display_name VARCHAR(50);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS bio VARCHAR(500);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS pronouns VARCHAR(40);
+-- Storage key of the resized avatar; the original upload is not kept.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS avatar_key TEXT;
+-- A custom status is hidden once status_expires_at has passed.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_text VARCHAR(100);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_emoji VARCHAR(32);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_expires_at TIMESTAMP;
+-- +goose StatementEnd
+
+-- +goose Down
+
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN IF EXISTS status_expires_at;
+ALTER TABLE users DROP COLUMN IF EXISTS status_emoji;
+ALTER TABLE users DROP COLUMN IF EXISTS status_text;
+ALTER TABLE users DROP COLUMN IF EXISTS avatar_key;
+ALTER TABLE users DROP COLUMN IF EXISTS pronouns;
+ALTER TABLE users DROP COLUMN IF EXISTS bio;
+ALTER TABLE users DROP COLUMN IF EXISTS display_name;
+-- +goose StatementEnd
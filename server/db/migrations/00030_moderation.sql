@@ -0,0 +1,50 @@
+-- +goose Up
+
+-- +goose StatementBegin
+-- Reports keep a copy of the message so the evidence survives its removal.
+-- A member can report a message once.
+CREATE TABLE IF NOT EXISTS message_reports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    room_id UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    message_id UUID REFERENCES messages(id) ON DELETE SET NULL,
+    reporter_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    author_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    author_username TEXT NOT NULL,
+    message_content TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    details VARCHAR(1000),
+    status TEXT NOT NULL DEFAULT 'open',
+    resolution TEXT,
+    resolved_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    resolved_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (message_id, reporter_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_reports_queue ON message_reports(room_id, status, created_at);
+
+-- Every moderator action, including role changes and bans made outside the
+-- report queue.
+CREATE TABLE IF NOT EXISTS moderation_log (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    room_id UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    moderator_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    moderator_username TEXT NOT NULL,
+    action TEXT NOT NULL,
+    target_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    target_username TEXT,
+    message_id UUID,
+    report_id UUID REFERENCES message_reports(id) ON DELETE SET NULL,
+    details JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_moderation_log_room ON moderation_log(room_id, created_at DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+
+-- +goose StatementBegin
+DROP TABLE IF EXISTS moderation_log;
+DROP TABLE IF EXISTS message_reports;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+
+-- +goose StatementBegin
+-- A room's AutoMod filters, stored as the JSON rules the automod package
+-- compiles. Rooms without a row have AutoMod off.
+CREATE TABLE IF NOT EXISTS room_automod (
+    room_id UUID PRIMARY KEY REFERENCES rooms(id) ON DELETE CASCADE,
+    rules JSONB NOT NULL DEFAULT '{}'::jsonb,
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+
+-- +goose StatementBegin
+DROP TABLE IF EXISTS room_automod;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+
+-- +goose StatementBegin
+-- Seconds members wait between messages in a channel; zero is off.
+ALTER TABLE room_channels ADD COLUMN IF NOT EXISTS slow_mode_seconds INTEGER NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+
+-- +goose StatementBegin
+ALTER TABLE room_channels DROP COLUMN IF EXISTS slow_mode_seconds;
+-- +goose StatementEnd
@@ -0,0 +1,38 @@
+-- +goose Up
+
+-- +goose StatementBegin
+-- Public rooms are listed to everyone; unlisted rooms can be joined by ID but
+-- are only listed to members; invite-only rooms admit members alone.
+ALTER TABLE rooms ADD COLUMN IF NOT EXISTS visibility TEXT NOT NULL DEFAULT 'public'
+    CHECK (visibility IN ('public', 'unlisted', 'invite_only'));
+
+-- Invite codes created by room managers. A NULL max_uses or expires_at means
+-- no limit.
+CREATE TABLE IF NOT EXISTS room_invites (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    room_id UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    code TEXT NOT NULL UNIQUE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    max_uses INTEGER,
+    uses INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_room_invites_room_id ON room_invites(room_id, created_at);
+
+-- Members who joined through an invite remember which one and who made it.
+ALTER TABLE room_members ADD COLUMN IF NOT EXISTS invite_id UUID REFERENCES room_invites(id) ON DELETE SET NULL;
+ALTER TABLE room_members ADD COLUMN IF NOT EXISTS invited_by UUID REFERENCES users(id) ON DELETE SET NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+
+-- +goose StatementBegin
+ALTER TABLE room_members DROP COLUMN IF EXISTS invited_by;
+ALTER TABLE room_members DROP COLUMN IF EXISTS invite_id;
+DROP INDEX IF EXISTS idx_room_invites_room_id;
+DROP TABLE IF EXISTS room_invites;
+ALTER TABLE rooms DROP COLUMN IF EXISTS visibility;
+-- +goose StatementEnd
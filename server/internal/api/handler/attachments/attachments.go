@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"chat-application/internal/middleware"
+	attachmentsService "chat-application/internal/service/attachments"
+	"chat-application/util"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// AttachmentsHandler handles HTTP requests for file uploads and downloads.
+type AttachmentsHandler struct {
+	attachmentsService *attachmentsService.AttachmentsService
+}
+
+// NewAttachmentsHandler creates a new AttachmentsHandler instance.
+func NewAttachmentsHandler(attachmentsService *attachmentsService.AttachmentsService) *AttachmentsHandler {
+	return &AttachmentsHandler{
+		attachmentsService: attachmentsService,
+	}
+}
+
+// MaxRequestSize returns the largest multipart request body accepted by Upload,
+// leaving headroom for the multipart envelope and form fields around the file.
+func (h *AttachmentsHandler) MaxRequestSize() int64 {
+	return h.attachmentsService.MaxSize() + (1 << 20)
+}
+
+// Upload handles multipart uploads with a "file" part and a "room_id" field.
+func (h *AttachmentsHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	userIDString, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		util.WriteErrorResponse(w, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+	userID, err := uuid.Parse(userIDString)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "invalid user ID format")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.MaxRequestSize())
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			util.WriteErrorResponse(w, http.StatusRequestEntityTooLarge, attachmentsService.ErrFileTooLarge.Error())
+			return
+		}
+		util.WriteErrorResponse(w, http.StatusBadRequest, "invalid multipart form")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	roomID, err := uuid.Parse(strings.TrimSpace(r.FormValue("room_id")))
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "invalid room ID")
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "file is required")
+		return
+	}
+	defer file.Close()
+
+	attachment, err := h.attachmentsService.Upload(r.Context(), attachmentsService.UploadInput{
+		RoomID:     roomID,
+		UploaderID: userID,
+		Filename:   header.Filename,
+		Body:       file,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, attachmentsService.ErrFileTooLarge):
+			util.WriteErrorResponse(w, http.StatusRequestEntityTooLarge, err.Error())
+		case errors.Is(err, attachmentsService.ErrUnsupportedFileType):
+			util.WriteErrorResponse(w, http.StatusUnsupportedMediaType, err.Error())
+		case errors.Is(err, attachmentsService.ErrEmptyFile):
+			util.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, attachmentsService.ErrNotRoomMember):
+			util.WriteErrorResponse(w, http.StatusForbidden, err.Error())
+		default:
+			log.Printf("Error uploading attachment for user %s: %v", userID, err)
+			util.WriteErrorResponse(w, http.StatusInternalServerError, "failed to upload attachment")
+		}
+		return
+	}
+
+	util.WriteJSONResponse(w, http.StatusCreated, attachment)
+}
+
+// Download streams the original uploaded file.
+func (h *AttachmentsHandler) Download(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, false)
+}
+
+// Thumbnail streams the generated preview image for an image attachment.
+func (h *AttachmentsHandler) Thumbnail(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, true)
+}
+
+func (h *AttachmentsHandler) serve(w http.ResponseWriter, r *http.Request, thumbnail bool) {
+	attachmentID, err := uuid.Parse(chi.URLParam(r, "attachmentId"))
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "invalid attachment ID")
+		return
+	}
+
+	var viewerID *uuid.UUID
+	if userIDString, ok := r.Context().Value(middleware.UserIDKey).(string); ok {
+		if userID, err := uuid.Parse(userIDString); err == nil {
+			viewerID = &userID
+		}
+	}
+
+	attachment, reader, err := h.attachmentsService.Open(r.Context(), attachmentID, viewerID, thumbnail)
+	if err != nil {
+		if errors.Is(err, attachmentsService.ErrAttachmentNotFound) {
+			util.WriteErrorResponse(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if errors.Is(err, attachmentsService.ErrAttachmentForbidden) {
+			util.WriteErrorResponse(w, http.StatusForbidden, err.Error())
+			return
+		}
+		log.Printf("Error opening attachment %s: %v", attachmentID, err)
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "failed to load attachment")
+		return
+	}
+	defer reader.Close()
+
+	contentType := attachment.ContentType
+	disposition := "attachment"
+	if thumbnail {
+		contentType = "image/jpeg"
+		if attachment.ContentType == "image/png" || attachment.ContentType == "image/gif" {
+			contentType = "image/png"
+		}
+		disposition = "inline"
+	} else {
+		w.Header().Set("Content-Length", strconv.FormatInt(attachment.SizeBytes, 10))
+		if strings.HasPrefix(contentType, "image/") {
+			disposition = "inline"
+		}
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, reader); err != nil {
+		log.Printf("Error streaming attachment %s: %v", attachmentID, err)
+	}
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"chat-application/internal/api/model"
+	"chat-application/internal/automod"
+	"chat-application/internal/constants"
+	roomRepository "chat-application/internal/repo/room"
+	"chat-application/util"
+)
+
+// GetAutoModRules returns the room's AutoMod configuration to room managers.
+func (h *CoreHandler) GetAutoModRules(w http.ResponseWriter, r *http.Request) {
+	roomID, _, ok := h.requireRoomManager(w, r)
+	if !ok {
+		return
+	}
+
+	settings, err := h.roomRepository.GetAutoModSettings(r.Context(), roomID)
+	if err != nil {
+		log.Printf("CoreHandler.GetAutoModRules - failed to load rules: %v", err)
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load AutoMod rules")
+		return
+	}
+
+	response, err := mapAutoModSettings(settings)
+	if err != nil {
+		log.Printf("CoreHandler.GetAutoModRules - failed to decode rules for room %s: %v", roomID, err)
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load AutoMod rules")
+		return
+	}
+	util.WriteJSONResponse(w, http.StatusOK, response)
+}
+
+// UpdateAutoModRules replaces the room's AutoMod configuration. The change is
+// recorded in the moderation log.
+func (h *CoreHandler) UpdateAutoModRules(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	roomID, member, ok := h.requireRoomManager(w, r)
+	if !ok {
+		return
+	}
+
+	var rules automod.Rules
+	if err := json.NewDecoder(r.Body).Decode(&rules); err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	rules.Normalize()
+	if err := rules.Validate(); err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	encoded, err := json.Marshal(rules)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to save AutoMod rules")
+		return
+	}
+	settings := &roomRepository.AutoModSettings{RoomID: roomID, Rules: encoded, UpdatedBy: &member.UserID}
+	if err := h.roomRepository.SaveAutoModSettings(ctx, settings); err != nil {
+		log.Printf("CoreHandler.UpdateAutoModRules - failed to save rules: %v", err)
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to save AutoMod rules")
+		return
+	}
+
+	h.logModeration(ctx, member, constants.ModerationUpdateAutoMod, nil, nil, nil, nil, rules)
+
+	response, err := mapAutoModSettings(settings)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to save AutoMod rules")
+		return
+	}
+	util.WriteJSONResponse(w, http.StatusOK, response)
+}
+
+func mapAutoModSettings(settings *roomRepository.AutoModSettings) (*model.AutoModRes, error) {
+	res := &model.AutoModRes{}
+	if settings != nil {
+		if err := json.Unmarshal(settings.Rules, &res.Rules); err != nil {
+			return nil, err
+		}
+		if settings.UpdatedBy != nil {
+			res.UpdatedBy = settings.UpdatedBy.String()
+		}
+		res.UpdatedAt = &settings.UpdatedAt
+	}
+	res.Rules.Normalize()
+	return res, nil
+}
@@ -15,6 +15,7 @@ import (
 	"chat-application/internal/api/model"
 	"chat-application/internal/constants"
 	"chat-application/internal/middleware"
+	"chat-application/internal/pagination"
 	roomRepository "chat-application/internal/repo/room"
 	websoc "chat-application/internal/websocket"
 	"chat-application/util"
@@ -82,6 +83,15 @@ func (h *CoreHandler) CreateRoom(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	visibility := strings.TrimSpace(req.Visibility)
+	if visibility == "" {
+		visibility = constants.RoomVisibilityPublic
+	}
+	if !constants.IsValidRoomVisibility(visibility) {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid room visibility")
+		return
+	}
+
 	ctx := r.Context()
 
 	var creatorID *uuid.UUID
@@ -97,6 +107,12 @@ func (h *CoreHandler) CreateRoom(w http.ResponseWriter, r *http.Request) {
 		log.Printf("User ID not found in context")
 	}
 
+	// Nobody could invite anyone into an invite-only room without an owner.
+	if visibility == constants.RoomVisibilityInviteOnly && creatorID == nil {
+		util.WriteErrorResponse(w, http.StatusUnauthorized, "Sign in to create an invite-only room")
+		return
+	}
+
 	activeRooms, err := h.roomRepository.CountActiveRooms(ctx)
 	if err != nil {
 		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve active rooms")
@@ -116,9 +132,10 @@ func (h *CoreHandler) CreateRoom(w http.ResponseWriter, r *http.Request) {
 	}
 
 	room := &roomRepository.Room{
-		Name:      req.Name,
-		CreatorID: creatorID,
-		ExpiresAt: expiresAt,
+		Name:       req.Name,
+		CreatorID:  creatorID,
+		ExpiresAt:  expiresAt,
+		Visibility: visibility,
 	}
 
 	room, err = h.roomRepository.CreateRoom(ctx, room)
@@ -140,6 +157,7 @@ func (h *CoreHandler) CreateRoom(w http.ResponseWriter, r *http.Request) {
 			member.CanManageChannels = true
 			member.CanModerate = true
 			member.CanPost = true
+			member.CanMentionEveryone = true
 			if err := h.roomRepository.UpdateRoomMember(ctx, *member); err != nil {
 				util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update room owner")
 				return
@@ -182,8 +200,9 @@ func (h *CoreHandler) CreateRoom(w http.ResponseWriter, r *http.Request) {
 	})
 
 	response := model.CreateRoomReq{
-		ID:   room.ID.String(),
-		Name: room.Name,
+		ID:         room.ID.String(),
+		Name:       room.Name,
+		Visibility: room.Visibility,
 	}
 
 	util.WriteJSONResponse(w, http.StatusOK, response)
@@ -220,6 +239,9 @@ func (h *CoreHandler) JoinRoom(w http.ResponseWriter, r *http.Request) {
 		util.WriteErrorResponse(w, http.StatusNotFound, "Room not found")
 		return
 	}
+	if !h.requireRoomAccess(w, r, dbRoom) {
+		return
+	}
 
 	var authenticatedUserID string
 	if userID, ok := ctx.Value(middleware.UserIDKey).(string); ok {
@@ -314,11 +336,28 @@ func (h *CoreHandler) JoinRoom(w http.ResponseWriter, r *http.Request) {
 func (h *CoreHandler) GetRooms(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	dbRooms, err := h.roomRepository.GetAllActiveRooms(ctx)
+	var viewerID *uuid.UUID
+	if userID, ok := ctx.Value(middleware.UserIDKey).(string); ok {
+		if parsedUserID, err := uuid.Parse(userID); err == nil {
+			viewerID = &parsedUserID
+		}
+	}
+
+	dbRooms, err := h.roomRepository.GetVisibleRooms(ctx, viewerID)
 	if err != nil {
 		util.WriteErrorResponse(w, http.StatusInternalServerError, "failed to fetch rooms")
 		return
 	}
+	roomIDs := make([]uuid.UUID, 0, len(dbRooms))
+	for _, room := range dbRooms {
+		roomIDs = append(roomIDs, room.ID)
+	}
+	unreadCounts := make(map[uuid.UUID]int)
+	mentionCounts := make(map[uuid.UUID]int)
+	for _, state := range h.loadReadStates(ctx, viewerID, roomIDs) {
+		unreadCounts[state.RoomID] += state.UnreadCount
+		mentionCounts[state.RoomID] += state.MentionCount
+	}
 
 	rooms := make([]model.RoomRes, 0, len(dbRooms))
 	for _, room := range dbRooms {
@@ -349,6 +388,9 @@ func (h *CoreHandler) GetRooms(w http.ResponseWriter, r *http.Request) {
 			TopicSource:      room.TopicSource,
 			CreatorUsername:  creatorUsername,
 			Participants:     participantCount,
+			UnreadCount:      unreadCounts[room.ID],
+			MentionCount:     mentionCounts[room.ID],
+			Visibility:       room.Visibility,
 		})
 
 		if _, exists := h.core.GetRoom(room.ID.String()); !exists {
@@ -376,13 +418,8 @@ func (h *CoreHandler) GetRoomDetail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	room, err := h.roomRepository.GetRoomByID(ctx, roomID)
-	if err != nil {
-		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load room")
-		return
-	}
-	if room == nil {
-		util.WriteErrorResponse(w, http.StatusNotFound, "Room not found")
+	room, ok := h.loadReadableRoom(w, r, roomID)
+	if !ok {
 		return
 	}
 
@@ -513,14 +550,29 @@ func (h *CoreHandler) SearchMessages(w http.ResponseWriter, r *http.Request) {
 		channelID = &parsedID
 	}
 
+	if _, ok := h.loadReadableRoom(w, r, roomID); !ok {
+		return
+	}
+
 	results, err := h.roomRepository.SearchMessages(ctx, roomID, queryText, channelID, strings.TrimSpace(r.URL.Query().Get("username")), 50)
 	if err != nil {
 		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to search messages")
 		return
 	}
 
+	var viewerID *uuid.UUID
+	if userID, ok := ctx.Value(middleware.UserIDKey).(string); ok {
+		if parsedUserID, err := uuid.Parse(userID); err == nil {
+			viewerID = &parsedUserID
+		}
+	}
+	blocked := h.blockedAuthors(ctx, viewerID)
+
 	response := make([]model.MessageSearchRes, 0, len(results))
 	for _, message := range results {
+		if isBlockedAuthor(blocked, message.UserID) {
+			continue
+		}
 		item := model.MessageSearchRes{
 			ID:          message.ID.String(),
 			RoomID:      message.RoomID.String(),
@@ -528,6 +580,7 @@ func (h *CoreHandler) SearchMessages(w http.ResponseWriter, r *http.Request) {
 			Content:     message.Content,
 			Highlighted: highlightQuery(message.Content, queryText),
 			CreatedAt:   message.CreatedAt,
+			Cursor:      pagination.Cursor{CreatedAt: message.CreatedAt, ID: message.ID}.Encode(),
 		}
 		if message.ChannelID != nil {
 			item.ChannelID = message.ChannelID.String()
@@ -541,51 +594,6 @@ func (h *CoreHandler) SearchMessages(w http.ResponseWriter, r *http.Request) {
 	util.WriteJSONResponse(w, http.StatusOK, response)
 }
 
-func (h *CoreHandler) GetNotifications(w http.ResponseWriter, r *http.Request) {
-	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
-	if !ok {
-		util.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
-		return
-	}
-	parsedUserID, err := uuid.Parse(userID)
-	if err != nil {
-		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID")
-		return
-	}
-
-	notifications, err := h.roomRepository.GetNotifications(r.Context(), parsedUserID, 50)
-	if err != nil {
-		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load notifications")
-		return
-	}
-
-	util.WriteJSONResponse(w, http.StatusOK, h.mapNotifications(notifications))
-}
-
-func (h *CoreHandler) MarkNotificationRead(w http.ResponseWriter, r *http.Request) {
-	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
-	if !ok {
-		util.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
-		return
-	}
-	parsedUserID, err := uuid.Parse(userID)
-	if err != nil {
-		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID")
-		return
-	}
-	notificationID, err := uuid.Parse(chi.URLParam(r, "notificationId"))
-	if err != nil {
-		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid notification ID")
-		return
-	}
-
-	if err := h.roomRepository.MarkNotificationRead(r.Context(), notificationID, parsedUserID); err != nil {
-		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update notification")
-		return
-	}
-	util.WriteJSONResponse(w, http.StatusOK, map[string]bool{"ok": true})
-}
-
 func (h *CoreHandler) UpdateMemberRole(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	roomID, actingMember, ok := h.requireRoomManager(w, r)
@@ -638,6 +646,9 @@ func (h *CoreHandler) UpdateMemberRole(w http.ResponseWriter, r *http.Request) {
 	if req.CanPost != nil {
 		targetMember.CanPost = *req.CanPost
 	}
+	if req.CanMentionEveryone != nil {
+		targetMember.CanMentionEveryone = *req.CanMentionEveryone
+	}
 	if req.Ban {
 		now := time.Now().UTC()
 		targetMember.BannedAt = &now
@@ -648,14 +659,23 @@ func (h *CoreHandler) UpdateMemberRole(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.logModeration(ctx, actingMember, constants.ModerationUpdateMember, &targetMember.UserID, &targetMember.Username, nil, nil, req)
+
 	util.WriteJSONResponse(w, http.StatusOK, map[string]bool{"ok": true})
 }
 
 func (h *CoreHandler) GetClients(w http.ResponseWriter, r *http.Request) {
 	var clients []model.ClientRes
-	roomID := chi.URLParam(r, "room_id")
+	roomID, err := uuid.Parse(chi.URLParam(r, "room_id"))
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid room ID")
+		return
+	}
+	if _, ok := h.loadReadableRoom(w, r, roomID); !ok {
+		return
+	}
 
-	room, ok := h.core.GetRoom(roomID)
+	room, ok := h.core.GetRoom(roomID.String())
 	if !ok {
 		util.WriteErrorResponse(w, http.StatusNotFound, "Room not found")
 		return
@@ -700,6 +720,56 @@ func (h *CoreHandler) requireRoomManager(w http.ResponseWriter, r *http.Request)
 	return roomID, member, true
 }
 
+// requireRoomAccess keeps everyone but unbanned members out of invite-only
+// rooms. Public and unlisted rooms are open to anyone with the room ID.
+func (h *CoreHandler) requireRoomAccess(w http.ResponseWriter, r *http.Request, room *roomRepository.Room) bool {
+	if room.Visibility != constants.RoomVisibilityInviteOnly {
+		return true
+	}
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		util.WriteErrorResponse(w, http.StatusForbidden, "This room is invite-only")
+		return false
+	}
+	parsedUserID, err := uuid.Parse(userID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID")
+		return false
+	}
+	member, err := h.roomRepository.GetRoomMember(r.Context(), room.ID, parsedUserID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load room membership")
+		return false
+	}
+	if member == nil {
+		util.WriteErrorResponse(w, http.StatusForbidden, "This room is invite-only")
+		return false
+	}
+	if member.BannedAt != nil {
+		util.WriteErrorResponse(w, http.StatusForbidden, "You are banned from this room")
+		return false
+	}
+	return true
+}
+
+// loadReadableRoom loads a room the caller may read, refusing missing rooms
+// and invite-only rooms the caller is not a member of.
+func (h *CoreHandler) loadReadableRoom(w http.ResponseWriter, r *http.Request, roomID uuid.UUID) (*roomRepository.Room, bool) {
+	room, err := h.roomRepository.GetRoomByID(r.Context(), roomID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load room")
+		return nil, false
+	}
+	if room == nil {
+		util.WriteErrorResponse(w, http.StatusNotFound, "Room not found")
+		return nil, false
+	}
+	if !h.requireRoomAccess(w, r, room) {
+		return nil, false
+	}
+	return room, true
+}
+
 func (h *CoreHandler) buildRoomDetailResponse(ctx context.Context, room *roomRepository.Room) (*model.RoomDetailRes, error) {
 	categories, err := h.roomRepository.GetRoomCategories(ctx, room.ID)
 	if err != nil {
@@ -718,7 +788,7 @@ func (h *CoreHandler) buildRoomDetailResponse(ctx context.Context, room *roomRep
 	if defaultChannel != nil {
 		defaultChannelID = &defaultChannel.ID
 	}
-	messages, err := h.roomRepository.GetRoomMessagesByChannel(ctx, room.ID, defaultChannelID, 100, 0)
+	messages, err := h.roomRepository.GetMessagesBefore(ctx, room.ID, defaultChannelID, nil, 100)
 	if err != nil {
 		return nil, err
 	}
@@ -729,22 +799,25 @@ func (h *CoreHandler) buildRoomDetailResponse(ctx context.Context, room *roomRep
 
 	var currentUser *model.RoomPermissionRes
 	var notifications []model.NotificationRes
+	var viewerID *uuid.UUID
 	if userID, ok := ctx.Value(middleware.UserIDKey).(string); ok {
 		parsedUserID, err := uuid.Parse(userID)
 		if err == nil {
+			viewerID = &parsedUserID
 			member, err := h.roomRepository.GetRoomMember(ctx, room.ID, parsedUserID)
 			if err == nil && member != nil {
 				currentUser = &model.RoomPermissionRes{
-					Role:              member.Role,
-					CanManageRoom:     member.CanManageRoom,
-					CanManageChannels: member.CanManageChannels,
-					CanModerate:       member.CanModerate,
-					CanPost:           member.CanPost,
-					IsMuted:           member.MutedUntil != nil && member.MutedUntil.After(time.Now()),
-					IsBanned:          member.BannedAt != nil,
+					Role:               member.Role,
+					CanManageRoom:      member.CanManageRoom,
+					CanManageChannels:  member.CanManageChannels,
+					CanModerate:        member.CanModerate,
+					CanPost:            member.CanPost,
+					CanMentionEveryone: member.CanMentionEveryone,
+					IsMuted:            member.MutedUntil != nil && member.MutedUntil.After(time.Now()),
+					IsBanned:           member.BannedAt != nil,
 				}
 			}
-			rawNotifications, err := h.roomRepository.GetNotifications(ctx, parsedUserID, 20)
+			rawNotifications, err := h.roomRepository.GetNotifications(ctx, parsedUserID, nil, false, 20)
 			if err == nil {
 				filtered := make([]roomRepository.Notification, 0, len(rawNotifications))
 				for _, notification := range rawNotifications {
@@ -770,14 +843,26 @@ func (h *CoreHandler) buildRoomDetailResponse(ctx context.Context, room *roomRep
 		categoryMap[category.ID] = &responseCategories[len(responseCategories)-1]
 	}
 
+	readStates := h.loadReadStates(ctx, viewerID, []uuid.UUID{room.ID})
+	unreadCount, mentionCount := 0, 0
 	for _, channel := range channels {
 		channelRes := model.RoomChannelRes{
-			ID:          channel.ID.String(),
-			Name:        channel.Name,
-			Description: channel.Description,
-			Kind:        channel.Kind,
-			Position:    channel.Position,
-			IsPrivate:   channel.IsPrivate,
+			ID:              channel.ID.String(),
+			Name:            channel.Name,
+			Description:     channel.Description,
+			Kind:            channel.Kind,
+			Position:        channel.Position,
+			IsPrivate:       channel.IsPrivate,
+			SlowModeSeconds: channel.SlowModeSeconds,
+		}
+		if state, ok := readStates[channel.ID]; ok {
+			channelRes.UnreadCount = state.UnreadCount
+			channelRes.MentionCount = state.MentionCount
+			if state.LastReadMessageID != nil {
+				channelRes.LastReadMessageID = state.LastReadMessageID.String()
+			}
+			unreadCount += state.UnreadCount
+			mentionCount += state.MentionCount
 		}
 		if channel.CategoryID != nil {
 			channelRes.CategoryID = channel.CategoryID.String()
@@ -797,48 +882,35 @@ func (h *CoreHandler) buildRoomDetailResponse(ctx context.Context, room *roomRep
 		responseCategories[0].Channels = append(responseCategories[0].Channels, channelRes)
 	}
 
-	messageResponses := make([]model.MessageRes, 0, len(messages))
+	messageResponses := h.mapMessages(ctx, messages, viewerID)
 	threadCount := 0
 	for _, message := range messages {
-		item := model.MessageRes{
-			ID:        message.ID.String(),
-			Content:   message.Content,
-			RoomID:    message.RoomID.String(),
-			Username:  message.Username,
-			System:    message.IsSystem,
-			CreatedAt: message.CreatedAt,
-		}
-		if message.ChannelID != nil {
-			item.ChannelID = message.ChannelID.String()
-		}
 		if message.ParentMessageID != nil {
-			item.ParentMessageID = message.ParentMessageID.String()
 			threadCount++
 		}
-		if message.UserID != nil {
-			item.UserID = message.UserID.String()
-		}
-		if len(message.Metadata) > 0 {
-			var metadata map[string]any
-			if err := json.Unmarshal(message.Metadata, &metadata); err == nil {
-				item.Metadata = metadata
-			}
-		}
-		reactions, err := h.roomRepository.GetReactions(ctx, message.ID.String())
-		if err == nil {
-			item.Reactions = reactions
-		}
-		messageResponses = append(messageResponses, item)
 	}
 
 	memberResponses := make([]model.RoomMemberRes, 0, len(members))
 	for _, member := range members {
-		memberResponses = append(memberResponses, model.RoomMemberRes{
+		memberRes := model.RoomMemberRes{
 			UserID:    member.UserID.String(),
 			Username:  member.Username,
 			Role:      member.Role,
 			CreatedAt: member.CreatedAt,
-		})
+		}
+		if member.InvitedBy != nil {
+			memberRes.InvitedBy = member.InvitedBy.String()
+		}
+		memberResponses = append(memberResponses, memberRes)
+	}
+
+	emojis, err := h.roomRepository.GetRoomEmojis(ctx, room.ID)
+	if err != nil {
+		return nil, err
+	}
+	emojiResponses := make([]model.RoomEmojiRes, 0, len(emojis))
+	for _, emoji := range emojis {
+		emojiResponses = append(emojiResponses, mapRoomEmoji(emoji))
 	}
 
 	participantCount := 0
@@ -857,6 +929,9 @@ func (h *CoreHandler) buildRoomDetailResponse(ctx context.Context, room *roomRep
 		TopicURL:         room.TopicURL,
 		TopicSource:      room.TopicSource,
 		Participants:     participantCount,
+		UnreadCount:      unreadCount,
+		MentionCount:     mentionCount,
+		Visibility:       room.Visibility,
 	}
 
 	res := &model.RoomDetailRes{
@@ -869,6 +944,9 @@ func (h *CoreHandler) buildRoomDetailResponse(ctx context.Context, room *roomRep
 		NotificationCount:  len(notifications),
 		OnlineMemberCount:  participantCount,
 		ThreadedReplyCount: threadCount,
+		Emojis:             emojiResponses,
+		UnreadCount:        unreadCount,
+		MentionCount:       mentionCount,
 	}
 	if defaultChannel != nil {
 		res.DefaultChannelID = defaultChannel.ID.String()
@@ -876,6 +954,98 @@ func (h *CoreHandler) buildRoomDetailResponse(ctx context.Context, room *roomRep
 	return res, nil
 }
 
+// mapMessages converts stored messages to responses, including aggregated
+// reactions from the viewer's point of view.
+func (h *CoreHandler) mapMessages(ctx context.Context, messages []*roomRepository.Message, viewerID *uuid.UUID) []model.MessageRes {
+	messageIDs := make([]uuid.UUID, 0, len(messages))
+	for _, message := range messages {
+		messageIDs = append(messageIDs, message.ID)
+	}
+	reactionCounts, err := h.roomRepository.GetReactionCounts(ctx, messageIDs, viewerID)
+	if err != nil {
+		log.Printf("CoreHandler.mapMessages - failed to load reactions: %v", err)
+	}
+
+	responses := make([]model.MessageRes, 0, len(messages))
+	for _, message := range messages {
+		item := model.MessageRes{
+			ID:          message.ID.String(),
+			Content:     message.Content,
+			RoomID:      message.RoomID.String(),
+			Username:    message.Username,
+			System:      message.IsSystem,
+			CreatedAt:   message.CreatedAt,
+			ReplyCount:  message.ReplyCount,
+			LastReplyAt: message.LastReplyAt,
+			Reactions:   reactionCounts[message.ID],
+		}
+		if message.ChannelID != nil {
+			item.ChannelID = message.ChannelID.String()
+		}
+		if message.ParentMessageID != nil {
+			item.ParentMessageID = message.ParentMessageID.String()
+		}
+		if message.UserID != nil {
+			item.UserID = message.UserID.String()
+		}
+		if len(message.Metadata) > 0 {
+			var metadata map[string]any
+			if err := json.Unmarshal(message.Metadata, &metadata); err == nil {
+				item.Metadata = metadata
+			}
+		}
+		responses = append(responses, item)
+	}
+	return responses
+}
+
+// blockedAuthors returns the users the viewer has blocked, or nil.
+func (h *CoreHandler) blockedAuthors(ctx context.Context, viewerID *uuid.UUID) map[uuid.UUID]struct{} {
+	if viewerID == nil || h.core == nil || h.core.Blocks == nil {
+		return nil
+	}
+	blockedIDs, err := h.core.Blocks.GetBlockedUserIDs(ctx, *viewerID)
+	if err != nil {
+		log.Printf("CoreHandler.blockedAuthors - failed to load blocks: %v", err)
+		return nil
+	}
+	if len(blockedIDs) == 0 {
+		return nil
+	}
+
+	blocked := make(map[uuid.UUID]struct{}, len(blockedIDs))
+	for _, id := range blockedIDs {
+		blocked[id] = struct{}{}
+	}
+	return blocked
+}
+
+func isBlockedAuthor(blocked map[uuid.UUID]struct{}, userID *uuid.UUID) bool {
+	if userID == nil {
+		return false
+	}
+	_, ok := blocked[*userID]
+	return ok
+}
+
+// withoutBlocked drops messages written by users the viewer has blocked.
+func (h *CoreHandler) withoutBlocked(ctx context.Context, messages []*roomRepository.Message, viewerID *uuid.UUID) []*roomRepository.Message {
+	if len(messages) == 0 {
+		return messages
+	}
+	blocked := h.blockedAuthors(ctx, viewerID)
+	if blocked == nil {
+		return messages
+	}
+	visible := make([]*roomRepository.Message, 0, len(messages))
+	for _, message := range messages {
+		if !isBlockedAuthor(blocked, message.UserID) {
+			visible = append(visible, message)
+		}
+	}
+	return visible
+}
+
 func (h *CoreHandler) mapNotifications(items []roomRepository.Notification) []model.NotificationRes {
 	response := make([]model.NotificationRes, 0, len(items))
 	for _, item := range items {
@@ -917,51 +1087,3 @@ func defaultString(value, fallback string) string {
 	}
 	return value
 }
-
-func (h *CoreHandler) AddReaction(w http.ResponseWriter, r *http.Request) {
-	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
-	if !ok {
-		util.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
-		return
-	}
-
-	var req model.RequestAddReaction
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON")
-		return
-	}
-
-	if !constants.IsValidReactionEmoji(req.Emoji) {
-		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid emoji")
-		return
-	}
-
-	reaction := &model.MessageReaction{
-		MessageID: req.MessageID,
-		UserID:    userID,
-		Emoji:     req.Emoji,
-	}
-
-	if err := h.roomRepository.AddReaction(r.Context(), reaction); err != nil {
-		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to add reaction")
-		return
-	}
-
-	util.WriteJSONResponse(w, http.StatusCreated, reaction)
-}
-
-func (h *CoreHandler) GetReactions(w http.ResponseWriter, r *http.Request) {
-	messageID := chi.URLParam(r, "messageID")
-	if messageID == "" {
-		util.WriteErrorResponse(w, http.StatusBadRequest, "Message ID is required")
-		return
-	}
-
-	reactions, err := h.roomRepository.GetReactions(r.Context(), messageID)
-	if err != nil {
-		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch reactions")
-		return
-	}
-
-	util.WriteJSONResponse(w, http.StatusOK, reactions)
-}
@@ -5,13 +5,20 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/go-chi/chi/v5"
+
 	"chat-application/internal/api/model"
+	"chat-application/internal/automod"
+	"chat-application/internal/constants"
 	"chat-application/internal/middleware"
+	"chat-application/internal/pagination"
 	roomRepository "chat-application/internal/repo/room"
 	statsRepository "chat-application/internal/repo/stats"
 	websoc "chat-application/internal/websocket"
@@ -25,7 +32,21 @@ type fakeRoomRepository struct {
 	countActiveRoomsFn func(ctx context.Context) (int, error)
 	getAllActiveFn     func(ctx context.Context) ([]*roomRepository.Room, error)
 	createMessageFn    func(ctx context.Context, message *roomRepository.Message) (*roomRepository.Message, error)
-	getMessagesFn      func(ctx context.Context, roomID uuid.UUID, limit int, offset int) ([]*roomRepository.Message, error)
+	history            []*roomRepository.Message
+	notifications      []roomRepository.Notification
+	getMessageByIDFn   func(ctx context.Context, id uuid.UUID) (*roomRepository.Message, error)
+	getRoomMemberFn    func(ctx context.Context, roomID, userID uuid.UUID) (*roomRepository.RoomMember, error)
+	reactions          []model.MessageReaction
+	updatedMembers     []roomRepository.RoomMember
+	reports            []*roomRepository.MessageReport
+	removedMessages    []uuid.UUID
+	moderationLog      []roomRepository.ModerationLogEntry
+	automod            *roomRepository.AutoModSettings
+	channels           []roomRepository.RoomChannel
+	visibility         string
+	invites            []*roomRepository.RoomInvite
+	joined             []uuid.UUID
+	memberInvites      map[uuid.UUID]uuid.UUID
 }
 
 func (f *fakeRoomRepository) GetDB() *sql.DB { return nil }
@@ -50,30 +71,204 @@ func (f *fakeRoomRepository) GetAllActiveRooms(ctx context.Context) ([]*roomRepo
 	}
 	return nil, nil
 }
+
+// GetVisibleRooms filters the active rooms by visibility and membership the
+// way the real query does.
+func (f *fakeRoomRepository) GetVisibleRooms(ctx context.Context, viewerID *uuid.UUID) ([]*roomRepository.Room, error) {
+	rooms, err := f.GetAllActiveRooms(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var visible []*roomRepository.Room
+	for _, room := range rooms {
+		if room.Visibility == "" || room.Visibility == constants.RoomVisibilityPublic {
+			visible = append(visible, room)
+			continue
+		}
+		if viewerID == nil {
+			continue
+		}
+		if member, _ := f.GetRoomMember(ctx, room.ID, *viewerID); member != nil && member.BannedAt == nil {
+			visible = append(visible, room)
+		}
+	}
+	return visible, nil
+}
+func (f *fakeRoomRepository) SetRoomVisibility(ctx context.Context, roomID uuid.UUID, visibility string) (bool, error) {
+	f.visibility = visibility
+	return true, nil
+}
+func (f *fakeRoomRepository) CreateRoomInvite(ctx context.Context, invite *roomRepository.RoomInvite) (*roomRepository.RoomInvite, error) {
+	invite.ID = uuid.New()
+	invite.CreatedAt = time.Now()
+	f.invites = append(f.invites, invite)
+	return invite, nil
+}
+func (f *fakeRoomRepository) GetRoomInvites(ctx context.Context, roomID uuid.UUID) ([]roomRepository.RoomInvite, error) {
+	var invites []roomRepository.RoomInvite
+	for _, invite := range f.invites {
+		if invite.RoomID == roomID {
+			invites = append(invites, *invite)
+		}
+	}
+	return invites, nil
+}
+func (f *fakeRoomRepository) GetRoomInviteByCode(ctx context.Context, code string) (*roomRepository.RoomInvite, error) {
+	for _, invite := range f.invites {
+		if invite.Code == code {
+			return invite, nil
+		}
+	}
+	return nil, nil
+}
+func (f *fakeRoomRepository) RevokeRoomInvite(ctx context.Context, roomID, inviteID uuid.UUID) (bool, error) {
+	for _, invite := range f.invites {
+		if invite.ID == inviteID && invite.RoomID == roomID && invite.RevokedAt == nil {
+			now := time.Now()
+			invite.RevokedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+func (f *fakeRoomRepository) UseRoomInvite(ctx context.Context, inviteID uuid.UUID) (bool, error) {
+	for _, invite := range f.invites {
+		if invite.ID == inviteID && invite.Usable(time.Now()) {
+			invite.Uses++
+			return true, nil
+		}
+	}
+	return false, nil
+}
+func (f *fakeRoomRepository) SetMemberInvite(ctx context.Context, roomID, userID uuid.UUID, invite *roomRepository.RoomInvite) error {
+	if f.memberInvites == nil {
+		f.memberInvites = make(map[uuid.UUID]uuid.UUID)
+	}
+	f.memberInvites[userID] = invite.ID
+	return nil
+}
 func (f *fakeRoomRepository) CreateMessage(ctx context.Context, message *roomRepository.Message) (*roomRepository.Message, error) {
 	if f.createMessageFn != nil {
 		return f.createMessageFn(ctx, message)
 	}
 	return message, nil
 }
-func (f *fakeRoomRepository) GetRoomMessages(ctx context.Context, roomID uuid.UUID, limit int, offset int) ([]*roomRepository.Message, error) {
-	if f.getMessagesFn != nil {
-		return f.getMessagesFn(ctx, roomID, limit, offset)
+func (f *fakeRoomRepository) MergeMessageMetadata(ctx context.Context, messageID uuid.UUID, patch []byte) error {
+	return nil
+}
+
+// GetMessagesBefore and GetMessagesAfter page through history, which tests keep
+// in chronological order.
+func (f *fakeRoomRepository) GetMessagesBefore(ctx context.Context, roomID uuid.UUID, channelID *uuid.UUID, before *pagination.Cursor, limit int) ([]*roomRepository.Message, error) {
+	var messages []*roomRepository.Message
+	for i := len(f.history) - 1; i >= 0 && len(messages) < limit; i-- {
+		msg := f.history[i]
+		if before != nil && !cursorLess(msg, *before) {
+			continue
+		}
+		messages = append([]*roomRepository.Message{msg}, messages...)
 	}
-	return nil, nil
+	return messages, nil
+}
+func (f *fakeRoomRepository) GetMessagesAfter(ctx context.Context, roomID uuid.UUID, channelID *uuid.UUID, after pagination.Cursor, limit int) ([]*roomRepository.Message, error) {
+	var messages []*roomRepository.Message
+	for _, msg := range f.history {
+		if len(messages) == limit {
+			break
+		}
+		if cursorLess(msg, after) || (msg.CreatedAt.Equal(after.CreatedAt) && msg.ID == after.ID) {
+			continue
+		}
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
+func cursorLess(msg *roomRepository.Message, cursor pagination.Cursor) bool {
+	if !msg.CreatedAt.Equal(cursor.CreatedAt) {
+		return msg.CreatedAt.Before(cursor.CreatedAt)
+	}
+	return msg.ID.String() < cursor.ID.String()
 }
 func (f *fakeRoomRepository) CountPinnedRooms(ctx context.Context) (int, error)   { return 0, nil }
 func (f *fakeRoomRepository) DeleteExpiredRooms(ctx context.Context) (int, error) { return 0, nil }
 func (f *fakeRoomRepository) EnsureRoomMembership(ctx context.Context, roomID, userID uuid.UUID) error {
+	f.joined = append(f.joined, userID)
 	return nil
 }
 func (f *fakeRoomRepository) GetRoomMember(ctx context.Context, roomID, userID uuid.UUID) (*roomRepository.RoomMember, error) {
+	if f.getRoomMemberFn != nil {
+		return f.getRoomMemberFn(ctx, roomID, userID)
+	}
 	return nil, nil
 }
 func (f *fakeRoomRepository) GetRoomMembers(ctx context.Context, roomID uuid.UUID) ([]roomRepository.RoomMember, error) {
 	return nil, nil
 }
 func (f *fakeRoomRepository) UpdateRoomMember(ctx context.Context, member roomRepository.RoomMember) error {
+	f.updatedMembers = append(f.updatedMembers, member)
+	return nil
+}
+func (f *fakeRoomRepository) CreateMessageReport(ctx context.Context, report *roomRepository.MessageReport) (bool, error) {
+	for _, existing := range f.reports {
+		if *existing.MessageID == *report.MessageID && *existing.ReporterID == *report.ReporterID {
+			return false, nil
+		}
+	}
+	report.ID = uuid.New()
+	report.Status = constants.ReportStatusOpen
+	report.CreatedAt = time.Now()
+	f.reports = append(f.reports, report)
+	return true, nil
+}
+func (f *fakeRoomRepository) GetMessageReport(ctx context.Context, id uuid.UUID) (*roomRepository.MessageReport, error) {
+	for _, report := range f.reports {
+		if report.ID == id {
+			copied := *report
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+func (f *fakeRoomRepository) GetMessageReports(ctx context.Context, roomID uuid.UUID, status string, after *pagination.Cursor, limit int) ([]roomRepository.MessageReport, error) {
+	var reports []roomRepository.MessageReport
+	for _, report := range f.reports {
+		if report.RoomID == roomID && report.Status == status && len(reports) < limit {
+			reports = append(reports, *report)
+		}
+	}
+	return reports, nil
+}
+func (f *fakeRoomRepository) ResolveMessageReports(ctx context.Context, report *roomRepository.MessageReport, resolution string, resolvedBy uuid.UUID) (int64, error) {
+	var resolved int64
+	for _, existing := range f.reports {
+		if existing.Status == constants.ReportStatusOpen && (existing.ID == report.ID || *existing.MessageID == *report.MessageID) {
+			existing.Status = constants.ReportStatusResolved
+			existing.Resolution = &resolution
+			existing.ResolvedBy = &resolvedBy
+			resolved++
+		}
+	}
+	return resolved, nil
+}
+func (f *fakeRoomRepository) RemoveMessageContent(ctx context.Context, messageID uuid.UUID) (bool, error) {
+	f.removedMessages = append(f.removedMessages, messageID)
+	return true, nil
+}
+func (f *fakeRoomRepository) CreateModerationLogEntry(ctx context.Context, entry *roomRepository.ModerationLogEntry) error {
+	entry.ID = uuid.New()
+	entry.CreatedAt = time.Now()
+	f.moderationLog = append(f.moderationLog, *entry)
+	return nil
+}
+func (f *fakeRoomRepository) GetModerationLog(ctx context.Context, roomID uuid.UUID, before *pagination.Cursor, limit int) ([]roomRepository.ModerationLogEntry, error) {
+	return f.moderationLog, nil
+}
+func (f *fakeRoomRepository) GetAutoModSettings(ctx context.Context, roomID uuid.UUID) (*roomRepository.AutoModSettings, error) {
+	return f.automod, nil
+}
+func (f *fakeRoomRepository) SaveAutoModSettings(ctx context.Context, settings *roomRepository.AutoModSettings) error {
+	settings.UpdatedAt = time.Now()
+	f.automod = settings
 	return nil
 }
 func (f *fakeRoomRepository) CreateCategory(ctx context.Context, category *roomRepository.RoomCategory) (*roomRepository.RoomCategory, error) {
@@ -88,11 +283,26 @@ func (f *fakeRoomRepository) GetRoomCategories(ctx context.Context, roomID uuid.
 func (f *fakeRoomRepository) GetRoomChannels(ctx context.Context, roomID uuid.UUID) ([]roomRepository.RoomChannel, error) {
 	return nil, nil
 }
-func (f *fakeRoomRepository) GetDefaultChannel(ctx context.Context, roomID uuid.UUID) (*roomRepository.RoomChannel, error) {
+func (f *fakeRoomRepository) GetRoomChannel(ctx context.Context, roomID, channelID uuid.UUID) (*roomRepository.RoomChannel, error) {
+	for i := range f.channels {
+		if f.channels[i].RoomID == roomID && f.channels[i].ID == channelID {
+			channel := f.channels[i]
+			return &channel, nil
+		}
+	}
 	return nil, nil
 }
-func (f *fakeRoomRepository) GetRoomMessagesByChannel(ctx context.Context, roomID uuid.UUID, channelID *uuid.UUID, limit int, offset int) ([]*roomRepository.Message, error) {
-	return f.GetRoomMessages(ctx, roomID, limit, offset)
+func (f *fakeRoomRepository) SetChannelSlowMode(ctx context.Context, roomID, channelID uuid.UUID, seconds int) (bool, error) {
+	for i := range f.channels {
+		if f.channels[i].RoomID == roomID && f.channels[i].ID == channelID {
+			f.channels[i].SlowModeSeconds = seconds
+			return true, nil
+		}
+	}
+	return false, nil
+}
+func (f *fakeRoomRepository) GetDefaultChannel(ctx context.Context, roomID uuid.UUID) (*roomRepository.RoomChannel, error) {
+	return nil, nil
 }
 func (f *fakeRoomRepository) SearchMessages(ctx context.Context, roomID uuid.UUID, queryText string, channelID *uuid.UUID, username string, limit int) ([]roomRepository.Message, error) {
 	return nil, nil
@@ -100,13 +310,71 @@ func (f *fakeRoomRepository) SearchMessages(ctx context.Context, roomID uuid.UUI
 func (f *fakeRoomRepository) CreateNotification(ctx context.Context, notification *roomRepository.Notification) error {
 	return nil
 }
-func (f *fakeRoomRepository) GetNotifications(ctx context.Context, userID uuid.UUID, limit int) ([]roomRepository.Notification, error) {
+
+// Notifications are kept newest first, as the repository returns them.
+func (f *fakeRoomRepository) GetNotifications(ctx context.Context, userID uuid.UUID, before *pagination.Cursor, unreadOnly bool, limit int) ([]roomRepository.Notification, error) {
+	var notifications []roomRepository.Notification
+	for _, notification := range f.notifications {
+		if len(notifications) == limit {
+			break
+		}
+		if notification.UserID != userID || (unreadOnly && notification.IsRead) {
+			continue
+		}
+		if before != nil && !notification.CreatedAt.Before(before.CreatedAt) {
+			continue
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications, nil
+}
+func (f *fakeRoomRepository) MarkAllNotificationsRead(ctx context.Context, userID uuid.UUID, roomID *uuid.UUID) (int64, error) {
+	var updated int64
+	for i := range f.notifications {
+		if f.notifications[i].UserID == userID && !f.notifications[i].IsRead {
+			f.notifications[i].IsRead = true
+			updated++
+		}
+	}
+	return updated, nil
+}
+func (f *fakeRoomRepository) CountUnreadNotifications(ctx context.Context, userID uuid.UUID) (int, error) {
+	count := 0
+	for _, notification := range f.notifications {
+		if notification.UserID == userID && !notification.IsRead {
+			count++
+		}
+	}
+	return count, nil
+}
+func (f *fakeRoomRepository) DeleteNotificationsBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	return 0, nil
+}
+func (f *fakeRoomRepository) GetNotificationPreferences(ctx context.Context, userID uuid.UUID) ([]roomRepository.NotificationPreference, error) {
 	return nil, nil
 }
+func (f *fakeRoomRepository) SetNotificationPreference(ctx context.Context, preference *roomRepository.NotificationPreference) error {
+	return nil
+}
+func (f *fakeRoomRepository) DeleteNotificationPreference(ctx context.Context, userID uuid.UUID, roomID *uuid.UUID) error {
+	return nil
+}
 func (f *fakeRoomRepository) MarkNotificationRead(ctx context.Context, notificationID, userID uuid.UUID) error {
 	return nil
 }
-func (f *fakeRoomRepository) CreateMentionNotifications(ctx context.Context, roomID uuid.UUID, message *roomRepository.Message) ([]roomRepository.Notification, error) {
+func (f *fakeRoomRepository) CreateNotificationIfAccepted(ctx context.Context, notification *roomRepository.Notification) (bool, error) {
+	return false, nil
+}
+func (f *fakeRoomRepository) GetPendingDigests(ctx context.Context, cutoff time.Time, perUser int) ([]roomRepository.DigestRecipient, error) {
+	return nil, nil
+}
+func (f *fakeRoomRepository) MarkNotificationsEmailed(ctx context.Context, userID uuid.UUID, cutoff time.Time) error {
+	return nil
+}
+func (f *fakeRoomRepository) SetEmailDigest(ctx context.Context, userID uuid.UUID, enabled bool) error {
+	return nil
+}
+func (f *fakeRoomRepository) CreateMentionNotifications(ctx context.Context, roomID uuid.UUID, message *roomRepository.Message, groups *roomRepository.MentionGroups) ([]roomRepository.Notification, error) {
 	return nil, nil
 }
 func (f *fakeRoomRepository) AddReaction(ctx context.Context, reaction *model.MessageReaction) error {
@@ -115,6 +383,87 @@ func (f *fakeRoomRepository) AddReaction(ctx context.Context, reaction *model.Me
 func (f *fakeRoomRepository) GetReactions(ctx context.Context, messageID string) ([]model.MessageReaction, error) {
 	return nil, nil
 }
+func (f *fakeRoomRepository) ToggleReaction(ctx context.Context, reaction *model.MessageReaction) (bool, error) {
+	for i, existing := range f.reactions {
+		if existing.MessageID == reaction.MessageID && existing.UserID == reaction.UserID && existing.Emoji == reaction.Emoji {
+			f.reactions = append(f.reactions[:i], f.reactions[i+1:]...)
+			return false, nil
+		}
+	}
+	f.reactions = append(f.reactions, *reaction)
+	return true, nil
+}
+func (f *fakeRoomRepository) RemoveReaction(ctx context.Context, messageID, userID uuid.UUID, emoji string) (bool, error) {
+	for i, existing := range f.reactions {
+		if existing.MessageID == messageID.String() && existing.UserID == userID.String() && existing.Emoji == emoji {
+			f.reactions = append(f.reactions[:i], f.reactions[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+func (f *fakeRoomRepository) GetReactionCounts(ctx context.Context, messageIDs []uuid.UUID, viewerID *uuid.UUID) (map[uuid.UUID][]model.ReactionCountRes, error) {
+	counts := map[uuid.UUID][]model.ReactionCountRes{}
+	for _, reaction := range f.reactions {
+		messageID := uuid.MustParse(reaction.MessageID)
+		found := false
+		for i := range counts[messageID] {
+			if counts[messageID][i].Emoji == reaction.Emoji {
+				counts[messageID][i].Count++
+				found = true
+			}
+		}
+		if !found {
+			counts[messageID] = append(counts[messageID], model.ReactionCountRes{Emoji: reaction.Emoji, Count: 1})
+		}
+	}
+	return counts, nil
+}
+func (f *fakeRoomRepository) GetMessageByID(ctx context.Context, id uuid.UUID) (*roomRepository.Message, error) {
+	if f.getMessageByIDFn != nil {
+		return f.getMessageByIDFn(ctx, id)
+	}
+	return nil, nil
+}
+func (f *fakeRoomRepository) GetThreadReplies(ctx context.Context, parentID uuid.UUID, after *pagination.Cursor, limit int) ([]*roomRepository.Message, error) {
+	return nil, nil
+}
+func (f *fakeRoomRepository) RecordThreadReply(ctx context.Context, parentID uuid.UUID, repliedAt time.Time) (*roomRepository.ThreadSummary, error) {
+	return &roomRepository.ThreadSummary{MessageID: parentID}, nil
+}
+func (f *fakeRoomRepository) FollowThread(ctx context.Context, messageID, userID uuid.UUID) error {
+	return nil
+}
+func (f *fakeRoomRepository) UnfollowThread(ctx context.Context, messageID, userID uuid.UUID) error {
+	return nil
+}
+func (f *fakeRoomRepository) IsFollowingThread(ctx context.Context, messageID, userID uuid.UUID) (bool, error) {
+	return false, nil
+}
+func (f *fakeRoomRepository) CreateThreadReplyNotifications(ctx context.Context, parent *roomRepository.Message, reply *roomRepository.Message, exclude []uuid.UUID) ([]roomRepository.Notification, error) {
+	return nil, nil
+}
+func (f *fakeRoomRepository) MarkChannelRead(ctx context.Context, userID uuid.UUID, message *roomRepository.Message) (bool, error) {
+	return true, nil
+}
+func (f *fakeRoomRepository) GetChannelReadStates(ctx context.Context, userID uuid.UUID, roomIDs []uuid.UUID) ([]roomRepository.ChannelReadState, error) {
+	return nil, nil
+}
+func (f *fakeRoomRepository) GetMessageReadReceipts(ctx context.Context, message *roomRepository.Message) ([]roomRepository.ReadReceipt, error) {
+	return nil, nil
+}
+func (f *fakeRoomRepository) CreateRoomEmoji(ctx context.Context, emoji *roomRepository.RoomEmoji) (*roomRepository.RoomEmoji, error) {
+	return emoji, nil
+}
+func (f *fakeRoomRepository) GetRoomEmojis(ctx context.Context, roomID uuid.UUID) ([]roomRepository.RoomEmoji, error) {
+	return nil, nil
+}
+func (f *fakeRoomRepository) GetRoomEmoji(ctx context.Context, roomID uuid.UUID, name string) (*roomRepository.RoomEmoji, error) {
+	return nil, nil
+}
+func (f *fakeRoomRepository) DeleteRoomEmoji(ctx context.Context, roomID uuid.UUID, name string) (bool, error) {
+	return false, nil
+}
 
 type fakeStatsRepository struct{}
 
@@ -151,6 +500,9 @@ func (f *fakeStatsRepository) IncrementMessageCount(ctx context.Context, userID
 func (f *fakeStatsRepository) GetLeaderboard(ctx context.Context, limit int) ([]statsRepository.LeaderboardEntry, error) {
 	return nil, nil
 }
+func (f *fakeStatsRepository) GetUsername(ctx context.Context, userID uuid.UUID) (string, error) {
+	return "", nil
+}
 
 func TestCreateRoomSuccess(t *testing.T) {
 	roomID := uuid.New()
@@ -237,3 +589,711 @@ func TestGetRoomsReturnsParticipantCounts(t *testing.T) {
 		t.Fatalf("expected 2 participants, got %d", rooms[0].Participants)
 	}
 }
+
+func TestToggleReactionAddsRemovesAndBroadcasts(t *testing.T) {
+	roomID := uuid.New()
+	messageID := uuid.New()
+	userID := uuid.New()
+
+	repo := &fakeRoomRepository{
+		getMessageByIDFn: func(ctx context.Context, id uuid.UUID) (*roomRepository.Message, error) {
+			if id != messageID {
+				return nil, nil
+			}
+			return &roomRepository.Message{ID: messageID, RoomID: roomID}, nil
+		},
+		getRoomMemberFn: func(ctx context.Context, gotRoomID, gotUserID uuid.UUID) (*roomRepository.RoomMember, error) {
+			if gotUserID != userID {
+				return nil, nil
+			}
+			return &roomRepository.RoomMember{RoomID: roomID, UserID: userID, Username: "alice"}, nil
+		},
+	}
+	core := websoc.NewCoreWithDependencies(nil, repo, &fakeStatsRepository{})
+	handler := NewCoreHandlerWithRoomRepository(core, repo)
+
+	toggle := func(asUser uuid.UUID, emoji string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(model.RequestAddReaction{MessageID: messageID.String(), Emoji: emoji})
+		req := httptest.NewRequest(http.MethodPost, "/api/websoc/reactions", bytes.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, asUser.String()))
+		rec := httptest.NewRecorder()
+		handler.ToggleReaction(rec, req)
+		return rec
+	}
+
+	rec := toggle(userID, "👍")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var response model.ReactionToggleRes
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !response.Added || response.Count != 1 {
+		t.Fatalf("expected reaction to be added with count 1, got %+v", response)
+	}
+	event := <-core.Broadcast
+	if event.Type != "reaction.added" || event.Reaction == nil || event.Reaction.RoomID != roomID.String() || event.Reaction.Username != "alice" {
+		t.Fatalf("unexpected broadcast %+v", event)
+	}
+
+	rec = toggle(userID, "👍")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d on second toggle, got %d", http.StatusOK, rec.Code)
+	}
+	if event := <-core.Broadcast; event.Type != "reaction.removed" || event.Reaction.Count != 0 {
+		t.Fatalf("expected reaction.removed with count 0, got %+v", event)
+	}
+
+	if rec := toggle(userID, "🦄"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected unknown emoji to be rejected, got %d", rec.Code)
+	}
+	if rec := toggle(uuid.New(), "👍"); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected non-member to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestGetMessagesPagesWithCursors(t *testing.T) {
+	roomID := uuid.New()
+	start := time.Now().Add(-time.Hour)
+	var history []*roomRepository.Message
+	for i := 0; i < 7; i++ {
+		history = append(history, &roomRepository.Message{
+			ID:        uuid.New(),
+			RoomID:    roomID,
+			Content:   fmt.Sprintf("message %d", i),
+			CreatedAt: start.Add(time.Duration(i) * time.Minute),
+		})
+	}
+
+	repo := &fakeRoomRepository{
+		history: history,
+		getRoomByIDFn: func(ctx context.Context, id uuid.UUID) (*roomRepository.Room, error) {
+			return &roomRepository.Room{ID: roomID}, nil
+		},
+		getMessageByIDFn: func(ctx context.Context, id uuid.UUID) (*roomRepository.Message, error) {
+			for _, msg := range history {
+				if msg.ID == id {
+					return msg, nil
+				}
+			}
+			return nil, nil
+		},
+	}
+	core := websoc.NewCoreWithDependencies(nil, repo, &fakeStatsRepository{})
+	handler := NewCoreHandlerWithRoomRepository(core, repo)
+
+	page := func(query string) model.MessagePageRes {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/websoc/rooms/"+roomID.String()+"/messages?limit=3&"+query, nil)
+		routeContext := chi.NewRouteContext()
+		routeContext.URLParams.Add("roomId", roomID.String())
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeContext))
+		rec := httptest.NewRecorder()
+		handler.GetMessages(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d: %s", query, http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var response model.MessagePageRes
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return response
+	}
+	contents := func(res model.MessagePageRes) string {
+		var parts []string
+		for _, msg := range res.Messages {
+			parts = append(parts, strings.TrimPrefix(msg.Content, "message "))
+		}
+		return strings.Join(parts, ",")
+	}
+
+	latest := page("")
+	if contents(latest) != "4,5,6" || latest.PrevCursor == "" || latest.NextCursor != "" {
+		t.Fatalf("unexpected latest page %q prev=%q next=%q", contents(latest), latest.PrevCursor, latest.NextCursor)
+	}
+
+	older := page("before=" + latest.PrevCursor)
+	if contents(older) != "1,2,3" || older.PrevCursor == "" || older.NextCursor == "" {
+		t.Fatalf("unexpected older page %q", contents(older))
+	}
+
+	oldest := page("before=" + older.PrevCursor)
+	if contents(oldest) != "0" || oldest.PrevCursor != "" {
+		t.Fatalf("unexpected oldest page %q prev=%q", contents(oldest), oldest.PrevCursor)
+	}
+
+	newer := page("after=" + older.NextCursor)
+	if contents(newer) != "4,5,6" || newer.NextCursor != "" || newer.PrevCursor == "" {
+		t.Fatalf("unexpected newer page %q next=%q", contents(newer), newer.NextCursor)
+	}
+
+	target := pagination.Cursor{CreatedAt: history[3].CreatedAt, ID: history[3].ID}.Encode()
+	around := page("around=" + target)
+	if contents(around) != "2,3,4" || around.PrevCursor == "" || around.NextCursor == "" {
+		t.Fatalf("unexpected page around message 3 %q", contents(around))
+	}
+}
+
+func TestNotificationsPageAndMarkAllRead(t *testing.T) {
+	userID := uuid.New()
+	now := time.Now()
+	repo := &fakeRoomRepository{}
+	for i := 0; i < 3; i++ {
+		repo.notifications = append(repo.notifications, roomRepository.Notification{
+			ID:        uuid.New(),
+			UserID:    userID,
+			Kind:      "mention",
+			Title:     fmt.Sprintf("notification %d", i),
+			CreatedAt: now.Add(-time.Duration(i) * time.Minute),
+		})
+	}
+	core := websoc.NewCoreWithDependencies(nil, repo, &fakeStatsRepository{})
+	handler := NewCoreHandlerWithRoomRepository(core, repo)
+
+	serve := func(method, target string, handle http.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID.String()))
+		rec := httptest.NewRecorder()
+		handle(rec, req)
+		return rec
+	}
+
+	rec := serve(http.MethodGet, "/api/websoc/notifications?limit=2", handler.GetNotifications)
+	var firstPage []model.NotificationRes
+	if err := json.NewDecoder(rec.Body).Decode(&firstPage); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	link := rec.Header().Get("Link")
+	if len(firstPage) != 2 || !strings.HasSuffix(link, `>; rel="next"`) {
+		t.Fatalf("expected two notifications and a next link, got %d and %q", len(firstPage), link)
+	}
+
+	next := strings.TrimSuffix(strings.TrimPrefix(link, "<"), `>; rel="next"`)
+	rec = serve(http.MethodGet, next, handler.GetNotifications)
+	var secondPage []model.NotificationRes
+	if err := json.NewDecoder(rec.Body).Decode(&secondPage); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(secondPage) != 1 || secondPage[0].Title != "notification 2" || rec.Header().Get("Link") != "" {
+		t.Fatalf("expected the last notification without a next link, got %+v", secondPage)
+	}
+
+	rec = serve(http.MethodPut, "/api/websoc/notifications/read-all", handler.MarkAllNotificationsRead)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	rec = serve(http.MethodGet, "/api/websoc/notifications/unread-count", handler.GetUnreadNotificationCount)
+	var count model.NotificationCountRes
+	if err := json.NewDecoder(rec.Body).Decode(&count); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if count.UnreadCount != 0 {
+		t.Fatalf("expected no unread notifications after marking all read, got %d", count.UnreadCount)
+	}
+}
+
+func TestReportQueueBanAndModerationLog(t *testing.T) {
+	roomID := uuid.New()
+	messageID := uuid.New()
+	authorID, reporterID, otherReporterID, moderatorID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	members := map[uuid.UUID]*roomRepository.RoomMember{
+		authorID:        {RoomID: roomID, UserID: authorID, Username: "mallory", Role: "member", CanPost: true},
+		reporterID:      {RoomID: roomID, UserID: reporterID, Username: "alice", Role: "member", CanPost: true},
+		otherReporterID: {RoomID: roomID, UserID: otherReporterID, Username: "bob", Role: "member", CanPost: true},
+		moderatorID:     {RoomID: roomID, UserID: moderatorID, Username: "mod", Role: "member", CanModerate: true},
+	}
+	repo := &fakeRoomRepository{
+		getMessageByIDFn: func(ctx context.Context, id uuid.UUID) (*roomRepository.Message, error) {
+			if id != messageID {
+				return nil, nil
+			}
+			return &roomRepository.Message{ID: messageID, RoomID: roomID, UserID: &authorID, Username: "mallory", Content: "abuse"}, nil
+		},
+		getRoomMemberFn: func(ctx context.Context, gotRoomID, userID uuid.UUID) (*roomRepository.RoomMember, error) {
+			if member, ok := members[userID]; ok && gotRoomID == roomID {
+				copied := *member
+				return &copied, nil
+			}
+			return nil, nil
+		},
+	}
+	core := websoc.NewCoreWithDependencies(nil, repo, &fakeStatsRepository{})
+	handler := NewCoreHandlerWithRoomRepository(core, repo)
+
+	call := func(handle http.HandlerFunc, method string, asUser uuid.UUID, params map[string]string, body any) *httptest.ResponseRecorder {
+		encoded, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, "/", bytes.NewReader(encoded))
+		routeContext := chi.NewRouteContext()
+		routeContext.URLParams.Add("roomId", roomID.String())
+		for key, value := range params {
+			routeContext.URLParams.Add(key, value)
+		}
+		ctx := context.WithValue(req.Context(), chi.RouteCtxKey, routeContext)
+		ctx = context.WithValue(ctx, middleware.UserIDKey, asUser.String())
+		rec := httptest.NewRecorder()
+		handle(rec, req.WithContext(ctx))
+		return rec
+	}
+	report := func(asUser uuid.UUID, reason string) *httptest.ResponseRecorder {
+		return call(handler.ReportMessage, http.MethodPost, asUser, map[string]string{"messageId": messageID.String()},
+			model.RequestReportMessage{Reason: reason, Details: "keeps posting this"})
+	}
+
+	if rec := report(reporterID, "spam"); rec.Code != http.StatusCreated {
+		t.Fatalf("expected report to be created, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := report(reporterID, "spam"); rec.Code != http.StatusOK || len(repo.reports) != 1 {
+		t.Fatalf("expected a repeat report to be accepted once, got %d with %d reports", rec.Code, len(repo.reports))
+	}
+	if rec := report(otherReporterID, "harassment"); rec.Code != http.StatusCreated {
+		t.Fatalf("expected second member's report to be created, got %d", rec.Code)
+	}
+	if rec := report(authorID, "spam"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected authors not to report themselves, got %d", rec.Code)
+	}
+	if rec := report(reporterID, "boring"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected unknown reason to be rejected, got %d", rec.Code)
+	}
+
+	if rec := call(handler.GetReports, http.MethodGet, reporterID, nil, nil); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected members without can_moderate to be refused the queue, got %d", rec.Code)
+	}
+	rec := call(handler.GetReports, http.MethodGet, moderatorID, nil, nil)
+	var queue []model.MessageReportRes
+	if err := json.NewDecoder(rec.Body).Decode(&queue); err != nil {
+		t.Fatalf("failed to decode queue: %v", err)
+	}
+	if len(queue) != 2 || queue[0].MessageContent != "abuse" || queue[0].Details != "keeps posting this" {
+		t.Fatalf("unexpected queue %+v", queue)
+	}
+
+	action := func(reportID string, body model.RequestModerationAction) *httptest.ResponseRecorder {
+		return call(handler.ResolveReport, http.MethodPost, moderatorID, map[string]string{"reportId": reportID}, body)
+	}
+	if rec := action(queue[0].ID, model.RequestModerationAction{Action: "shout"}); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected unknown action to be rejected, got %d", rec.Code)
+	}
+	rec = action(queue[0].ID, model.RequestModerationAction{Action: constants.ModerationBanAuthor, Note: "repeat offender"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected ban to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var result model.ModerationActionRes
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("failed to decode action: %v", err)
+	}
+	if result.ResolvedReports != 2 {
+		t.Fatalf("expected both reports of the message to be resolved, got %d", result.ResolvedReports)
+	}
+	if len(repo.updatedMembers) != 1 || repo.updatedMembers[0].UserID != authorID || repo.updatedMembers[0].BannedAt == nil {
+		t.Fatalf("expected the author to be banned through UpdateRoomMember, got %+v", repo.updatedMembers)
+	}
+	if rec := action(queue[1].ID, model.RequestModerationAction{Action: constants.ModerationDismiss}); rec.Code != http.StatusConflict {
+		t.Fatalf("expected resolved report to be refused, got %d", rec.Code)
+	}
+
+	if len(repo.moderationLog) != 1 {
+		t.Fatalf("expected one log entry, got %+v", repo.moderationLog)
+	}
+	entry := repo.moderationLog[0]
+	if entry.Action != constants.ModerationBanAuthor || entry.ModeratorUsername != "mod" || *entry.TargetUserID != authorID || *entry.ReportID != repo.reports[0].ID {
+		t.Fatalf("unexpected log entry %+v", entry)
+	}
+	if !strings.Contains(string(entry.Details), "repeat offender") {
+		t.Fatalf("expected the note in the log details, got %s", entry.Details)
+	}
+}
+
+func TestDeleteMessageActionRemovesAndBroadcasts(t *testing.T) {
+	roomID := uuid.New()
+	messageID := uuid.New()
+	authorID, moderatorID := uuid.New(), uuid.New()
+	repo := &fakeRoomRepository{
+		getMessageByIDFn: func(ctx context.Context, id uuid.UUID) (*roomRepository.Message, error) {
+			return &roomRepository.Message{ID: messageID, RoomID: roomID, UserID: &authorID, Username: "mallory", Content: "abuse"}, nil
+		},
+		getRoomMemberFn: func(ctx context.Context, gotRoomID, userID uuid.UUID) (*roomRepository.RoomMember, error) {
+			return &roomRepository.RoomMember{RoomID: roomID, UserID: userID, Username: "mod", Role: "owner"}, nil
+		},
+		reports: []*roomRepository.MessageReport{{
+			ID: uuid.New(), RoomID: roomID, MessageID: &messageID, ReporterID: &authorID,
+			AuthorID: &authorID, AuthorUsername: "mallory", Reason: "spam", Status: constants.ReportStatusOpen,
+		}},
+	}
+	core := websoc.NewCoreWithDependencies(nil, repo, &fakeStatsRepository{})
+	handler := NewCoreHandlerWithRoomRepository(core, repo)
+
+	body, _ := json.Marshal(model.RequestModerationAction{Action: constants.ModerationDeleteMessage})
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	routeContext := chi.NewRouteContext()
+	routeContext.URLParams.Add("roomId", roomID.String())
+	routeContext.URLParams.Add("reportId", repo.reports[0].ID.String())
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, routeContext)
+	ctx = context.WithValue(ctx, middleware.UserIDKey, moderatorID.String())
+	rec := httptest.NewRecorder()
+	handler.ResolveReport(rec, req.WithContext(ctx))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected delete to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(repo.removedMessages) != 1 || repo.removedMessages[0] != messageID {
+		t.Fatalf("expected the message to be removed, got %v", repo.removedMessages)
+	}
+	event := <-core.Broadcast
+	if event.Type != "message.updated" || event.Message.ID != messageID.String() || event.Message.Content != "" || event.Message.Metadata["removed"] != true {
+		t.Fatalf("unexpected broadcast %+v", event.Message)
+	}
+}
+
+func TestAutoModRulesAreManagedByRoomManagers(t *testing.T) {
+	roomID := uuid.New()
+	managerID, memberID := uuid.New(), uuid.New()
+	members := map[uuid.UUID]*roomRepository.RoomMember{
+		managerID: {RoomID: roomID, UserID: managerID, Username: "owner", Role: "owner", CanManageRoom: true},
+		memberID:  {RoomID: roomID, UserID: memberID, Username: "member", Role: "member", CanPost: true},
+	}
+	repo := &fakeRoomRepository{
+		getRoomMemberFn: func(ctx context.Context, gotRoomID, userID uuid.UUID) (*roomRepository.RoomMember, error) {
+			if member, ok := members[userID]; ok && gotRoomID == roomID {
+				copied := *member
+				return &copied, nil
+			}
+			return nil, nil
+		},
+	}
+	handler := NewCoreHandlerWithRoomRepository(websoc.NewCoreWithDependencies(nil, repo, &fakeStatsRepository{}), repo)
+
+	call := func(handle http.HandlerFunc, method string, asUser uuid.UUID, body any) *httptest.ResponseRecorder {
+		encoded, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, "/", bytes.NewReader(encoded))
+		routeContext := chi.NewRouteContext()
+		routeContext.URLParams.Add("roomId", roomID.String())
+		ctx := context.WithValue(req.Context(), chi.RouteCtxKey, routeContext)
+		ctx = context.WithValue(ctx, middleware.UserIDKey, asUser.String())
+		rec := httptest.NewRecorder()
+		handle(rec, req.WithContext(ctx))
+		return rec
+	}
+
+	rec := call(handler.GetAutoModRules, http.MethodGet, managerID, nil)
+	var initial model.AutoModRes
+	if err := json.NewDecoder(rec.Body).Decode(&initial); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("expected default rules, got %d: %v", rec.Code, err)
+	}
+	if initial.Rules.Enabled || initial.UpdatedAt != nil || initial.Rules.BlockedWords.Words == nil {
+		t.Fatalf("expected disabled rules with empty lists, got %+v", initial)
+	}
+
+	rules := automod.Rules{Enabled: true, BlockedWords: automod.WordFilter{Words: []string{" Scam* ", "scam*"}, Action: automod.ActionFlag}}
+	if rec := call(handler.UpdateAutoModRules, http.MethodPut, memberID, rules); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected members to be refused, got %d", rec.Code)
+	}
+	invalid := automod.Rules{Enabled: true, Caps: automod.CapsFilter{MaxRatio: 2}}
+	if rec := call(handler.UpdateAutoModRules, http.MethodPut, managerID, invalid); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected invalid rules to be rejected, got %d", rec.Code)
+	}
+
+	rec = call(handler.UpdateAutoModRules, http.MethodPut, managerID, rules)
+	var saved model.AutoModRes
+	if err := json.NewDecoder(rec.Body).Decode(&saved); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("expected rules to be saved, got %d: %v", rec.Code, err)
+	}
+	if len(saved.Rules.BlockedWords.Words) != 1 || saved.Rules.BlockedWords.Words[0] != "scam*" || saved.UpdatedBy != managerID.String() {
+		t.Fatalf("expected normalized rules, got %+v", saved)
+	}
+	if len(repo.moderationLog) != 1 || repo.moderationLog[0].Action != constants.ModerationUpdateAutoMod {
+		t.Fatalf("expected the change to be logged, got %+v", repo.moderationLog)
+	}
+}
+
+func TestSetSlowModeRequiresModeratorAndLogs(t *testing.T) {
+	roomID, channelID := uuid.New(), uuid.New()
+	moderatorID, memberID := uuid.New(), uuid.New()
+	members := map[uuid.UUID]*roomRepository.RoomMember{
+		moderatorID: {RoomID: roomID, UserID: moderatorID, Username: "mod", Role: "member", CanModerate: true},
+		memberID:    {RoomID: roomID, UserID: memberID, Username: "member", Role: "member", CanPost: true},
+	}
+	repo := &fakeRoomRepository{
+		channels: []roomRepository.RoomChannel{{ID: channelID, RoomID: roomID}},
+		getRoomMemberFn: func(ctx context.Context, gotRoomID, userID uuid.UUID) (*roomRepository.RoomMember, error) {
+			if member, ok := members[userID]; ok && gotRoomID == roomID {
+				copied := *member
+				return &copied, nil
+			}
+			return nil, nil
+		},
+	}
+	handler := NewCoreHandlerWithRoomRepository(websoc.NewCoreWithDependencies(nil, repo, &fakeStatsRepository{}), repo)
+
+	call := func(asUser uuid.UUID, channel string, seconds int) int {
+		encoded, _ := json.Marshal(model.RequestSlowMode{Seconds: seconds})
+		req := httptest.NewRequest(http.MethodPut, "/", bytes.NewReader(encoded))
+		routeContext := chi.NewRouteContext()
+		routeContext.URLParams.Add("roomId", roomID.String())
+		routeContext.URLParams.Add("channelId", channel)
+		ctx := context.WithValue(req.Context(), chi.RouteCtxKey, routeContext)
+		ctx = context.WithValue(ctx, middleware.UserIDKey, asUser.String())
+		rec := httptest.NewRecorder()
+		handler.SetSlowMode(rec, req.WithContext(ctx))
+		return rec.Code
+	}
+
+	if code := call(memberID, channelID.String(), 30); code != http.StatusForbidden {
+		t.Fatalf("expected members to be refused, got %d", code)
+	}
+	if code := call(moderatorID, channelID.String(), constants.MaxSlowModeSeconds+1); code != http.StatusBadRequest {
+		t.Fatalf("expected an out of range interval to be rejected, got %d", code)
+	}
+	if code := call(moderatorID, uuid.New().String(), 30); code != http.StatusNotFound {
+		t.Fatalf("expected an unknown channel to be rejected, got %d", code)
+	}
+	if code := call(moderatorID, channelID.String(), 30); code != http.StatusOK {
+		t.Fatalf("expected slow mode to be set, got %d", code)
+	}
+	if repo.channels[0].SlowModeSeconds != 30 {
+		t.Fatalf("expected slow mode to be stored, got %+v", repo.channels[0])
+	}
+	if len(repo.moderationLog) != 1 || repo.moderationLog[0].Action != constants.ModerationSlowMode {
+		t.Fatalf("expected the change to be logged, got %+v", repo.moderationLog)
+	}
+}
+
+func TestInvitesAdmitMembersToInviteOnlyRooms(t *testing.T) {
+	roomID := uuid.New()
+	managerID, outsiderID, latecomerID := uuid.New(), uuid.New(), uuid.New()
+	room := &roomRepository.Room{ID: roomID, Name: "secret", ExpiresAt: time.Now().Add(time.Hour), Visibility: constants.RoomVisibilityInviteOnly}
+	repo := &fakeRoomRepository{
+		getRoomByIDFn: func(ctx context.Context, id uuid.UUID) (*roomRepository.Room, error) {
+			if id == roomID {
+				return room, nil
+			}
+			return nil, nil
+		},
+	}
+	repo.getRoomMemberFn = func(ctx context.Context, gotRoomID, userID uuid.UUID) (*roomRepository.RoomMember, error) {
+		if userID == managerID {
+			return &roomRepository.RoomMember{RoomID: roomID, UserID: managerID, Username: "owner", Role: "owner", CanManageRoom: true}, nil
+		}
+		for _, joined := range repo.joined {
+			if joined == userID {
+				return &roomRepository.RoomMember{RoomID: roomID, UserID: userID, Username: "member", Role: "member", CanPost: true}, nil
+			}
+		}
+		return nil, nil
+	}
+	handler := NewCoreHandlerWithRoomRepository(websoc.NewCoreWithDependencies(nil, repo, &fakeStatsRepository{}), repo)
+
+	call := func(handle http.HandlerFunc, method string, asUser *uuid.UUID, params map[string]string, body any) *httptest.ResponseRecorder {
+		encoded, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, "/", bytes.NewReader(encoded))
+		routeContext := chi.NewRouteContext()
+		routeContext.URLParams.Add("roomId", roomID.String())
+		for key, value := range params {
+			routeContext.URLParams.Add(key, value)
+		}
+		ctx := context.WithValue(req.Context(), chi.RouteCtxKey, routeContext)
+		if asUser != nil {
+			ctx = context.WithValue(ctx, middleware.UserIDKey, asUser.String())
+		}
+		rec := httptest.NewRecorder()
+		handle(rec, req.WithContext(ctx))
+		return rec
+	}
+
+	if rec := call(handler.GetRoomDetail, http.MethodGet, nil, nil, nil); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected guests to be refused, got %d", rec.Code)
+	}
+	if rec := call(handler.GetRoomDetail, http.MethodGet, &outsiderID, nil, nil); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected non-members to be refused, got %d", rec.Code)
+	}
+	if rec := call(handler.CreateInvite, http.MethodPost, &outsiderID, nil, model.RequestCreateInvite{}); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected non-managers to be refused invites, got %d", rec.Code)
+	}
+	tooMany := constants.MaxInviteUses + 1
+	if rec := call(handler.CreateInvite, http.MethodPost, &managerID, nil, model.RequestCreateInvite{MaxUses: &tooMany}); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected max uses to be capped, got %d", rec.Code)
+	}
+
+	oneUse := 1
+	rec := call(handler.CreateInvite, http.MethodPost, &managerID, nil, model.RequestCreateInvite{MaxUses: &oneUse})
+	var invite model.RoomInviteRes
+	if err := json.NewDecoder(rec.Body).Decode(&invite); err != nil || rec.Code != http.StatusCreated {
+		t.Fatalf("expected the invite to be created, got %d: %v", rec.Code, err)
+	}
+	if invite.Code == "" || !invite.Active || invite.ExpiresAt == nil || invite.CreatedByUsername != "owner" {
+		t.Fatalf("unexpected invite %+v", invite)
+	}
+
+	redeem := map[string]string{"code": invite.Code}
+	if rec := call(handler.RedeemInvite, http.MethodPost, &outsiderID, redeem, nil); rec.Code != http.StatusOK {
+		t.Fatalf("expected the invite to be redeemed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if repo.memberInvites[outsiderID].String() != invite.ID {
+		t.Fatalf("expected the inviter to be recorded, got %v", repo.memberInvites)
+	}
+	if rec := call(handler.GetRoomDetail, http.MethodGet, &outsiderID, nil, nil); rec.Code != http.StatusOK {
+		t.Fatalf("expected the new member to see the room, got %d", rec.Code)
+	}
+	// Redeeming again as a member does not spend the invite's last use.
+	if rec := call(handler.RedeemInvite, http.MethodPost, &outsiderID, redeem, nil); rec.Code != http.StatusOK || repo.invites[0].Uses != 1 {
+		t.Fatalf("expected a repeat redemption to be free, got %d with %d uses", rec.Code, repo.invites[0].Uses)
+	}
+	if rec := call(handler.RedeemInvite, http.MethodPost, &latecomerID, redeem, nil); rec.Code != http.StatusGone {
+		t.Fatalf("expected a used-up invite to be refused, got %d", rec.Code)
+	}
+
+	revoke := map[string]string{"inviteId": invite.ID}
+	if rec := call(handler.RevokeInvite, http.MethodDelete, &managerID, revoke, nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected the invite to be revoked, got %d", rec.Code)
+	}
+	if rec := call(handler.RevokeInvite, http.MethodDelete, &managerID, revoke, nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected a second revoke to find nothing, got %d", rec.Code)
+	}
+	if len(repo.moderationLog) != 2 || repo.moderationLog[0].Action != constants.ModerationCreateInvite || repo.moderationLog[1].Action != constants.ModerationRevokeInvite {
+		t.Fatalf("expected invite changes to be logged, got %+v", repo.moderationLog)
+	}
+}
+
+func TestGetRoomsListsHiddenRoomsToMembersOnly(t *testing.T) {
+	memberID := uuid.New()
+	public := &roomRepository.Room{ID: uuid.New(), Name: "public", Visibility: constants.RoomVisibilityPublic}
+	unlisted := &roomRepository.Room{ID: uuid.New(), Name: "unlisted", Visibility: constants.RoomVisibilityUnlisted}
+	private := &roomRepository.Room{ID: uuid.New(), Name: "private", Visibility: constants.RoomVisibilityInviteOnly}
+	repo := &fakeRoomRepository{
+		getAllActiveFn: func(ctx context.Context) ([]*roomRepository.Room, error) {
+			return []*roomRepository.Room{public, unlisted, private}, nil
+		},
+		getRoomMemberFn: func(ctx context.Context, roomID, userID uuid.UUID) (*roomRepository.RoomMember, error) {
+			if userID == memberID && roomID == unlisted.ID {
+				return &roomRepository.RoomMember{RoomID: roomID, UserID: userID, Role: "member"}, nil
+			}
+			return nil, nil
+		},
+	}
+	handler := NewCoreHandlerWithRoomRepository(websoc.NewCoreWithDependencies(nil, repo, &fakeStatsRepository{}), repo)
+
+	list := func(ctx context.Context) []string {
+		req := httptest.NewRequest(http.MethodGet, "/api/websoc/get-rooms", nil).WithContext(ctx)
+		rec := httptest.NewRecorder()
+		handler.GetRooms(rec, req)
+		var rooms []model.RoomRes
+		if err := json.NewDecoder(rec.Body).Decode(&rooms); err != nil {
+			t.Fatalf("failed to decode rooms: %v", err)
+		}
+		names := make([]string, 0, len(rooms))
+		for _, room := range rooms {
+			names = append(names, room.Name)
+		}
+		return names
+	}
+
+	if names := list(context.Background()); fmt.Sprint(names) != "[public]" {
+		t.Fatalf("expected guests to see public rooms only, got %v", names)
+	}
+	memberCtx := context.WithValue(context.Background(), middleware.UserIDKey, memberID.String())
+	if names := list(memberCtx); fmt.Sprint(names) != "[public unlisted]" {
+		t.Fatalf("expected members to see their unlisted room, got %v", names)
+	}
+}
+
+func TestInviteOnlyRoomReadsRequireMembership(t *testing.T) {
+	roomID, memberID, outsiderID := uuid.New(), uuid.New(), uuid.New()
+	root := &roomRepository.Message{ID: uuid.New(), RoomID: roomID, Username: "member", Content: "secret plans", CreatedAt: time.Now()}
+	repo := &fakeRoomRepository{
+		getRoomByIDFn: func(ctx context.Context, id uuid.UUID) (*roomRepository.Room, error) {
+			return &roomRepository.Room{ID: roomID, Name: "secret", Visibility: constants.RoomVisibilityInviteOnly}, nil
+		},
+		getMessageByIDFn: func(ctx context.Context, id uuid.UUID) (*roomRepository.Message, error) {
+			if id == root.ID {
+				return root, nil
+			}
+			return nil, nil
+		},
+		getRoomMemberFn: func(ctx context.Context, gotRoomID, userID uuid.UUID) (*roomRepository.RoomMember, error) {
+			if userID == memberID {
+				return &roomRepository.RoomMember{RoomID: roomID, UserID: memberID, Username: "member", Role: "member"}, nil
+			}
+			return nil, nil
+		},
+	}
+	handler := NewCoreHandlerWithRoomRepository(websoc.NewCoreWithDependencies(nil, repo, &fakeStatsRepository{}), repo)
+
+	call := func(handle http.HandlerFunc, asUser *uuid.UUID, params map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		routeContext := chi.NewRouteContext()
+		for key, value := range params {
+			routeContext.URLParams.Add(key, value)
+		}
+		ctx := context.WithValue(req.Context(), chi.RouteCtxKey, routeContext)
+		if asUser != nil {
+			ctx = context.WithValue(ctx, middleware.UserIDKey, asUser.String())
+		}
+		rec := httptest.NewRecorder()
+		handle(rec, req.WithContext(ctx))
+		return rec
+	}
+
+	thread := map[string]string{"roomId": roomID.String(), "messageId": root.ID.String()}
+	reads := []struct {
+		name   string
+		handle http.HandlerFunc
+		params map[string]string
+	}{
+		{"thread", handler.GetThread, thread},
+		{"reactions", handler.GetReactions, map[string]string{"messageID": root.ID.String()}},
+		{"emojis", handler.GetRoomEmojis, map[string]string{"roomId": roomID.String()}},
+		{"clients", handler.GetClients, map[string]string{"room_id": roomID.String()}},
+	}
+	for _, read := range reads {
+		if rec := call(read.handle, &outsiderID, read.params); rec.Code != http.StatusForbidden {
+			t.Fatalf("expected %s to refuse non-members, got %d", read.name, rec.Code)
+		}
+		if rec := call(read.handle, nil, read.params); rec.Code != http.StatusForbidden {
+			t.Fatalf("expected %s to refuse guests, got %d", read.name, rec.Code)
+		}
+	}
+
+	rec := call(handler.GetThread, &memberID, thread)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "secret plans") {
+		t.Fatalf("expected members to read the thread, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRoomNotificationPreferencesRequireRoomAccess(t *testing.T) {
+	roomID, memberID, outsiderID := uuid.New(), uuid.New(), uuid.New()
+	repo := &fakeRoomRepository{
+		getRoomByIDFn: func(ctx context.Context, id uuid.UUID) (*roomRepository.Room, error) {
+			return &roomRepository.Room{ID: roomID, Name: "secret", Visibility: constants.RoomVisibilityInviteOnly}, nil
+		},
+		getRoomMemberFn: func(ctx context.Context, gotRoomID, userID uuid.UUID) (*roomRepository.RoomMember, error) {
+			if userID == memberID {
+				return &roomRepository.RoomMember{RoomID: roomID, UserID: memberID, Username: "member", Role: "member"}, nil
+			}
+			return nil, nil
+		},
+	}
+	handler := NewCoreHandlerWithRoomRepository(websoc.NewCoreWithDependencies(nil, repo, &fakeStatsRepository{}), repo)
+
+	call := func(handle http.HandlerFunc, method string, userID uuid.UUID, body string) int {
+		req := httptest.NewRequest(method, "/", strings.NewReader(body))
+		routeContext := chi.NewRouteContext()
+		routeContext.URLParams.Add("roomId", roomID.String())
+		ctx := context.WithValue(req.Context(), chi.RouteCtxKey, routeContext)
+		ctx = context.WithValue(ctx, middleware.UserIDKey, userID.String())
+		rec := httptest.NewRecorder()
+		handle(rec, req.WithContext(ctx))
+		return rec.Code
+	}
+
+	if code := call(handler.UpdateRoomNotificationPreferences, http.MethodPut, outsiderID, `{"level":"none"}`); code != http.StatusForbidden {
+		t.Fatalf("expected non-members to be refused, got %d", code)
+	}
+	if code := call(handler.DeleteRoomNotificationPreferences, http.MethodDelete, outsiderID, ""); code != http.StatusForbidden {
+		t.Fatalf("expected non-members to be refused, got %d", code)
+	}
+	if code := call(handler.UpdateRoomNotificationPreferences, http.MethodPut, memberID, `{"level":"none"}`); code != http.StatusOK {
+		t.Fatalf("expected members to set preferences, got %d", code)
+	}
+}
@@ -0,0 +1,216 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"chat-application/internal/api/model"
+	"chat-application/internal/middleware"
+	"chat-application/internal/pagination"
+	roomRepository "chat-application/internal/repo/room"
+	"chat-application/util"
+
+	"github.com/google/uuid"
+)
+
+// GetMessages returns a page of channel history. With no cursor it returns the
+// latest messages; ?before= and ?after= page backwards and forwards, and
+// ?around= centres the page on a message, e.g. a search result. Without
+// ?channel_id= the room's default channel is used, or for ?around= the
+// channel of the target message.
+func (h *CoreHandler) GetMessages(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	roomID, err := uuid.Parse(chi.URLParam(r, "roomId"))
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid room ID")
+		return
+	}
+
+	limit, ok := parseLimit(w, r)
+	if !ok {
+		return
+	}
+
+	var cursors []string
+	for _, key := range []string{"before", "after", "around"} {
+		if query.Get(key) != "" {
+			cursors = append(cursors, key)
+		}
+	}
+	if len(cursors) > 1 {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Only one of before, after or around may be given")
+		return
+	}
+	var direction string
+	var cursor *pagination.Cursor
+	if len(cursors) == 1 {
+		direction = cursors[0]
+		cursor, err = pagination.Decode(query.Get(direction))
+		if err != nil {
+			util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
+	}
+
+	if _, ok := h.loadReadableRoom(w, r, roomID); !ok {
+		return
+	}
+
+	var target *roomRepository.Message
+	if direction == "around" {
+		target, err = h.roomRepository.GetMessageByID(ctx, cursor.ID)
+		if err != nil {
+			util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load message")
+			return
+		}
+		if target == nil || target.RoomID != roomID {
+			util.WriteErrorResponse(w, http.StatusNotFound, "Message not found")
+			return
+		}
+	}
+
+	channelID, ok := h.resolveHistoryChannel(w, r, roomID, target)
+	if !ok {
+		return
+	}
+	if target != nil && channelID != nil && (target.ChannelID == nil || *target.ChannelID != *channelID) {
+		util.WriteErrorResponse(w, http.StatusNotFound, "Message not found")
+		return
+	}
+
+	var messages []*roomRepository.Message
+	var hasOlder, hasNewer bool
+	switch direction {
+	case "", "before":
+		messages, err = h.roomRepository.GetMessagesBefore(ctx, roomID, channelID, cursor, limit+1)
+		if err == nil && len(messages) > limit {
+			messages = messages[1:]
+			hasOlder = true
+		}
+		hasNewer = direction == "before"
+	case "after":
+		messages, err = h.roomRepository.GetMessagesAfter(ctx, roomID, channelID, *cursor, limit+1)
+		if err == nil && len(messages) > limit {
+			messages = messages[:limit]
+			hasNewer = true
+		}
+		hasOlder = true
+	case "around":
+		messages, hasOlder, hasNewer, err = h.messagesAround(r, roomID, channelID, target, limit)
+	}
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load messages")
+		return
+	}
+
+	var viewerID *uuid.UUID
+	if userID, ok := ctx.Value(middleware.UserIDKey).(string); ok {
+		if parsedUserID, err := uuid.Parse(userID); err == nil {
+			viewerID = &parsedUserID
+		}
+	}
+
+	// Cursors come from the unfiltered page so hidden messages are not
+	// fetched again.
+	res := model.MessagePageRes{Messages: h.mapMessages(ctx, h.withoutBlocked(ctx, messages, viewerID), viewerID)}
+	if channelID != nil {
+		res.ChannelID = channelID.String()
+	}
+	if len(messages) > 0 {
+		if hasOlder {
+			first := messages[0]
+			res.PrevCursor = pagination.Cursor{CreatedAt: first.CreatedAt, ID: first.ID}.Encode()
+		}
+		if hasNewer {
+			last := messages[len(messages)-1]
+			res.NextCursor = pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+		}
+	} else if cursor != nil {
+		// An empty page still lets the client continue from where it asked.
+		if direction == "before" {
+			res.NextCursor = query.Get(direction)
+		} else if direction == "after" {
+			res.PrevCursor = query.Get(direction)
+		}
+	}
+
+	util.WriteJSONResponse(w, http.StatusOK, res)
+}
+
+// messagesAround returns the target message with up to limit messages in total
+// split around it, older ones first.
+func (h *CoreHandler) messagesAround(r *http.Request, roomID uuid.UUID, channelID *uuid.UUID, target *roomRepository.Message, limit int) ([]*roomRepository.Message, bool, bool, error) {
+	ctx := r.Context()
+	at := pagination.Cursor{CreatedAt: target.CreatedAt, ID: target.ID}
+	olderLimit := (limit - 1) / 2
+	newerLimit := limit - 1 - olderLimit
+
+	older, err := h.roomRepository.GetMessagesBefore(ctx, roomID, channelID, &at, olderLimit+1)
+	if err != nil {
+		return nil, false, false, err
+	}
+	hasOlder := len(older) > olderLimit
+	if hasOlder {
+		older = older[1:]
+	}
+
+	newer, err := h.roomRepository.GetMessagesAfter(ctx, roomID, channelID, at, newerLimit+1)
+	if err != nil {
+		return nil, false, false, err
+	}
+	hasNewer := len(newer) > newerLimit
+	if hasNewer {
+		newer = newer[:newerLimit]
+	}
+
+	messages := make([]*roomRepository.Message, 0, len(older)+1+len(newer))
+	messages = append(messages, older...)
+	messages = append(messages, target)
+	messages = append(messages, newer...)
+	return messages, hasOlder, hasNewer, nil
+}
+
+// resolveHistoryChannel picks the channel to page through: the ?channel_id=
+// parameter, else the target message's channel, else the room default.
+func (h *CoreHandler) resolveHistoryChannel(w http.ResponseWriter, r *http.Request, roomID uuid.UUID, target *roomRepository.Message) (*uuid.UUID, bool) {
+	ctx := r.Context()
+
+	rawChannelID := strings.TrimSpace(r.URL.Query().Get("channel_id"))
+	if rawChannelID == "" {
+		if target != nil {
+			return target.ChannelID, true
+		}
+		defaultChannel, err := h.roomRepository.GetDefaultChannel(ctx, roomID)
+		if err != nil {
+			util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load channel")
+			return nil, false
+		}
+		if defaultChannel == nil {
+			return nil, true
+		}
+		return &defaultChannel.ID, true
+	}
+
+	channelID, err := uuid.Parse(rawChannelID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid channel ID")
+		return nil, false
+	}
+	channels, err := h.roomRepository.GetRoomChannels(ctx, roomID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load channel")
+		return nil, false
+	}
+	for _, channel := range channels {
+		if channel.ID == channelID {
+			return &channelID, true
+		}
+	}
+
+	util.WriteErrorResponse(w, http.StatusNotFound, "Channel not found")
+	return nil, false
+}
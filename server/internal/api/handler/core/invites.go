@@ -0,0 +1,280 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"chat-application/internal/api/model"
+	"chat-application/internal/constants"
+	roomRepository "chat-application/internal/repo/room"
+	"chat-application/util"
+
+	"github.com/google/uuid"
+)
+
+// SetRoomVisibility changes whether a room is public, unlisted or
+// invite-only. Existing members keep their access either way.
+func (h *CoreHandler) SetRoomVisibility(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	roomID, manager, ok := h.requireRoomManager(w, r)
+	if !ok {
+		return
+	}
+
+	var req model.RequestRoomVisibility
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	visibility := strings.TrimSpace(req.Visibility)
+	if !constants.IsValidRoomVisibility(visibility) {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid room visibility")
+		return
+	}
+
+	room, err := h.roomRepository.GetRoomByID(ctx, roomID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load room")
+		return
+	}
+	if room == nil {
+		util.WriteErrorResponse(w, http.StatusNotFound, "Room not found")
+		return
+	}
+
+	if visibility != room.Visibility {
+		if _, err := h.roomRepository.SetRoomVisibility(ctx, roomID, visibility); err != nil {
+			log.Printf("CoreHandler.SetRoomVisibility - failed to update room: %v", err)
+			util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to set room visibility")
+			return
+		}
+		h.logModeration(ctx, manager, constants.ModerationUpdateVisibility, nil, nil, nil, nil, map[string]any{
+			"from": room.Visibility,
+			"to":   visibility,
+		})
+	}
+
+	util.WriteJSONResponse(w, http.StatusOK, map[string]string{
+		"room_id":    roomID.String(),
+		"visibility": visibility,
+	})
+}
+
+// CreateInvite creates an invite code for the room.
+func (h *CoreHandler) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	roomID, manager, ok := h.requireRoomManager(w, r)
+	if !ok {
+		return
+	}
+
+	var req model.RequestCreateInvite
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.MaxUses != nil && (*req.MaxUses < 1 || *req.MaxUses > constants.MaxInviteUses) {
+		util.WriteErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Max uses must be between 1 and %d", constants.MaxInviteUses))
+		return
+	}
+	now := time.Now()
+	expiresAt := now.Add(constants.DefaultInviteExpiry)
+	if req.ExpiresAt != nil {
+		expiresAt = *req.ExpiresAt
+	}
+	if !expiresAt.After(now) || expiresAt.After(now.Add(constants.MaxInviteExpiry)) {
+		util.WriteErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invites must expire within %d days", int(constants.MaxInviteExpiry.Hours()/24)))
+		return
+	}
+
+	code, err := newInviteCode()
+	if err != nil {
+		log.Printf("CoreHandler.CreateInvite - %v", err)
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to create invite")
+		return
+	}
+	invite, err := h.roomRepository.CreateRoomInvite(ctx, &roomRepository.RoomInvite{
+		RoomID:            roomID,
+		Code:              code,
+		CreatedBy:         &manager.UserID,
+		CreatedByUsername: &manager.Username,
+		MaxUses:           req.MaxUses,
+		ExpiresAt:         &expiresAt,
+	})
+	if err != nil {
+		log.Printf("CoreHandler.CreateInvite - failed to store invite: %v", err)
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to create invite")
+		return
+	}
+
+	h.logModeration(ctx, manager, constants.ModerationCreateInvite, nil, nil, nil, nil, map[string]any{
+		"invite_id":  invite.ID,
+		"max_uses":   invite.MaxUses,
+		"expires_at": invite.ExpiresAt,
+	})
+	util.WriteJSONResponse(w, http.StatusCreated, mapRoomInvite(*invite))
+}
+
+// GetInvites lists the room's invites to room managers.
+func (h *CoreHandler) GetInvites(w http.ResponseWriter, r *http.Request) {
+	roomID, _, ok := h.requireRoomManager(w, r)
+	if !ok {
+		return
+	}
+
+	invites, err := h.roomRepository.GetRoomInvites(r.Context(), roomID)
+	if err != nil {
+		log.Printf("CoreHandler.GetInvites - %v", err)
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load invites")
+		return
+	}
+
+	response := make([]model.RoomInviteRes, 0, len(invites))
+	for _, invite := range invites {
+		response = append(response, mapRoomInvite(invite))
+	}
+	util.WriteJSONResponse(w, http.StatusOK, response)
+}
+
+// RevokeInvite stops an invite from admitting anyone else. Members who
+// already joined through it stay.
+func (h *CoreHandler) RevokeInvite(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	roomID, manager, ok := h.requireRoomManager(w, r)
+	if !ok {
+		return
+	}
+	inviteID, err := uuid.Parse(chi.URLParam(r, "inviteId"))
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid invite ID")
+		return
+	}
+
+	revoked, err := h.roomRepository.RevokeRoomInvite(ctx, roomID, inviteID)
+	if err != nil {
+		log.Printf("CoreHandler.RevokeInvite - %v", err)
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to revoke invite")
+		return
+	}
+	if !revoked {
+		util.WriteErrorResponse(w, http.StatusNotFound, "Invite not found")
+		return
+	}
+
+	h.logModeration(ctx, manager, constants.ModerationRevokeInvite, nil, nil, nil, nil, map[string]any{
+		"invite_id": inviteID,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RedeemInvite makes the caller a member of the invite's room and records who
+// invited them. Redeeming an invite to a room the caller already belongs to
+// does not use it up.
+func (h *CoreHandler) RedeemInvite(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	invite, err := h.roomRepository.GetRoomInviteByCode(ctx, chi.URLParam(r, "code"))
+	if err != nil {
+		log.Printf("CoreHandler.RedeemInvite - %v", err)
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load invite")
+		return
+	}
+	if invite == nil {
+		util.WriteErrorResponse(w, http.StatusNotFound, "Invite not found")
+		return
+	}
+	room, err := h.roomRepository.GetRoomByID(ctx, invite.RoomID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load room")
+		return
+	}
+	if room == nil || !room.ExpiresAt.After(time.Now()) {
+		util.WriteErrorResponse(w, http.StatusNotFound, "Room not found")
+		return
+	}
+
+	member, err := h.roomRepository.GetRoomMember(ctx, room.ID, userID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load room membership")
+		return
+	}
+	if member != nil && member.BannedAt != nil {
+		util.WriteErrorResponse(w, http.StatusForbidden, "You are banned from this room")
+		return
+	}
+
+	if member == nil {
+		used, err := h.roomRepository.UseRoomInvite(ctx, invite.ID)
+		if err != nil {
+			log.Printf("CoreHandler.RedeemInvite - %v", err)
+			util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to redeem invite")
+			return
+		}
+		if !used {
+			util.WriteErrorResponse(w, http.StatusGone, "This invite is no longer valid")
+			return
+		}
+		if err := h.roomRepository.EnsureRoomMembership(ctx, room.ID, userID); err != nil {
+			util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to join room")
+			return
+		}
+		if err := h.roomRepository.SetMemberInvite(ctx, room.ID, userID, invite); err != nil {
+			log.Printf("CoreHandler.RedeemInvite - %v", err)
+		}
+	}
+
+	util.WriteJSONResponse(w, http.StatusOK, model.RoomRes{
+		ID:               room.ID.String(),
+		Name:             room.Name,
+		IsPinned:         room.IsPinned,
+		CreatedAt:        room.CreatedAt,
+		Expires:          room.ExpiresAt,
+		TopicTitle:       room.TopicTitle,
+		TopicDescription: room.TopicDescription,
+		TopicURL:         room.TopicURL,
+		TopicSource:      room.TopicSource,
+		Visibility:       room.Visibility,
+	})
+}
+
+// newInviteCode returns a random code short enough to share in a link.
+func newInviteCode() (string, error) {
+	raw := make([]byte, constants.InviteCodeBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate invite code: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func mapRoomInvite(invite roomRepository.RoomInvite) model.RoomInviteRes {
+	res := model.RoomInviteRes{
+		ID:        invite.ID.String(),
+		RoomID:    invite.RoomID.String(),
+		Code:      invite.Code,
+		MaxUses:   invite.MaxUses,
+		Uses:      invite.Uses,
+		Active:    invite.Usable(time.Now()),
+		ExpiresAt: invite.ExpiresAt,
+		RevokedAt: invite.RevokedAt,
+		CreatedAt: invite.CreatedAt,
+	}
+	if invite.CreatedBy != nil {
+		res.CreatedBy = invite.CreatedBy.String()
+	}
+	if invite.CreatedByUsername != nil {
+		res.CreatedByUsername = *invite.CreatedByUsername
+	}
+	return res
+}
@@ -0,0 +1,497 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+
+	"chat-application/internal/api/model"
+	"chat-application/internal/constants"
+	"chat-application/internal/pagination"
+	roomRepository "chat-application/internal/repo/room"
+	"chat-application/util"
+
+	"github.com/google/uuid"
+)
+
+// ReportMessage lets a member flag a message for the room's moderators.
+// Reporting the same message twice is accepted but stores one report.
+func (h *CoreHandler) ReportMessage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	reporterID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	roomID, err := uuid.Parse(chi.URLParam(r, "roomId"))
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid room ID")
+		return
+	}
+	messageID, err := uuid.Parse(chi.URLParam(r, "messageId"))
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid message ID")
+		return
+	}
+
+	var req model.RequestReportMessage
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if !constants.IsValidReportReason(req.Reason) {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Reason must be one of "+strings.Join(constants.ReportReasons, ", "))
+		return
+	}
+	req.Details = strings.TrimSpace(req.Details)
+	if utf8.RuneCountInString(req.Details) > constants.MaxReportDetailsLength {
+		util.WriteErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Details must be at most %d characters", constants.MaxReportDetailsLength))
+		return
+	}
+
+	message, err := h.roomRepository.GetMessageByID(ctx, messageID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load message")
+		return
+	}
+	if message == nil || message.RoomID != roomID {
+		util.WriteErrorResponse(w, http.StatusNotFound, "Message not found")
+		return
+	}
+	if message.IsSystem {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "System messages cannot be reported")
+		return
+	}
+	if message.UserID != nil && *message.UserID == reporterID {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "You cannot report your own message")
+		return
+	}
+
+	member, err := h.roomRepository.GetRoomMember(ctx, roomID, reporterID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load room membership")
+		return
+	}
+	if member == nil || member.BannedAt != nil {
+		util.WriteErrorResponse(w, http.StatusForbidden, "You must be a member of this room to report messages")
+		return
+	}
+
+	report := &roomRepository.MessageReport{
+		RoomID:         roomID,
+		MessageID:      &message.ID,
+		ReporterID:     &reporterID,
+		AuthorID:       message.UserID,
+		AuthorUsername: message.Username,
+		MessageContent: message.Content,
+		Reason:         req.Reason,
+	}
+	if req.Details != "" {
+		report.Details = &req.Details
+	}
+	created, err := h.roomRepository.CreateMessageReport(ctx, report)
+	if err != nil {
+		log.Printf("CoreHandler.ReportMessage - failed to store report: %v", err)
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to report message")
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	util.WriteJSONResponse(w, status, map[string]bool{"reported": true})
+}
+
+// GetReports returns the room's moderation queue, oldest first. ?status=
+// selects open (the default) or resolved reports. When more remain, the next
+// page is linked from the Link header (rel="next").
+func (h *CoreHandler) GetReports(w http.ResponseWriter, r *http.Request) {
+	roomID, _, ok := h.requireModerator(w, r)
+	if !ok {
+		return
+	}
+
+	limit, ok := parseLimit(w, r)
+	if !ok {
+		return
+	}
+	after, err := pagination.DecodeOptional(r.URL.Query().Get("cursor"))
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = constants.ReportStatusOpen
+	}
+	if status != constants.ReportStatusOpen && status != constants.ReportStatusResolved {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid status")
+		return
+	}
+
+	reports, err := h.roomRepository.GetMessageReports(r.Context(), roomID, status, after, limit+1)
+	if err != nil {
+		log.Printf("CoreHandler.GetReports - failed to load reports: %v", err)
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load reports")
+		return
+	}
+
+	if len(reports) > limit {
+		reports = reports[:limit]
+		last := reports[len(reports)-1]
+		next := url.Values{}
+		next.Set("cursor", pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode())
+		next.Set("limit", fmt.Sprint(limit))
+		next.Set("status", status)
+		w.Header().Set("Link", fmt.Sprintf(`<%s?%s>; rel="next"`, r.URL.Path, next.Encode()))
+	}
+
+	response := make([]model.MessageReportRes, 0, len(reports))
+	for _, report := range reports {
+		response = append(response, mapMessageReport(report))
+	}
+	util.WriteJSONResponse(w, http.StatusOK, response)
+}
+
+// ResolveReport applies a moderator's decision to an open report. Every
+// other open report of the same message is resolved with it.
+func (h *CoreHandler) ResolveReport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	roomID, moderator, ok := h.requireModerator(w, r)
+	if !ok {
+		return
+	}
+	reportID, err := uuid.Parse(chi.URLParam(r, "reportId"))
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid report ID")
+		return
+	}
+
+	var req model.RequestModerationAction
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	report, err := h.roomRepository.GetMessageReport(ctx, reportID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load report")
+		return
+	}
+	if report == nil || report.RoomID != roomID {
+		util.WriteErrorResponse(w, http.StatusNotFound, "Report not found")
+		return
+	}
+	if report.Status != constants.ReportStatusOpen {
+		util.WriteErrorResponse(w, http.StatusConflict, "Report is already resolved")
+		return
+	}
+
+	details := map[string]any{"reason": report.Reason}
+	if note := strings.TrimSpace(req.Note); note != "" {
+		details["note"] = note
+	}
+
+	switch req.Action {
+	case constants.ModerationDismiss:
+	case constants.ModerationDeleteMessage:
+		if !h.removeReportedMessage(w, ctx, report) {
+			return
+		}
+	case constants.ModerationMuteAuthor, constants.ModerationBanAuthor:
+		author, ok := h.reportedAuthor(w, ctx, report, moderator)
+		if !ok {
+			return
+		}
+		now := time.Now().UTC()
+		if req.Action == constants.ModerationBanAuthor {
+			author.BannedAt = &now
+		} else {
+			duration := constants.DefaultMuteDuration
+			if req.MuteMinutes < 0 {
+				util.WriteErrorResponse(w, http.StatusBadRequest, "Mute duration must be positive")
+				return
+			}
+			if req.MuteMinutes > 0 {
+				duration = time.Duration(min(req.MuteMinutes, int(constants.MaxMuteDuration/time.Minute))) * time.Minute
+			}
+			mutedUntil := now.Add(duration)
+			author.MutedUntil = &mutedUntil
+			details["muted_until"] = mutedUntil
+		}
+		if err := h.roomRepository.UpdateRoomMember(ctx, *author); err != nil {
+			util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update member")
+			return
+		}
+	default:
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid action")
+		return
+	}
+
+	resolved, err := h.roomRepository.ResolveMessageReports(ctx, report, req.Action, moderator.UserID)
+	if err != nil {
+		log.Printf("CoreHandler.ResolveReport - failed to resolve reports: %v", err)
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to resolve report")
+		return
+	}
+
+	h.logModeration(ctx, moderator, req.Action, report.AuthorID, &report.AuthorUsername, report.MessageID, &report.ID, details)
+
+	util.WriteJSONResponse(w, http.StatusOK, model.ModerationActionRes{Action: req.Action, ResolvedReports: resolved})
+}
+
+// GetModerationLog returns the room's moderator actions, newest first. When
+// more remain, the next page is linked from the Link header (rel="next").
+func (h *CoreHandler) GetModerationLog(w http.ResponseWriter, r *http.Request) {
+	roomID, _, ok := h.requireModerator(w, r)
+	if !ok {
+		return
+	}
+
+	limit, ok := parseLimit(w, r)
+	if !ok {
+		return
+	}
+	before, err := pagination.DecodeOptional(r.URL.Query().Get("cursor"))
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+
+	entries, err := h.roomRepository.GetModerationLog(r.Context(), roomID, before, limit+1)
+	if err != nil {
+		log.Printf("CoreHandler.GetModerationLog - failed to load log: %v", err)
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load moderation log")
+		return
+	}
+
+	if len(entries) > limit {
+		entries = entries[:limit]
+		last := entries[len(entries)-1]
+		next := url.Values{}
+		next.Set("cursor", pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode())
+		next.Set("limit", fmt.Sprint(limit))
+		w.Header().Set("Link", fmt.Sprintf(`<%s?%s>; rel="next"`, r.URL.Path, next.Encode()))
+	}
+
+	response := make([]model.ModerationLogEntryRes, 0, len(entries))
+	for _, entry := range entries {
+		response = append(response, mapModerationLogEntry(entry))
+	}
+	util.WriteJSONResponse(w, http.StatusOK, response)
+}
+
+// SetSlowMode sets how long members wait between messages in a channel.
+// Zero seconds turns slow mode off.
+func (h *CoreHandler) SetSlowMode(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	roomID, moderator, ok := h.requireModerator(w, r)
+	if !ok {
+		return
+	}
+	channelID, err := uuid.Parse(chi.URLParam(r, "channelId"))
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid channel ID")
+		return
+	}
+
+	var req model.RequestSlowMode
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Seconds < 0 || req.Seconds > constants.MaxSlowModeSeconds {
+		util.WriteErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Slow mode must be between 0 and %d seconds", constants.MaxSlowModeSeconds))
+		return
+	}
+
+	updated, err := h.roomRepository.SetChannelSlowMode(ctx, roomID, channelID, req.Seconds)
+	if err != nil {
+		log.Printf("CoreHandler.SetSlowMode - failed to update channel: %v", err)
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to set slow mode")
+		return
+	}
+	if !updated {
+		util.WriteErrorResponse(w, http.StatusNotFound, "Channel not found")
+		return
+	}
+
+	h.logModeration(ctx, moderator, constants.ModerationSlowMode, nil, nil, nil, nil, map[string]any{
+		"channel_id": channelID,
+		"seconds":    req.Seconds,
+	})
+	util.WriteJSONResponse(w, http.StatusOK, map[string]any{
+		"channel_id":        channelID.String(),
+		"slow_mode_seconds": req.Seconds,
+	})
+}
+
+// requireModerator loads the caller's membership and refuses anyone who
+// cannot moderate the room.
+func (h *CoreHandler) requireModerator(w http.ResponseWriter, r *http.Request) (uuid.UUID, *roomRepository.RoomMember, bool) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return uuid.Nil, nil, false
+	}
+	roomID, err := uuid.Parse(chi.URLParam(r, "roomId"))
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid room ID")
+		return uuid.Nil, nil, false
+	}
+	member, err := h.roomRepository.GetRoomMember(r.Context(), roomID, userID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load room membership")
+		return uuid.Nil, nil, false
+	}
+	if member == nil || member.BannedAt != nil || (!member.CanModerate && !member.CanManageRoom && member.Role != "owner") {
+		util.WriteErrorResponse(w, http.StatusForbidden, "Insufficient permissions")
+		return uuid.Nil, nil, false
+	}
+	return roomID, member, true
+}
+
+// removeReportedMessage blanks the reported message and updates open
+// clients. A message that is already gone counts as removed.
+func (h *CoreHandler) removeReportedMessage(w http.ResponseWriter, ctx context.Context, report *roomRepository.MessageReport) bool {
+	if report.MessageID == nil {
+		return true
+	}
+	message, err := h.roomRepository.GetMessageByID(ctx, *report.MessageID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load message")
+		return false
+	}
+	if message == nil {
+		return true
+	}
+	if _, err := h.roomRepository.RemoveMessageContent(ctx, message.ID); err != nil {
+		log.Printf("CoreHandler.removeReportedMessage - failed to remove message: %v", err)
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to delete message")
+		return false
+	}
+	h.core.PublishMessageRemoved(message)
+	return true
+}
+
+// reportedAuthor loads the membership of a report's author for a mute or
+// ban, applying the same owner protection as UpdateMemberRole.
+func (h *CoreHandler) reportedAuthor(w http.ResponseWriter, ctx context.Context, report *roomRepository.MessageReport, moderator *roomRepository.RoomMember) (*roomRepository.RoomMember, bool) {
+	if report.AuthorID == nil {
+		util.WriteErrorResponse(w, http.StatusConflict, "The author no longer has an account")
+		return nil, false
+	}
+	if *report.AuthorID == moderator.UserID {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "You cannot moderate yourself")
+		return nil, false
+	}
+	author, err := h.roomRepository.GetRoomMember(ctx, report.RoomID, *report.AuthorID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load member")
+		return nil, false
+	}
+	if author == nil {
+		util.WriteErrorResponse(w, http.StatusConflict, "The author is not a member of this room")
+		return nil, false
+	}
+	if author.Role == "owner" && moderator.Role != "owner" {
+		util.WriteErrorResponse(w, http.StatusForbidden, "Only room owners can modify other owners")
+		return nil, false
+	}
+	return author, true
+}
+
+// logModeration records an action in the room's moderation log, with details
+// encoded as a JSON object. A failure is logged rather than undoing the
+// action.
+func (h *CoreHandler) logModeration(ctx context.Context, moderator *roomRepository.RoomMember, action string, targetUserID *uuid.UUID, targetUsername *string, messageID, reportID *uuid.UUID, details any) {
+	entry := &roomRepository.ModerationLogEntry{
+		RoomID:            moderator.RoomID,
+		ModeratorID:       &moderator.UserID,
+		ModeratorUsername: moderator.Username,
+		Action:            action,
+		TargetUserID:      targetUserID,
+		TargetUsername:    targetUsername,
+		MessageID:         messageID,
+		ReportID:          reportID,
+	}
+	if encoded, err := json.Marshal(details); err == nil {
+		entry.Details = encoded
+	}
+	if err := h.roomRepository.CreateModerationLogEntry(ctx, entry); err != nil {
+		log.Printf("CoreHandler.logModeration - failed to record %s in room %s: %v", action, moderator.RoomID, err)
+	}
+}
+
+func mapMessageReport(report roomRepository.MessageReport) model.MessageReportRes {
+	res := model.MessageReportRes{
+		ID:             report.ID.String(),
+		RoomID:         report.RoomID.String(),
+		AuthorUsername: report.AuthorUsername,
+		MessageContent: report.MessageContent,
+		Reason:         report.Reason,
+		Status:         report.Status,
+		ResolvedAt:     report.ResolvedAt,
+		CreatedAt:      report.CreatedAt,
+	}
+	if report.MessageID != nil {
+		res.MessageID = report.MessageID.String()
+	}
+	if report.ReporterID != nil {
+		res.ReporterID = report.ReporterID.String()
+	}
+	if report.ReporterUsername != nil {
+		res.ReporterUsername = *report.ReporterUsername
+	}
+	if report.AuthorID != nil {
+		res.AuthorID = report.AuthorID.String()
+	}
+	if report.Details != nil {
+		res.Details = *report.Details
+	}
+	if report.Resolution != nil {
+		res.Resolution = *report.Resolution
+	}
+	if report.ResolvedBy != nil {
+		res.ResolvedBy = report.ResolvedBy.String()
+	}
+	return res
+}
+
+func mapModerationLogEntry(entry roomRepository.ModerationLogEntry) model.ModerationLogEntryRes {
+	res := model.ModerationLogEntryRes{
+		ID:                entry.ID.String(),
+		ModeratorUsername: entry.ModeratorUsername,
+		Action:            entry.Action,
+		CreatedAt:         entry.CreatedAt,
+	}
+	if entry.ModeratorID != nil {
+		res.ModeratorID = entry.ModeratorID.String()
+	}
+	if entry.TargetUserID != nil {
+		res.TargetUserID = entry.TargetUserID.String()
+	}
+	if entry.TargetUsername != nil {
+		res.TargetUsername = *entry.TargetUsername
+	}
+	if entry.MessageID != nil {
+		res.MessageID = entry.MessageID.String()
+	}
+	if entry.ReportID != nil {
+		res.ReportID = entry.ReportID.String()
+	}
+	if len(entry.Details) > 0 {
+		var details map[string]any
+		if err := json.Unmarshal(entry.Details, &details); err == nil && len(details) > 0 {
+			res.Details = details
+		}
+	}
+	return res
+}
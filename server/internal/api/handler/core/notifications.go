@@ -0,0 +1,312 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"chat-application/internal/api/model"
+	"chat-application/internal/constants"
+	"chat-application/internal/middleware"
+	"chat-application/internal/pagination"
+	roomRepository "chat-application/internal/repo/room"
+	"chat-application/util"
+
+	"github.com/google/uuid"
+)
+
+// GetNotifications returns the caller's notifications, newest first. When more
+// remain, the next page is linked from the Link header (rel="next");
+// ?unread=true limits the list to unread notifications.
+func (h *CoreHandler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	parsedUserID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	limit, ok := parseLimit(w, r)
+	if !ok {
+		return
+	}
+	before, err := pagination.DecodeOptional(r.URL.Query().Get("cursor"))
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+
+	notifications, err := h.roomRepository.GetNotifications(r.Context(), parsedUserID, before, unreadOnly, limit+1)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load notifications")
+		return
+	}
+
+	if len(notifications) > limit {
+		notifications = notifications[:limit]
+		last := notifications[len(notifications)-1]
+		next := url.Values{}
+		next.Set("cursor", pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode())
+		next.Set("limit", fmt.Sprint(limit))
+		if unreadOnly {
+			next.Set("unread", "true")
+		}
+		w.Header().Set("Link", fmt.Sprintf(`<%s?%s>; rel="next"`, r.URL.Path, next.Encode()))
+	}
+
+	util.WriteJSONResponse(w, http.StatusOK, h.mapNotifications(notifications))
+}
+
+func (h *CoreHandler) GetUnreadNotificationCount(w http.ResponseWriter, r *http.Request) {
+	parsedUserID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	count, err := h.roomRepository.CountUnreadNotifications(r.Context(), parsedUserID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to count notifications")
+		return
+	}
+
+	util.WriteJSONResponse(w, http.StatusOK, model.NotificationCountRes{UnreadCount: count})
+}
+
+func (h *CoreHandler) MarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	parsedUserID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	notificationID, err := uuid.Parse(chi.URLParam(r, "notificationId"))
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid notification ID")
+		return
+	}
+
+	if err := h.roomRepository.MarkNotificationRead(r.Context(), notificationID, parsedUserID); err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update notification")
+		return
+	}
+	util.WriteJSONResponse(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+// MarkAllNotificationsRead marks every notification read, or only those for
+// the room given by ?room_id=.
+func (h *CoreHandler) MarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	parsedUserID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var roomID *uuid.UUID
+	if rawRoomID := strings.TrimSpace(r.URL.Query().Get("room_id")); rawRoomID != "" {
+		parsedRoomID, err := uuid.Parse(rawRoomID)
+		if err != nil {
+			util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid room ID")
+			return
+		}
+		roomID = &parsedRoomID
+	}
+
+	updated, err := h.roomRepository.MarkAllNotificationsRead(r.Context(), parsedUserID, roomID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update notifications")
+		return
+	}
+	util.WriteJSONResponse(w, http.StatusOK, map[string]int64{"updated": updated})
+}
+
+func (h *CoreHandler) GetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	parsedUserID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	preferences, err := h.roomRepository.GetNotificationPreferences(r.Context(), parsedUserID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load notification preferences")
+		return
+	}
+
+	emailDigest := true
+	res := model.NotificationPreferencesRes{
+		Default: model.NotificationPreferenceRes{Level: constants.NotificationLevelAll, EmailDigest: &emailDigest},
+		Rooms:   []model.NotificationPreferenceRes{},
+	}
+	for _, preference := range preferences {
+		if preference.RoomID == nil {
+			res.Default = mapNotificationPreference(preference)
+			if res.Default.Level == "" {
+				res.Default.Level = constants.NotificationLevelAll
+			}
+			continue
+		}
+		res.Rooms = append(res.Rooms, mapNotificationPreference(preference))
+	}
+
+	util.WriteJSONResponse(w, http.StatusOK, res)
+}
+
+// UpdateNotificationPreferences sets the caller's default level and global
+// mute, and optionally turns email digests on or off.
+func (h *CoreHandler) UpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	parsedUserID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req model.NotificationPreferenceReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	preference, ok := buildNotificationPreference(w, req, parsedUserID, nil)
+	if !ok {
+		return
+	}
+	if preference.Level == nil {
+		level := constants.NotificationLevelAll
+		preference.Level = &level
+	}
+
+	if err := h.roomRepository.SetNotificationPreference(r.Context(), preference); err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to save notification preferences")
+		return
+	}
+	res := mapNotificationPreference(*preference)
+	if req.EmailDigest != nil {
+		if err := h.roomRepository.SetEmailDigest(r.Context(), parsedUserID, *req.EmailDigest); err != nil {
+			util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to save notification preferences")
+			return
+		}
+		res.EmailDigest = req.EmailDigest
+	}
+	util.WriteJSONResponse(w, http.StatusOK, res)
+}
+
+// UpdateRoomNotificationPreferences overrides the caller's level or mute for
+// one room. Leaving level empty keeps the default level.
+func (h *CoreHandler) UpdateRoomNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	parsedUserID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	roomID, ok := h.loadPreferenceRoom(w, r)
+	if !ok {
+		return
+	}
+
+	var req model.NotificationPreferenceReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	preference, ok := buildNotificationPreference(w, req, parsedUserID, &roomID)
+	if !ok {
+		return
+	}
+
+	if err := h.roomRepository.SetNotificationPreference(r.Context(), preference); err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to save notification preferences")
+		return
+	}
+	util.WriteJSONResponse(w, http.StatusOK, mapNotificationPreference(*preference))
+}
+
+// DeleteRoomNotificationPreferences resets a room to the caller's defaults.
+func (h *CoreHandler) DeleteRoomNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	parsedUserID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	roomID, ok := h.loadPreferenceRoom(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.roomRepository.DeleteNotificationPreference(r.Context(), parsedUserID, &roomID); err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to reset notification preferences")
+		return
+	}
+	util.WriteJSONResponse(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+// loadPreferenceRoom resolves the room whose preferences are being changed.
+// Only rooms the caller may read qualify, so invite-only rooms need membership.
+func (h *CoreHandler) loadPreferenceRoom(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	roomID, err := uuid.Parse(chi.URLParam(r, "roomId"))
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid room ID")
+		return uuid.Nil, false
+	}
+
+	if _, ok := h.loadReadableRoom(w, r, roomID); !ok {
+		return uuid.Nil, false
+	}
+	return roomID, true
+}
+
+func buildNotificationPreference(w http.ResponseWriter, req model.NotificationPreferenceReq, userID uuid.UUID, roomID *uuid.UUID) (*roomRepository.NotificationPreference, bool) {
+	if roomID != nil && req.EmailDigest != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "email_digest can only be set on the default preference")
+		return nil, false
+	}
+
+	preference := &roomRepository.NotificationPreference{UserID: userID, RoomID: roomID}
+	switch req.Level {
+	case "":
+	case constants.NotificationLevelAll, constants.NotificationLevelMentions, constants.NotificationLevelNone:
+		level := req.Level
+		preference.Level = &level
+	default:
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Level must be all, mentions or none")
+		return nil, false
+	}
+
+	if req.MutedUntil != nil {
+		if !req.MutedUntil.After(time.Now()) {
+			util.WriteErrorResponse(w, http.StatusBadRequest, "muted_until must be in the future")
+			return nil, false
+		}
+		mutedUntil := req.MutedUntil.UTC()
+		preference.MutedUntil = &mutedUntil
+	}
+
+	return preference, true
+}
+
+func mapNotificationPreference(preference roomRepository.NotificationPreference) model.NotificationPreferenceRes {
+	res := model.NotificationPreferenceRes{}
+	if preference.RoomID != nil {
+		res.RoomID = preference.RoomID.String()
+	}
+	if preference.Level != nil {
+		res.Level = *preference.Level
+	}
+	if preference.MutedUntil != nil && preference.MutedUntil.After(time.Now()) {
+		res.MutedUntil = preference.MutedUntil
+	}
+	if preference.RoomID == nil {
+		emailDigest := preference.EmailDigest
+		res.EmailDigest = &emailDigest
+	}
+	return res
+}
+
+func requireUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		util.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return uuid.Nil, false
+	}
+	parsedUserID, err := uuid.Parse(userID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID")
+		return uuid.Nil, false
+	}
+	return parsedUserID, true
+}
@@ -0,0 +1,362 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"chat-application/internal/api/model"
+	"chat-application/internal/constants"
+	"chat-application/internal/middleware"
+	roomRepository "chat-application/internal/repo/room"
+	websoc "chat-application/internal/websocket"
+	"chat-application/util"
+
+	"github.com/google/uuid"
+)
+
+// emojiNameRegex matches custom emoji names; reactions reference them as ":name:".
+var emojiNameRegex = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// ToggleReaction adds the caller's reaction to a message, or removes it if
+// they already reacted with the same emoji.
+func (h *CoreHandler) ToggleReaction(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req model.RequestAddReaction
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	message, member, ok := h.loadReactionTarget(w, r, req.MessageID)
+	if !ok {
+		return
+	}
+
+	emoji := strings.TrimSpace(req.Emoji)
+	allowed, err := h.isAllowedReaction(ctx, message.RoomID, emoji)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to validate emoji")
+		return
+	}
+	if !allowed {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid emoji")
+		return
+	}
+
+	reaction := &model.MessageReaction{
+		MessageID: message.ID.String(),
+		UserID:    member.UserID.String(),
+		Emoji:     emoji,
+	}
+	added, err := h.roomRepository.ToggleReaction(ctx, reaction)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update reaction")
+		return
+	}
+
+	eventType, status := "reaction.removed", http.StatusOK
+	if added {
+		eventType, status = "reaction.added", http.StatusCreated
+		h.core.NotifyReaction(ctx, message, member, emoji)
+	}
+	count := h.publishReaction(ctx, eventType, message, member, emoji)
+
+	util.WriteJSONResponse(w, status, model.ReactionToggleRes{
+		MessageID: message.ID.String(),
+		Emoji:     emoji,
+		Added:     added,
+		Count:     count,
+	})
+}
+
+// RemoveReaction removes the caller's reaction given by the emoji query parameter.
+func (h *CoreHandler) RemoveReaction(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	emoji := strings.TrimSpace(r.URL.Query().Get("emoji"))
+	if emoji == "" {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Emoji is required")
+		return
+	}
+
+	message, member, ok := h.loadReactionTarget(w, r, chi.URLParam(r, "messageID"))
+	if !ok {
+		return
+	}
+
+	removed, err := h.roomRepository.RemoveReaction(ctx, message.ID, member.UserID, emoji)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to remove reaction")
+		return
+	}
+	if !removed {
+		util.WriteErrorResponse(w, http.StatusNotFound, "Reaction not found")
+		return
+	}
+
+	count := h.publishReaction(ctx, "reaction.removed", message, member, emoji)
+
+	util.WriteJSONResponse(w, http.StatusOK, model.ReactionToggleRes{
+		MessageID: message.ID.String(),
+		Emoji:     emoji,
+		Added:     false,
+		Count:     count,
+	})
+}
+
+func (h *CoreHandler) GetReactions(w http.ResponseWriter, r *http.Request) {
+	messageID := chi.URLParam(r, "messageID")
+	if messageID == "" {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Message ID is required")
+		return
+	}
+	parsedMessageID, err := uuid.Parse(messageID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid message ID")
+		return
+	}
+	message, err := h.roomRepository.GetMessageByID(r.Context(), parsedMessageID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load message")
+		return
+	}
+	if message == nil {
+		util.WriteErrorResponse(w, http.StatusNotFound, "Message not found")
+		return
+	}
+	if _, ok := h.loadReadableRoom(w, r, message.RoomID); !ok {
+		return
+	}
+
+	reactions, err := h.roomRepository.GetReactions(r.Context(), messageID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch reactions")
+		return
+	}
+
+	util.WriteJSONResponse(w, http.StatusOK, reactions)
+}
+
+func (h *CoreHandler) GetRoomEmojis(w http.ResponseWriter, r *http.Request) {
+	roomID, err := uuid.Parse(chi.URLParam(r, "roomId"))
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid room ID")
+		return
+	}
+
+	if _, ok := h.loadReadableRoom(w, r, roomID); !ok {
+		return
+	}
+
+	emojis, err := h.roomRepository.GetRoomEmojis(r.Context(), roomID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to fetch emojis")
+		return
+	}
+
+	response := make([]model.RoomEmojiRes, 0, len(emojis))
+	for _, emoji := range emojis {
+		response = append(response, mapRoomEmoji(emoji))
+	}
+
+	util.WriteJSONResponse(w, http.StatusOK, response)
+}
+
+// CreateRoomEmoji registers an uploaded image as a custom emoji for the room.
+// The image must have been uploaded to the room by the caller.
+func (h *CoreHandler) CreateRoomEmoji(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	roomID, member, ok := h.requireRoomManager(w, r)
+	if !ok {
+		return
+	}
+
+	var req model.CreateRoomEmojiReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	name := strings.ToLower(strings.Trim(strings.TrimSpace(req.Name), ":"))
+	if len(name) < constants.MinEmojiNameLength || len(name) > constants.MaxEmojiNameLength || !emojiNameRegex.MatchString(name) {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Emoji names must be 2-32 lowercase letters, digits or underscores")
+		return
+	}
+
+	attachmentID, err := uuid.Parse(req.AttachmentID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid attachment ID")
+		return
+	}
+
+	existing, err := h.roomRepository.GetRoomEmojis(ctx, roomID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load emojis")
+		return
+	}
+	if len(existing) >= constants.MaxRoomEmojis {
+		util.WriteErrorResponse(w, http.StatusConflict, "Room emoji limit reached")
+		return
+	}
+	for _, emoji := range existing {
+		if emoji.Name == name {
+			util.WriteErrorResponse(w, http.StatusConflict, "An emoji with this name already exists")
+			return
+		}
+	}
+
+	if h.core.Attachments == nil {
+		util.WriteErrorResponse(w, http.StatusServiceUnavailable, "Uploads are not available")
+		return
+	}
+	attachments, err := h.core.Attachments.ResolveForMessage(ctx, roomID, member.UserID, []uuid.UUID{attachmentID})
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load attachment")
+		return
+	}
+	if len(attachments) != 1 || !strings.HasPrefix(attachments[0].ContentType, "image/") {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Attachment must be an image you uploaded to this room")
+		return
+	}
+
+	emoji, err := h.roomRepository.CreateRoomEmoji(ctx, &roomRepository.RoomEmoji{
+		RoomID:       roomID,
+		Name:         name,
+		AttachmentID: attachmentID,
+		CreatedBy:    &member.UserID,
+	})
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to create emoji")
+		return
+	}
+
+	util.WriteJSONResponse(w, http.StatusCreated, mapRoomEmoji(*emoji))
+}
+
+func (h *CoreHandler) DeleteRoomEmoji(w http.ResponseWriter, r *http.Request) {
+	roomID, _, ok := h.requireRoomManager(w, r)
+	if !ok {
+		return
+	}
+
+	deleted, err := h.roomRepository.DeleteRoomEmoji(r.Context(), roomID, chi.URLParam(r, "name"))
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to delete emoji")
+		return
+	}
+	if !deleted {
+		util.WriteErrorResponse(w, http.StatusNotFound, "Emoji not found")
+		return
+	}
+
+	util.WriteJSONResponse(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+// loadReactionTarget resolves the message being reacted to and the caller's
+// membership in its room. Banned users and non-members cannot react.
+func (h *CoreHandler) loadReactionTarget(w http.ResponseWriter, r *http.Request, rawMessageID string) (*roomRepository.Message, *roomRepository.RoomMember, bool) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(middleware.UserIDKey).(string)
+	if !ok {
+		util.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return nil, nil, false
+	}
+	parsedUserID, err := uuid.Parse(userID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID")
+		return nil, nil, false
+	}
+	messageID, err := uuid.Parse(rawMessageID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid message ID")
+		return nil, nil, false
+	}
+
+	message, err := h.roomRepository.GetMessageByID(ctx, messageID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load message")
+		return nil, nil, false
+	}
+	if message == nil {
+		util.WriteErrorResponse(w, http.StatusNotFound, "Message not found")
+		return nil, nil, false
+	}
+
+	member, err := h.roomRepository.GetRoomMember(ctx, message.RoomID, parsedUserID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load room membership")
+		return nil, nil, false
+	}
+	if member == nil || member.BannedAt != nil {
+		util.WriteErrorResponse(w, http.StatusForbidden, "You must be a member of this room to react")
+		return nil, nil, false
+	}
+
+	return message, member, true
+}
+
+// isAllowedReaction accepts the default emoji set plus the room's custom emoji.
+func (h *CoreHandler) isAllowedReaction(ctx context.Context, roomID uuid.UUID, emoji string) (bool, error) {
+	if constants.IsValidReactionEmoji(emoji) {
+		return true, nil
+	}
+	if len(emoji) < 3 || !strings.HasPrefix(emoji, ":") || !strings.HasSuffix(emoji, ":") {
+		return false, nil
+	}
+
+	custom, err := h.roomRepository.GetRoomEmoji(ctx, roomID, emoji[1:len(emoji)-1])
+	if err != nil {
+		return false, err
+	}
+	return custom != nil, nil
+}
+
+// publishReaction pushes a reaction change to the room and returns the new
+// count for the emoji.
+func (h *CoreHandler) publishReaction(ctx context.Context, eventType string, message *roomRepository.Message, member *roomRepository.RoomMember, emoji string) int {
+	count := 0
+	counts, err := h.roomRepository.GetReactionCounts(ctx, []uuid.UUID{message.ID}, nil)
+	if err != nil {
+		log.Printf("CoreHandler.publishReaction - failed to count reactions: %v", err)
+	}
+	for _, item := range counts[message.ID] {
+		if item.Emoji == emoji {
+			count = item.Count
+		}
+	}
+
+	event := &websoc.ReactionEvent{
+		RoomID:    message.RoomID.String(),
+		MessageID: message.ID.String(),
+		UserID:    member.UserID.String(),
+		Username:  member.Username,
+		Emoji:     emoji,
+		Count:     count,
+	}
+	if message.ChannelID != nil {
+		event.ChannelID = message.ChannelID.String()
+	}
+	h.core.Broadcast <- &websoc.Event{Type: eventType, Reaction: event}
+
+	return count
+}
+
+func mapRoomEmoji(emoji roomRepository.RoomEmoji) model.RoomEmojiRes {
+	response := model.RoomEmojiRes{
+		Name:      emoji.Name,
+		Shortcode: ":" + emoji.Name + ":",
+		ImageURL:  "/api/attachments/" + emoji.AttachmentID.String(),
+		CreatedAt: emoji.CreatedAt,
+	}
+	if emoji.CreatedBy != nil {
+		response.CreatedBy = emoji.CreatedBy.String()
+	}
+	return response
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"chat-application/internal/api/model"
+	"chat-application/internal/middleware"
+	roomRepository "chat-application/internal/repo/room"
+	"chat-application/util"
+
+	"github.com/google/uuid"
+)
+
+// MarkRead moves the caller's read pointer in a channel up to the given message.
+// It is the REST equivalent of the "read" WebSocket event.
+func (h *CoreHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req model.MarkReadReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	message, member, ok := h.loadReadTarget(w, r, req.MessageID)
+	if !ok {
+		return
+	}
+	if message.ChannelID == nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Message is not in a channel")
+		return
+	}
+
+	if _, err := h.core.MarkRead(ctx, member, message); err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to mark as read")
+		return
+	}
+
+	res := model.ChannelReadStateRes{ChannelID: message.ChannelID.String()}
+	states := h.loadReadStates(ctx, &member.UserID, []uuid.UUID{message.RoomID})
+	if state, ok := states[*message.ChannelID]; ok {
+		res.UnreadCount = state.UnreadCount
+		res.MentionCount = state.MentionCount
+		if state.LastReadMessageID != nil {
+			res.LastReadMessageID = state.LastReadMessageID.String()
+		}
+	}
+
+	util.WriteJSONResponse(w, http.StatusOK, res)
+}
+
+// GetReadReceipts lists who has read a message. Receipts are only shared in
+// small rooms.
+func (h *CoreHandler) GetReadReceipts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	message, _, ok := h.loadReadTarget(w, r, chi.URLParam(r, "messageId"))
+	if !ok {
+		return
+	}
+	if !h.core.ReadReceiptsEnabled(ctx, message.RoomID) {
+		util.WriteErrorResponse(w, http.StatusForbidden, "Read receipts are not available in this room")
+		return
+	}
+
+	receipts, err := h.roomRepository.GetMessageReadReceipts(ctx, message)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load read receipts")
+		return
+	}
+
+	response := make([]model.ReadReceiptRes, 0, len(receipts))
+	for _, receipt := range receipts {
+		response = append(response, model.ReadReceiptRes{
+			UserID:   receipt.UserID.String(),
+			Username: receipt.Username,
+			ReadAt:   receipt.ReadAt,
+		})
+	}
+
+	util.WriteJSONResponse(w, http.StatusOK, response)
+}
+
+// loadReadTarget resolves a message in the {roomId} room and the caller's membership there.
+func (h *CoreHandler) loadReadTarget(w http.ResponseWriter, r *http.Request, rawMessageID string) (*roomRepository.Message, *roomRepository.RoomMember, bool) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(middleware.UserIDKey).(string)
+	if !ok {
+		util.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return nil, nil, false
+	}
+	parsedUserID, err := uuid.Parse(userID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID")
+		return nil, nil, false
+	}
+	messageID, err := uuid.Parse(rawMessageID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid message ID")
+		return nil, nil, false
+	}
+
+	message, err := h.roomRepository.GetMessageByID(ctx, messageID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load message")
+		return nil, nil, false
+	}
+	if message == nil || message.RoomID.String() != chi.URLParam(r, "roomId") {
+		util.WriteErrorResponse(w, http.StatusNotFound, "Message not found")
+		return nil, nil, false
+	}
+
+	member, err := h.roomRepository.GetRoomMember(ctx, message.RoomID, parsedUserID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load room membership")
+		return nil, nil, false
+	}
+	if member == nil || member.BannedAt != nil {
+		util.WriteErrorResponse(w, http.StatusForbidden, "You must be a member of this room")
+		return nil, nil, false
+	}
+
+	return message, member, true
+}
+
+// loadReadStates returns the viewer's read state per channel of the given
+// rooms. Failures are logged and yield no counts rather than failing the request.
+func (h *CoreHandler) loadReadStates(ctx context.Context, viewerID *uuid.UUID, roomIDs []uuid.UUID) map[uuid.UUID]roomRepository.ChannelReadState {
+	states := make(map[uuid.UUID]roomRepository.ChannelReadState)
+	if viewerID == nil {
+		return states
+	}
+
+	items, err := h.roomRepository.GetChannelReadStates(ctx, *viewerID, roomIDs)
+	if err != nil {
+		log.Printf("CoreHandler.loadReadStates - failed to load read states: %v", err)
+		return states
+	}
+	for _, item := range items {
+		states[item.ChannelID] = item
+	}
+	return states
+}
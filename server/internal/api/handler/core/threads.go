@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"chat-application/internal/api/model"
+	"chat-application/internal/constants"
+	"chat-application/internal/middleware"
+	"chat-application/internal/pagination"
+	roomRepository "chat-application/internal/repo/room"
+	"chat-application/util"
+
+	"github.com/google/uuid"
+)
+
+// GetThread returns a thread root and a page of its replies, oldest first.
+// Pass next_cursor back as ?cursor= to fetch the following page.
+func (h *CoreHandler) GetThread(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	parent, ok := h.loadThreadRoot(w, r)
+	if !ok {
+		return
+	}
+	if _, ok := h.loadReadableRoom(w, r, parent.RoomID); !ok {
+		return
+	}
+
+	limit, ok := parseLimit(w, r)
+	if !ok {
+		return
+	}
+	after, err := pagination.DecodeOptional(r.URL.Query().Get("cursor"))
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+
+	replies, err := h.roomRepository.GetThreadReplies(ctx, parent.ID, after, limit+1)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load thread")
+		return
+	}
+
+	res := model.ThreadRes{}
+	if len(replies) > limit {
+		replies = replies[:limit]
+		last := replies[len(replies)-1]
+		res.NextCursor = pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+
+	var viewerID *uuid.UUID
+	if userID, ok := ctx.Value(middleware.UserIDKey).(string); ok {
+		if parsedUserID, err := uuid.Parse(userID); err == nil {
+			viewerID = &parsedUserID
+			following, err := h.roomRepository.IsFollowingThread(ctx, parent.ID, parsedUserID)
+			if err == nil {
+				res.Following = following
+			}
+		}
+	}
+
+	replies = h.withoutBlocked(ctx, replies, viewerID)
+	mapped := h.mapMessages(ctx, append([]*roomRepository.Message{parent}, replies...), viewerID)
+	res.Parent = mapped[0]
+	res.Replies = mapped[1:]
+
+	util.WriteJSONResponse(w, http.StatusOK, res)
+}
+
+func (h *CoreHandler) FollowThread(w http.ResponseWriter, r *http.Request) {
+	h.setThreadFollow(w, r, true)
+}
+
+func (h *CoreHandler) UnfollowThread(w http.ResponseWriter, r *http.Request) {
+	h.setThreadFollow(w, r, false)
+}
+
+func (h *CoreHandler) setThreadFollow(w http.ResponseWriter, r *http.Request, follow bool) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(middleware.UserIDKey).(string)
+	if !ok {
+		util.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+	parsedUserID, err := uuid.Parse(userID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	parent, ok := h.loadThreadRoot(w, r)
+	if !ok {
+		return
+	}
+
+	member, err := h.roomRepository.GetRoomMember(ctx, parent.RoomID, parsedUserID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load room membership")
+		return
+	}
+	if member == nil || member.BannedAt != nil {
+		util.WriteErrorResponse(w, http.StatusForbidden, "You must be a member of this room to follow threads")
+		return
+	}
+
+	if follow {
+		err = h.roomRepository.FollowThread(ctx, parent.ID, parsedUserID)
+	} else {
+		err = h.roomRepository.UnfollowThread(ctx, parent.ID, parsedUserID)
+	}
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update thread follow")
+		return
+	}
+
+	util.WriteJSONResponse(w, http.StatusOK, map[string]bool{"following": follow})
+}
+
+// loadThreadRoot resolves the {roomId}/{messageId} pair to a root message in that room.
+func (h *CoreHandler) loadThreadRoot(w http.ResponseWriter, r *http.Request) (*roomRepository.Message, bool) {
+	roomID, err := uuid.Parse(chi.URLParam(r, "roomId"))
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid room ID")
+		return nil, false
+	}
+	messageID, err := uuid.Parse(chi.URLParam(r, "messageId"))
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid message ID")
+		return nil, false
+	}
+
+	message, err := h.roomRepository.GetMessageByID(r.Context(), messageID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load message")
+		return nil, false
+	}
+	if message == nil || message.RoomID != roomID || message.ParentMessageID != nil {
+		util.WriteErrorResponse(w, http.StatusNotFound, "Thread not found")
+		return nil, false
+	}
+
+	return message, true
+}
+
+// parseLimit reads the optional ?limit= page size, capped at constants.MaxPageSize.
+func parseLimit(w http.ResponseWriter, r *http.Request) (int, bool) {
+	limit := constants.DefaultPageSize
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		parsedLimit, err := strconv.Atoi(limitParam)
+		if err != nil || parsedLimit <= 0 {
+			util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid limit")
+			return 0, false
+		}
+		if parsedLimit > constants.MaxPageSize {
+			parsedLimit = constants.MaxPageSize
+		}
+		limit = parsedLimit
+	}
+	return limit, true
+}
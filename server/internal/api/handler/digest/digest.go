@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	digestService "chat-application/internal/service/digest"
+	"chat-application/util"
+)
+
+// DigestHandler handles HTTP requests for email digests.
+type DigestHandler struct {
+	digestService *digestService.DigestService
+}
+
+// NewDigestHandler creates a new DigestHandler instance.
+func NewDigestHandler(digestService *digestService.DigestService) *DigestHandler {
+	return &DigestHandler{
+		digestService: digestService,
+	}
+}
+
+// Unsubscribe turns off email digests from the link in a digest. It needs no
+// session: the signed token names the user.
+func (h *DigestHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	err := h.digestService.Unsubscribe(r.Context(), r.URL.Query().Get("token"))
+	if errors.Is(err, digestService.ErrInvalidToken) {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid unsubscribe link")
+		return
+	}
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to unsubscribe")
+		return
+	}
+
+	util.WriteJSONResponse(w, http.StatusOK, map[string]bool{"unsubscribed": true})
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"chat-application/internal/api/model"
+	"chat-application/internal/middleware"
+	pushService "chat-application/internal/service/push"
+	"chat-application/internal/webpush"
+	"chat-application/util"
+
+	"github.com/google/uuid"
+)
+
+// PushHandler handles HTTP requests for browser push subscriptions.
+type PushHandler struct {
+	pushService *pushService.PushService
+}
+
+// NewPushHandler creates a new PushHandler instance.
+func NewPushHandler(pushService *pushService.PushService) *PushHandler {
+	return &PushHandler{
+		pushService: pushService,
+	}
+}
+
+// GetVAPIDPublicKey returns the application server key browsers pass to
+// pushManager.subscribe.
+func (h *PushHandler) GetVAPIDPublicKey(w http.ResponseWriter, r *http.Request) {
+	publicKey := h.pushService.VAPIDPublicKey()
+	if publicKey == "" {
+		util.WriteErrorResponse(w, http.StatusServiceUnavailable, pushService.ErrPushDisabled.Error())
+		return
+	}
+
+	util.WriteJSONResponse(w, http.StatusOK, model.VAPIDPublicKeyRes{PublicKey: publicKey})
+}
+
+// Subscribe registers the browser subscription in the body for the current user.
+func (h *PushHandler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req model.PushSubscriptionReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	err := h.pushService.Subscribe(r.Context(), userID, webpush.Subscription{
+		Endpoint: strings.TrimSpace(req.Endpoint),
+		P256dh:   req.Keys.P256dh,
+		Auth:     req.Keys.Auth,
+	}, r.UserAgent())
+	switch {
+	case errors.Is(err, pushService.ErrInvalidSubscription):
+		util.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, pushService.ErrPushDisabled):
+		util.WriteErrorResponse(w, http.StatusServiceUnavailable, err.Error())
+		return
+	case err != nil:
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to save push subscription")
+		return
+	}
+
+	util.WriteJSONResponse(w, http.StatusCreated, map[string]bool{"subscribed": true})
+}
+
+// Unsubscribe removes the current user's subscription for the endpoint in the body.
+func (h *PushHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req model.PushUnsubscribeReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Endpoint) == "" {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "endpoint is required")
+		return
+	}
+
+	deleted, err := h.pushService.Unsubscribe(r.Context(), userID, strings.TrimSpace(req.Endpoint))
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to remove push subscription")
+		return
+	}
+	if !deleted {
+		util.WriteErrorResponse(w, http.StatusNotFound, "Push subscription not found")
+		return
+	}
+
+	util.WriteJSONResponse(w, http.StatusOK, map[string]bool{"unsubscribed": true})
+}
+
+func currentUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userIDString, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		util.WriteErrorResponse(w, http.StatusUnauthorized, "user not authenticated")
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(userIDString)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "invalid user ID format")
+		return uuid.Nil, false
+	}
+	return userID, true
+}
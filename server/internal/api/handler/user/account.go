@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"chat-application/internal/api/model"
+	"chat-application/internal/constants"
+	service "chat-application/internal/service/user"
+	"chat-application/util"
+)
+
+// ExportData downloads everything stored about the current user, as JSON or,
+// with ?format=zip, as a ZIP archive of JSON files.
+func (h *UserHandler) ExportData(w http.ResponseWriter, r *http.Request) {
+	uid, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "zip" {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "format must be json or zip")
+		return
+	}
+
+	export, err := h.userService.ExportUserData(r.Context(), uid)
+	if err != nil {
+		log.Printf("ExportData - Service error: %v", err)
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "failed to export data")
+		return
+	}
+
+	filename := "yappin-export-" + export.ExportedAt.Format("2006-01-02")
+	if format == "zip" {
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.zip"`)
+		w.WriteHeader(http.StatusOK)
+		if err := service.WriteExportArchive(w, export); err != nil {
+			log.Printf("ExportData - Archive error: %v", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.json"`)
+	util.WriteJSONResponse(w, http.StatusOK, export)
+}
+
+// DeleteAccount deletes the current user after re-authentication and signs
+// them out.
+func (h *UserHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	uid, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req model.RequestDeleteAccount
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+
+	err := h.userService.DeleteAccount(r.Context(), uid, req)
+	switch {
+	case errors.Is(err, service.ErrInvalidPassword),
+		errors.Is(err, service.ErrInvalidTOTPCode),
+		errors.Is(err, service.ErrDeleteNotConfirmed):
+		util.WriteErrorResponse(w, http.StatusForbidden, err.Error())
+		return
+	case err != nil:
+		log.Printf("DeleteAccount - Service error: %v", err)
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "failed to delete account")
+		return
+	}
+
+	util.ClearSecureCookie(w, constants.JWTCookieName)
+	util.WriteJSONResponse(w, http.StatusOK, map[string]bool{"deleted": true})
+}
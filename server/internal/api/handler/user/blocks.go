@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"chat-application/internal/api/model"
+	service "chat-application/internal/service/user"
+	"chat-application/util"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// GetBlockedUsers lists the users the current user has blocked.
+func (h *UserHandler) GetBlockedUsers(w http.ResponseWriter, r *http.Request) {
+	uid, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	blocked, err := h.userService.GetBlockedUsers(r.Context(), uid)
+	if err != nil {
+		log.Printf("GetBlockedUsers - Service error: %v", err)
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "failed to get blocked users")
+		return
+	}
+
+	response := make([]model.BlockedUserRes, 0, len(blocked))
+	for _, user := range blocked {
+		response = append(response, model.BlockedUserRes{
+			UserID:    user.UserID.String(),
+			Username:  user.Username,
+			CreatedAt: user.CreatedAt,
+		})
+	}
+
+	util.WriteJSONResponse(w, http.StatusOK, response)
+}
+
+// BlockUser blocks the user in the path for the current user.
+func (h *UserHandler) BlockUser(w http.ResponseWriter, r *http.Request) {
+	uid, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+	blockedID, err := uuid.Parse(chi.URLParam(r, "userID"))
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "invalid user ID format")
+		return
+	}
+
+	err = h.userService.BlockUser(r.Context(), uid, blockedID)
+	switch {
+	case errors.Is(err, service.ErrCannotBlockSelf):
+		util.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, service.ErrUserNotFound):
+		util.WriteErrorResponse(w, http.StatusNotFound, err.Error())
+		return
+	case err != nil:
+		log.Printf("BlockUser - Service error: %v", err)
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "failed to block user")
+		return
+	}
+
+	util.WriteJSONResponse(w, http.StatusOK, map[string]bool{"blocked": true})
+}
+
+// UnblockUser removes the current user's block on the user in the path.
+func (h *UserHandler) UnblockUser(w http.ResponseWriter, r *http.Request) {
+	uid, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+	blockedID, err := uuid.Parse(chi.URLParam(r, "userID"))
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "invalid user ID format")
+		return
+	}
+
+	if err := h.userService.UnblockUser(r.Context(), uid, blockedID); err != nil {
+		log.Printf("UnblockUser - Service error: %v", err)
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "failed to unblock user")
+		return
+	}
+
+	util.WriteJSONResponse(w, http.StatusOK, map[string]bool{"blocked": false})
+}
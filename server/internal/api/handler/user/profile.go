@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"chat-application/internal/api/model"
+	"chat-application/internal/constants"
+	attachmentsService "chat-application/internal/service/attachments"
+	service "chat-application/internal/service/user"
+	"chat-application/util"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// GetMyProfile returns the current user's profile.
+func (h *UserHandler) GetMyProfile(w http.ResponseWriter, r *http.Request) {
+	uid, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	profile, err := h.userService.GetProfile(r.Context(), uid)
+	if err != nil {
+		log.Printf("GetMyProfile - Service error: %v", err)
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "failed to get profile")
+		return
+	}
+	if profile == nil {
+		util.WriteErrorResponse(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	util.WriteJSONResponse(w, http.StatusOK, profile)
+}
+
+// UpdateProfile replaces the current user's display name, bio, pronouns and
+// status.
+func (h *UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	uid, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req model.RequestUpdateProfile
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+
+	profile, err := h.userService.UpdateProfile(r.Context(), uid, req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidProfile) {
+			util.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("UpdateProfile - Service error: %v", err)
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "failed to update profile")
+		return
+	}
+
+	util.WriteJSONResponse(w, http.StatusOK, profile)
+}
+
+// UploadAvatar handles multipart uploads with a "file" part holding the new
+// avatar image.
+func (h *UserHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
+	uid, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, constants.MaxAvatarSize+(1<<20))
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			util.WriteErrorResponse(w, http.StatusRequestEntityTooLarge, "avatar exceeds the maximum size")
+			return
+		}
+		util.WriteErrorResponse(w, http.StatusBadRequest, "invalid multipart form")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "file is required")
+		return
+	}
+	defer file.Close()
+
+	profile, err := h.userService.UploadAvatar(r.Context(), uid, file)
+	if err != nil {
+		switch {
+		case errors.Is(err, attachmentsService.ErrFileTooLarge):
+			util.WriteErrorResponse(w, http.StatusRequestEntityTooLarge, "avatar exceeds the maximum size")
+		case errors.Is(err, attachmentsService.ErrUnsupportedFileType):
+			util.WriteErrorResponse(w, http.StatusUnsupportedMediaType, "avatar must be a PNG, JPEG or GIF image")
+		case errors.Is(err, attachmentsService.ErrEmptyFile):
+			util.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrAvatarsUnavailable):
+			util.WriteErrorResponse(w, http.StatusServiceUnavailable, err.Error())
+		default:
+			log.Printf("UploadAvatar - Service error: %v", err)
+			util.WriteErrorResponse(w, http.StatusInternalServerError, "failed to upload avatar")
+		}
+		return
+	}
+
+	util.WriteJSONResponse(w, http.StatusOK, profile)
+}
+
+// RemoveAvatar clears the current user's avatar.
+func (h *UserHandler) RemoveAvatar(w http.ResponseWriter, r *http.Request) {
+	uid, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	profile, err := h.userService.RemoveAvatar(r.Context(), uid)
+	if err != nil {
+		log.Printf("RemoveAvatar - Service error: %v", err)
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "failed to remove avatar")
+		return
+	}
+
+	util.WriteJSONResponse(w, http.StatusOK, profile)
+}
+
+// GetAvatar streams a user's avatar image.
+func (h *UserHandler) GetAvatar(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userID"))
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "invalid user ID format")
+		return
+	}
+
+	reader, contentType, err := h.userService.OpenAvatar(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, service.ErrAvatarNotFound) || errors.Is(err, attachmentsService.ErrAvatarNotFound) {
+			util.WriteErrorResponse(w, http.StatusNotFound, "avatar not found")
+			return
+		}
+		log.Printf("GetAvatar - Service error for user %s: %v", userID, err)
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "failed to load avatar")
+		return
+	}
+	defer reader.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, reader); err != nil {
+		log.Printf("GetAvatar - Error streaming avatar for user %s: %v", userID, err)
+	}
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"chat-application/internal/constants"
+	"chat-application/internal/pagination"
+	service "chat-application/internal/service/user"
+	"chat-application/util"
+
+	"github.com/google/uuid"
+)
+
+// SearchUsers looks users up by ?q=, matching usernames and display names.
+// When more remain, the next page is linked from the Link header
+// (rel="next").
+func (h *UserHandler) SearchUsers(w http.ResponseWriter, r *http.Request) {
+	uid, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	limit := constants.DefaultPageSize
+	if limitParam := query.Get("limit"); limitParam != "" {
+		parsedLimit, err := strconv.Atoi(limitParam)
+		if err != nil || parsedLimit <= 0 {
+			util.WriteErrorResponse(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = min(parsedLimit, constants.MaxPageSize)
+	}
+	after, err := pagination.DecodeRank(query.Get("cursor"))
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "invalid cursor")
+		return
+	}
+
+	results, next, err := h.userService.SearchUsers(r.Context(), uid, query.Get("q"), after, limit)
+	if errors.Is(err, service.ErrInvalidSearch) {
+		util.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("SearchUsers - Service error: %v", err)
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "failed to search users")
+		return
+	}
+
+	if next != nil {
+		params := url.Values{}
+		params.Set("q", query.Get("q"))
+		params.Set("cursor", next.Encode())
+		params.Set("limit", fmt.Sprint(limit))
+		w.Header().Set("Link", fmt.Sprintf(`<%s?%s>; rel="next"`, r.URL.Path, params.Encode()))
+	}
+
+	util.WriteJSONResponse(w, http.StatusOK, results)
+}
+
+// AutocompleteMembers suggests members of ?room_id= for the mention picker
+// from the start of their username or display name in ?q=.
+func (h *UserHandler) AutocompleteMembers(w http.ResponseWriter, r *http.Request) {
+	uid, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+	roomID, err := uuid.Parse(r.URL.Query().Get("room_id"))
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "invalid room ID format")
+		return
+	}
+
+	results, err := h.userService.AutocompleteMembers(r.Context(), uid, roomID, r.URL.Query().Get("q"))
+	switch {
+	case errors.Is(err, service.ErrInvalidSearch):
+		util.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, service.ErrRoomNotFound):
+		util.WriteErrorResponse(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, service.ErrRoomForbidden):
+		util.WriteErrorResponse(w, http.StatusForbidden, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("AutocompleteMembers - Service error: %v", err)
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "failed to search members")
+		return
+	}
+
+	util.WriteJSONResponse(w, http.StatusOK, results)
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"chat-application/internal/api/model"
+	"chat-application/internal/constants"
+	"chat-application/internal/pagination"
+	service "chat-application/internal/service/user"
+	"chat-application/util"
+)
+
+// GetLoginHistory returns the current user's recent sign-in attempts, newest
+// first. When more remain, the next page is linked from the Link header
+// (rel="next").
+func (h *UserHandler) GetLoginHistory(w http.ResponseWriter, r *http.Request) {
+	uid, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	limit := constants.DefaultPageSize
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		parsedLimit, err := strconv.Atoi(limitParam)
+		if err != nil || parsedLimit <= 0 {
+			util.WriteErrorResponse(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = min(parsedLimit, constants.MaxPageSize)
+	}
+	before, err := pagination.DecodeOptional(r.URL.Query().Get("cursor"))
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "invalid cursor")
+		return
+	}
+
+	events, err := h.userService.GetLoginHistory(r.Context(), uid, before, limit+1)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "failed to get sign-in history")
+		return
+	}
+
+	if len(events) > limit {
+		events = events[:limit]
+		last := events[len(events)-1]
+		next := url.Values{}
+		next.Set("cursor", pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode())
+		next.Set("limit", fmt.Sprint(limit))
+		w.Header().Set("Link", fmt.Sprintf(`<%s?%s>; rel="next"`, r.URL.Path, next.Encode()))
+	}
+
+	response := make([]model.LoginEventRes, 0, len(events))
+	for _, event := range events {
+		response = append(response, model.LoginEventRes{
+			ID:        event.ID.String(),
+			Method:    event.Method,
+			Outcome:   event.Outcome,
+			IPAddress: event.IPAddress,
+			UserAgent: event.UserAgent,
+			CreatedAt: event.CreatedAt,
+		})
+	}
+
+	util.WriteJSONResponse(w, http.StatusOK, response)
+}
+
+// loginClient describes the client making a sign-in request. RemoteAddr
+// already holds the forwarded address when the RealIP middleware runs.
+func loginClient(r *http.Request) service.LoginClient {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		ip = host
+	}
+	return service.LoginClient{IPAddress: ip, UserAgent: r.UserAgent()}
+}
+
+// writeAccountLocked responds 429 with Retry-After if err is an account
+// lock, and reports whether it was.
+func writeAccountLocked(w http.ResponseWriter, err error) bool {
+	var locked *service.AccountLockedError
+	if !errors.As(err, &locked) {
+		return false
+	}
+	retryAfter := math.Ceil(time.Until(locked.Until).Seconds())
+	w.Header().Set("Retry-After", strconv.Itoa(max(int(retryAfter), 1)))
+	util.WriteErrorResponse(w, http.StatusTooManyRequests, err.Error())
+	return true
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"chat-application/internal/api/model"
+	"chat-application/internal/constants"
+	"chat-application/internal/middleware"
+	service "chat-application/internal/service/user"
+	"chat-application/util"
+
+	"github.com/google/uuid"
+)
+
+// CompleteMFALogin finishes a two-step login and sets the session cookie.
+func (h *UserHandler) CompleteMFALogin(w http.ResponseWriter, r *http.Request) {
+	var req model.RequestMFALogin
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+
+	user, err := h.userService.CompleteMFALogin(r.Context(), req, loginClient(r))
+	if err != nil {
+		if writeAccountLocked(w, err) {
+			return
+		}
+		if errors.Is(err, service.ErrInvalidTOTPCode) || errors.Is(err, service.ErrInvalidMFAChallenge) {
+			util.WriteErrorResponse(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		log.Printf("CompleteMFALogin - Service error: %v", err)
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "failed to authenticate user")
+		return
+	}
+
+	util.SetCookie(w, constants.JWTCookieName, user.AccessToken, int(constants.JWTCookieDuration.Seconds()))
+
+	util.WriteJSONResponse(w, http.StatusOK, user)
+}
+
+// GetTwoFactorStatus reports whether the current user has two-factor
+// authentication enabled.
+func (h *UserHandler) GetTwoFactorStatus(w http.ResponseWriter, r *http.Request) {
+	uid, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	status, err := h.userService.GetTwoFactorStatus(r.Context(), uid)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "failed to get two-factor status")
+		return
+	}
+
+	util.WriteJSONResponse(w, http.StatusOK, status)
+}
+
+// SetupTOTP starts authenticator enrollment and returns the secret and the
+// provisioning URI to show as a QR code.
+func (h *UserHandler) SetupTOTP(w http.ResponseWriter, r *http.Request) {
+	uid, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	setup, err := h.userService.BeginTOTPEnrollment(r.Context(), uid)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrTwoFactorEnabled):
+			util.WriteErrorResponse(w, http.StatusConflict, err.Error())
+		case errors.Is(err, service.ErrTwoFactorUnavailable):
+			util.WriteErrorResponse(w, http.StatusServiceUnavailable, err.Error())
+		default:
+			log.Printf("SetupTOTP - Service error: %v", err)
+			util.WriteErrorResponse(w, http.StatusInternalServerError, "failed to start two-factor setup")
+		}
+		return
+	}
+
+	util.WriteJSONResponse(w, http.StatusOK, setup)
+}
+
+// EnableTOTP confirms enrollment with a code from the authenticator and
+// returns the recovery codes.
+func (h *UserHandler) EnableTOTP(w http.ResponseWriter, r *http.Request) {
+	uid, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req model.RequestTOTPCode
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+
+	codes, err := h.userService.ConfirmTOTPEnrollment(r.Context(), uid, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidTOTPCode), errors.Is(err, service.ErrTwoFactorNotPending):
+			util.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrTwoFactorEnabled):
+			util.WriteErrorResponse(w, http.StatusConflict, err.Error())
+		default:
+			log.Printf("EnableTOTP - Service error: %v", err)
+			util.WriteErrorResponse(w, http.StatusInternalServerError, "failed to enable two-factor authentication")
+		}
+		return
+	}
+
+	util.WriteJSONResponse(w, http.StatusOK, model.ResponseRecoveryCodes{RecoveryCodes: codes})
+}
+
+// DisableTOTP turns off two-factor authentication after checking the
+// current user's password.
+func (h *UserHandler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	uid, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req model.RequestDisableTOTP
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+
+	err := h.userService.DisableTOTP(r.Context(), uid, req)
+	switch {
+	case errors.Is(err, service.ErrInvalidPassword), errors.Is(err, service.ErrInvalidTOTPCode):
+		util.WriteErrorResponse(w, http.StatusForbidden, err.Error())
+		return
+	case errors.Is(err, service.ErrTwoFactorNotEnabled):
+		util.WriteErrorResponse(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		log.Printf("DisableTOTP - Service error: %v", err)
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "failed to disable two-factor authentication")
+		return
+	}
+
+	util.WriteJSONResponse(w, http.StatusOK, map[string]bool{"enabled": false})
+}
+
+// currentUserID reads the authenticated user's ID, writing an error
+// response if there is none.
+func currentUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		util.WriteErrorResponse(w, http.StatusUnauthorized, "user not authenticated")
+		return uuid.Nil, false
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "invalid user id")
+		return uuid.Nil, false
+	}
+
+	return uid, true
+}
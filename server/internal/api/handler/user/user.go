@@ -2,8 +2,11 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"chat-application/internal/api/model"
 	"chat-application/internal/constants"
@@ -11,6 +14,7 @@ import (
 	service "chat-application/internal/service/user"
 	"chat-application/util"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 )
 
@@ -58,12 +62,21 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.userService.Login(r.Context(), req)
+	user, err := h.userService.Login(r.Context(), req, loginClient(r))
 	if err != nil {
+		if writeAccountLocked(w, err) {
+			return
+		}
 		util.WriteErrorResponse(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
+	// The session cookie is only set once the second factor is checked.
+	if user.MFARequired {
+		util.WriteJSONResponse(w, http.StatusOK, user)
+		return
+	}
+
 	util.SetCookie(w, constants.JWTCookieName, user.AccessToken, int(constants.JWTCookieDuration.Seconds()))
 
 	util.WriteJSONResponse(w, http.StatusOK, user)
@@ -127,10 +140,270 @@ func (h *UserHandler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := model.ResponseLoginUser{
-		ID:       user.ID.String(),
-		Username: user.Username,
-		Email:    user.Email,
+		ID:            user.ID.String(),
+		Username:      user.Username,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt != nil,
+	}
+
+	util.WriteJSONResponse(w, http.StatusOK, response)
+}
+
+// RequestPasswordReset emails a reset link. It responds the same whether or
+// not the address has an account.
+func (h *UserHandler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req model.RequestPasswordReset
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+
+	if err := h.userService.RequestPasswordReset(r.Context(), req.Email); err != nil {
+		log.Printf("RequestPasswordReset - Service error: %v", err)
+	}
+
+	util.WriteJSONResponse(w, http.StatusAccepted, map[string]string{
+		"message": "if an account uses that email, a reset link is on its way",
+	})
+}
+
+// ConfirmPasswordReset sets a new password using the token from a reset email.
+func (h *UserHandler) ConfirmPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req model.RequestConfirmPasswordReset
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+
+	password := util.SanitizeString(req.Password)
+	if err := util.ValidatePassword(password); err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.userService.ResetPassword(r.Context(), req.Token, password); err != nil {
+		if errors.Is(err, service.ErrInvalidToken) {
+			util.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("ConfirmPasswordReset - Service error: %v", err)
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "failed to reset password")
+		return
+	}
+
+	util.WriteJSONResponse(w, http.StatusOK, map[string]string{"message": "password updated"})
+}
+
+// RequestEmailVerification emails the current user a new verification link.
+func (h *UserHandler) RequestEmailVerification(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		util.WriteErrorResponse(w, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "invalid user id")
+		return
+	}
+
+	if err := h.userService.RequestEmailVerification(r.Context(), uid); err != nil {
+		if errors.Is(err, service.ErrEmailAlreadyVerified) {
+			util.WriteErrorResponse(w, http.StatusConflict, err.Error())
+			return
+		}
+		log.Printf("RequestEmailVerification - Service error: %v", err)
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "failed to send verification email")
+		return
+	}
+
+	util.WriteJSONResponse(w, http.StatusAccepted, map[string]string{"message": "verification email sent"})
+}
+
+// VerifyEmail marks the address verified using the token from a verification email.
+func (h *UserHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req model.RequestVerifyEmail
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+
+	if err := h.userService.VerifyEmail(r.Context(), req.Token); err != nil {
+		if errors.Is(err, service.ErrInvalidToken) {
+			util.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("VerifyEmail - Service error: %v", err)
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "failed to verify email")
+		return
+	}
+
+	util.WriteJSONResponse(w, http.StatusOK, map[string]bool{"email_verified": true})
+}
+
+// RequireVerifiedEmail is middleware that refuses users whose email is not
+// verified, when the service enforces verification. It must run after the
+// JWT middleware; anonymous requests are refused too.
+func (h *UserHandler) RequireVerifiedEmail(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !h.userService.RequireVerifiedEmail {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+		uid, err := uuid.Parse(userID)
+		if !ok || err != nil {
+			util.WriteErrorResponse(w, http.StatusForbidden, service.ErrEmailNotVerified.Error())
+			return
+		}
+
+		if err := h.userService.CheckEmailVerified(r.Context(), uid); err != nil {
+			if errors.Is(err, service.ErrEmailNotVerified) {
+				util.WriteErrorResponse(w, http.StatusForbidden, err.Error())
+				return
+			}
+			util.WriteErrorResponse(w, http.StatusInternalServerError, "failed to check email verification")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// GetOAuthProviders lists the social login providers that are configured.
+func (h *UserHandler) GetOAuthProviders(w http.ResponseWriter, r *http.Request) {
+	util.WriteJSONResponse(w, http.StatusOK, map[string][]string{"providers": h.userService.OAuthProviderNames()})
+}
+
+// StartOAuth redirects to the provider's login page. A signed-in user links
+// the provider account to their account instead.
+func (h *UserHandler) StartOAuth(w http.ResponseWriter, r *http.Request) {
+	var linkUserID *uuid.UUID
+	if userID, ok := r.Context().Value(middleware.UserIDKey).(string); ok {
+		if uid, err := uuid.Parse(userID); err == nil {
+			linkUserID = &uid
+		}
+	}
+
+	authURL, state, err := h.userService.BeginOAuth(r.Context(), chi.URLParam(r, "provider"), linkUserID)
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownProvider) {
+			util.WriteErrorResponse(w, http.StatusNotFound, err.Error())
+			return
+		}
+		log.Printf("StartOAuth - Service error: %v", err)
+		util.WriteErrorResponse(w, http.StatusBadGateway, "login provider is unavailable")
+		return
+	}
+
+	util.SetCookie(w, constants.OAuthStateCookieName, state, int(constants.OAuthStateExpiry.Seconds()))
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OAuthCallback completes a provider login, sets the session cookie and
+// sends the browser back to the app. Failures are reported to the app in an
+// error query parameter.
+func (h *UserHandler) OAuthCallback(w http.ResponseWriter, r *http.Request) {
+	util.ClearSecureCookie(w, constants.OAuthStateCookieName)
+	appURL := strings.TrimRight(h.userService.AppURL, "/")
+
+	query := r.URL.Query()
+	if query.Get("error") != "" {
+		http.Redirect(w, r, appURL+"/login?error="+url.QueryEscape("login was cancelled"), http.StatusFound)
+		return
+	}
+
+	var stateCookie string
+	if cookie, err := r.Cookie(constants.OAuthStateCookieName); err == nil {
+		stateCookie = cookie.Value
+	}
+
+	user, err := h.userService.CompleteOAuth(r.Context(), chi.URLParam(r, "provider"), query.Get("code"), query.Get("state"), stateCookie, loginClient(r))
+	if err != nil {
+		log.Printf("OAuthCallback - Service error: %v", err)
+		message := "login failed, please try again"
+		switch {
+		case errors.Is(err, service.ErrInvalidOAuthState),
+			errors.Is(err, service.ErrOAuthEmailRequired),
+			errors.Is(err, service.ErrIdentityInUse):
+			message = err.Error()
+		}
+		http.Redirect(w, r, appURL+"/login?error="+url.QueryEscape(message), http.StatusFound)
+		return
+	}
+
+	// The challenge is useless without the second factor, so it can travel
+	// in the URL to the app's login page.
+	if user.MFARequired {
+		http.Redirect(w, r, appURL+"/login?mfa_token="+url.QueryEscape(user.MFAToken), http.StatusFound)
+		return
+	}
+
+	util.SetCookie(w, constants.JWTCookieName, user.AccessToken, int(constants.JWTCookieDuration.Seconds()))
+	http.Redirect(w, r, appURL+"/", http.StatusFound)
+}
+
+// GetLinkedIdentities lists the provider accounts linked to the current user.
+func (h *UserHandler) GetLinkedIdentities(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		util.WriteErrorResponse(w, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "invalid user id")
+		return
+	}
+
+	identities, err := h.userService.GetLinkedIdentities(r.Context(), uid)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "failed to get linked accounts")
+		return
+	}
+
+	response := make([]model.LinkedIdentityRes, 0, len(identities))
+	for _, identity := range identities {
+		response = append(response, model.LinkedIdentityRes{
+			Provider:    identity.Provider,
+			Email:       identity.Email,
+			CreatedAt:   identity.CreatedAt,
+			LastLoginAt: identity.LastLoginAt,
+		})
 	}
 
 	util.WriteJSONResponse(w, http.StatusOK, response)
 }
+
+// UnlinkIdentity removes a linked provider account from the current user.
+func (h *UserHandler) UnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		util.WriteErrorResponse(w, http.StatusUnauthorized, "user not authenticated")
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		util.WriteErrorResponse(w, http.StatusBadRequest, "invalid user id")
+		return
+	}
+
+	err = h.userService.UnlinkIdentity(r.Context(), uid, chi.URLParam(r, "provider"))
+	switch {
+	case errors.Is(err, service.ErrIdentityNotLinked):
+		util.WriteErrorResponse(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, service.ErrLastSignInMethod):
+		util.WriteErrorResponse(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		util.WriteErrorResponse(w, http.StatusInternalServerError, "failed to unlink account")
+		return
+	}
+
+	util.WriteJSONResponse(w, http.StatusOK, map[string]bool{"unlinked": true})
+}
@@ -3,19 +3,21 @@ package model
 import "time"
 
 type RoomPermissionRes struct {
-	Role              string `json:"role"`
-	CanManageRoom     bool   `json:"can_manage_room"`
-	CanManageChannels bool   `json:"can_manage_channels"`
-	CanModerate       bool   `json:"can_moderate"`
-	CanPost           bool   `json:"can_post"`
-	IsMuted           bool   `json:"is_muted"`
-	IsBanned          bool   `json:"is_banned"`
+	Role               string `json:"role"`
+	CanManageRoom      bool   `json:"can_manage_room"`
+	CanManageChannels  bool   `json:"can_manage_channels"`
+	CanModerate        bool   `json:"can_moderate"`
+	CanPost            bool   `json:"can_post"`
+	CanMentionEveryone bool   `json:"can_mention_everyone"`
+	IsMuted            bool   `json:"is_muted"`
+	IsBanned           bool   `json:"is_banned"`
 }
 
 type RoomMemberRes struct {
 	UserID    string    `json:"user_id"`
 	Username  string    `json:"username"`
 	Role      string    `json:"role"`
+	InvitedBy string    `json:"invited_by,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
@@ -34,6 +36,29 @@ type RoomChannelRes struct {
 	Kind        string `json:"kind"`
 	Position    int    `json:"position"`
 	IsPrivate   bool   `json:"is_private"`
+	// SlowModeSeconds is how long members wait between messages; zero is off.
+	SlowModeSeconds int `json:"slow_mode_seconds"`
+
+	LastReadMessageID string `json:"last_read_message_id,omitempty"`
+	UnreadCount       int    `json:"unread_count"`
+	MentionCount      int    `json:"mention_count"`
+}
+
+type ChannelReadStateRes struct {
+	ChannelID         string `json:"channel_id"`
+	LastReadMessageID string `json:"last_read_message_id,omitempty"`
+	UnreadCount       int    `json:"unread_count"`
+	MentionCount      int    `json:"mention_count"`
+}
+
+type MarkReadReq struct {
+	MessageID string `json:"message_id"`
+}
+
+type ReadReceiptRes struct {
+	UserID   string    `json:"user_id"`
+	Username string    `json:"username"`
+	ReadAt   time.Time `json:"read_at"`
 }
 
 type NotificationRes struct {
@@ -48,6 +73,32 @@ type NotificationRes struct {
 	RoomID    string         `json:"room_id,omitempty"`
 }
 
+type NotificationPreferenceReq struct {
+	Level      string     `json:"level,omitempty"`
+	MutedUntil *time.Time `json:"muted_until,omitempty"`
+	// EmailDigest is only accepted on the default preference; omitting it
+	// leaves the current setting.
+	EmailDigest *bool `json:"email_digest,omitempty"`
+}
+
+// NotificationPreferenceRes is a stored preference. An empty Level on a room
+// preference means the room follows the default level.
+type NotificationPreferenceRes struct {
+	RoomID      string     `json:"room_id,omitempty"`
+	Level       string     `json:"level,omitempty"`
+	MutedUntil  *time.Time `json:"muted_until,omitempty"`
+	EmailDigest *bool      `json:"email_digest,omitempty"`
+}
+
+type NotificationPreferencesRes struct {
+	Default NotificationPreferenceRes   `json:"default"`
+	Rooms   []NotificationPreferenceRes `json:"rooms"`
+}
+
+type NotificationCountRes struct {
+	UnreadCount int `json:"unread_count"`
+}
+
 type MessageSearchRes struct {
 	ID              string    `json:"id"`
 	RoomID          string    `json:"room_id"`
@@ -57,20 +108,39 @@ type MessageSearchRes struct {
 	Content         string    `json:"content"`
 	Highlighted     string    `json:"highlighted"`
 	CreatedAt       time.Time `json:"created_at"`
+	Cursor          string    `json:"cursor"`
 }
 
 type MessageRes struct {
-	ID              string            `json:"id"`
-	Content         string            `json:"content"`
-	RoomID          string            `json:"room_id"`
-	ChannelID       string            `json:"channel_id"`
-	ParentMessageID string            `json:"parent_message_id,omitempty"`
-	Username        string            `json:"username"`
-	UserID          string            `json:"user_id,omitempty"`
-	System          bool              `json:"system"`
-	CreatedAt       time.Time         `json:"created_at"`
-	Metadata        map[string]any    `json:"metadata,omitempty"`
-	Reactions       []MessageReaction `json:"reactions,omitempty"`
+	ID              string             `json:"id"`
+	Content         string             `json:"content"`
+	RoomID          string             `json:"room_id"`
+	ChannelID       string             `json:"channel_id"`
+	ParentMessageID string             `json:"parent_message_id,omitempty"`
+	Username        string             `json:"username"`
+	UserID          string             `json:"user_id,omitempty"`
+	System          bool               `json:"system"`
+	CreatedAt       time.Time          `json:"created_at"`
+	ReplyCount      int                `json:"reply_count"`
+	LastReplyAt     *time.Time         `json:"last_reply_at,omitempty"`
+	Metadata        map[string]any     `json:"metadata,omitempty"`
+	Reactions       []ReactionCountRes `json:"reactions,omitempty"`
+}
+
+// MessagePageRes is a window of channel history. PrevCursor is set when older
+// messages exist (pass it as ?before=), NextCursor when newer ones do (?after=).
+type MessagePageRes struct {
+	ChannelID  string       `json:"channel_id,omitempty"`
+	Messages   []MessageRes `json:"messages"`
+	PrevCursor string       `json:"prev_cursor,omitempty"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+type ThreadRes struct {
+	Parent     MessageRes   `json:"parent"`
+	Replies    []MessageRes `json:"replies"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+	Following  bool         `json:"following"`
 }
 
 type RoomDetailRes struct {
@@ -84,6 +154,9 @@ type RoomDetailRes struct {
 	NotificationCount  int                `json:"notification_count"`
 	OnlineMemberCount  int                `json:"online_member_count"`
 	ThreadedReplyCount int                `json:"threaded_reply_count"`
+	Emojis             []RoomEmojiRes     `json:"emojis"`
+	UnreadCount        int                `json:"unread_count"`
+	MentionCount       int                `json:"mention_count"`
 }
 
 type CreateCategoryReq struct {
@@ -101,10 +174,11 @@ type CreateChannelReq struct {
 }
 
 type UpdateMemberRoleReq struct {
-	Role              string `json:"role"`
-	CanManageRoom     *bool  `json:"can_manage_room,omitempty"`
-	CanManageChannels *bool  `json:"can_manage_channels,omitempty"`
-	CanModerate       *bool  `json:"can_moderate,omitempty"`
-	CanPost           *bool  `json:"can_post,omitempty"`
-	Ban               bool   `json:"ban"`
+	Role               string `json:"role"`
+	CanManageRoom      *bool  `json:"can_manage_room,omitempty"`
+	CanManageChannels  *bool  `json:"can_manage_channels,omitempty"`
+	CanModerate        *bool  `json:"can_moderate,omitempty"`
+	CanPost            *bool  `json:"can_post,omitempty"`
+	CanMentionEveryone *bool  `json:"can_mention_everyone,omitempty"`
+	Ban                bool   `json:"ban"`
 }
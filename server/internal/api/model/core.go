@@ -6,6 +6,8 @@ type CreateRoomReq struct {
 	ID        string     `json:"id,omitempty"`
 	Name      string     `json:"name"`
 	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// Visibility defaults to public.
+	Visibility string `json:"visibility,omitempty"`
 }
 
 type ClientRes struct {
@@ -25,6 +27,9 @@ type RoomRes struct {
 	TopicSource      *string   `json:"topic_source,omitempty"`
 	CreatorUsername  *string   `json:"creator_username,omitempty"`
 	Participants     int       `json:"participants"`
+	UnreadCount      int       `json:"unread_count"`
+	MentionCount     int       `json:"mention_count"`
+	Visibility       string    `json:"visibility"`
 }
 
 type MessageReaction struct {
@@ -39,3 +44,37 @@ type RequestAddReaction struct {
 	MessageID string `json:"message_id"`
 	Emoji     string `json:"emoji"`
 }
+
+type ReactionCountRes struct {
+	Emoji   string `json:"emoji"`
+	Count   int    `json:"count"`
+	Reacted bool   `json:"reacted"`
+}
+
+type ReactionToggleRes struct {
+	MessageID string `json:"message_id"`
+	Emoji     string `json:"emoji"`
+	Added     bool   `json:"added"`
+	Count     int    `json:"count"`
+}
+
+type RoomEmojiRes struct {
+	Name      string    `json:"name"`
+	Shortcode string    `json:"shortcode"`
+	ImageURL  string    `json:"image_url"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateRoomEmojiReq struct {
+	Name         string `json:"name"`
+	AttachmentID string `json:"attachment_id"`
+}
+
+type LinkPreviewRes struct {
+	URL         string `json:"url"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	ImageURL    string `json:"image_url,omitempty"`
+	SiteName    string `json:"site_name,omitempty"`
+}
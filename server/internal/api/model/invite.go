@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+type RequestRoomVisibility struct {
+	Visibility string `json:"visibility"`
+}
+
+// RequestCreateInvite creates an invite code. Without max_uses the invite
+// admits any number of people; without expires_at it lasts a week.
+type RequestCreateInvite struct {
+	MaxUses   *int       `json:"max_uses,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type RoomInviteRes struct {
+	ID                string `json:"id"`
+	RoomID            string `json:"room_id"`
+	Code              string `json:"code"`
+	CreatedBy         string `json:"created_by,omitempty"`
+	CreatedByUsername string `json:"created_by_username,omitempty"`
+	MaxUses           *int   `json:"max_uses,omitempty"`
+	Uses              int    `json:"uses"`
+	// Active is false once the invite is revoked, expired or used up.
+	Active    bool       `json:"active"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
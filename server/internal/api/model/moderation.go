@@ -0,0 +1,69 @@
+package model
+
+import (
+	"time"
+
+	"chat-application/internal/automod"
+)
+
+type RequestReportMessage struct {
+	Reason  string `json:"reason"`
+	Details string `json:"details,omitempty"`
+}
+
+// RequestModerationAction resolves a report. MuteMinutes applies to
+// mute_author and defaults to an hour.
+type RequestModerationAction struct {
+	Action      string `json:"action"`
+	MuteMinutes int    `json:"mute_minutes,omitempty"`
+	Note        string `json:"note,omitempty"`
+}
+
+// RequestSlowMode sets a channel's slow mode. Zero seconds turns it off.
+type RequestSlowMode struct {
+	Seconds int `json:"seconds"`
+}
+
+type MessageReportRes struct {
+	ID               string     `json:"id"`
+	RoomID           string     `json:"room_id"`
+	MessageID        string     `json:"message_id,omitempty"`
+	ReporterID       string     `json:"reporter_id,omitempty"`
+	ReporterUsername string     `json:"reporter_username,omitempty"`
+	AuthorID         string     `json:"author_id,omitempty"`
+	AuthorUsername   string     `json:"author_username"`
+	MessageContent   string     `json:"message_content"`
+	Reason           string     `json:"reason"`
+	Details          string     `json:"details,omitempty"`
+	Status           string     `json:"status"`
+	Resolution       string     `json:"resolution,omitempty"`
+	ResolvedBy       string     `json:"resolved_by,omitempty"`
+	ResolvedAt       *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+type ModerationActionRes struct {
+	Action          string `json:"action"`
+	ResolvedReports int64  `json:"resolved_reports"`
+}
+
+type ModerationLogEntryRes struct {
+	ID                string         `json:"id"`
+	ModeratorID       string         `json:"moderator_id,omitempty"`
+	ModeratorUsername string         `json:"moderator_username"`
+	Action            string         `json:"action"`
+	TargetUserID      string         `json:"target_user_id,omitempty"`
+	TargetUsername    string         `json:"target_username,omitempty"`
+	MessageID         string         `json:"message_id,omitempty"`
+	ReportID          string         `json:"report_id,omitempty"`
+	Details           map[string]any `json:"details,omitempty"`
+	CreatedAt         time.Time      `json:"created_at"`
+}
+
+// AutoModRes is a room's AutoMod configuration. Rooms that have never
+// configured AutoMod return disabled, empty rules.
+type AutoModRes struct {
+	Rules     automod.Rules `json:"rules"`
+	UpdatedBy string        `json:"updated_by,omitempty"`
+	UpdatedAt *time.Time    `json:"updated_at,omitempty"`
+}
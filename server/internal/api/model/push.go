@@ -0,0 +1,18 @@
+package model
+
+// PushSubscriptionReq mirrors the browser's PushSubscription.toJSON().
+type PushSubscriptionReq struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+type PushUnsubscribeReq struct {
+	Endpoint string `json:"endpoint"`
+}
+
+type VAPIDPublicKeyRes struct {
+	PublicKey string `json:"public_key"`
+}
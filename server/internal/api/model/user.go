@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 // RequestCreateUser represents the request body for user registration.
 type RequestCreateUser struct {
 	Username string `json:"username"`
@@ -14,8 +16,122 @@ type RequestLoginUser struct {
 
 // ResponseLoginUser represents the response body after successful login or registration.
 type ResponseLoginUser struct {
-	AccessToken string `json:"access_token,omitempty"`
-	ID          string `json:"id"`
-	Username    string `json:"username"`
-	Email       string `json:"email,omitempty"`
+	AccessToken   string `json:"access_token,omitempty"`
+	ID            string `json:"id"`
+	Username      string `json:"username"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified bool   `json:"email_verified"`
+	// MFARequired means the password was right but the user must finish
+	// signing in with MFAToken and a code; no session was issued.
+	MFARequired bool   `json:"mfa_required,omitempty"`
+	MFAToken    string `json:"mfa_token,omitempty"`
+}
+
+// RequestMFALogin completes a two-step login with either an authenticator
+// code or a recovery code.
+type RequestMFALogin struct {
+	MFAToken     string `json:"mfa_token"`
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+type ResponseTwoFactorStatus struct {
+	Enabled                bool `json:"enabled"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+// ResponseTOTPSetup carries a new secret; ProvisioningURI is the otpauth://
+// URI that clients render as a QR code.
+type ResponseTOTPSetup struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type RequestTOTPCode struct {
+	Code string `json:"code"`
+}
+
+type ResponseRecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type RequestDisableTOTP struct {
+	Password string `json:"password,omitempty"`
+	Code     string `json:"code,omitempty"`
+}
+
+type RequestPasswordReset struct {
+	Email string `json:"email"`
+}
+
+type RequestConfirmPasswordReset struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type RequestVerifyEmail struct {
+	Token string `json:"token"`
+}
+
+// RequestDeleteAccount confirms account deletion. Password is required when
+// the account has one, otherwise Confirm must be the username; Code is
+// required with two-factor authentication.
+type RequestDeleteAccount struct {
+	Password string `json:"password,omitempty"`
+	Confirm  string `json:"confirm,omitempty"`
+	Code     string `json:"code,omitempty"`
+}
+
+type LoginEventRes struct {
+	ID        string    `json:"id"`
+	Method    string    `json:"method"`
+	Outcome   string    `json:"outcome"`
+	IPAddress *string   `json:"ip_address,omitempty"`
+	UserAgent *string   `json:"user_agent,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type LinkedIdentityRes struct {
+	Provider    string     `json:"provider"`
+	Email       *string    `json:"email,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+// UserProfileRes is what a user shows to others next to their name.
+type UserProfileRes struct {
+	UserID      string         `json:"user_id"`
+	Username    string         `json:"username"`
+	DisplayName string         `json:"display_name,omitempty"`
+	Bio         string         `json:"bio,omitempty"`
+	Pronouns    string         `json:"pronouns,omitempty"`
+	AvatarURL   string         `json:"avatar_url,omitempty"`
+	Status      *UserStatusRes `json:"status,omitempty"`
+}
+
+type UserStatusRes struct {
+	Text      string     `json:"text,omitempty"`
+	Emoji     string     `json:"emoji,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// RequestUpdateProfile replaces the editable profile fields; empty values
+// clear them. A status without ExpiresAt stays until it is changed.
+type RequestUpdateProfile struct {
+	DisplayName string               `json:"display_name"`
+	Bio         string               `json:"bio"`
+	Pronouns    string               `json:"pronouns"`
+	Status      *RequestUpdateStatus `json:"status,omitempty"`
+}
+
+type RequestUpdateStatus struct {
+	Text      string     `json:"text"`
+	Emoji     string     `json:"emoji"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type BlockedUserRes struct {
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
 }
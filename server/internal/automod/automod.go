@@ -0,0 +1,334 @@
+// Package automod screens chat messages against a room's content filters.
+// Rules are plain data stored per room; Compile turns them into a Filter that
+// checks message content and reports every rule it breaks.
+package automod
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"chat-application/internal/constants"
+	"chat-application/internal/markdown"
+)
+
+// Actions, from mildest to strictest.
+const (
+	// ActionFlag lets the message through and files a report for the
+	// room's moderators.
+	ActionFlag = "flag"
+	// ActionBlock refuses the message.
+	ActionBlock = "block"
+	// ActionMute refuses the message and mutes its author.
+	ActionMute = "mute"
+)
+
+// Rule names
+const (
+	RuleBlockedWords = "blocked_words"
+	RuleLinks        = "links"
+	RuleMentions     = "mentions"
+	RuleCaps         = "caps"
+	RuleInvites      = "invites"
+)
+
+var actionSeverity = map[string]int{ActionFlag: 1, ActionBlock: 2, ActionMute: 3}
+
+// WordFilter matches whole words case-insensitively. A "*" in a word matches
+// any run of letters or digits, so "spam*" also matches "spammer".
+type WordFilter struct {
+	Words  []string `json:"words"`
+	Action string   `json:"action,omitempty"`
+}
+
+// LinkFilter restricts the domains messages may link to. A domain also
+// covers its subdomains. When Allow is set, links to any other domain break
+// the rule; links to a Deny domain always do.
+type LinkFilter struct {
+	Allow  []string `json:"allow"`
+	Deny   []string `json:"deny"`
+	Action string   `json:"action,omitempty"`
+}
+
+// MentionFilter limits the distinct users and groups mentioned in a message.
+// Zero disables it.
+type MentionFilter struct {
+	Max    int    `json:"max"`
+	Action string `json:"action,omitempty"`
+}
+
+// CapsFilter limits the share of upper-case letters in messages with at
+// least MinLength letters. A MaxRatio of zero disables it.
+type CapsFilter struct {
+	MaxRatio  float64 `json:"max_ratio"`
+	MinLength int     `json:"min_length,omitempty"`
+	Action    string  `json:"action,omitempty"`
+}
+
+// InviteFilter catches invite links to other chat services.
+type InviteFilter struct {
+	Enabled bool   `json:"enabled"`
+	Action  string `json:"action,omitempty"`
+}
+
+// Rules are a room's AutoMod settings. Filters without an action block.
+type Rules struct {
+	Enabled      bool          `json:"enabled"`
+	BlockedWords WordFilter    `json:"blocked_words"`
+	Links        LinkFilter    `json:"links"`
+	Mentions     MentionFilter `json:"mentions"`
+	Caps         CapsFilter    `json:"caps"`
+	Invites      InviteFilter  `json:"invites"`
+	// MuteMinutes is how long the mute action mutes for. Zero uses
+	// constants.DefaultMuteDuration.
+	MuteMinutes int `json:"mute_minutes,omitempty"`
+}
+
+// Violation is one rule a message broke. Detail is meant for moderators and
+// may quote the matched content.
+type Violation struct {
+	Rule   string `json:"rule"`
+	Action string `json:"action"`
+	Detail string `json:"detail"`
+}
+
+// Reason is the explanation shown to the author, which never repeats what
+// was matched.
+func (v Violation) Reason() string {
+	switch v.Rule {
+	case RuleBlockedWords:
+		return "it contains a blocked word"
+	case RuleLinks:
+		return "it links to a domain that is not allowed here"
+	case RuleMentions:
+		return "it mentions too many people"
+	case RuleCaps:
+		return "it is mostly capital letters"
+	case RuleInvites:
+		return "it contains an invite link"
+	}
+	return "it breaks this room's rules"
+}
+
+// invitePattern matches invite links to other chat services, with or without
+// a scheme.
+var invitePattern = regexp.MustCompile(`(?i)(?:^|[^\w.])((?:discord(?:app)?\.com/invite|discord\.(?:gg|io|me)|t\.me|telegram\.(?:me|dog)|chat\.whatsapp\.com|join\.slack\.com)/[\w-]+)`)
+
+// Normalize trims and lower-cases words and domains, drops blanks and
+// duplicates, and replaces nil lists with empty ones so they encode as [].
+func (r *Rules) Normalize() {
+	r.BlockedWords.Words = normalizeList(r.BlockedWords.Words, func(word string) string {
+		return strings.ToLower(strings.TrimSpace(word))
+	})
+	r.Links.Allow = normalizeList(r.Links.Allow, normalizeDomain)
+	r.Links.Deny = normalizeList(r.Links.Deny, normalizeDomain)
+}
+
+// Validate checks limits and actions. It expects normalized rules.
+func (r *Rules) Validate() error {
+	if len(r.BlockedWords.Words) > constants.MaxAutoModWords {
+		return fmt.Errorf("at most %d blocked words are allowed", constants.MaxAutoModWords)
+	}
+	for _, word := range r.BlockedWords.Words {
+		if utf8.RuneCountInString(word) > constants.MaxAutoModWordLength {
+			return fmt.Errorf("blocked words must be at most %d characters", constants.MaxAutoModWordLength)
+		}
+		if strings.Trim(word, "*") == "" {
+			return errors.New("blocked words must contain more than wildcards")
+		}
+	}
+	if len(r.Links.Allow)+len(r.Links.Deny) > constants.MaxAutoModDomains {
+		return fmt.Errorf("at most %d link domains are allowed", constants.MaxAutoModDomains)
+	}
+	for _, domain := range append(r.Links.Allow, r.Links.Deny...) {
+		if strings.ContainsAny(domain, "/:@ ") || !strings.Contains(domain, ".") {
+			return fmt.Errorf("invalid link domain %q", domain)
+		}
+	}
+	if r.Mentions.Max < 0 {
+		return errors.New("mention limit must not be negative")
+	}
+	if r.Caps.MaxRatio < 0 || r.Caps.MaxRatio > 1 {
+		return errors.New("caps ratio must be between 0 and 1")
+	}
+	if r.Caps.MinLength < 0 {
+		return errors.New("caps minimum length must not be negative")
+	}
+	if r.MuteMinutes < 0 || r.MuteMinutes > int(constants.MaxMuteDuration/time.Minute) {
+		return errors.New("mute duration is out of range")
+	}
+	for _, action := range []string{r.BlockedWords.Action, r.Links.Action, r.Mentions.Action, r.Caps.Action, r.Invites.Action} {
+		if action != "" && actionSeverity[action] == 0 {
+			return fmt.Errorf("invalid action %q", action)
+		}
+	}
+	return nil
+}
+
+// MuteDuration is how long the mute action mutes for.
+func (r *Rules) MuteDuration() time.Duration {
+	if r.MuteMinutes <= 0 {
+		return constants.DefaultMuteDuration
+	}
+	return time.Duration(r.MuteMinutes) * time.Minute
+}
+
+// Filter is a compiled set of rules.
+type Filter struct {
+	rules Rules
+	words *regexp.Regexp
+}
+
+// Compile prepares rules for checking messages. Rules that are not enabled
+// compile to a filter that never matches.
+func Compile(rules Rules) *Filter {
+	filter := &Filter{rules: rules}
+	if !rules.Enabled {
+		return filter
+	}
+	if len(rules.BlockedWords.Words) > 0 {
+		patterns := make([]string, 0, len(rules.BlockedWords.Words))
+		for _, word := range rules.BlockedWords.Words {
+			parts := strings.Split(word, "*")
+			for i, part := range parts {
+				parts[i] = regexp.QuoteMeta(part)
+			}
+			patterns = append(patterns, strings.Join(parts, `[\p{L}\p{N}_]*`))
+		}
+		filter.words = regexp.MustCompile(`(?i)(?:^|[^\p{L}\p{N}_])(` + strings.Join(patterns, "|") + `)(?:$|[^\p{L}\p{N}_])`)
+	}
+	return filter
+}
+
+// Check returns every rule content breaks, in rule order.
+func (f *Filter) Check(content string) []Violation {
+	if !f.rules.Enabled {
+		return nil
+	}
+
+	var violations []Violation
+	add := func(rule, action, detail string) {
+		if action == "" {
+			action = ActionBlock
+		}
+		violations = append(violations, Violation{Rule: rule, Action: action, Detail: detail})
+	}
+
+	if f.words != nil {
+		if match := f.words.FindStringSubmatch(content); match != nil {
+			add(RuleBlockedWords, f.rules.BlockedWords.Action, fmt.Sprintf("matched %q", match[1]))
+		}
+	}
+	if domain := f.forbiddenLink(content); domain != "" {
+		add(RuleLinks, f.rules.Links.Action, "linked to "+domain)
+	}
+	if limit := f.rules.Mentions.Max; limit > 0 {
+		if count := len(markdown.Mentions(content)); count > limit {
+			add(RuleMentions, f.rules.Mentions.Action, fmt.Sprintf("%d mentions, limit %d", count, limit))
+		}
+	}
+	if ratio, ok := f.capsRatio(content); ok && ratio > f.rules.Caps.MaxRatio {
+		add(RuleCaps, f.rules.Caps.Action, fmt.Sprintf("%.0f%% capital letters", ratio*100))
+	}
+	if f.rules.Invites.Enabled {
+		if match := invitePattern.FindStringSubmatch(content); match != nil {
+			add(RuleInvites, f.rules.Invites.Action, "invite link "+match[1])
+		}
+	}
+	return violations
+}
+
+// forbiddenLink returns the host of the first link the link lists forbid.
+func (f *Filter) forbiddenLink(content string) string {
+	allow, deny := f.rules.Links.Allow, f.rules.Links.Deny
+	if len(allow) == 0 && len(deny) == 0 {
+		return ""
+	}
+	for _, link := range markdown.Links(content) {
+		parsed, err := url.Parse(link)
+		if err != nil || parsed.Hostname() == "" {
+			continue
+		}
+		host := strings.ToLower(parsed.Hostname())
+		if matchesDomain(host, deny) || (len(allow) > 0 && !matchesDomain(host, allow)) {
+			return host
+		}
+	}
+	return ""
+}
+
+// capsRatio returns the share of upper-case letters, and false when the
+// message is too short to judge or the filter is off.
+func (f *Filter) capsRatio(content string) (float64, bool) {
+	if f.rules.Caps.MaxRatio <= 0 {
+		return 0, false
+	}
+	minLength := f.rules.Caps.MinLength
+	if minLength == 0 {
+		minLength = constants.DefaultAutoModCapsMinLength
+	}
+
+	var letters, upper int
+	for _, r := range content {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.IsUpper(r) {
+			upper++
+		}
+	}
+	if letters < minLength {
+		return 0, false
+	}
+	return float64(upper) / float64(letters), true
+}
+
+// Strictest returns the first violation with the strictest action, which
+// decides what happens to the message, or a zero Violation if there are none.
+func Strictest(violations []Violation) Violation {
+	var strictest Violation
+	for _, violation := range violations {
+		if actionSeverity[violation.Action] > actionSeverity[strictest.Action] {
+			strictest = violation
+		}
+	}
+	return strictest
+}
+
+func matchesDomain(host string, domains []string) bool {
+	for _, domain := range domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeDomain(domain string) string {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	domain = strings.TrimPrefix(domain, "*.")
+	return strings.TrimSuffix(domain, ".")
+}
+
+func normalizeList(values []string, normalize func(string) string) []string {
+	normalized := make([]string, 0, len(values))
+	seen := make(map[string]struct{}, len(values))
+	for _, value := range values {
+		value = normalize(value)
+		if value == "" {
+			continue
+		}
+		if _, ok := seen[value]; ok {
+			continue
+		}
+		seen[value] = struct{}{}
+		normalized = append(normalized, value)
+	}
+	return normalized
+}
@@ -0,0 +1,109 @@
+package automod
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	rules := Rules{
+		Enabled:      true,
+		BlockedWords: WordFilter{Words: []string{"spam*", "scam"}},
+		Links:        LinkFilter{Deny: []string{"bad.example"}, Action: ActionFlag},
+		Mentions:     MentionFilter{Max: 2, Action: ActionMute},
+		Caps:         CapsFilter{MaxRatio: 0.7, Action: ActionFlag},
+		Invites:      InviteFilter{Enabled: true},
+	}
+	filter := Compile(rules)
+
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{name: "clean message", content: "hello there, how is everyone?", want: nil},
+		{name: "blocked word", content: "this is a SCAM!", want: []string{RuleBlockedWords}},
+		{name: "wildcard word", content: "stop spamming please", want: []string{RuleBlockedWords}},
+		{name: "blocked word inside another word", content: "scampi for dinner", want: nil},
+		{name: "denied subdomain", content: "see https://www.bad.example/page", want: []string{RuleLinks}},
+		{name: "mentions over limit", content: "@ann @bob @cat look", want: []string{RuleMentions}},
+		{name: "repeated mention counts once", content: "@ann @ann @ann", want: nil},
+		{name: "shouting", content: "WHY IS NOBODY ANSWERING ME", want: []string{RuleCaps}},
+		{name: "short shouting", content: "OK THEN", want: nil},
+		{name: "invite without scheme", content: "join us at discord.gg/abc123", want: []string{RuleInvites}},
+		{name: "several rules", content: "spam at https://bad.example", want: []string{RuleBlockedWords, RuleLinks}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, violation := range filter.Check(tt.content) {
+				got = append(got, violation.Rule)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Check(%q) = %v, want %v", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckAllowList(t *testing.T) {
+	filter := Compile(Rules{Enabled: true, Links: LinkFilter{Allow: []string{"go.dev"}}})
+	if got := filter.Check("docs at https://pkg.go.dev/net/http"); got != nil {
+		t.Fatalf("expected allowed subdomain to pass, got %+v", got)
+	}
+	if got := filter.Check("or https://elsewhere.example"); len(got) != 1 || got[0].Action != ActionBlock {
+		t.Fatalf("expected unlisted domain to be blocked by default, got %+v", got)
+	}
+}
+
+func TestDisabledRulesNeverMatch(t *testing.T) {
+	filter := Compile(Rules{BlockedWords: WordFilter{Words: []string{"scam"}}})
+	if got := filter.Check("scam"); got != nil {
+		t.Fatalf("expected disabled rules to pass everything, got %+v", got)
+	}
+}
+
+func TestStrictest(t *testing.T) {
+	violations := []Violation{
+		{Rule: RuleLinks, Action: ActionFlag},
+		{Rule: RuleCaps, Action: ActionMute},
+		{Rule: RuleInvites, Action: ActionBlock},
+	}
+	if got := Strictest(violations); got.Action != ActionMute || got.Rule != RuleCaps {
+		t.Fatalf("expected the caps mute, got %+v", got)
+	}
+	if got := Strictest(nil); got.Action != "" {
+		t.Fatalf("expected no action, got %+v", got)
+	}
+}
+
+func TestNormalizeAndValidate(t *testing.T) {
+	rules := Rules{
+		BlockedWords: WordFilter{Words: []string{" Scam ", "scam", ""}},
+		Links:        LinkFilter{Allow: []string{"*.Go.dev."}},
+	}
+	rules.Normalize()
+	if !reflect.DeepEqual(rules.BlockedWords.Words, []string{"scam"}) || !reflect.DeepEqual(rules.Links.Allow, []string{"go.dev"}) {
+		t.Fatalf("unexpected normalized rules: %+v", rules)
+	}
+	if rules.Links.Deny == nil {
+		t.Fatal("expected empty lists rather than nil")
+	}
+	if err := rules.Validate(); err != nil {
+		t.Fatalf("expected valid rules, got %v", err)
+	}
+
+	invalid := []Rules{
+		{BlockedWords: WordFilter{Words: []string{"**"}}},
+		{Links: LinkFilter{Deny: []string{"https://bad.example"}}},
+		{Caps: CapsFilter{MaxRatio: 1.5}},
+		{Mentions: MentionFilter{Max: 3, Action: "ban"}},
+		{MuteMinutes: -1},
+	}
+	for _, rules := range invalid {
+		rules.Normalize()
+		if err := rules.Validate(); err == nil {
+			t.Fatalf("expected %+v to be invalid", rules)
+		}
+	}
+}
@@ -41,6 +41,9 @@ type Config struct {
 	AuthRateLimit     int
 	RoomCreationLimit int
 	RateLimitWindow   time.Duration
+	// RateLimitStore is "memory" for per-process limits or "postgres" to
+	// share them between replicas
+	RateLimitStore string
 
 	// Room settings
 	MaxRooms            int
@@ -48,6 +51,56 @@ type Config struct {
 	RoomCleanupInterval time.Duration
 	MaxRoomHistory      int
 
+	// Notifications older than this are deleted by the cleanup job
+	NotificationRetention time.Duration
+
+	// Email for digests and account links; without an SMTP host, development
+	// only logs each email's recipient and subject
+	SMTPHost           string
+	SMTPPort           int
+	SMTPUsername       string
+	SMTPPassword       string
+	SMTPFrom           string
+	DigestInterval     time.Duration
+	EmailSigningSecret string
+	AppURL             string
+	APIURL             string
+
+	// Only users with a verified email may create rooms
+	RequireVerifiedEmail bool
+
+	// Social login; a provider is enabled when its client ID is set. The
+	// generic OIDC provider is listed under OIDCProviderName.
+	GitHubClientID     string
+	GitHubClientSecret string
+	GoogleClientID     string
+	GoogleClientSecret string
+	OIDCProviderName   string
+	OIDCIssuer         string
+	OIDCClientID       string
+	OIDCClientSecret   string
+
+	// Two-factor secrets are encrypted at rest with a key derived from this
+	TOTPEncryptionKey string
+
+	// Web push; VAPIDPrivateKey is a base64url P-256 private key
+	VAPIDPrivateKey string
+	VAPIDSubject    string
+
+	// Attachment storage
+	StorageDriver     string
+	StorageLocalPath  string
+	S3Endpoint        string
+	S3Region          string
+	S3Bucket          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+	S3UsePathStyle    bool
+	MaxAttachmentSize int64
+
+	// Link previews
+	LinkPreviewsEnabled bool
+
 	// External services
 	RedditClientID     string
 	RedditClientSecret string
@@ -92,6 +145,7 @@ func Load() (*Config, error) {
 		AuthRateLimit:     constants.AuthRateLimit,
 		RoomCreationLimit: constants.RoomCreationLimit,
 		RateLimitWindow:   constants.RateLimitWindow,
+		RateLimitStore:    getEnv("RATE_LIMIT_STORE", "memory"),
 
 		// Room settings
 		MaxRooms:            getEnvInt("MAX_ROOMS", constants.DefaultRoomLimit),
@@ -99,6 +153,54 @@ func Load() (*Config, error) {
 		RoomCleanupInterval: constants.RoomCleanupInterval,
 		MaxRoomHistory:      constants.MaxRoomHistory,
 
+		// Notifications
+		NotificationRetention: time.Duration(getEnvInt("NOTIFICATION_RETENTION_DAYS", int(constants.DefaultNotificationRetention/(24*time.Hour)))) * 24 * time.Hour,
+
+		// Email digests
+		SMTPHost:           getEnv("SMTP_HOST", ""),
+		SMTPPort:           getEnvInt("SMTP_PORT", 587),
+		SMTPUsername:       getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:       getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:           getEnv("SMTP_FROM", "Yappin <no-reply@yappin.chat>"),
+		DigestInterval:     time.Duration(getEnvInt("EMAIL_DIGEST_INTERVAL_MINUTES", int(constants.DefaultDigestInterval/time.Minute))) * time.Minute,
+		EmailSigningSecret: getEnv("EMAIL_SIGNING_SECRET", getEnv("JWT_SECRET_KEY", "")),
+		AppURL:             getEnv("APP_URL", "http://localhost:5173"),
+		APIURL:             getEnv("API_URL", "http://localhost:8080"),
+
+		// Email verification
+		RequireVerifiedEmail: getEnvBool("REQUIRE_VERIFIED_EMAIL", false),
+
+		// Social login
+		GitHubClientID:     getEnv("GITHUB_CLIENT_ID", ""),
+		GitHubClientSecret: getEnv("GITHUB_CLIENT_SECRET", ""),
+		GoogleClientID:     getEnv("GOOGLE_CLIENT_ID", ""),
+		GoogleClientSecret: getEnv("GOOGLE_CLIENT_SECRET", ""),
+		OIDCProviderName:   getEnv("OIDC_PROVIDER_NAME", "oidc"),
+		OIDCIssuer:         getEnv("OIDC_ISSUER", ""),
+		OIDCClientID:       getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret:   getEnv("OIDC_CLIENT_SECRET", ""),
+
+		// Two-factor authentication
+		TOTPEncryptionKey: getEnv("TOTP_ENCRYPTION_KEY", getEnv("JWT_SECRET_KEY", "")),
+
+		// Web push
+		VAPIDPrivateKey: getEnv("VAPID_PRIVATE_KEY", ""),
+		VAPIDSubject:    getEnv("VAPID_SUBJECT", "mailto:admin@yappin.chat"),
+
+		// Attachment storage
+		StorageDriver:     getEnv("STORAGE_DRIVER", "local"),
+		StorageLocalPath:  getEnv("STORAGE_LOCAL_PATH", "./uploads"),
+		S3Endpoint:        getEnv("S3_ENDPOINT", ""),
+		S3Region:          getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:          getEnv("S3_BUCKET", ""),
+		S3AccessKeyID:     getEnv("S3_ACCESS_KEY_ID", ""),
+		S3SecretAccessKey: getEnv("S3_SECRET_ACCESS_KEY", ""),
+		S3UsePathStyle:    getEnvBool("S3_USE_PATH_STYLE", true),
+		MaxAttachmentSize: int64(getEnvInt("MAX_ATTACHMENT_SIZE", constants.MaxAttachmentSize)),
+
+		// Link previews
+		LinkPreviewsEnabled: getEnvBool("LINK_PREVIEWS_ENABLED", true),
+
 		// External services
 		RedditClientID:     getEnv("REDDIT_CLIENT_ID", ""),
 		RedditClientSecret: getEnv("REDDIT_CLIENT_SECRET", ""),
@@ -129,6 +231,32 @@ func (c *Config) Validate() error {
 		if c.DatabaseURL == "" {
 			return fmt.Errorf("DATABASE_URL is required in production")
 		}
+		// Each secret guards something different, so leaking or rotating one
+		// must not affect the others. Only development falls back to the JWT key.
+		if c.EmailSigningSecret == "" || c.EmailSigningSecret == c.JWTSecretKey {
+			return fmt.Errorf("EMAIL_SIGNING_SECRET must be set and differ from JWT_SECRET_KEY in production")
+		}
+		if c.TOTPEncryptionKey == "" || c.TOTPEncryptionKey == c.JWTSecretKey {
+			return fmt.Errorf("TOTP_ENCRYPTION_KEY must be set and differ from JWT_SECRET_KEY in production")
+		}
+		if c.SMTPHost == "" {
+			return fmt.Errorf("SMTP_HOST is required in production")
+		}
+	}
+	if c.OIDCClientID != "" && c.OIDCIssuer == "" {
+		return fmt.Errorf("OIDC_ISSUER is required when OIDC_CLIENT_ID is set")
+	}
+	if c.RateLimitStore != "memory" && c.RateLimitStore != "postgres" {
+		return fmt.Errorf("unsupported RATE_LIMIT_STORE %q", c.RateLimitStore)
+	}
+	switch c.StorageDriver {
+	case "local":
+	case "s3":
+		if c.S3Endpoint == "" || c.S3Bucket == "" {
+			return fmt.Errorf("S3_ENDPOINT and S3_BUCKET are required when STORAGE_DRIVER is s3")
+		}
+	default:
+		return fmt.Errorf("unsupported STORAGE_DRIVER %q", c.StorageDriver)
 	}
 	return nil
 }
@@ -171,3 +299,12 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolVal, err := strconv.ParseBool(value); err == nil {
+			return boolVal
+		}
+	}
+	return defaultValue
+}
@@ -7,6 +7,30 @@ const (
 	JWTCookieName     = "jwt"
 	JWTCookieDuration = 24 * time.Hour
 	JWTTokenExpiry    = 24 * time.Hour
+
+	PasswordResetTokenExpiry     = time.Hour
+	EmailVerificationTokenExpiry = 48 * time.Hour
+	AccountTokenBytes            = 32
+	AccountEmailTimeout          = 30 * time.Second
+
+	OAuthStateCookieName = "oauth_state"
+	OAuthStateExpiry     = 10 * time.Minute
+	OAuthRequestTimeout  = 10 * time.Second
+
+	TOTPIssuer         = "Yappin"
+	TOTPSkew           = 1
+	MFAChallengeExpiry = 5 * time.Minute
+	RecoveryCodeCount  = 10
+
+	// After LoginLockoutThreshold consecutive failures each further failure
+	// locks sign-in, starting at LoginLockoutBase and doubling up to
+	// LoginLockoutMax.
+	LoginLockoutThreshold = 5
+	LoginLockoutBase      = time.Minute
+	LoginLockoutMax       = time.Hour
+	LoginEventRetention   = 90 * 24 * time.Hour
+
+	AccountExportTimeout = 30 * time.Second
 )
 
 // Room Configuration
@@ -17,12 +41,59 @@ const (
 	MaxRoomHistory      = 100
 )
 
+// Pagination
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 100
+)
+
+// Notifications
+const (
+	NotificationLevelAll      = "all"
+	NotificationLevelMentions = "mentions"
+	NotificationLevelNone     = "none"
+
+	DefaultNotificationRetention = 90 * 24 * time.Hour
+)
+
+// Email Digests
+const (
+	DefaultDigestInterval = time.Hour
+	// DigestDelay gives users a chance to see notifications in the app
+	// before they are emailed.
+	DigestDelay            = 15 * time.Minute
+	DigestMaxNotifications = 20
+	DigestSendTimeout      = 30 * time.Second
+)
+
+// Web Push
+const (
+	PushTTL              = 24 * time.Hour
+	PushSendTimeout      = 10 * time.Second
+	PushMaxAttempts      = 5
+	PushRetryBaseDelay   = 2 * time.Second
+	PushRetryMaxDelay    = 5 * time.Minute
+	PushQueueSize        = 256
+	PushWorkers          = 4
+	VAPIDTokenExpiry     = 12 * time.Hour
+	MaxPushSubscriptions = 10
+)
+
+// Read State
+const (
+	// ReadReceiptMaxMembers is the largest room in which read receipts are shared
+	// with other members; in bigger rooms only the reader's own sessions see them.
+	ReadReceiptMaxMembers = 25
+)
+
 // Rate Limiting
 const (
 	DefaultRateLimit  = 100
 	AuthRateLimit     = 10
 	RoomCreationLimit = 10
 	RateLimitWindow   = time.Minute
+	// Shared rate limit buckets that have refilled are deleted this often.
+	RateLimitSweepInterval = 5 * time.Minute
 )
 
 // WebSocket
@@ -44,7 +115,178 @@ const (
 	HTTPServerTimeout     = 30 * time.Second
 )
 
-// AllowedReactionEmojis defines the valid emoji reactions for messages
+// Attachments
+const (
+	MaxAttachmentSize        = 10 << 20
+	MaxAttachmentsPerMessage = 10
+	ThumbnailMaxDimension    = 320
+	AttachmentUnsentTTL      = 24 * time.Hour
+)
+
+// Link Previews
+const (
+	MaxLinkPreviewsPerMessage = 3
+	LinkPreviewFetchTimeout   = 5 * time.Second
+	LinkPreviewMaxBodySize    = 512 << 10
+	LinkPreviewCacheTTL       = 24 * time.Hour
+	LinkPreviewFailureTTL     = time.Hour
+	LinkPreviewMaxRedirects   = 3
+)
+
+// AllowedAttachmentTypes defines the sniffed MIME types accepted for uploads
+var AllowedAttachmentTypes = []string{
+	"image/png",
+	"image/jpeg",
+	"image/gif",
+	"image/webp",
+	"application/pdf",
+	"text/plain",
+}
+
+// IsAllowedAttachmentType checks if the given MIME type may be uploaded
+func IsAllowedAttachmentType(contentType string) bool {
+	for _, t := range AllowedAttachmentTypes {
+		if t == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+// User Profiles
+const (
+	MaxDisplayNameLength = 50
+	MaxBioLength         = 500
+	MaxPronounsLength    = 40
+	MaxStatusTextLength  = 100
+	MaxStatusEmojiLength = 32
+	MaxAvatarSize        = 5 << 20
+	AvatarDimension      = 256
+)
+
+// User Search
+const (
+	MaxUserSearchLength = 50
+	// Trigram matching is noise below three characters, so shorter queries
+	// only match prefixes.
+	MinFuzzySearchLength = 3
+	AutocompleteLimit    = 10
+)
+
+// Moderation
+const (
+	ReportStatusOpen     = "open"
+	ReportStatusResolved = "resolved"
+
+	ModerationDismiss       = "dismiss"
+	ModerationDeleteMessage = "delete_message"
+	ModerationMuteAuthor    = "mute_author"
+	ModerationBanAuthor     = "ban_author"
+	// ModerationUpdateMember is logged for changes made through the member
+	// endpoint rather than a report.
+	ModerationUpdateMember = "update_member"
+	// AutoMod actions are logged under the AutoMod moderator name with no
+	// moderator ID.
+	ModerationAutoModBlock  = "automod_block"
+	ModerationAutoModFlag   = "automod_flag"
+	ModerationAutoModMute   = "automod_mute"
+	ModerationUpdateAutoMod = "update_automod"
+	ModerationSlowMode      = "slow_mode"
+	// Room access changes are logged like moderator actions.
+	ModerationUpdateVisibility = "update_visibility"
+	ModerationCreateInvite     = "create_invite"
+	ModerationRevokeInvite     = "revoke_invite"
+	// ModerationFloodMute is logged under the AutoMod moderator name when
+	// flood control mutes a member.
+	ModerationFloodMute = "flood_mute"
+	AutoModUsername     = "AutoMod"
+	// ReportReasonAutoMod marks reports filed by AutoMod. Members cannot
+	// report with it.
+	ReportReasonAutoMod = "automod"
+
+	MaxReportDetailsLength = 1000
+	DefaultMuteDuration    = time.Hour
+	MaxMuteDuration        = 30 * 24 * time.Hour
+)
+
+// ReportReasons are the reasons a message can be reported for.
+var ReportReasons = []string{"spam", "harassment", "hate", "sexual", "violence", "other"}
+
+// IsValidReportReason checks if the given reason is in ReportReasons
+func IsValidReportReason(reason string) bool {
+	for _, r := range ReportReasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// AutoMod
+const (
+	MaxAutoModWords      = 500
+	MaxAutoModWordLength = 64
+	MaxAutoModDomains    = 100
+	// Caps filtering ignores messages with fewer letters than this unless
+	// the room sets its own minimum.
+	DefaultAutoModCapsMinLength = 10
+)
+
+// Room visibility and invites
+const (
+	// Public rooms are listed to everyone. Unlisted rooms can be joined by
+	// anyone with the room ID but are only listed to members. Invite-only
+	// rooms admit members alone, who join by redeeming an invite.
+	RoomVisibilityPublic     = "public"
+	RoomVisibilityUnlisted   = "unlisted"
+	RoomVisibilityInviteOnly = "invite_only"
+
+	InviteCodeBytes     = 8
+	DefaultInviteExpiry = 7 * 24 * time.Hour
+	MaxInviteExpiry     = 30 * 24 * time.Hour
+	MaxInviteUses       = 1000
+)
+
+// IsValidRoomVisibility checks if the given visibility is one of the
+// RoomVisibility values
+func IsValidRoomVisibility(visibility string) bool {
+	switch visibility {
+	case RoomVisibilityPublic, RoomVisibilityUnlisted, RoomVisibilityInviteOnly:
+		return true
+	}
+	return false
+}
+
+// WebSocket flood control
+const (
+	// Message events refill at WebSocketMessageRate per second up to a burst
+	// of WebSocketMessageBurst; typing and read events have a looser budget.
+	WebSocketMessageBurst = 5
+	WebSocketMessageRate  = 1.0
+	WebSocketEventBurst   = 20
+	WebSocketEventRate    = 5.0
+	// A message repeating the sender's previous one within this window is
+	// refused.
+	DuplicateMessageWindow = 30 * time.Second
+	// Flood strikes are forgotten after FloodStrikeDecay without a new one.
+	// Earlier strikes warn; FloodStrikesBeforeMute mutes for
+	// FloodMuteDuration and FloodStrikesBeforeDisconnect closes the
+	// connection.
+	FloodStrikeDecay             = 10 * time.Minute
+	FloodStrikesBeforeMute       = 3
+	FloodStrikesBeforeDisconnect = 5
+	FloodMuteDuration            = 5 * time.Minute
+	MaxSlowModeSeconds           = 6 * 60 * 60
+)
+
+// Custom Emoji
+const (
+	MaxRoomEmojis      = 50
+	MaxEmojiNameLength = 32
+	MinEmojiNameLength = 2
+)
+
+// AllowedReactionEmojis defines the default emoji reactions available in every room
 var AllowedReactionEmojis = []string{"👍", "❤️", "😂", "😮", "😢", "👏", "🎉"}
 
 // IsValidReactionEmoji checks if the given emoji is in the allowed list
@@ -0,0 +1,81 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// Message is an email with a plain-text body and an optional HTML alternative.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	// Headers are extra headers such as List-Unsubscribe.
+	Headers map[string]string
+}
+
+// Mailer delivers email.
+type Mailer interface {
+	// Send delivers the message or returns an error; it does not retry.
+	Send(ctx context.Context, msg Message) error
+}
+
+// validate rejects messages that would produce a malformed or injected header.
+func (m Message) validate() error {
+	if strings.TrimSpace(m.To) == "" {
+		return fmt.Errorf("recipient is required")
+	}
+	if m.Text == "" && m.HTML == "" {
+		return fmt.Errorf("message body is required")
+	}
+	values := []string{m.To, m.Subject}
+	for key, value := range m.Headers {
+		values = append(values, key, value)
+	}
+	for _, value := range values {
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("header values must not contain line breaks")
+		}
+	}
+	return nil
+}
+
+// LogMailer logs the recipient and subject of messages instead of sending
+// them. It is used in development when no SMTP server is configured. Bodies
+// are not logged because they carry account tokens.
+type LogMailer struct{}
+
+func (LogMailer) Send(ctx context.Context, msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+	log.Printf("LogMailer - email to %s: %s", msg.To, msg.Subject)
+	return nil
+}
+
+// MemoryMailer keeps sent messages in memory, for tests.
+type MemoryMailer struct {
+	mu   sync.Mutex
+	sent []Message
+}
+
+func (m *MemoryMailer) Send(ctx context.Context, msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// Sent returns the messages sent so far, oldest first.
+func (m *MemoryMailer) Sent() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.sent...)
+}
@@ -0,0 +1,160 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net"
+	"net/textproto"
+	"os"
+	"strings"
+	"testing"
+)
+
+// smtpStandIn accepts one SMTP session and records what it was sent.
+type smtpStandIn struct {
+	listener net.Listener
+	from     string
+	to       []string
+	data     string
+	done     chan struct{}
+}
+
+func startSMTPStandIn(t *testing.T) *smtpStandIn {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	server := &smtpStandIn{listener: listener, done: make(chan struct{})}
+	go server.serve()
+	return server
+}
+
+func (s *smtpStandIn) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpStandIn) serve() {
+	defer close(s.done)
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	text := textproto.NewConn(conn)
+	_ = text.PrintfLine("220 localhost ESMTP stand-in")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch command {
+		case "EHLO", "HELO":
+			_ = text.PrintfLine("250-localhost\r\n250 8BITMIME")
+		case "MAIL":
+			s.from = line
+			_ = text.PrintfLine("250 OK")
+		case "RCPT":
+			s.to = append(s.to, line)
+			_ = text.PrintfLine("250 OK")
+		case "DATA":
+			_ = text.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.data = string(data)
+			_ = text.PrintfLine("250 OK")
+		case "QUIT":
+			_ = text.PrintfLine("221 Bye")
+			return
+		default:
+			_ = text.PrintfLine("502 Not implemented")
+		}
+	}
+}
+
+func TestSMTPMailerSendsMultipartMessage(t *testing.T) {
+	server := startSMTPStandIn(t)
+
+	mailer, err := NewSMTPMailer(SMTPConfig{
+		Host: "127.0.0.1",
+		Port: server.port(),
+		From: "Yappin <no-reply@yappin.chat>",
+	})
+	if err != nil {
+		t.Fatalf("NewSMTPMailer: %v", err)
+	}
+
+	err = mailer.Send(context.Background(), Message{
+		To:      "alice@example.com",
+		Subject: "You have 2 unread notifications",
+		Text:    "Hello alice",
+		HTML:    "<p>Hello alice</p>",
+		Headers: map[string]string{"List-Unsubscribe": "<https://yappin.chat/unsubscribe>"},
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	<-server.done
+
+	if !strings.HasPrefix(server.from, "MAIL FROM:<no-reply@yappin.chat>") {
+		t.Fatalf("unexpected sender %q", server.from)
+	}
+	if len(server.to) != 1 || server.to[0] != "RCPT TO:<alice@example.com>" {
+		t.Fatalf("unexpected recipients %v", server.to)
+	}
+	for _, want := range []string{
+		"Subject: You have 2 unread notifications",
+		"List-Unsubscribe: <https://yappin.chat/unsubscribe>",
+		"Content-Type: multipart/alternative",
+		"Hello alice",
+		"<p>Hello alice</p>",
+	} {
+		if !strings.Contains(server.data, want) {
+			t.Errorf("expected message to contain %q, got:\n%s", want, server.data)
+		}
+	}
+}
+
+func TestMessageRejectsHeaderInjection(t *testing.T) {
+	mailer := &MemoryMailer{}
+	err := mailer.Send(context.Background(), Message{
+		To:      "alice@example.com",
+		Subject: "Hi\r\nBcc: mallory@example.com",
+		Text:    "body",
+	})
+	if err == nil {
+		t.Fatal("expected a subject with a line break to be rejected")
+	}
+	if len(mailer.Sent()) != 0 {
+		t.Fatal("expected nothing to be sent")
+	}
+
+	if _, err := NewSMTPMailer(SMTPConfig{Host: "localhost", Port: 25, From: "not an address"}); err == nil {
+		t.Fatal("expected an invalid sender to be rejected")
+	}
+}
+
+func TestLogMailerDoesNotLogBodies(t *testing.T) {
+	var output bytes.Buffer
+	log.SetOutput(&output)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	err := LogMailer{}.Send(context.Background(), Message{
+		To:      "ana@example.com",
+		Subject: "Reset your password",
+		Text:    "https://yappin.chat/reset?token=secret-token",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(output.String(), "Reset your password") || strings.Contains(output.String(), "secret-token") {
+		t.Fatalf("expected only the recipient and subject to be logged, got %q", output.String())
+	}
+}
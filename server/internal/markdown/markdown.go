@@ -0,0 +1,427 @@
+// Package markdown parses the Markdown subset supported in chat messages into
+// a flat list of entities. Message content is stored untouched; clients render
+// it by applying the entities instead of running their own parsers.
+package markdown
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// Entity types
+const (
+	TypeBold      = "bold"
+	TypeCode      = "code"
+	TypeCodeBlock = "code_block"
+	TypeLink      = "link"
+	TypeSpoiler   = "spoiler"
+	TypeQuote     = "quote"
+	TypeMention   = "mention"
+	TypeChannel   = "channel"
+	TypeEmoji     = "emoji"
+)
+
+// AllowedLinkSchemes are the only URL schemes that produce link entities.
+var AllowedLinkSchemes = []string{"http", "https", "mailto"}
+
+// Entity marks a span of message content. Offset and Length are measured in
+// UTF-16 code units, so JavaScript clients can slice the content directly, and
+// cover the whole source span including any markup delimiters.
+type Entity struct {
+	Type     string `json:"type"`
+	Offset   int    `json:"offset"`
+	Length   int    `json:"length"`
+	URL      string `json:"url,omitempty"`
+	Language string `json:"language,omitempty"`
+	Username string `json:"username,omitempty"`
+	Channel  string `json:"channel,omitempty"`
+	Name     string `json:"name,omitempty"`
+}
+
+type parser struct {
+	src      []rune
+	units    []int
+	entities []Entity
+}
+
+// Parse returns the entities found in content, ordered by position. Text inside
+// code spans and code blocks is never parsed further.
+func Parse(content string) []Entity {
+	p := &parser{src: []rune(content)}
+	p.units = make([]int, len(p.src)+1)
+	for i, r := range p.src {
+		p.units[i+1] = p.units[i] + utf16.RuneLen(r)
+	}
+	p.parseBlocks(0, len(p.src))
+
+	sort.SliceStable(p.entities, func(i, j int) bool {
+		if p.entities[i].Offset != p.entities[j].Offset {
+			return p.entities[i].Offset < p.entities[j].Offset
+		}
+		return p.entities[i].Length > p.entities[j].Length
+	})
+	return p.entities
+}
+
+// Mentions returns the distinct, lower-cased usernames mentioned in content.
+func Mentions(content string) []string {
+	var usernames []string
+	seen := map[string]struct{}{}
+	for _, entity := range Parse(content) {
+		if entity.Type != TypeMention {
+			continue
+		}
+		name := strings.ToLower(entity.Username)
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		usernames = append(usernames, name)
+	}
+	return usernames
+}
+
+// Links returns the distinct sanitised URLs linked from content, in order.
+func Links(content string) []string {
+	var links []string
+	seen := map[string]struct{}{}
+	for _, entity := range Parse(content) {
+		if entity.Type != TypeLink {
+			continue
+		}
+		if _, ok := seen[entity.URL]; ok {
+			continue
+		}
+		seen[entity.URL] = struct{}{}
+		links = append(links, entity.URL)
+	}
+	return links
+}
+
+// SanitizeURL returns the normalised URL if it uses an allowed scheme, or ""
+// if it must not be linked. Control characters and whitespace are rejected
+// outright because browsers strip them when resolving schemes.
+func SanitizeURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+	for _, r := range raw {
+		if unicode.IsControl(r) || unicode.IsSpace(r) {
+			return ""
+		}
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Scheme == "" {
+		return ""
+	}
+	scheme := strings.ToLower(parsed.Scheme)
+	allowed := false
+	for _, s := range AllowedLinkSchemes {
+		if scheme == s {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return ""
+	}
+	if scheme != "mailto" && parsed.Host == "" {
+		return ""
+	}
+	if scheme == "mailto" && !strings.Contains(parsed.Opaque, "@") {
+		return ""
+	}
+	parsed.Scheme = scheme
+	return parsed.String()
+}
+
+func (p *parser) add(entity Entity, start, end int) {
+	entity.Offset = p.units[start]
+	entity.Length = p.units[end] - p.units[start]
+	p.entities = append(p.entities, entity)
+}
+
+// parseBlocks splits the source into fenced code blocks and runs of lines.
+func (p *parser) parseBlocks(start, end int) {
+	i := start
+	for i < end {
+		if p.hasPrefix(i, "```") {
+			if closeAt := p.index(i+3, end, "```"); closeAt >= 0 {
+				language := p.fenceLanguage(i+3, closeAt)
+				p.add(Entity{Type: TypeCodeBlock, Language: language}, i, closeAt+3)
+				i = closeAt + 3
+				continue
+			}
+		}
+
+		if p.src[i] == '>' && p.atLineStart(i) {
+			p.parseQuote(i, end)
+			i = p.quoteEnd(i, end)
+			continue
+		}
+
+		// An unclosed fence is plain text.
+		lineEnd := i
+		if p.hasPrefix(i, "```") {
+			lineEnd += 3
+		}
+		for lineEnd < end && p.src[lineEnd] != '\n' && !p.hasPrefix(lineEnd, "```") {
+			lineEnd++
+		}
+		p.parseInline(i, lineEnd)
+		i = lineEnd
+		if i < end && p.src[i] == '\n' {
+			i++
+		}
+	}
+}
+
+// parseQuote merges consecutive lines starting with '>' into one quote entity.
+func (p *parser) parseQuote(start, end int) {
+	quoteEnd := p.quoteEnd(start, end)
+	spanEnd := quoteEnd
+	if spanEnd > start && p.src[spanEnd-1] == '\n' {
+		spanEnd--
+	}
+	p.add(Entity{Type: TypeQuote}, start, spanEnd)
+
+	i := start
+	for i < spanEnd {
+		lineEnd := i
+		for lineEnd < spanEnd && p.src[lineEnd] != '\n' {
+			lineEnd++
+		}
+		textStart := i + 1
+		if textStart < lineEnd && p.src[textStart] == ' ' {
+			textStart++
+		}
+		p.parseInline(textStart, lineEnd)
+		i = lineEnd + 1
+	}
+}
+
+func (p *parser) quoteEnd(start, end int) int {
+	i := start
+	for i < end && p.src[i] == '>' {
+		for i < end && p.src[i] != '\n' {
+			i++
+		}
+		if i < end {
+			i++
+		}
+	}
+	return i
+}
+
+func (p *parser) parseInline(start, end int) {
+	i := start
+	for i < end {
+		switch {
+		case p.src[i] == '`':
+			if closeAt := p.indexRune(i+1, end, '`'); closeAt > i+1 {
+				p.add(Entity{Type: TypeCode}, i, closeAt+1)
+				i = closeAt + 1
+				continue
+			}
+		case p.hasPrefix(i, "**"):
+			if closeAt := p.index(i+2, end, "**"); closeAt > i+2 {
+				p.add(Entity{Type: TypeBold}, i, closeAt+2)
+				p.parseInline(i+2, closeAt)
+				i = closeAt + 2
+				continue
+			}
+		case p.hasPrefix(i, "||"):
+			if closeAt := p.index(i+2, end, "||"); closeAt > i+2 {
+				p.add(Entity{Type: TypeSpoiler}, i, closeAt+2)
+				p.parseInline(i+2, closeAt)
+				i = closeAt + 2
+				continue
+			}
+		case p.src[i] == '[':
+			if next, ok := p.parseLink(i, end); ok {
+				i = next
+				continue
+			}
+		case p.src[i] == '@' && p.atWordStart(i):
+			if nameEnd := p.scanWord(i+1, end, false); nameEnd > i+1 {
+				p.add(Entity{Type: TypeMention, Username: string(p.src[i+1 : nameEnd])}, i, nameEnd)
+				i = nameEnd
+				continue
+			}
+		case p.src[i] == '#' && p.atWordStart(i):
+			if i+1 < end && unicode.IsLetter(p.src[i+1]) {
+				nameEnd := p.scanWord(i+1, end, true)
+				p.add(Entity{Type: TypeChannel, Channel: string(p.src[i+1 : nameEnd])}, i, nameEnd)
+				i = nameEnd
+				continue
+			}
+		case p.src[i] == ':' && p.atWordStart(i):
+			if nameEnd := p.scanShortcode(i+1, end); nameEnd > i+1 && nameEnd < end && p.src[nameEnd] == ':' {
+				p.add(Entity{Type: TypeEmoji, Name: string(p.src[i+1 : nameEnd])}, i, nameEnd+1)
+				i = nameEnd + 1
+				continue
+			}
+		case (p.src[i] == 'h' || p.src[i] == 'H') && p.atWordStart(i):
+			if next, ok := p.parseAutolink(i, end); ok {
+				i = next
+				continue
+			}
+		}
+		i++
+	}
+}
+
+// parseLink handles [text](url). Links with a disallowed scheme are left as
+// plain text so the label is still shown but never becomes clickable.
+func (p *parser) parseLink(start, end int) (int, bool) {
+	labelEnd := p.indexRune(start+1, end, ']')
+	if labelEnd <= start+1 || labelEnd+1 >= end || p.src[labelEnd+1] != '(' {
+		return 0, false
+	}
+	urlEnd := p.indexRune(labelEnd+2, end, ')')
+	if urlEnd < 0 {
+		return 0, false
+	}
+	target := SanitizeURL(string(p.src[labelEnd+2 : urlEnd]))
+	if target == "" {
+		return urlEnd + 1, true
+	}
+	p.add(Entity{Type: TypeLink, URL: target}, start, urlEnd+1)
+	p.parseInline(start+1, labelEnd)
+	return urlEnd + 1, true
+}
+
+func (p *parser) parseAutolink(start, end int) (int, bool) {
+	if !p.hasPrefixFold(start, "http://") && !p.hasPrefixFold(start, "https://") {
+		return 0, false
+	}
+	i := start
+	for i < end && !unicode.IsSpace(p.src[i]) && !strings.ContainsRune("<>\"`", p.src[i]) {
+		i++
+	}
+	i = p.trimTrailingPunctuation(start, i)
+
+	target := SanitizeURL(string(p.src[start:i]))
+	if target == "" {
+		return 0, false
+	}
+	p.add(Entity{Type: TypeLink, URL: target}, start, i)
+	return i, true
+}
+
+// trimTrailingPunctuation drops sentence punctuation after a bare URL, keeping
+// a closing parenthesis only when it balances one inside the URL.
+func (p *parser) trimTrailingPunctuation(start, end int) int {
+	for end > start {
+		last := p.src[end-1]
+		if strings.ContainsRune(".,;:!?'*|]}", last) {
+			end--
+			continue
+		}
+		if last == ')' {
+			text := string(p.src[start:end])
+			if strings.Count(text, "(") < strings.Count(text, ")") {
+				end--
+				continue
+			}
+		}
+		break
+	}
+	return end
+}
+
+func (p *parser) fenceLanguage(start, end int) string {
+	i := start
+	for i < end && p.src[i] != '\n' {
+		r := p.src[i]
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("+-_#.", r) {
+			return ""
+		}
+		i++
+	}
+	if i == end {
+		return ""
+	}
+	return strings.ToLower(string(p.src[start:i]))
+}
+
+func (p *parser) scanWord(start, end int, allowHyphen bool) int {
+	i := start
+	for i < end {
+		r := p.src[i]
+		if r == '_' || (r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r))) || (allowHyphen && r == '-') {
+			i++
+			continue
+		}
+		break
+	}
+	return i
+}
+
+func (p *parser) scanShortcode(start, end int) int {
+	i := start
+	for i < end {
+		r := p.src[i]
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' || r == '+' || r == '-' {
+			i++
+			continue
+		}
+		break
+	}
+	return i
+}
+
+func (p *parser) atWordStart(i int) bool {
+	if i == 0 {
+		return true
+	}
+	prev := p.src[i-1]
+	return !unicode.IsLetter(prev) && !unicode.IsDigit(prev) && prev != '_'
+}
+
+func (p *parser) atLineStart(i int) bool {
+	return i == 0 || p.src[i-1] == '\n'
+}
+
+func (p *parser) hasPrefix(i int, prefix string) bool {
+	for _, r := range prefix {
+		if i >= len(p.src) || p.src[i] != r {
+			return false
+		}
+		i++
+	}
+	return true
+}
+
+func (p *parser) hasPrefixFold(i int, prefix string) bool {
+	for _, r := range prefix {
+		if i >= len(p.src) || unicode.ToLower(p.src[i]) != r {
+			return false
+		}
+		i++
+	}
+	return true
+}
+
+func (p *parser) index(start, end int, needle string) int {
+	for i := start; i < end; i++ {
+		if p.hasPrefix(i, needle) && i+len(needle) <= end {
+			return i
+		}
+	}
+	return -1
+}
+
+func (p *parser) indexRune(start, end int, r rune) int {
+	for i := start; i < end; i++ {
+		if p.src[i] == r {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,154 @@
+package markdown
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []Entity
+	}{
+		{
+			name:    "plain text",
+			content: "just chatting",
+			want:    nil,
+		},
+		{
+			name:    "bold with nested mention",
+			content: "hi **@alice** there",
+			want: []Entity{
+				{Type: TypeBold, Offset: 3, Length: 10},
+				{Type: TypeMention, Offset: 5, Length: 6, Username: "alice"},
+			},
+		},
+		{
+			name:    "inline code is not parsed further",
+			content: "run `@bob **x**` now",
+			want: []Entity{
+				{Type: TypeCode, Offset: 4, Length: 12},
+			},
+		},
+		{
+			name:    "code block with language",
+			content: "```go\nfmt.Println(\"@carol\")\n```\nafter #general",
+			want: []Entity{
+				{Type: TypeCodeBlock, Offset: 0, Length: 31, Language: "go"},
+				{Type: TypeChannel, Offset: 38, Length: 8, Channel: "general"},
+			},
+		},
+		{
+			name:    "unclosed fence is literal",
+			content: "```oops @dave",
+			want: []Entity{
+				{Type: TypeMention, Offset: 8, Length: 5, Username: "dave"},
+			},
+		},
+		{
+			name:    "markdown link",
+			content: "see [the docs](https://go.dev/doc)",
+			want: []Entity{
+				{Type: TypeLink, Offset: 4, Length: 30, URL: "https://go.dev/doc"},
+			},
+		},
+		{
+			name:    "dangerous link scheme is dropped",
+			content: "[click](javascript:alert(1)) [x](JaVaScRiPt:alert(1)) [y](data:text/html,hi) [z](java\tscript:1)",
+			want:    nil,
+		},
+		{
+			name:    "mailto link",
+			content: "[mail me](mailto:me@example.com)",
+			want: []Entity{
+				{Type: TypeLink, Offset: 0, Length: 32, URL: "mailto:me@example.com"},
+			},
+		},
+		{
+			name:    "autolink trims punctuation",
+			content: "go to https://example.com/a_(b). or (https://example.com/c)",
+			want: []Entity{
+				{Type: TypeLink, Offset: 6, Length: 25, URL: "https://example.com/a_(b)"},
+				{Type: TypeLink, Offset: 37, Length: 21, URL: "https://example.com/c"},
+			},
+		},
+		{
+			name:    "spoiler",
+			content: "the end: ||they win||",
+			want: []Entity{
+				{Type: TypeSpoiler, Offset: 9, Length: 12},
+			},
+		},
+		{
+			name:    "consecutive quote lines merge",
+			content: "> first @erin\n> second\nreply",
+			want: []Entity{
+				{Type: TypeQuote, Offset: 0, Length: 22},
+				{Type: TypeMention, Offset: 8, Length: 5, Username: "erin"},
+			},
+		},
+		{
+			name:    "emoji shortcodes but not times",
+			content: "meet at 10:30:00 :tada: :+1:",
+			want: []Entity{
+				{Type: TypeEmoji, Offset: 17, Length: 6, Name: "tada"},
+				{Type: TypeEmoji, Offset: 24, Length: 4, Name: "+1"},
+			},
+		},
+		{
+			name:    "email addresses are not mentions",
+			content: "mail bob@example.com",
+			want:    nil,
+		},
+		{
+			name:    "offsets count UTF-16 code units",
+			content: "😀 **hi**",
+			want: []Entity{
+				{Type: TypeBold, Offset: 3, Length: 6},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(tt.content)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Parse(%q)\n got: %+v\nwant: %+v", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeURL(t *testing.T) {
+	tests := map[string]string{
+		"https://example.com/path?q=1": "https://example.com/path?q=1",
+		"HTTP://Example.com":           "http://Example.com",
+		"mailto:someone@example.com":   "mailto:someone@example.com",
+		"javascript:alert(1)":          "",
+		"vbscript:msgbox":              "",
+		"data:text/html;base64,AAAA":   "",
+		"file:///etc/passwd":           "",
+		"//example.com":                "",
+		"https:example.com":            "",
+		"mailto:":                      "",
+		"https://exa\nmple.com":        "",
+	}
+
+	for raw, want := range tests {
+		if got := SanitizeURL(raw); got != want {
+			t.Errorf("SanitizeURL(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestMentionsAndLinks(t *testing.T) {
+	content := "@Alice and @alice, `@bob` https://a.example [b](https://b.example) https://a.example"
+
+	if got, want := Mentions(content), []string{"alice"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Mentions = %v, want %v", got, want)
+	}
+	if got, want := Links(content), []string{"https://a.example", "https://b.example"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Links = %v, want %v", got, want)
+	}
+}
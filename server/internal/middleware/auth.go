@@ -28,17 +28,7 @@ func JWTAuth(next http.Handler) http.Handler {
 			return
 		}
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
-			}
-			secretKey := util.GetEnv("JWT_SECRET_KEY", "")
-			if secretKey == "" {
-				log.Println("JWT_SECRET_KEY is not set in environment variables")
-				return nil, jwt.ErrSignatureInvalid
-			}
-			return []byte(secretKey), nil
-		})
+		token, err := jwt.Parse(tokenString, signingKey)
 
 		if err != nil || !token.Valid {
 			util.WriteErrorResponse(w, http.StatusUnauthorized, "invalid auth token")
@@ -62,6 +52,38 @@ func JWTAuth(next http.Handler) http.Handler {
 	})
 }
 
+// signingKey is the jwt.Keyfunc for session tokens.
+func signingKey(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, jwt.ErrSignatureInvalid
+	}
+	secretKey := util.GetEnv("JWT_SECRET_KEY", "")
+	if secretKey == "" {
+		log.Println("JWT_SECRET_KEY is not set in environment variables")
+		return nil, jwt.ErrSignatureInvalid
+	}
+	return []byte(secretKey), nil
+}
+
+// tokenUserID returns the user ID of the request's session cookie when its
+// token is valid, without requiring one.
+func tokenUserID(r *http.Request) (string, bool) {
+	cookie, err := r.Cookie("jwt")
+	if err != nil || cookie.Value == "" {
+		return "", false
+	}
+	token, err := jwt.Parse(cookie.Value, signingKey)
+	if err != nil || !token.Valid {
+		return "", false
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", false
+	}
+	userID, ok := claims["id"].(string)
+	return userID, ok && userID != ""
+}
+
 func OptionalJWTAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		log.Printf("OptionalJWTAuth middleware triggered for %s", r.URL.Path)
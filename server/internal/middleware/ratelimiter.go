@@ -1,60 +1,104 @@
 package middleware
 
 import (
+	"log"
+	"math"
 	"net"
 	"net/http"
-	"sync"
+	"strconv"
 	"time"
+
+	"chat-application/internal/ratelimit"
+	"chat-application/util"
 )
 
+// rateLimitStore holds the buckets of every limiter created by
+// GetRateLimiter. main replaces it with a shared store when replicas must
+// agree on limits.
+var rateLimitStore ratelimit.Store = ratelimit.NewMemoryStore()
+
+// SetRateLimitStore sets the store used by limiters created afterwards.
+func SetRateLimitStore(store ratelimit.Store) {
+	rateLimitStore = store
+}
+
+// RateLimiter limits requests with a token bucket per client: the signed-in
+// user when the request carries a valid session, otherwise the client IP.
+// Limiters with different scopes never share buckets.
 type RateLimiter struct {
-	requests  map[string]int
-	mu        sync.Mutex
-	resetTime time.Time
-	limit     int
-	window    time.Duration
+	store ratelimit.Store
+	scope string
+	limit ratelimit.Limit
 }
 
-func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+func NewRateLimiter(store ratelimit.Store, scope string, limit int, window time.Duration) *RateLimiter {
 	return &RateLimiter{
-		requests:  make(map[string]int),
-		resetTime: time.Now().Add(window),
-		limit:     limit,
-		window:    window,
+		store: store,
+		scope: scope,
+		limit: ratelimit.Limit{Burst: limit, Per: window},
 	}
 }
 
 func (r *RateLimiter) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		r.mu.Lock()
-		defer r.mu.Unlock()
-
-		// Normalize remote address to IP only (strip port) when possible
-		ip := req.RemoteAddr
-		if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
-			ip = host
+		result, err := r.store.Take(req.Context(), r.scope+":"+rateLimitKey(req), r.limit, time.Now())
+		if err != nil {
+			// Failing open keeps the API up when the shared store is not.
+			log.Printf("RateLimiter - failed to check %s limit: %v", r.scope, err)
+			next.ServeHTTP(w, req)
+			return
 		}
 
-		// Reset if window expired
-		if time.Now().After(r.resetTime) {
-			r.requests = make(map[string]int)
-			r.resetTime = time.Now().Add(r.window)
+		setRateLimitHeaders(w, result)
+		if !result.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(seconds(result.RetryAfter)))
+			util.WriteErrorResponse(w, http.StatusTooManyRequests, "Too many requests, please try again later.")
+			return
 		}
 
-		r.requests[ip]++
+		next.ServeHTTP(w, req)
+	})
+}
 
-		if r.requests[ip] > r.limit {
-			http.Error(w, "Too many requests, please try again later.", http.StatusTooManyRequests)
+// rateLimitKey identifies the client a request counts against. Limiters
+// usually run before JWTAuth, so the session cookie is checked here too.
+func rateLimitKey(req *http.Request) string {
+	if userID, ok := req.Context().Value(UserIDKey).(string); ok && userID != "" {
+		return "user:" + userID
+	}
+	if userID, ok := tokenUserID(req); ok {
+		return "user:" + userID
+	}
+	// RemoteAddr already holds the forwarded client address when the
+	// router's RealIP middleware runs first.
+	ip := req.RemoteAddr
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		ip = host
+	}
+	return "ip:" + ip
+}
+
+// setRateLimitHeaders reports the bucket in RateLimit-* headers. When
+// limiters are nested, the one with the fewest requests left wins.
+func setRateLimitHeaders(w http.ResponseWriter, result ratelimit.Result) {
+	header := w.Header()
+	if existing := header.Get("RateLimit-Remaining"); existing != "" {
+		if remaining, err := strconv.Atoi(existing); err == nil && remaining <= result.Remaining {
 			return
 		}
+	}
+	header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	header.Set("RateLimit-Reset", strconv.Itoa(seconds(result.ResetAfter)))
+}
 
-		next.ServeHTTP(w, req)
-	})
+// seconds rounds a duration up to whole seconds for headers.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
 }
 
-// RateLimiter is a convenience wrapper so router code can call authMiddleware.RateLimiter(limit)
-// It returns a middleware that enforces `limit` requests per 1 minute window.
-func GetRateLimiter(limit int) func(http.Handler) http.Handler {
-	rl := NewRateLimiter(limit, time.Minute)
-	return rl.Middleware
+// GetRateLimiter returns middleware allowing `limit` requests per minute per
+// client, tracked under scope in the shared rate limit store.
+func GetRateLimiter(scope string, limit int) func(http.Handler) http.Handler {
+	return NewRateLimiter(rateLimitStore, scope, limit, time.Minute).Middleware
 }
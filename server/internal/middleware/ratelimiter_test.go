@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"chat-application/internal/ratelimit"
+)
+
+type failingStore struct{}
+
+func (failingStore) Take(ctx context.Context, key string, limit ratelimit.Limit, now time.Time) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("store unavailable")
+}
+
+func TestRateLimiterSetsHeadersAndRefuses(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	handler := NewRateLimiter(store, "test", 2, time.Minute).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	request := func(remoteAddr, userID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		if userID != "" {
+			req = req.WithContext(context.WithValue(req.Context(), UserIDKey, userID))
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := request("203.0.113.1:1000", "")
+	if first.Code != http.StatusNoContent || first.Header().Get("RateLimit-Limit") != "2" || first.Header().Get("RateLimit-Remaining") != "1" {
+		t.Fatalf("expected an allowed request with headers, got %d %v", first.Code, first.Header())
+	}
+	// A different port is the same client.
+	request("203.0.113.1:2000", "")
+	refused := request("203.0.113.1:3000", "")
+	if refused.Code != http.StatusTooManyRequests || refused.Header().Get("Retry-After") != "30" {
+		t.Fatalf("expected a refusal with Retry-After, got %d %v", refused.Code, refused.Header())
+	}
+
+	if rec := request("203.0.113.2:1000", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected other addresses to have their own budget, got %d", rec.Code)
+	}
+	if rec := request("203.0.113.1:1000", "user-1"); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected signed-in users to be limited by user ID, got %d", rec.Code)
+	}
+}
+
+func TestRateLimiterScopesShareAStoreIndependently(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	strict := NewRateLimiter(store, "strict", 1, time.Minute).Middleware(ok)
+	loose := NewRateLimiter(store, "loose", 10, time.Minute).Middleware(ok)
+
+	serve := func(handler http.Handler) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	serve(strict)
+	if rec := serve(strict); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected the strict scope to be exhausted, got %d", rec.Code)
+	}
+	if rec := serve(loose); rec.Code != http.StatusOK {
+		t.Fatalf("expected the loose scope to be unaffected, got %d", rec.Code)
+	}
+
+	nested := NewRateLimiter(store, "outer", 10, time.Minute).Middleware(NewRateLimiter(store, "inner", 3, time.Minute).Middleware(ok))
+	if rec := serve(nested); rec.Header().Get("RateLimit-Remaining") != "2" || rec.Header().Get("RateLimit-Limit") != "3" {
+		t.Fatalf("expected the tighter limiter's headers, got %v", rec.Header())
+	}
+}
+
+func TestRateLimiterFailsOpen(t *testing.T) {
+	called := false
+	handler := NewRateLimiter(failingStore{}, "test", 1, time.Minute).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if !called {
+		t.Fatal("expected requests to pass when the store fails")
+	}
+}
+
+func TestRateLimiterKeysBySessionCookie(t *testing.T) {
+	t.Setenv("JWT_SECRET_KEY", "test-secret")
+	handler := NewRateLimiter(ratelimit.NewMemoryStore(), "test", 1, time.Minute).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	request := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "203.0.113.1:1000"
+		if token != "" {
+			req.AddCookie(&http.Cookie{Name: "jwt", Value: token})
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	request("")
+	if code := request(issueTestToken(t, "test-secret", "user-1")); code != http.StatusOK {
+		t.Fatalf("expected a signed-in user behind a shared address to have their own budget, got %d", code)
+	}
+	if code := request(issueTestToken(t, "test-secret", "user-2")); code != http.StatusOK {
+		t.Fatalf("expected each signed-in user to have their own budget, got %d", code)
+	}
+	if code := request(issueTestToken(t, "wrong-secret", "user-3")); code != http.StatusTooManyRequests {
+		t.Fatalf("expected a forged token to count against the address, got %d", code)
+	}
+}
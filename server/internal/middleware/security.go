@@ -25,12 +25,13 @@ func SecurityHeaders(next http.Handler) http.Handler {
 	})
 }
 
-// ContentTypeJSON ensures JSON content type for API endpoints
+// ContentTypeJSON ensures JSON content type for API endpoints.
+// Multipart bodies are let through for file uploads.
 func ContentTypeJSON(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "POST" || r.Method == "PUT" || r.Method == "PATCH" {
 			contentType := r.Header.Get("Content-Type")
-			if !strings.Contains(contentType, "application/json") {
+			if !strings.Contains(contentType, "application/json") && !isMultipart(r) {
 				http.Error(w, "Content-Type must be application/json", http.StatusBadRequest)
 				return
 			}
@@ -49,6 +50,25 @@ func RequestSizeLimit(maxSize int64) func(next http.Handler) http.Handler {
 	}
 }
 
+// RequestSizeLimitWithUploads limits the size of request bodies, allowing
+// multipart uploads a separate, larger limit
+func RequestSizeLimitWithUploads(maxSize, maxUploadSize int64) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limit := maxSize
+			if isMultipart(r) {
+				limit = maxUploadSize
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func isMultipart(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data")
+}
+
 // NoCache adds cache control headers to prevent caching
 func NoCache(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,64 @@
+// Package netguard keeps outbound requests to user-supplied URLs away from
+// internal networks.
+package netguard
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"syscall"
+)
+
+var ErrForbiddenAddress = errors.New("destination address is not allowed")
+
+// blockedPrefixes covers the special-purpose ranges that net.IP's helpers miss.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+// DenyPrivateAddresses is a net.Dialer Control hook. It runs after DNS
+// resolution for every connection, including redirects, so a hostname that
+// resolves (or re-resolves) to an internal address is refused.
+func DenyPrivateAddresses(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return ErrForbiddenAddress
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return ErrForbiddenAddress
+	}
+	if !isPublicAddress(addr) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+func isPublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() ||
+		addr.IsUnspecified() ||
+		addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package netguard
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestIsPublicAddress(t *testing.T) {
+	tests := map[string]bool{
+		"93.184.216.34":            true,
+		"2606:4700:4700::1111":     true,
+		"127.0.0.1":                false,
+		"10.1.2.3":                 false,
+		"172.16.0.1":               false,
+		"192.168.1.1":              false,
+		"169.254.169.254":          false,
+		"100.64.0.1":               false,
+		"0.0.0.0":                  false,
+		"::1":                      false,
+		"fe80::1":                  false,
+		"fd00::1":                  false,
+		"::ffff:127.0.0.1":         false,
+		"::ffff:169.254.169.254":   false,
+		"64:ff9b::a9fe:a9fe":       false,
+		"255.255.255.255":          false,
+		"224.0.0.1":                false,
+		"2001:db8::1":              false,
+		"198.18.0.1":               false,
+		"::ffff:93.184.216.34":     true,
+		"2001:4860:4860::8888":     true,
+		"8.8.8.8":                  true,
+		"fc00::1":                  false,
+		"ff02::1":                  false,
+		"192.0.0.8":                false,
+		"203.0.113.10":             false,
+		"198.51.100.7":             false,
+		"240.0.0.1":                false,
+		"100.127.255.254":          false,
+		"100.128.0.1":              true,
+		"172.32.0.1":               true,
+		"11.0.0.1":                 true,
+		"192.169.0.1":              true,
+		"169.255.0.1":              true,
+		"1.1.1.1":                  true,
+		"::":                       false,
+		"2001:db8:ffff:ffff::ffff": false,
+	}
+
+	for raw, want := range tests {
+		addr := netip.MustParseAddr(raw)
+		if got := isPublicAddress(addr); got != want {
+			t.Errorf("isPublicAddress(%s) = %v, want %v", raw, got, want)
+		}
+	}
+}
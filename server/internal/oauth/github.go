@@ -0,0 +1,68 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// NewGitHubProvider creates a provider for GitHub accounts. GitHub is not an
+// OpenID Connect provider, so the identity comes from its REST API. apiURL
+// is normally https://api.github.com and webURL https://github.com.
+func NewGitHubProvider(clientID, clientSecret, webURL, apiURL string, httpClient *http.Client) *Provider {
+	webURL = strings.TrimRight(webURL, "/")
+	apiURL = strings.TrimRight(apiURL, "/")
+	return &Provider{
+		Name:         "github",
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scopes:       []string{"read:user", "user:email"},
+		AuthURL:      webURL + "/login/oauth/authorize",
+		TokenURL:     webURL + "/login/oauth/access_token",
+		HTTPClient:   httpClient,
+		identity: func(ctx context.Context, p *Provider, token *Token) (*Identity, error) {
+			return githubIdentity(ctx, p, apiURL, token)
+		},
+	}
+}
+
+func githubIdentity(ctx context.Context, p *Provider, apiURL string, token *Token) (*Identity, error) {
+	var user struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+	}
+	if err := p.getJSON(ctx, apiURL+"/user", token.AccessToken, &user); err != nil {
+		return nil, err
+	}
+	if user.ID == 0 {
+		return nil, fmt.Errorf("github returned no user")
+	}
+
+	// The profile email is whatever the user made public; the emails
+	// endpoint says which address is primary and whether it is verified.
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := p.getJSON(ctx, apiURL+"/user/emails", token.AccessToken, &emails); err != nil {
+		return nil, err
+	}
+
+	identity := &Identity{
+		Subject:  strconv.FormatInt(user.ID, 10),
+		Username: user.Login,
+		Name:     user.Name,
+	}
+	for _, email := range emails {
+		if email.Primary {
+			identity.Email = email.Email
+			identity.EmailVerified = email.Verified
+			break
+		}
+	}
+	return identity, nil
+}
@@ -0,0 +1,217 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrProviderRejected is returned when the provider refuses the
+	// authorization code, for example because the PKCE verifier is wrong.
+	ErrProviderRejected = errors.New("provider rejected the authorization")
+	// ErrInvalidIDToken is returned when an OpenID Connect ID token fails
+	// signature, issuer, audience, expiry or nonce checks.
+	ErrInvalidIDToken = errors.New("invalid ID token")
+)
+
+// maxResponseSize bounds every response read from a provider.
+const maxResponseSize = 1 << 20
+
+// Identity is the account a provider vouches for.
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	// Username is the provider's handle, if it has one.
+	Username string
+	Name     string
+}
+
+// Token is the token endpoint response.
+type Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+}
+
+// Provider is an OAuth2 authorization server used for login. OpenID Connect
+// providers are configured with an Issuer and have their endpoints
+// discovered; others set the endpoints and an identity lookup directly.
+type Provider struct {
+	Name         string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	Issuer       string
+	AuthURL      string
+	TokenURL     string
+	HTTPClient   *http.Client
+
+	// identity looks up the user for plain OAuth2 providers.
+	identity func(ctx context.Context, p *Provider, token *Token) (*Identity, error)
+
+	mu          sync.Mutex
+	jwksURL     string
+	keys        map[string]any
+	keysFetched time.Time
+}
+
+// NewOIDCProvider creates an OpenID Connect provider. Its endpoints are
+// discovered from the issuer on first use.
+func NewOIDCProvider(name, issuer, clientID, clientSecret string, httpClient *http.Client) *Provider {
+	return &Provider{
+		Name:         name,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scopes:       []string{"openid", "email", "profile"},
+		Issuer:       strings.TrimRight(issuer, "/"),
+		HTTPClient:   httpClient,
+	}
+}
+
+// NewGoogleProvider creates a provider for Google accounts.
+func NewGoogleProvider(clientID, clientSecret string, httpClient *http.Client) *Provider {
+	return NewOIDCProvider("google", "https://accounts.google.com", clientID, clientSecret, httpClient)
+}
+
+// NewVerifier returns a PKCE code verifier (RFC 7636); the same function
+// makes state and nonce values.
+func NewVerifier() string {
+	raw := make([]byte, 32)
+	_, _ = rand.Read(raw)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// challenge is the S256 code challenge for a verifier.
+func challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns the provider URL the user is sent to. nonce is only
+// used by OpenID Connect providers.
+func (p *Provider) AuthCodeURL(ctx context.Context, redirectURI, state, nonce, verifier string) (string, error) {
+	authURL, _, err := p.endpoints(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.ClientID},
+		"redirect_uri":          {redirectURI},
+		"scope":                 {strings.Join(p.Scopes, " ")},
+		"state":                 {state},
+		"code_challenge":        {challenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	if p.Issuer != "" {
+		params.Set("nonce", nonce)
+	}
+
+	separator := "?"
+	if strings.Contains(authURL, "?") {
+		separator = "&"
+	}
+	return authURL + separator + params.Encode(), nil
+}
+
+// Exchange trades an authorization code and its PKCE verifier for tokens.
+func (p *Provider) Exchange(ctx context.Context, code, redirectURI, verifier string) (*Token, error) {
+	_, tokenURL, err := p.endpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"client_id":     {p.ClientID},
+		"client_secret": {p.ClientSecret},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var body struct {
+		Token
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := p.doJSON(req, &body)
+	if err != nil {
+		return nil, err
+	}
+	// GitHub reports errors with a 200 status and an error field.
+	if status != http.StatusOK || body.Error != "" || body.AccessToken == "" {
+		return nil, fmt.Errorf("%w: %s %s", ErrProviderRejected, body.Error, body.ErrorDescription)
+	}
+	return &body.Token, nil
+}
+
+// Identity returns the user the tokens belong to. For OpenID Connect
+// providers the ID token is verified, including that it carries nonce.
+func (p *Provider) Identity(ctx context.Context, token *Token, nonce string) (*Identity, error) {
+	if p.identity != nil {
+		return p.identity(ctx, p, token)
+	}
+	return p.verifyIDToken(ctx, token.IDToken, nonce)
+}
+
+// getJSON fetches a JSON document, authenticating with the access token if given.
+func (p *Provider) getJSON(ctx context.Context, endpoint, accessToken string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	status, err := p.doJSON(req, out)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("%s responded %d", endpoint, status)
+	}
+	return nil
+}
+
+func (p *Provider) doJSON(req *http.Request, out any) (int, error) {
+	client := p.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reach %s: %w", p.Name, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s response: %w", p.Name, err)
+	}
+	if err := json.Unmarshal(data, out); err != nil && resp.StatusCode == http.StatusOK {
+		return 0, fmt.Errorf("invalid %s response: %w", p.Name, err)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,95 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"chat-application/internal/oauth/oauthtest"
+)
+
+const redirectURI = "http://localhost:8080/api/users/oauth/test/callback"
+
+func TestOIDCAuthorizationCodeFlowWithPKCE(t *testing.T) {
+	mock := oauthtest.NewProvider("yappin", "client-secret")
+	defer mock.Close()
+	mock.SignInAs(oauthtest.User{Subject: "user-1", Email: "alice@example.com", EmailVerified: true, Username: "alice"})
+
+	provider := NewOIDCProvider("test", mock.Issuer(), "yappin", "client-secret", mock.Client())
+	ctx := context.Background()
+
+	authorize := func(nonce, verifier string) string {
+		t.Helper()
+		authURL, err := provider.AuthCodeURL(ctx, redirectURI, "state-1", nonce, verifier)
+		if err != nil {
+			t.Fatalf("AuthCodeURL: %v", err)
+		}
+		callback, err := mock.Authorize(authURL)
+		if err != nil {
+			t.Fatalf("Authorize: %v", err)
+		}
+		if callback.Query().Get("state") != "state-1" {
+			t.Fatalf("expected state to round-trip, got %s", callback)
+		}
+		return callback.Query().Get("code")
+	}
+
+	verifier := NewVerifier()
+	token, err := provider.Exchange(ctx, authorize("nonce-1", verifier), redirectURI, verifier)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	identity, err := provider.Identity(ctx, token, "nonce-1")
+	if err != nil {
+		t.Fatalf("Identity: %v", err)
+	}
+	if identity.Subject != "user-1" || identity.Email != "alice@example.com" || !identity.EmailVerified || identity.Username != "alice" {
+		t.Fatalf("unexpected identity %+v", identity)
+	}
+
+	if _, err := provider.Identity(ctx, token, "another-nonce"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("expected a nonce mismatch to be rejected, got %v", err)
+	}
+
+	if _, err := provider.Exchange(ctx, authorize("nonce-2", NewVerifier()), redirectURI, NewVerifier()); !errors.Is(err, ErrProviderRejected) {
+		t.Fatalf("expected a wrong PKCE verifier to be rejected, got %v", err)
+	}
+
+	other := NewOIDCProvider("test", mock.Issuer(), "another-client", "client-secret", mock.Client())
+	if _, err := other.Identity(ctx, token, "nonce-1"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("expected an ID token for another audience to be rejected, got %v", err)
+	}
+}
+
+func TestGitHubIdentityUsesPrimaryVerifiedEmail(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer gho_token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/user":
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": 42, "login": "octocat", "name": "Mona"})
+		case "/user/emails":
+			_ = json.NewEncoder(w).Encode([]map[string]any{
+				{"email": "old@example.com", "primary": false, "verified": true},
+				{"email": "mona@example.com", "primary": true, "verified": true},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer api.Close()
+
+	provider := NewGitHubProvider("client", "secret", api.URL, api.URL, api.Client())
+	identity, err := provider.Identity(context.Background(), &Token{AccessToken: "gho_token"}, "")
+	if err != nil {
+		t.Fatalf("Identity: %v", err)
+	}
+	if identity.Subject != "42" || identity.Email != "mona@example.com" || !identity.EmailVerified || identity.Username != "octocat" {
+		t.Fatalf("unexpected identity %+v", identity)
+	}
+}
@@ -0,0 +1,166 @@
+package oauth
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// keyRefreshInterval limits how often an unknown key ID triggers a JWKS fetch.
+const keyRefreshInterval = time.Minute
+
+// endpoints returns the authorization and token endpoints, discovering them
+// first for an OpenID Connect provider. A failed discovery is retried on the
+// next call.
+func (p *Provider) endpoints(ctx context.Context) (string, string, error) {
+	p.mu.Lock()
+	authURL, tokenURL := p.AuthURL, p.TokenURL
+	discovered := p.Issuer == "" || p.jwksURL != ""
+	p.mu.Unlock()
+	if discovered {
+		return authURL, tokenURL, nil
+	}
+
+	var document struct {
+		Issuer                string `json:"issuer"`
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		JWKSURI               string `json:"jwks_uri"`
+	}
+	if err := p.getJSON(ctx, p.Issuer+"/.well-known/openid-configuration", "", &document); err != nil {
+		return "", "", fmt.Errorf("failed to discover %s: %w", p.Name, err)
+	}
+	if document.Issuer != p.Issuer || document.AuthorizationEndpoint == "" || document.TokenEndpoint == "" || document.JWKSURI == "" {
+		return "", "", fmt.Errorf("failed to discover %s: incomplete or mismatched configuration", p.Name)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.AuthURL = document.AuthorizationEndpoint
+	p.TokenURL = document.TokenEndpoint
+	p.jwksURL = document.JWKSURI
+	return p.AuthURL, p.TokenURL, nil
+}
+
+// verifyIDToken checks an ID token's signature against the provider's keys
+// and its standard claims, and returns the identity it asserts.
+func (p *Provider) verifyIDToken(ctx context.Context, idToken, nonce string) (*Identity, error) {
+	if idToken == "" {
+		return nil, fmt.Errorf("%w: missing", ErrInvalidIDToken)
+	}
+
+	var claims struct {
+		jwt.RegisteredClaims
+		Nonce             string `json:"nonce"`
+		Email             string `json:"email"`
+		EmailVerified     any    `json:"email_verified"`
+		PreferredUsername string `json:"preferred_username"`
+		Name              string `json:"name"`
+	}
+	_, err := jwt.ParseWithClaims(idToken, &claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.signingKey(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "ES256"}),
+		jwt.WithIssuer(p.Issuer),
+		jwt.WithAudience(p.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	if nonce == "" || claims.Nonce != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidIDToken)
+	}
+
+	// Some providers send email_verified as a string.
+	verified := claims.EmailVerified == true || claims.EmailVerified == "true"
+	return &Identity{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: verified,
+		Username:      claims.PreferredUsername,
+		Name:          claims.Name,
+	}, nil
+}
+
+// signingKey returns the provider key with the given ID, refetching the key
+// set when the ID is unknown so that key rotation is picked up.
+func (p *Provider) signingKey(ctx context.Context, kid string) (any, error) {
+	p.mu.Lock()
+	key, ok := p.keys[kid]
+	stale := time.Since(p.keysFetched) > keyRefreshInterval
+	jwksURL := p.jwksURL
+	p.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+	if !stale {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	var set struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := p.getJSON(ctx, jwksURL, "", &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+
+	keys := make(map[string]any, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		switch jwk.Kty {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+			e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+			if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+				continue
+			}
+			keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+			y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+			if jwk.Crv != "P-256" || errX != nil || errY != nil || len(x) != 32 || len(y) != 32 {
+				continue
+			}
+			// Rejects points that are not on the curve.
+			if _, err := ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+				continue
+			}
+			keys[jwk.Kid] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.keysFetched = time.Now()
+	p.mu.Unlock()
+
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
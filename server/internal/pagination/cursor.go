@@ -0,0 +1,59 @@
+// Package pagination implements the opaque keyset cursors used by paginated
+// endpoints. A cursor identifies a row by (created_at, id), or by
+// (rank, name, id) for ranked lists, which is stable under concurrent
+// inserts, unlike LIMIT/OFFSET paging.
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// Encode returns the opaque string form of the cursor.
+func (c Cursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// Decode parses a cursor produced by Encode.
+func Decode(value string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	parsedTime, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &Cursor{CreatedAt: parsedTime, ID: parsedID}, nil
+}
+
+// DecodeOptional decodes value, returning nil for an empty string.
+func DecodeOptional(value string) (*Cursor, error) {
+	if value == "" {
+		return nil, nil
+	}
+	return Decode(value)
+}
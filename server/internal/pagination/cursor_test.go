@@ -0,0 +1,56 @@
+package pagination
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursor := Cursor{
+		CreatedAt: time.Date(2024, 5, 1, 12, 30, 0, 123456000, time.UTC),
+		ID:        uuid.New(),
+	}
+
+	decoded, err := Decode(cursor.Encode())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !decoded.CreatedAt.Equal(cursor.CreatedAt) || decoded.ID != cursor.ID {
+		t.Fatalf("expected %+v, got %+v", cursor, decoded)
+	}
+}
+
+func TestDecodeRejectsMalformedCursors(t *testing.T) {
+	for _, value := range []string{"not base64!", "bm8tc2VwYXJhdG9y", "Z2FyYmFnZXxub3QtYS11dWlk"} {
+		if _, err := Decode(value); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("Decode(%q) = %v, want ErrInvalidCursor", value, err)
+		}
+	}
+
+	cursor, err := DecodeOptional("")
+	if err != nil || cursor != nil {
+		t.Fatalf("expected empty cursor to decode to nil, got %+v, %v", cursor, err)
+	}
+}
+
+func TestRankCursorRoundTrip(t *testing.T) {
+	cursor := RankCursor{Rank: 2, Name: "a|b", ID: uuid.New()}
+
+	decoded, err := DecodeRank(cursor.Encode())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *decoded != cursor {
+		t.Fatalf("expected %+v, got %+v", cursor, decoded)
+	}
+
+	if _, err := DecodeRank(Cursor{CreatedAt: time.Now(), ID: uuid.New()}.Encode()); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("expected a time cursor to be rejected, got %v", err)
+	}
+	if cursor, err := DecodeRank(""); err != nil || cursor != nil {
+		t.Fatalf("expected empty cursor to decode to nil, got %+v, %v", cursor, err)
+	}
+}
@@ -0,0 +1,51 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// RankCursor identifies a row in a list ordered by (rank, name, id), such as
+// search results grouped by how well they match.
+type RankCursor struct {
+	Rank int
+	Name string
+	ID   uuid.UUID
+}
+
+// Encode returns the opaque string form of the cursor.
+func (c RankCursor) Encode() string {
+	raw := strconv.Itoa(c.Rank) + "|" + c.ID.String() + "|" + c.Name
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeRank parses a cursor produced by RankCursor.Encode, returning nil for
+// an empty string.
+func DecodeRank(value string) (*RankCursor, error) {
+	if value == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	// The name goes last since it may itself contain the separator.
+	parts := strings.SplitN(string(raw), "|", 3)
+	if len(parts) != 3 {
+		return nil, ErrInvalidCursor
+	}
+	rank, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	id, err := uuid.Parse(parts[1])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &RankCursor{Rank: rank, Name: parts[2], ID: id}, nil
+}
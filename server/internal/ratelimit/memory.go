@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+const (
+	memoryShards = 64
+	// Idle buckets are swept from a shard at most this often.
+	memorySweepInterval = time.Minute
+)
+
+// MemoryStore keeps buckets in process memory. Keys are spread over shards
+// with their own locks so unrelated requests do not contend.
+type MemoryStore struct {
+	shards [memoryShards]memoryShard
+}
+
+type memoryShard struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+type memoryBucket struct {
+	tokens  float64
+	updated time.Time
+	// full is when the bucket refills completely and can be forgotten.
+	full time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	store := &MemoryStore{}
+	for i := range store.shards {
+		store.shards[i].buckets = make(map[string]*memoryBucket)
+	}
+	return store
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if now.Sub(shard.lastSweep) > memorySweepInterval {
+		for bucketKey, bucket := range shard.buckets {
+			if !now.Before(bucket.full) {
+				delete(shard.buckets, bucketKey)
+			}
+		}
+		shard.lastSweep = now
+	}
+
+	bucket, ok := shard.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{}
+		shard.buckets[key] = bucket
+	}
+	tokens, result := limit.spend(bucket.tokens, bucket.updated, now)
+	bucket.tokens = tokens
+	bucket.updated = now
+	bucket.full = now.Add(result.ResetAfter)
+	return result, nil
+}
+
+func (s *MemoryStore) shard(key string) *memoryShard {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return &s.shards[hash.Sum32()%memoryShards]
+}
@@ -0,0 +1,85 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// PostgresStore keeps buckets in the rate_limit_buckets table so every
+// replica sees the same limits. Each Take locks the key's row for the
+// duration of a short transaction.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore creates a PostgresStore on db.
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	now = now.UTC()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// A new key starts with a full bucket; inserting first gives concurrent
+	// requests a row to lock.
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO rate_limit_buckets (key, tokens, updated_at, full_at)
+		VALUES ($1, $2, $3, $3)
+		ON CONFLICT (key) DO NOTHING
+	`, key, float64(limit.Burst), now); err != nil {
+		return Result{}, fmt.Errorf("failed to create rate limit bucket: %w", err)
+	}
+
+	var tokens float64
+	var updated time.Time
+	if err := tx.QueryRowContext(ctx, `
+		SELECT tokens, updated_at FROM rate_limit_buckets WHERE key = $1 FOR UPDATE
+	`, key).Scan(&tokens, &updated); err != nil {
+		return Result{}, fmt.Errorf("failed to load rate limit bucket: %w", err)
+	}
+
+	tokens, result := limit.spend(tokens, updated, now)
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE rate_limit_buckets SET tokens = $2, updated_at = $3, full_at = $4 WHERE key = $1
+	`, key, tokens, now, now.Add(result.ResetAfter)); err != nil {
+		return Result{}, fmt.Errorf("failed to update rate limit bucket: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return Result{}, fmt.Errorf("failed to commit rate limit bucket: %w", err)
+	}
+	return result, nil
+}
+
+// DeleteFullBuckets removes buckets that have refilled completely by now,
+// which behave the same as missing ones.
+func (s *PostgresStore) DeleteFullBuckets(ctx context.Context, now time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM rate_limit_buckets WHERE full_at <= $1`, now.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete full rate limit buckets: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// Start deletes full buckets every interval until ctx is cancelled.
+func (s *PostgresStore) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := s.DeleteFullBuckets(ctx, now); err != nil {
+				log.Printf("PostgresStore.Start - %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Package ratelimit implements token-bucket rate limiting over a pluggable
+// store. The in-memory store limits a single process; the Postgres store
+// shares buckets between replicas.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit allows Burst requests at once, refilled evenly over Per.
+type Limit struct {
+	Burst int
+	Per   time.Duration
+}
+
+// Result describes a bucket after a request.
+type Result struct {
+	Allowed bool
+	// Limit is the bucket's capacity.
+	Limit int
+	// Remaining is the number of whole tokens left.
+	Remaining int
+	// RetryAfter is how long until the next token, when not Allowed.
+	RetryAfter time.Duration
+	// ResetAfter is how long until the bucket is full again.
+	ResetAfter time.Duration
+}
+
+// Store keeps token buckets by key.
+type Store interface {
+	// Take spends a token from the bucket under key if one is left, creating
+	// a full bucket for new keys.
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// rate is the refill speed in tokens per second.
+func (l Limit) rate() float64 {
+	return float64(l.Burst) / l.Per.Seconds()
+}
+
+// spend refills a bucket last updated at updated for the time passed and
+// spends a token if one is left. A zero updated time is a new, full bucket.
+// It returns the tokens left and the result.
+func (l Limit) spend(tokens float64, updated, now time.Time) (float64, Result) {
+	burst := float64(l.Burst)
+	if updated.IsZero() {
+		tokens = burst
+	} else if elapsed := now.Sub(updated); elapsed > 0 {
+		tokens = math.Min(burst, tokens+elapsed.Seconds()*l.rate())
+	}
+
+	result := Result{Limit: l.Burst}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = l.refillTime(1 - tokens)
+	}
+	result.Remaining = int(tokens)
+	result.ResetAfter = l.refillTime(burst - tokens)
+	return tokens, result
+}
+
+// refillTime is how long refilling the given number of tokens takes.
+func (l Limit) refillTime(tokens float64) time.Duration {
+	return time.Duration(math.Ceil(tokens / l.rate() * float64(time.Second)))
+}
@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreSpendsAndRefills(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Burst: 3, Per: 3 * time.Second}
+	now := time.Now()
+	take := func(key string, at time.Time) Result {
+		t.Helper()
+		result, err := store.Take(context.Background(), key, limit, at)
+		if err != nil {
+			t.Fatalf("Take failed: %v", err)
+		}
+		return result
+	}
+
+	for want := 2; want >= 0; want-- {
+		if result := take("a", now); !result.Allowed || result.Remaining != want {
+			t.Fatalf("expected an allowed request with %d left, got %+v", want, result)
+		}
+	}
+	refused := take("a", now)
+	if refused.Allowed || refused.RetryAfter != time.Second || refused.ResetAfter != 3*time.Second {
+		t.Fatalf("expected a refusal with a one second retry, got %+v", refused)
+	}
+	if result := take("b", now); !result.Allowed {
+		t.Fatalf("expected other keys to have their own bucket, got %+v", result)
+	}
+
+	if result := take("a", now.Add(time.Second)); !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("expected one token to refill after a second, got %+v", result)
+	}
+	if result := take("a", now.Add(time.Hour)); !result.Allowed || result.Remaining != 2 {
+		t.Fatalf("expected the bucket to refill only up to its burst, got %+v", result)
+	}
+}
+
+func TestMemoryStoreIsSafeForConcurrentUse(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Burst: 50, Per: time.Hour}
+	now := time.Now()
+
+	var mu sync.Mutex
+	allowed := 0
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, _ := store.Take(context.Background(), "shared", limit, now)
+			if result.Allowed {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != 50 {
+		t.Fatalf("expected exactly the burst to be allowed, got %d", allowed)
+	}
+}
+
+func TestMemoryStoreForgetsFullBuckets(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Burst: 1, Per: time.Second}
+	now := time.Now()
+
+	// Find a second key in the same shard to trigger the sweep with.
+	shard := store.shard("idle")
+	other := ""
+	for i := 0; other == ""; i++ {
+		if key := fmt.Sprintf("key-%d", i); store.shard(key) == shard {
+			other = key
+		}
+	}
+
+	store.Take(context.Background(), "idle", limit, now)
+	store.Take(context.Background(), other, limit, now.Add(2*memorySweepInterval))
+	if _, ok := shard.buckets["idle"]; ok || len(shard.buckets) != 1 {
+		t.Fatalf("expected the refilled bucket to be swept, got %d buckets", len(shard.buckets))
+	}
+}
@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type Attachment struct {
+	ID           uuid.UUID  `json:"id"`
+	RoomID       *uuid.UUID `json:"room_id,omitempty"`
+	MessageID    *uuid.UUID `json:"message_id,omitempty"`
+	UploaderID   *uuid.UUID `json:"uploader_id,omitempty"`
+	Filename     string     `json:"filename"`
+	ContentType  string     `json:"content_type"`
+	SizeBytes    int64      `json:"size_bytes"`
+	StorageKey   string     `json:"-"`
+	ThumbnailKey *string    `json:"-"`
+	Width        *int       `json:"width,omitempty"`
+	Height       *int       `json:"height,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type AttachmentRepository struct {
+	db *sql.DB
+}
+
+func NewAttachmentRepository(db *sql.DB) *AttachmentRepository {
+	return &AttachmentRepository{db: db}
+}
+
+const attachmentColumns = `
+	id, room_id, message_id, uploader_id, filename, content_type, size_bytes,
+	storage_key, thumbnail_key, width, height, created_at
+`
+
+func (r *AttachmentRepository) CreateAttachment(ctx context.Context, attachment *Attachment) (*Attachment, error) {
+	query := `
+		INSERT INTO attachments (id, room_id, uploader_id, filename, content_type, size_bytes, storage_key, thumbnail_key, width, height)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING created_at
+	`
+
+	if attachment.ID == uuid.Nil {
+		attachment.ID = uuid.New()
+	}
+
+	err := r.db.QueryRowContext(ctx, query,
+		attachment.ID,
+		attachment.RoomID,
+		attachment.UploaderID,
+		attachment.Filename,
+		attachment.ContentType,
+		attachment.SizeBytes,
+		attachment.StorageKey,
+		attachment.ThumbnailKey,
+		attachment.Width,
+		attachment.Height,
+	).Scan(&attachment.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create attachment: %w", err)
+	}
+
+	return attachment, nil
+}
+
+func (r *AttachmentRepository) GetAttachmentByID(ctx context.Context, id uuid.UUID) (*Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE id = $1`
+
+	attachment, err := scanAttachment(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get attachment by ID: %w", err)
+	}
+
+	return attachment, nil
+}
+
+func (r *AttachmentRepository) IsRoomEmoji(ctx context.Context, id uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM room_emojis WHERE attachment_id = $1)`, id).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check room emoji: %w", err)
+	}
+	return exists, nil
+}
+
+func (r *AttachmentRepository) GetPendingAttachments(ctx context.Context, roomID, uploaderID uuid.UUID, ids []uuid.UUID) ([]Attachment, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	query := `
+		SELECT ` + attachmentColumns + `
+		FROM attachments
+		WHERE id = ANY($1)
+			AND room_id = $2
+			AND uploader_id = $3
+			AND message_id IS NULL
+		ORDER BY created_at ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(uuidStrings(ids)), roomID, uploaderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending attachments: %w", err)
+	}
+	defer rows.Close()
+
+	return scanAttachments(rows)
+}
+
+func (r *AttachmentRepository) AssignToMessage(ctx context.Context, messageID uuid.UUID, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query := `
+		UPDATE attachments a
+		SET message_id = m.id
+		FROM messages m
+		WHERE m.id = $1
+			AND a.id = ANY($2)
+			AND a.room_id = m.room_id
+			AND a.uploader_id = m.user_id
+			AND a.message_id IS NULL
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, messageID, pq.Array(uuidStrings(ids))); err != nil {
+		return fmt.Errorf("failed to assign attachments to message: %w", err)
+	}
+
+	return nil
+}
+
+func (r *AttachmentRepository) GetOrphanedAttachments(ctx context.Context, unsentBefore time.Time, limit int) ([]Attachment, error) {
+	query := `
+		SELECT ` + attachmentColumns + `
+		FROM attachments
+		WHERE (room_id IS NULL OR (message_id IS NULL AND created_at < $1))
+			AND NOT EXISTS (SELECT 1 FROM room_emojis e WHERE e.attachment_id = attachments.id)
+		ORDER BY created_at ASC
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, unsentBefore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get orphaned attachments: %w", err)
+	}
+	defer rows.Close()
+
+	return scanAttachments(rows)
+}
+
+func (r *AttachmentRepository) DeleteAttachments(ctx context.Context, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM attachments WHERE id = ANY($1)`, pq.Array(uuidStrings(ids))); err != nil {
+		return fmt.Errorf("failed to delete attachments: %w", err)
+	}
+
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAttachment(row rowScanner) (*Attachment, error) {
+	var attachment Attachment
+	err := row.Scan(
+		&attachment.ID,
+		&attachment.RoomID,
+		&attachment.MessageID,
+		&attachment.UploaderID,
+		&attachment.Filename,
+		&attachment.ContentType,
+		&attachment.SizeBytes,
+		&attachment.StorageKey,
+		&attachment.ThumbnailKey,
+		&attachment.Width,
+		&attachment.Height,
+		&attachment.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+func scanAttachments(rows *sql.Rows) ([]Attachment, error) {
+	var attachments []Attachment
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		attachments = append(attachments, *attachment)
+	}
+	return attachments, rows.Err()
+}
+
+func uuidStrings(ids []uuid.UUID) []string {
+	values := make([]string, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, id.String())
+	}
+	return values
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AttachmentRepositoryInterface interface {
+	// CreateAttachment records an uploaded file.
+	CreateAttachment(ctx context.Context, attachment *Attachment) (*Attachment, error)
+
+	// GetAttachmentByID retrieves an attachment by its unique identifier.
+	// Returns nil, nil if the attachment is not found.
+	GetAttachmentByID(ctx context.Context, id uuid.UUID) (*Attachment, error)
+
+	// IsRoomEmoji reports whether the attachment is the image of a room emoji.
+	IsRoomEmoji(ctx context.Context, id uuid.UUID) (bool, error)
+
+	// GetPendingAttachments retrieves attachments uploaded by a user to a room
+	// that have not yet been referenced by a message.
+	GetPendingAttachments(ctx context.Context, roomID, uploaderID uuid.UUID, ids []uuid.UUID) ([]Attachment, error)
+
+	// AssignToMessage links pending attachments to the message that references them.
+	AssignToMessage(ctx context.Context, messageID uuid.UUID, ids []uuid.UUID) error
+
+	// GetOrphanedAttachments retrieves attachments whose room has been deleted,
+	// or that were never sent in a message and were uploaded before the cutoff.
+	// Images in use as room emoji are never considered orphaned.
+	GetOrphanedAttachments(ctx context.Context, unsentBefore time.Time, limit int) ([]Attachment, error)
+
+	// DeleteAttachments removes attachment rows by ID.
+	DeleteAttachments(ctx context.Context, ids []uuid.UUID) error
+}
+
+// Ensure AttachmentRepository implements AttachmentRepositoryInterface
+var _ AttachmentRepositoryInterface = (*AttachmentRepository)(nil)
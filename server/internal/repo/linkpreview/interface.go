@@ -0,0 +1,15 @@
+package repository
+
+import "context"
+
+type LinkPreviewRepositoryInterface interface {
+	// GetLinkPreview retrieves the cached preview for a URL.
+	// Returns nil, nil if the URL has not been fetched before.
+	GetLinkPreview(ctx context.Context, url string) (*LinkPreview, error)
+
+	// UpsertLinkPreview stores the result of fetching a URL, including failed fetches.
+	UpsertLinkPreview(ctx context.Context, preview *LinkPreview) error
+}
+
+// Ensure LinkPreviewRepository implements LinkPreviewRepositoryInterface
+var _ LinkPreviewRepositoryInterface = (*LinkPreviewRepository)(nil)
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// LinkPreview is the cached Open Graph / Twitter card metadata for a URL.
+// OK is false when the fetch failed or the page had nothing worth previewing,
+// so repeated posts of a dead link do not trigger repeated fetches.
+type LinkPreview struct {
+	URL         string
+	OK          bool
+	Title       string
+	Description string
+	ImageURL    string
+	SiteName    string
+	FetchedAt   time.Time
+}
+
+type LinkPreviewRepository struct {
+	db *sql.DB
+}
+
+func NewLinkPreviewRepository(db *sql.DB) *LinkPreviewRepository {
+	return &LinkPreviewRepository{db: db}
+}
+
+func (r *LinkPreviewRepository) GetLinkPreview(ctx context.Context, url string) (*LinkPreview, error) {
+	query := `
+		SELECT url, ok, COALESCE(title, ''), COALESCE(description, ''), COALESCE(image_url, ''),
+			COALESCE(site_name, ''), fetched_at
+		FROM link_previews
+		WHERE url = $1
+	`
+
+	var preview LinkPreview
+	err := r.db.QueryRowContext(ctx, query, url).Scan(
+		&preview.URL,
+		&preview.OK,
+		&preview.Title,
+		&preview.Description,
+		&preview.ImageURL,
+		&preview.SiteName,
+		&preview.FetchedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get link preview: %w", err)
+	}
+
+	return &preview, nil
+}
+
+func (r *LinkPreviewRepository) UpsertLinkPreview(ctx context.Context, preview *LinkPreview) error {
+	query := `
+		INSERT INTO link_previews (url, ok, title, description, image_url, site_name, fetched_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		ON CONFLICT (url) DO UPDATE
+		SET ok = EXCLUDED.ok,
+			title = EXCLUDED.title,
+			description = EXCLUDED.description,
+			image_url = EXCLUDED.image_url,
+			site_name = EXCLUDED.site_name,
+			fetched_at = EXCLUDED.fetched_at
+		RETURNING fetched_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		preview.URL,
+		preview.OK,
+		preview.Title,
+		preview.Description,
+		preview.ImageURL,
+		preview.SiteName,
+	).Scan(&preview.FetchedAt)
+	if err != nil {
+		return fmt.Errorf("failed to upsert link preview: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type PushRepositoryInterface interface {
+	// SaveSubscription stores a subscription, moving an existing endpoint to
+	// the given user and keys. Beyond maxPerUser the user's oldest
+	// subscriptions are removed.
+	SaveSubscription(ctx context.Context, subscription *PushSubscription, maxPerUser int) error
+
+	// GetUserSubscriptions returns the user's subscriptions, newest first.
+	GetUserSubscriptions(ctx context.Context, userID uuid.UUID) ([]PushSubscription, error)
+
+	// DeleteSubscription removes one of the user's subscriptions by endpoint.
+	// Returns false if the user had no such subscription.
+	DeleteSubscription(ctx context.Context, userID uuid.UUID, endpoint string) (bool, error)
+
+	// DeleteSubscriptionByID removes a subscription the push service reported as gone.
+	DeleteSubscriptionByID(ctx context.Context, id uuid.UUID) error
+
+	// MarkSubscriptionUsed records a successful delivery.
+	MarkSubscriptionUsed(ctx context.Context, id uuid.UUID) error
+}
+
+// Ensure PushRepository implements PushRepositoryInterface
+var _ PushRepositoryInterface = (*PushRepository)(nil)
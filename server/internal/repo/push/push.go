@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PushSubscription is a browser push endpoint registered by a user.
+type PushSubscription struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Endpoint   string
+	P256dh     string
+	Auth       string
+	UserAgent  string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+}
+
+type PushRepository struct {
+	db *sql.DB
+}
+
+func NewPushRepository(db *sql.DB) *PushRepository {
+	return &PushRepository{db: db}
+}
+
+func (r *PushRepository) SaveSubscription(ctx context.Context, subscription *PushSubscription, maxPerUser int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO push_subscriptions (user_id, endpoint, p256dh, auth, user_agent)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (endpoint) DO UPDATE
+		SET user_id = EXCLUDED.user_id,
+			p256dh = EXCLUDED.p256dh,
+			auth = EXCLUDED.auth,
+			user_agent = EXCLUDED.user_agent
+		RETURNING id, created_at
+	`,
+		subscription.UserID,
+		subscription.Endpoint,
+		subscription.P256dh,
+		subscription.Auth,
+		subscription.UserAgent,
+	).Scan(&subscription.ID, &subscription.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save push subscription: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM push_subscriptions
+		WHERE user_id = $1 AND id IN (
+			SELECT id FROM push_subscriptions
+			WHERE user_id = $1
+			ORDER BY created_at DESC, id DESC
+			OFFSET $2
+		)
+	`, subscription.UserID, maxPerUser)
+	if err != nil {
+		return fmt.Errorf("failed to prune push subscriptions: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func (r *PushRepository) GetUserSubscriptions(ctx context.Context, userID uuid.UUID) ([]PushSubscription, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, user_id, endpoint, p256dh, auth, user_agent, created_at, last_used_at
+		FROM push_subscriptions
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get push subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var subscriptions []PushSubscription
+	for rows.Next() {
+		var subscription PushSubscription
+		if err := rows.Scan(
+			&subscription.ID,
+			&subscription.UserID,
+			&subscription.Endpoint,
+			&subscription.P256dh,
+			&subscription.Auth,
+			&subscription.UserAgent,
+			&subscription.CreatedAt,
+			&subscription.LastUsedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan push subscription: %w", err)
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	return subscriptions, rows.Err()
+}
+
+func (r *PushRepository) DeleteSubscription(ctx context.Context, userID uuid.UUID, endpoint string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM push_subscriptions WHERE user_id = $1 AND endpoint = $2
+	`, userID, endpoint)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete push subscription: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete push subscription: %w", err)
+	}
+	return deleted > 0, nil
+}
+
+func (r *PushRepository) DeleteSubscriptionByID(ctx context.Context, id uuid.UUID) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM push_subscriptions WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete push subscription: %w", err)
+	}
+	return nil
+}
+
+func (r *PushRepository) MarkSubscriptionUsed(ctx context.Context, id uuid.UUID) error {
+	if _, err := r.db.ExecContext(ctx, `UPDATE push_subscriptions SET last_used_at = NOW() WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to update push subscription: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AutoModSettings holds a room's AutoMod rules as encoded JSON.
+type AutoModSettings struct {
+	RoomID    uuid.UUID
+	Rules     []byte
+	UpdatedBy *uuid.UUID
+	UpdatedAt time.Time
+}
+
+func (r *RoomRepository) GetAutoModSettings(ctx context.Context, roomID uuid.UUID) (*AutoModSettings, error) {
+	var settings AutoModSettings
+	err := r.db.QueryRowContext(ctx, `
+		SELECT room_id, rules, updated_by, updated_at
+		FROM room_automod
+		WHERE room_id = $1
+	`, roomID).Scan(&settings.RoomID, &settings.Rules, &settings.UpdatedBy, &settings.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get automod settings: %w", err)
+	}
+	return &settings, nil
+}
+
+func (r *RoomRepository) SaveAutoModSettings(ctx context.Context, settings *AutoModSettings) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO room_automod (room_id, rules, updated_by, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (room_id) DO UPDATE
+		SET rules = EXCLUDED.rules, updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at
+		RETURNING updated_at
+	`, settings.RoomID, settings.Rules, settings.UpdatedBy).Scan(&settings.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save automod settings: %w", err)
+	}
+	return nil
+}
@@ -3,17 +3,17 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"regexp"
-	"strings"
 	"time"
 
+	"chat-application/internal/markdown"
+	"chat-application/internal/pagination"
+
 	"github.com/google/uuid"
 	"github.com/lib/pq"
 )
 
-var mentionRegex = regexp.MustCompile(`@([a-zA-Z0-9_]+)`)
-
 type RoomMember struct {
 	RoomID            uuid.UUID
 	UserID            uuid.UUID
@@ -23,9 +23,14 @@ type RoomMember struct {
 	CanManageChannels bool
 	CanModerate       bool
 	CanPost           bool
-	MutedUntil        *time.Time
-	BannedAt          *time.Time
-	CreatedAt         time.Time
+	// CanMentionEveryone allows @here, @channel/@room and role mentions.
+	CanMentionEveryone bool
+	MutedUntil         *time.Time
+	BannedAt           *time.Time
+	// InviteID and InvitedBy record the invite a member joined through.
+	InviteID  *uuid.UUID
+	InvitedBy *uuid.UUID
+	CreatedAt time.Time
 }
 
 type RoomCategory struct {
@@ -45,7 +50,33 @@ type RoomChannel struct {
 	Kind        string
 	Position    int
 	IsPrivate   bool
-	CreatedAt   time.Time
+	// SlowModeSeconds is how long members wait between messages in the
+	// channel. Zero turns slow mode off.
+	SlowModeSeconds int
+	CreatedAt       time.Time
+}
+
+const channelColumns = `id, room_id, category_id, name, description, kind, position, is_private,
+	slow_mode_seconds, created_at`
+
+func scanChannel(row rowScanner) (*RoomChannel, error) {
+	var channel RoomChannel
+	err := row.Scan(
+		&channel.ID,
+		&channel.RoomID,
+		&channel.CategoryID,
+		&channel.Name,
+		&channel.Description,
+		&channel.Kind,
+		&channel.Position,
+		&channel.IsPrivate,
+		&channel.SlowModeSeconds,
+		&channel.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &channel, nil
 }
 
 type Notification struct {
@@ -61,6 +92,36 @@ type Notification struct {
 	CreatedAt time.Time
 }
 
+// memberColumns is the column list read by scanMember; queries must alias
+// room_members as rm and users as u.
+const memberColumns = `rm.room_id, rm.user_id, u.username, rm.role, rm.can_manage_room, rm.can_manage_channels,
+	rm.can_moderate, rm.can_post, rm.can_mention_everyone, rm.muted_until, rm.banned_at,
+	rm.invite_id, rm.invited_by, rm.created_at`
+
+func scanMember(row rowScanner) (*RoomMember, error) {
+	var member RoomMember
+	err := row.Scan(
+		&member.RoomID,
+		&member.UserID,
+		&member.Username,
+		&member.Role,
+		&member.CanManageRoom,
+		&member.CanManageChannels,
+		&member.CanModerate,
+		&member.CanPost,
+		&member.CanMentionEveryone,
+		&member.MutedUntil,
+		&member.BannedAt,
+		&member.InviteID,
+		&member.InvitedBy,
+		&member.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
 func (r *RoomRepository) EnsureRoomMembership(ctx context.Context, roomID, userID uuid.UUID) error {
 	query := `
 		INSERT INTO room_members (room_id, user_id, role, can_post)
@@ -73,40 +134,25 @@ func (r *RoomRepository) EnsureRoomMembership(ctx context.Context, roomID, userI
 
 func (r *RoomRepository) GetRoomMember(ctx context.Context, roomID, userID uuid.UUID) (*RoomMember, error) {
 	query := `
-		SELECT rm.room_id, rm.user_id, u.username, rm.role, rm.can_manage_room, rm.can_manage_channels,
-			rm.can_moderate, rm.can_post, rm.muted_until, rm.banned_at, rm.created_at
+		SELECT ` + memberColumns + `
 		FROM room_members rm
 		JOIN users u ON u.id = rm.user_id
 		WHERE rm.room_id = $1 AND rm.user_id = $2
 	`
 
-	var member RoomMember
-	err := r.db.QueryRowContext(ctx, query, roomID, userID).Scan(
-		&member.RoomID,
-		&member.UserID,
-		&member.Username,
-		&member.Role,
-		&member.CanManageRoom,
-		&member.CanManageChannels,
-		&member.CanModerate,
-		&member.CanPost,
-		&member.MutedUntil,
-		&member.BannedAt,
-		&member.CreatedAt,
-	)
+	member, err := scanMember(r.db.QueryRowContext(ctx, query, roomID, userID))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, err
 	}
-	return &member, nil
+	return member, nil
 }
 
 func (r *RoomRepository) GetRoomMembers(ctx context.Context, roomID uuid.UUID) ([]RoomMember, error) {
 	query := `
-		SELECT rm.room_id, rm.user_id, u.username, rm.role, rm.can_manage_room, rm.can_manage_channels,
-			rm.can_moderate, rm.can_post, rm.muted_until, rm.banned_at, rm.created_at
+		SELECT ` + memberColumns + `
 		FROM room_members rm
 		JOIN users u ON u.id = rm.user_id
 		WHERE rm.room_id = $1 AND rm.banned_at IS NULL
@@ -120,23 +166,11 @@ func (r *RoomRepository) GetRoomMembers(ctx context.Context, roomID uuid.UUID) (
 
 	var members []RoomMember
 	for rows.Next() {
-		var member RoomMember
-		if err := rows.Scan(
-			&member.RoomID,
-			&member.UserID,
-			&member.Username,
-			&member.Role,
-			&member.CanManageRoom,
-			&member.CanManageChannels,
-			&member.CanModerate,
-			&member.CanPost,
-			&member.MutedUntil,
-			&member.BannedAt,
-			&member.CreatedAt,
-		); err != nil {
+		member, err := scanMember(rows)
+		if err != nil {
 			return nil, err
 		}
-		members = append(members, member)
+		members = append(members, *member)
 	}
 	return members, rows.Err()
 }
@@ -150,6 +184,8 @@ func (r *RoomRepository) UpdateRoomMember(ctx context.Context, member RoomMember
 			can_moderate = $6,
 			can_post = $7,
 			banned_at = $8,
+			can_mention_everyone = $9,
+			muted_until = $10,
 			updated_at = NOW()
 		WHERE room_id = $1 AND user_id = $2
 	`
@@ -162,6 +198,8 @@ func (r *RoomRepository) UpdateRoomMember(ctx context.Context, member RoomMember
 		member.CanModerate,
 		member.CanPost,
 		member.BannedAt,
+		member.CanMentionEveryone,
+		member.MutedUntil,
 	)
 	return err
 }
@@ -228,7 +266,7 @@ func (r *RoomRepository) GetRoomCategories(ctx context.Context, roomID uuid.UUID
 
 func (r *RoomRepository) GetRoomChannels(ctx context.Context, roomID uuid.UUID) ([]RoomChannel, error) {
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT id, room_id, category_id, name, description, kind, position, is_private, created_at
+		SELECT `+channelColumns+`
 		FROM room_channels
 		WHERE room_id = $1
 		ORDER BY position ASC, created_at ASC
@@ -240,98 +278,61 @@ func (r *RoomRepository) GetRoomChannels(ctx context.Context, roomID uuid.UUID)
 
 	var channels []RoomChannel
 	for rows.Next() {
-		var channel RoomChannel
-		if err := rows.Scan(
-			&channel.ID,
-			&channel.RoomID,
-			&channel.CategoryID,
-			&channel.Name,
-			&channel.Description,
-			&channel.Kind,
-			&channel.Position,
-			&channel.IsPrivate,
-			&channel.CreatedAt,
-		); err != nil {
+		channel, err := scanChannel(rows)
+		if err != nil {
 			return nil, err
 		}
-		channels = append(channels, channel)
+		channels = append(channels, *channel)
 	}
 	return channels, rows.Err()
 }
 
 func (r *RoomRepository) GetDefaultChannel(ctx context.Context, roomID uuid.UUID) (*RoomChannel, error) {
-	row := r.db.QueryRowContext(ctx, `
-		SELECT id, room_id, category_id, name, description, kind, position, is_private, created_at
+	channel, err := scanChannel(r.db.QueryRowContext(ctx, `
+		SELECT `+channelColumns+`
 		FROM room_channels
 		WHERE room_id = $1
 		ORDER BY position ASC, created_at ASC
 		LIMIT 1
-	`, roomID)
-
-	var channel RoomChannel
-	err := row.Scan(
-		&channel.ID,
-		&channel.RoomID,
-		&channel.CategoryID,
-		&channel.Name,
-		&channel.Description,
-		&channel.Kind,
-		&channel.Position,
-		&channel.IsPrivate,
-		&channel.CreatedAt,
-	)
+	`, roomID))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, err
 	}
-	return &channel, nil
+	return channel, nil
 }
 
-func (r *RoomRepository) GetRoomMessagesByChannel(ctx context.Context, roomID uuid.UUID, channelID *uuid.UUID, limit int, offset int) ([]*Message, error) {
-	query := `
-		SELECT id, room_id, user_id, username, content, is_system, created_at, channel_id, parent_message_id, metadata
-		FROM messages
-		WHERE room_id = $1
-	`
-	args := []any{roomID}
-	if channelID != nil {
-		query += ` AND channel_id = $2`
-		args = append(args, *channelID)
+func (r *RoomRepository) GetRoomChannel(ctx context.Context, roomID, channelID uuid.UUID) (*RoomChannel, error) {
+	channel, err := scanChannel(r.db.QueryRowContext(ctx, `
+		SELECT `+channelColumns+`
+		FROM room_channels
+		WHERE room_id = $1 AND id = $2
+	`, roomID, channelID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
 	}
-	query += ` ORDER BY created_at DESC LIMIT $` + fmt.Sprintf("%d", len(args)+1) + ` OFFSET $` + fmt.Sprintf("%d", len(args)+2)
-	args = append(args, limit, offset)
-
-	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to get channel: %w", err)
 	}
-	defer rows.Close()
+	return channel, nil
+}
 
-	var messages []*Message
-	for rows.Next() {
-		var msg Message
-		if err := rows.Scan(
-			&msg.ID,
-			&msg.RoomID,
-			&msg.UserID,
-			&msg.Username,
-			&msg.Content,
-			&msg.IsSystem,
-			&msg.CreatedAt,
-			&msg.ChannelID,
-			&msg.ParentMessageID,
-			&msg.Metadata,
-		); err != nil {
-			return nil, err
-		}
-		messages = append(messages, &msg)
+func (r *RoomRepository) SetChannelSlowMode(ctx context.Context, roomID, channelID uuid.UUID, seconds int) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE room_channels
+		SET slow_mode_seconds = $3
+		WHERE room_id = $1 AND id = $2
+	`, roomID, channelID, seconds)
+	if err != nil {
+		return false, fmt.Errorf("failed to set slow mode: %w", err)
 	}
-	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
-		messages[i], messages[j] = messages[j], messages[i]
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to check rows affected: %w", err)
 	}
-	return messages, rows.Err()
+	return rows > 0, nil
 }
 
 func (r *RoomRepository) SearchMessages(ctx context.Context, roomID uuid.UUID, queryText string, channelID *uuid.UUID, username string, limit int) ([]Message, error) {
@@ -402,14 +403,24 @@ func (r *RoomRepository) CreateNotification(ctx context.Context, notification *N
 	return err
 }
 
-func (r *RoomRepository) GetNotifications(ctx context.Context, userID uuid.UUID, limit int) ([]Notification, error) {
-	rows, err := r.db.QueryContext(ctx, `
+func (r *RoomRepository) GetNotifications(ctx context.Context, userID uuid.UUID, before *pagination.Cursor, unreadOnly bool, limit int) ([]Notification, error) {
+	query := `
 		SELECT id, user_id, room_id, message_id, kind, title, body, payload, is_read, created_at
 		FROM notifications
 		WHERE user_id = $1
-		ORDER BY created_at DESC
-		LIMIT $2
-	`, userID, limit)
+	`
+	args := []any{userID}
+	if unreadOnly {
+		query += ` AND is_read = FALSE`
+	}
+	if before != nil {
+		args = append(args, before.CreatedAt, before.ID)
+		query += fmt.Sprintf(` AND (created_at, id) < ($%d, $%d)`, len(args)-1, len(args))
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(` ORDER BY created_at DESC, id DESC LIMIT $%d`, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -446,72 +457,113 @@ func (r *RoomRepository) MarkNotificationRead(ctx context.Context, notificationI
 	return err
 }
 
-func (r *RoomRepository) CreateMentionNotifications(ctx context.Context, roomID uuid.UUID, message *Message) ([]Notification, error) {
+// MentionGroups lists the groups a message mentions beyond individual users.
+// Callers are responsible for checking that the author may mention them.
+type MentionGroups struct {
+	// Everyone is the label ("@channel" or "@room") when the whole room is mentioned.
+	Everyone   string
+	Moderators bool
+	// Roles are lowercased member roles mentioned by name, e.g. "admin".
+	Roles []string
+	// Online holds the users currently present in the room, for @here.
+	Online []uuid.UUID
+}
+
+// CreateMentionNotifications notifies the users a message mentions, directly
+// or through groups, in a single INSERT. Direct mentions use the "mention"
+// kind and win over group mentions, which use "group_mention". The author and
+// banned members are never notified.
+func (r *RoomRepository) CreateMentionNotifications(ctx context.Context, roomID uuid.UUID, message *Message, groups *MentionGroups) ([]Notification, error) {
 	if message.UserID == nil {
 		return nil, nil
 	}
 
-	matches := mentionRegex.FindAllStringSubmatch(message.Content, -1)
-	if len(matches) == 0 {
+	usernames := markdown.Mentions(message.Content)
+	if groups == nil {
+		groups = &MentionGroups{}
+	}
+	if len(usernames) == 0 && groups.Everyone == "" && !groups.Moderators && len(groups.Roles) == 0 && len(groups.Online) == 0 {
 		return nil, nil
 	}
 
-	seen := map[string]struct{}{}
-	usernames := make([]string, 0, len(matches))
-	for _, match := range matches {
-		if len(match) < 2 {
-			continue
-		}
-		name := strings.ToLower(match[1])
-		if _, ok := seen[name]; ok {
-			continue
-		}
-		seen[name] = struct{}{}
-		usernames = append(usernames, name)
-	}
-	if len(usernames) == 0 {
-		return nil, nil
+	online := make([]string, 0, len(groups.Online))
+	for _, userID := range groups.Online {
+		online = append(online, userID.String())
 	}
 
 	query := `
-		SELECT u.id, u.username
-		FROM users u
-		JOIN room_members rm ON rm.user_id = u.id
-		WHERE rm.room_id = $1
-			AND LOWER(u.username) = ANY($2)
-			AND rm.banned_at IS NULL
+		WITH recipients AS (
+			SELECT rm.user_id,
+				CASE
+					WHEN LOWER(u.username) = ANY($4) THEN NULL
+					WHEN rm.user_id::text = ANY($5) THEN '@here'
+					WHEN $6::text <> '' THEN $6::text
+					WHEN $7::boolean AND (rm.can_moderate OR rm.can_manage_room) THEN '@moderators'
+					ELSE '@' || LOWER(rm.role)
+				END AS via
+			FROM room_members rm
+			JOIN users u ON u.id = rm.user_id
+			WHERE rm.room_id = $1
+				AND rm.user_id <> $3
+				AND rm.banned_at IS NULL
+				AND (
+					LOWER(u.username) = ANY($4)
+					OR rm.user_id::text = ANY($5)
+					OR $6::text <> ''
+					OR ($7::boolean AND (rm.can_moderate OR rm.can_manage_room))
+					OR LOWER(rm.role) = ANY($8)
+				)
+		)
+		INSERT INTO notifications (user_id, room_id, message_id, kind, title, body, payload, is_read)
+		SELECT rc.user_id, $1::uuid, $2::uuid,
+			CASE WHEN rc.via IS NULL THEN 'mention' ELSE 'group_mention' END,
+			CASE WHEN rc.via IS NULL THEN 'You were mentioned' ELSE $9::text || ' mentioned ' || rc.via END,
+			CASE WHEN rc.via IS NULL THEN $10::text ELSE $11::text END,
+			jsonb_build_object('room_id', $1::uuid::text, 'message_id', $2::uuid::text, 'username', $9::text)
+				|| CASE WHEN rc.via IS NULL THEN '{}'::jsonb ELSE jsonb_build_object('group', rc.via) END,
+			FALSE
+		FROM recipients rc
+		WHERE ` + acceptsNotificationSQL("rc.user_id", "$1", "$12") + `
+			AND ` + notBlockedSQL("rc.user_id", "$3") + `
+		RETURNING id, user_id, room_id, message_id, kind, title, body, payload, is_read, created_at
 	`
-	rows, err := r.db.QueryContext(ctx, query, roomID, pq.Array(usernames))
+	rows, err := r.db.QueryContext(ctx, query,
+		roomID,
+		message.ID,
+		*message.UserID,
+		pq.Array(usernames),
+		pq.Array(online),
+		groups.Everyone,
+		groups.Moderators,
+		pq.Array(groups.Roles),
+		message.Username,
+		fmt.Sprintf("%s mentioned you in %s", message.Username, message.Content),
+		fmt.Sprintf("%s: %s", message.Username, message.Content),
+		pq.Array(acceptedLevels("mention")),
+	)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to create mention notifications: %w", err)
 	}
 	defer rows.Close()
 
 	var notifications []Notification
 	for rows.Next() {
-		var mentionedUserID uuid.UUID
-		var username string
-		if err := rows.Scan(&mentionedUserID, &username); err != nil {
-			return nil, err
-		}
-		if mentionedUserID == *message.UserID {
-			continue
-		}
-
-		notification := Notification{
-			UserID:    mentionedUserID,
-			RoomID:    &roomID,
-			MessageID: &message.ID,
-			Kind:      "mention",
-			Title:     "You were mentioned",
-			Body:      fmt.Sprintf("%s mentioned you in %s", message.Username, message.Content),
-			Payload:   []byte(fmt.Sprintf(`{"room_id":"%s","message_id":"%s","username":"%s"}`, roomID.String(), message.ID.String(), message.Username)),
-		}
-		if err := r.CreateNotification(ctx, &notification); err != nil {
+		var notification Notification
+		if err := rows.Scan(
+			&notification.ID,
+			&notification.UserID,
+			&notification.RoomID,
+			&notification.MessageID,
+			&notification.Kind,
+			&notification.Title,
+			&notification.Body,
+			&notification.Payload,
+			&notification.IsRead,
+			&notification.CreatedAt,
+		); err != nil {
 			return nil, err
 		}
 		notifications = append(notifications, notification)
 	}
-
-	return notifications, nil
+	return notifications, rows.Err()
 }
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DigestRecipient is a user with unread notifications that have not been emailed.
+type DigestRecipient struct {
+	UserID   uuid.UUID
+	Username string
+	Email    string
+	// Notifications holds the oldest pending notifications, up to the
+	// requested limit; Pending counts all of them.
+	Notifications []Notification
+	Pending       int
+}
+
+// GetPendingDigests returns users with unread notifications created at or
+// before cutoff that have not been emailed yet. Users who turned email digests
+// off or muted all notifications are skipped.
+func (r *RoomRepository) GetPendingDigests(ctx context.Context, cutoff time.Time, perUser int) ([]DigestRecipient, error) {
+	query := `
+		SELECT u.id, u.username, u.email, n.pending,
+			n.id, n.user_id, n.room_id, n.message_id, n.kind, n.title, n.body, n.payload, n.is_read, n.created_at
+		FROM (
+			SELECT n.*,
+				ROW_NUMBER() OVER (PARTITION BY n.user_id ORDER BY n.created_at, n.id) AS position,
+				COUNT(*) OVER (PARTITION BY n.user_id) AS pending
+			FROM notifications n
+			WHERE n.is_read = FALSE
+				AND n.emailed_at IS NULL
+				AND n.created_at <= $1
+		) n
+		JOIN users u ON u.id = n.user_id
+		WHERE n.position <= $2
+			AND u.email <> ''
+			AND NOT EXISTS (
+				SELECT 1 FROM notification_preferences np
+				WHERE np.user_id = n.user_id
+					AND np.room_id IS NULL
+					AND (np.email_digest = FALSE OR np.muted_until > NOW())
+			)
+		ORDER BY u.id, n.created_at, n.id
+	`
+	rows, err := r.db.QueryContext(ctx, query, cutoff, perUser)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending digests: %w", err)
+	}
+	defer rows.Close()
+
+	var recipients []DigestRecipient
+	for rows.Next() {
+		var recipient DigestRecipient
+		var notification Notification
+		if err := rows.Scan(
+			&recipient.UserID,
+			&recipient.Username,
+			&recipient.Email,
+			&recipient.Pending,
+			&notification.ID,
+			&notification.UserID,
+			&notification.RoomID,
+			&notification.MessageID,
+			&notification.Kind,
+			&notification.Title,
+			&notification.Body,
+			&notification.Payload,
+			&notification.IsRead,
+			&notification.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan pending digest: %w", err)
+		}
+
+		if n := len(recipients); n > 0 && recipients[n-1].UserID == recipient.UserID {
+			recipients[n-1].Notifications = append(recipients[n-1].Notifications, notification)
+			continue
+		}
+		recipient.Notifications = []Notification{notification}
+		recipients = append(recipients, recipient)
+	}
+
+	return recipients, rows.Err()
+}
+
+// MarkNotificationsEmailed records that the user's unread notifications created
+// at or before cutoff were covered by a digest.
+func (r *RoomRepository) MarkNotificationsEmailed(ctx context.Context, userID uuid.UUID, cutoff time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE notifications
+		SET emailed_at = NOW()
+		WHERE user_id = $1 AND emailed_at IS NULL AND created_at <= $2
+	`, userID, cutoff)
+	if err != nil {
+		return fmt.Errorf("failed to mark notifications emailed: %w", err)
+	}
+	return nil
+}
+
+// SetEmailDigest turns email digests on or off, keeping the rest of the
+// user's default preference.
+func (r *RoomRepository) SetEmailDigest(ctx context.Context, userID uuid.UUID, enabled bool) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO notification_preferences (user_id, room_id, email_digest)
+		VALUES ($1, NULL, $2)
+		ON CONFLICT (user_id) WHERE room_id IS NULL DO UPDATE
+		SET email_digest = EXCLUDED.email_digest,
+			updated_at = NOW()
+	`, userID, enabled)
+	if err != nil {
+		return fmt.Errorf("failed to update email digest preference: %w", err)
+	}
+	return nil
+}
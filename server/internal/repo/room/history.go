@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"chat-application/internal/pagination"
+
+	"github.com/google/uuid"
+)
+
+func (r *RoomRepository) GetMessagesBefore(ctx context.Context, roomID uuid.UUID, channelID *uuid.UUID, before *pagination.Cursor, limit int) ([]*Message, error) {
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages m
+		WHERE m.room_id = $1
+	`
+	args := []any{roomID}
+	if channelID != nil {
+		args = append(args, *channelID)
+		query += fmt.Sprintf(` AND m.channel_id = $%d`, len(args))
+	}
+	if before != nil {
+		args = append(args, before.CreatedAt, before.ID)
+		query += fmt.Sprintf(` AND (m.created_at, m.id) < ($%d, $%d)`, len(args)-1, len(args))
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(` ORDER BY m.created_at DESC, m.id DESC LIMIT $%d`, len(args))
+
+	messages, err := r.queryMessages(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messages before cursor: %w", err)
+	}
+
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 { // Reverse the messages to get chronological order
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+
+	return messages, nil
+}
+
+func (r *RoomRepository) GetMessagesAfter(ctx context.Context, roomID uuid.UUID, channelID *uuid.UUID, after pagination.Cursor, limit int) ([]*Message, error) {
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages m
+		WHERE m.room_id = $1
+	`
+	args := []any{roomID}
+	if channelID != nil {
+		args = append(args, *channelID)
+		query += fmt.Sprintf(` AND m.channel_id = $%d`, len(args))
+	}
+	args = append(args, after.CreatedAt, after.ID, limit)
+	query += fmt.Sprintf(` AND (m.created_at, m.id) > ($%d, $%d) ORDER BY m.created_at ASC, m.id ASC LIMIT $%d`,
+		len(args)-2, len(args)-1, len(args))
+
+	messages, err := r.queryMessages(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messages after cursor: %w", err)
+	}
+
+	return messages, nil
+}
+
+func (r *RoomRepository) queryMessages(ctx context.Context, query string, args ...any) ([]*Message, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []*Message
+	for rows.Next() {
+		msg, err := scanMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+
+	return messages, rows.Err()
+}
@@ -3,8 +3,10 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"chat-application/internal/api/model"
+	"chat-application/internal/pagination"
 
 	"github.com/google/uuid"
 )
@@ -26,11 +28,51 @@ type RoomRepositoryInterface interface {
 	// GetAllActiveRooms retrieves all non-expired rooms.
 	GetAllActiveRooms(ctx context.Context) ([]*Room, error)
 
+	// GetVisibleRooms retrieves the non-expired rooms listed to the viewer:
+	// public rooms, plus unlisted and invite-only rooms the viewer is an
+	// unbanned member of. A nil viewerID lists public rooms only.
+	GetVisibleRooms(ctx context.Context, viewerID *uuid.UUID) ([]*Room, error)
+
+	// SetRoomVisibility changes who can find and join a room.
+	// Returns false if the room is not found.
+	SetRoomVisibility(ctx context.Context, roomID uuid.UUID, visibility string) (bool, error)
+
+	// CreateRoomInvite stores a new invite code for a room.
+	CreateRoomInvite(ctx context.Context, invite *RoomInvite) (*RoomInvite, error)
+
+	// GetRoomInvites lists a room's invites, newest first, including revoked
+	// and used-up ones.
+	GetRoomInvites(ctx context.Context, roomID uuid.UUID) ([]RoomInvite, error)
+
+	// GetRoomInviteByCode retrieves an invite by its code.
+	// Returns nil, nil if the invite is not found.
+	GetRoomInviteByCode(ctx context.Context, code string) (*RoomInvite, error)
+
+	// RevokeRoomInvite stops an invite from admitting anyone else.
+	// Returns false if the invite is not found or already revoked.
+	RevokeRoomInvite(ctx context.Context, roomID, inviteID uuid.UUID) (bool, error)
+
+	// UseRoomInvite counts a redemption of the invite.
+	// Returns false if the invite is revoked, expired or used up.
+	UseRoomInvite(ctx context.Context, inviteID uuid.UUID) (bool, error)
+
+	// SetMemberInvite records the invite a member joined through, unless one
+	// is already recorded.
+	SetMemberInvite(ctx context.Context, roomID, userID uuid.UUID, invite *RoomInvite) error
+
 	// CreateMessage creates a new message in a room.
 	CreateMessage(ctx context.Context, message *Message) (*Message, error)
 
-	// GetRoomMessages retrieves messages for a room with pagination.
-	GetRoomMessages(ctx context.Context, roomID uuid.UUID, limit int, offset int) ([]*Message, error)
+	// MergeMessageMetadata merges the given JSON object into a message's metadata.
+	MergeMessageMetadata(ctx context.Context, messageID uuid.UUID, patch []byte) error
+
+	// GetMessagesBefore retrieves up to limit messages older than the cursor, or the
+	// latest messages when before is nil, in chronological order. A nil channelID
+	// covers the whole room.
+	GetMessagesBefore(ctx context.Context, roomID uuid.UUID, channelID *uuid.UUID, before *pagination.Cursor, limit int) ([]*Message, error)
+
+	// GetMessagesAfter retrieves up to limit messages newer than the cursor, in chronological order.
+	GetMessagesAfter(ctx context.Context, roomID uuid.UUID, channelID *uuid.UUID, after pagination.Cursor, limit int) ([]*Message, error)
 
 	// CountPinnedRooms returns the count of pinned rooms.
 	CountPinnedRooms(ctx context.Context) (int, error)
@@ -44,21 +86,128 @@ type RoomRepositoryInterface interface {
 	// GetReactions retrieves all reactions for a message.
 	GetReactions(ctx context.Context, messageID string) ([]model.MessageReaction, error)
 
+	// ToggleReaction adds the reaction, or removes it if the user already reacted with that emoji.
+	// Returns true if the reaction was added.
+	ToggleReaction(ctx context.Context, reaction *model.MessageReaction) (bool, error)
+
+	// RemoveReaction removes a user's reaction. Returns false if there was nothing to remove.
+	RemoveReaction(ctx context.Context, messageID, userID uuid.UUID, emoji string) (bool, error)
+
+	// GetReactionCounts aggregates reactions per emoji for each message, flagging the viewer's own.
+	GetReactionCounts(ctx context.Context, messageIDs []uuid.UUID, viewerID *uuid.UUID) (map[uuid.UUID][]model.ReactionCountRes, error)
+
+	// GetMessageByID retrieves a single message.
+	// Returns nil, nil if the message is not found.
+	GetMessageByID(ctx context.Context, id uuid.UUID) (*Message, error)
+
+	// GetThreadReplies retrieves replies to a thread root in chronological order, starting after the cursor.
+	GetThreadReplies(ctx context.Context, parentID uuid.UUID, after *pagination.Cursor, limit int) ([]*Message, error)
+
+	// RecordThreadReply bumps the reply count and last reply time of a thread root.
+	RecordThreadReply(ctx context.Context, parentID uuid.UUID, repliedAt time.Time) (*ThreadSummary, error)
+
+	FollowThread(ctx context.Context, messageID, userID uuid.UUID) error
+	UnfollowThread(ctx context.Context, messageID, userID uuid.UUID) error
+	IsFollowingThread(ctx context.Context, messageID, userID uuid.UUID) (bool, error)
+	CreateThreadReplyNotifications(ctx context.Context, parent *Message, reply *Message, exclude []uuid.UUID) ([]Notification, error)
+
+	// MarkChannelRead moves the user's read pointer in the message's channel up to
+	// the message and clears notifications for the messages read. Returns false if
+	// the pointer was already at or past it.
+	MarkChannelRead(ctx context.Context, userID uuid.UUID, message *Message) (bool, error)
+
+	// GetChannelReadStates returns the user's read state for every channel of the
+	// given rooms they are a member of.
+	GetChannelReadStates(ctx context.Context, userID uuid.UUID, roomIDs []uuid.UUID) ([]ChannelReadState, error)
+
+	// GetMessageReadReceipts lists the users, other than its author, who have read a message.
+	GetMessageReadReceipts(ctx context.Context, message *Message) ([]ReadReceipt, error)
+
+	CreateRoomEmoji(ctx context.Context, emoji *RoomEmoji) (*RoomEmoji, error)
+	GetRoomEmojis(ctx context.Context, roomID uuid.UUID) ([]RoomEmoji, error)
+	GetRoomEmoji(ctx context.Context, roomID uuid.UUID, name string) (*RoomEmoji, error)
+	DeleteRoomEmoji(ctx context.Context, roomID uuid.UUID, name string) (bool, error)
+
 	EnsureRoomMembership(ctx context.Context, roomID, userID uuid.UUID) error
 	GetRoomMember(ctx context.Context, roomID, userID uuid.UUID) (*RoomMember, error)
 	GetRoomMembers(ctx context.Context, roomID uuid.UUID) ([]RoomMember, error)
 	UpdateRoomMember(ctx context.Context, member RoomMember) error
+
+	// CreateMessageReport stores a report. Returns false if the reporter has
+	// already reported the message.
+	CreateMessageReport(ctx context.Context, report *MessageReport) (bool, error)
+
+	// GetMessageReport retrieves a report.
+	// Returns nil, nil if the report is not found.
+	GetMessageReport(ctx context.Context, id uuid.UUID) (*MessageReport, error)
+
+	// GetMessageReports lists a room's reports with the given status, oldest
+	// first, starting after the cursor.
+	GetMessageReports(ctx context.Context, roomID uuid.UUID, status string, after *pagination.Cursor, limit int) ([]MessageReport, error)
+
+	// ResolveMessageReports resolves the report together with any other open
+	// reports of the same message, and returns how many were resolved.
+	ResolveMessageReports(ctx context.Context, report *MessageReport, resolution string, resolvedBy uuid.UUID) (int64, error)
+
+	// RemoveMessageContent blanks a message, dropping its metadata, attachments
+	// and reactions but keeping its place in threads.
+	// Returns false if the message is not found.
+	RemoveMessageContent(ctx context.Context, messageID uuid.UUID) (bool, error)
+
+	// CreateModerationLogEntry records a moderator action.
+	CreateModerationLogEntry(ctx context.Context, entry *ModerationLogEntry) error
+
+	// GetModerationLog lists a room's moderator actions, newest first,
+	// starting after the before cursor.
+	GetModerationLog(ctx context.Context, roomID uuid.UUID, before *pagination.Cursor, limit int) ([]ModerationLogEntry, error)
+
+	// GetAutoModSettings retrieves a room's AutoMod rules.
+	// Returns nil, nil if the room has never configured AutoMod.
+	GetAutoModSettings(ctx context.Context, roomID uuid.UUID) (*AutoModSettings, error)
+
+	// SaveAutoModSettings creates or replaces a room's AutoMod rules and sets
+	// UpdatedAt.
+	SaveAutoModSettings(ctx context.Context, settings *AutoModSettings) error
+
 	CreateCategory(ctx context.Context, category *RoomCategory) (*RoomCategory, error)
 	CreateChannel(ctx context.Context, channel *RoomChannel) (*RoomChannel, error)
 	GetRoomCategories(ctx context.Context, roomID uuid.UUID) ([]RoomCategory, error)
 	GetRoomChannels(ctx context.Context, roomID uuid.UUID) ([]RoomChannel, error)
 	GetDefaultChannel(ctx context.Context, roomID uuid.UUID) (*RoomChannel, error)
-	GetRoomMessagesByChannel(ctx context.Context, roomID uuid.UUID, channelID *uuid.UUID, limit int, offset int) ([]*Message, error)
+
+	// GetRoomChannel retrieves a channel of the room.
+	// Returns nil, nil if the room has no such channel.
+	GetRoomChannel(ctx context.Context, roomID, channelID uuid.UUID) (*RoomChannel, error)
+
+	// SetChannelSlowMode sets the seconds members wait between messages in
+	// the channel. Returns false if the room has no such channel.
+	SetChannelSlowMode(ctx context.Context, roomID, channelID uuid.UUID, seconds int) (bool, error)
+
 	SearchMessages(ctx context.Context, roomID uuid.UUID, queryText string, channelID *uuid.UUID, username string, limit int) ([]Message, error)
 	CreateNotification(ctx context.Context, notification *Notification) error
-	GetNotifications(ctx context.Context, userID uuid.UUID, limit int) ([]Notification, error)
+	// CreateNotificationIfAccepted stores a notification the recipient's preferences accept,
+	// skipping senders the recipient has blocked and repeats that are still unread,
+	// and reports whether it was stored.
+	CreateNotificationIfAccepted(ctx context.Context, notification *Notification) (bool, error)
+	// GetNotifications returns the user's notifications newest first, starting before the cursor.
+	GetNotifications(ctx context.Context, userID uuid.UUID, before *pagination.Cursor, unreadOnly bool, limit int) ([]Notification, error)
 	MarkNotificationRead(ctx context.Context, notificationID, userID uuid.UUID) error
-	CreateMentionNotifications(ctx context.Context, roomID uuid.UUID, message *Message) ([]Notification, error)
+	CreateMentionNotifications(ctx context.Context, roomID uuid.UUID, message *Message, groups *MentionGroups) ([]Notification, error)
+
+	// MarkAllNotificationsRead marks the user's notifications read, optionally only those for one room.
+	MarkAllNotificationsRead(ctx context.Context, userID uuid.UUID, roomID *uuid.UUID) (int64, error)
+	CountUnreadNotifications(ctx context.Context, userID uuid.UUID) (int, error)
+
+	// DeleteNotificationsBefore removes notifications created before the cutoff.
+	DeleteNotificationsBefore(ctx context.Context, cutoff time.Time) (int64, error)
+
+	GetNotificationPreferences(ctx context.Context, userID uuid.UUID) ([]NotificationPreference, error)
+	SetNotificationPreference(ctx context.Context, preference *NotificationPreference) error
+	DeleteNotificationPreference(ctx context.Context, userID uuid.UUID, roomID *uuid.UUID) error
+	// GetPendingDigests returns users whose unread notifications up to cutoff have not been emailed.
+	GetPendingDigests(ctx context.Context, cutoff time.Time, perUser int) ([]DigestRecipient, error)
+	MarkNotificationsEmailed(ctx context.Context, userID uuid.UUID, cutoff time.Time) error
+	SetEmailDigest(ctx context.Context, userID uuid.UUID, enabled bool) error
 }
 
 // Ensure RoomRepository implements RoomRepositoryInterface
@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RoomInvite is an invite code for a room. A nil MaxUses or ExpiresAt means
+// no limit.
+type RoomInvite struct {
+	ID                uuid.UUID
+	RoomID            uuid.UUID
+	Code              string
+	CreatedBy         *uuid.UUID
+	CreatedByUsername *string
+	MaxUses           *int
+	Uses              int
+	ExpiresAt         *time.Time
+	RevokedAt         *time.Time
+	CreatedAt         time.Time
+}
+
+// Usable reports whether the invite can still admit someone at now.
+func (i *RoomInvite) Usable(now time.Time) bool {
+	if i.RevokedAt != nil {
+		return false
+	}
+	if i.ExpiresAt != nil && !i.ExpiresAt.After(now) {
+		return false
+	}
+	return i.MaxUses == nil || i.Uses < *i.MaxUses
+}
+
+// inviteColumns is the column list read by scanInvite; queries must alias
+// room_invites as ri and LEFT JOIN users as u on the creator.
+const inviteColumns = `ri.id, ri.room_id, ri.code, ri.created_by, u.username, ri.max_uses, ri.uses,
+	ri.expires_at, ri.revoked_at, ri.created_at`
+
+func scanInvite(row rowScanner) (*RoomInvite, error) {
+	var invite RoomInvite
+	err := row.Scan(
+		&invite.ID,
+		&invite.RoomID,
+		&invite.Code,
+		&invite.CreatedBy,
+		&invite.CreatedByUsername,
+		&invite.MaxUses,
+		&invite.Uses,
+		&invite.ExpiresAt,
+		&invite.RevokedAt,
+		&invite.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+func (r *RoomRepository) SetRoomVisibility(ctx context.Context, roomID uuid.UUID, visibility string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `UPDATE rooms SET visibility = $2 WHERE id = $1`, roomID, visibility)
+	if err != nil {
+		return false, fmt.Errorf("failed to set room visibility: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rows > 0, nil
+}
+
+func (r *RoomRepository) CreateRoomInvite(ctx context.Context, invite *RoomInvite) (*RoomInvite, error) {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO room_invites (room_id, code, created_by, max_uses, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, uses, created_at
+	`, invite.RoomID, invite.Code, invite.CreatedBy, invite.MaxUses, invite.ExpiresAt).Scan(
+		&invite.ID,
+		&invite.Uses,
+		&invite.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create room invite: %w", err)
+	}
+	return invite, nil
+}
+
+func (r *RoomRepository) GetRoomInvites(ctx context.Context, roomID uuid.UUID) ([]RoomInvite, error) {
+	query := `
+		SELECT ` + inviteColumns + `
+		FROM room_invites ri
+		LEFT JOIN users u ON u.id = ri.created_by
+		WHERE ri.room_id = $1
+		ORDER BY ri.created_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get room invites: %w", err)
+	}
+	defer rows.Close()
+
+	var invites []RoomInvite
+	for rows.Next() {
+		invite, err := scanInvite(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan room invite: %w", err)
+		}
+		invites = append(invites, *invite)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over room invites: %w", err)
+	}
+	return invites, nil
+}
+
+func (r *RoomRepository) GetRoomInviteByCode(ctx context.Context, code string) (*RoomInvite, error) {
+	query := `
+		SELECT ` + inviteColumns + `
+		FROM room_invites ri
+		LEFT JOIN users u ON u.id = ri.created_by
+		WHERE ri.code = $1
+	`
+
+	invite, err := scanInvite(r.db.QueryRowContext(ctx, query, code))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get room invite: %w", err)
+	}
+	return invite, nil
+}
+
+func (r *RoomRepository) RevokeRoomInvite(ctx context.Context, roomID, inviteID uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE room_invites SET revoked_at = NOW()
+		WHERE id = $1 AND room_id = $2 AND revoked_at IS NULL
+	`, inviteID, roomID)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke room invite: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rows > 0, nil
+}
+
+func (r *RoomRepository) UseRoomInvite(ctx context.Context, inviteID uuid.UUID) (bool, error) {
+	// The conditions repeat RoomInvite.Usable so concurrent redemptions
+	// cannot push an invite past its limit.
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE room_invites SET uses = uses + 1
+		WHERE id = $1
+			AND revoked_at IS NULL
+			AND (expires_at IS NULL OR expires_at > NOW())
+			AND (max_uses IS NULL OR uses < max_uses)
+	`, inviteID)
+	if err != nil {
+		return false, fmt.Errorf("failed to use room invite: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rows > 0, nil
+}
+
+func (r *RoomRepository) SetMemberInvite(ctx context.Context, roomID, userID uuid.UUID, invite *RoomInvite) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE room_members SET invite_id = $3, invited_by = $4, updated_at = NOW()
+		WHERE room_id = $1 AND user_id = $2 AND invite_id IS NULL
+	`, roomID, userID, invite.ID, invite.CreatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to record member invite: %w", err)
+	}
+	return nil
+}
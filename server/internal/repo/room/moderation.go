@@ -0,0 +1,278 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"chat-application/internal/constants"
+	"chat-application/internal/pagination"
+
+	"github.com/google/uuid"
+)
+
+// MessageReport is a member's report of a message. AuthorUsername and
+// MessageContent are copied when the report is made, so they outlive the
+// message.
+type MessageReport struct {
+	ID               uuid.UUID
+	RoomID           uuid.UUID
+	MessageID        *uuid.UUID
+	ReporterID       *uuid.UUID
+	ReporterUsername *string
+	AuthorID         *uuid.UUID
+	AuthorUsername   string
+	MessageContent   string
+	Reason           string
+	Details          *string
+	Status           string
+	Resolution       *string
+	ResolvedBy       *uuid.UUID
+	ResolvedAt       *time.Time
+	CreatedAt        time.Time
+}
+
+// ModerationLogEntry records one moderator action.
+type ModerationLogEntry struct {
+	ID                uuid.UUID
+	RoomID            uuid.UUID
+	ModeratorID       *uuid.UUID
+	ModeratorUsername string
+	Action            string
+	TargetUserID      *uuid.UUID
+	TargetUsername    *string
+	MessageID         *uuid.UUID
+	ReportID          *uuid.UUID
+	Details           []byte
+	CreatedAt         time.Time
+}
+
+const reportColumns = `mr.id, mr.room_id, mr.message_id, mr.reporter_id, u.username, mr.author_id,
+	mr.author_username, mr.message_content, mr.reason, mr.details, mr.status, mr.resolution,
+	mr.resolved_by, mr.resolved_at, mr.created_at`
+
+func scanReport(row rowScanner) (*MessageReport, error) {
+	var report MessageReport
+	err := row.Scan(
+		&report.ID,
+		&report.RoomID,
+		&report.MessageID,
+		&report.ReporterID,
+		&report.ReporterUsername,
+		&report.AuthorID,
+		&report.AuthorUsername,
+		&report.MessageContent,
+		&report.Reason,
+		&report.Details,
+		&report.Status,
+		&report.Resolution,
+		&report.ResolvedBy,
+		&report.ResolvedAt,
+		&report.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (r *RoomRepository) CreateMessageReport(ctx context.Context, report *MessageReport) (bool, error) {
+	query := `
+		INSERT INTO message_reports (room_id, message_id, reporter_id, author_id, author_username, message_content, reason, details)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (message_id, reporter_id) DO NOTHING
+		RETURNING id, status, created_at
+	`
+	err := r.db.QueryRowContext(ctx, query,
+		report.RoomID,
+		report.MessageID,
+		report.ReporterID,
+		report.AuthorID,
+		report.AuthorUsername,
+		report.MessageContent,
+		report.Reason,
+		report.Details,
+	).Scan(&report.ID, &report.Status, &report.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil // Already reported by this member
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create message report: %w", err)
+	}
+	return true, nil
+}
+
+func (r *RoomRepository) GetMessageReport(ctx context.Context, id uuid.UUID) (*MessageReport, error) {
+	query := `
+		SELECT ` + reportColumns + `
+		FROM message_reports mr
+		LEFT JOIN users u ON u.id = mr.reporter_id
+		WHERE mr.id = $1
+	`
+	report, err := scanReport(r.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message report: %w", err)
+	}
+	return report, nil
+}
+
+func (r *RoomRepository) GetMessageReports(ctx context.Context, roomID uuid.UUID, status string, after *pagination.Cursor, limit int) ([]MessageReport, error) {
+	query := `
+		SELECT ` + reportColumns + `
+		FROM message_reports mr
+		LEFT JOIN users u ON u.id = mr.reporter_id
+		WHERE mr.room_id = $1 AND mr.status = $2
+	`
+	args := []any{roomID, status}
+	if after != nil {
+		args = append(args, after.CreatedAt, after.ID)
+		query += fmt.Sprintf(` AND (mr.created_at, mr.id) > ($%d, $%d)`, len(args)-1, len(args))
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(` ORDER BY mr.created_at, mr.id LIMIT $%d`, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message reports: %w", err)
+	}
+	defer rows.Close()
+
+	var reports []MessageReport
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan message report: %w", err)
+		}
+		reports = append(reports, *report)
+	}
+	return reports, rows.Err()
+}
+
+func (r *RoomRepository) ResolveMessageReports(ctx context.Context, report *MessageReport, resolution string, resolvedBy uuid.UUID) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE message_reports
+		SET status = $4, resolution = $5, resolved_by = $6, resolved_at = NOW()
+		WHERE room_id = $1
+			AND status = $7
+			AND (id = $2 OR message_id = $3)
+	`, report.RoomID, report.ID, report.MessageID, constants.ReportStatusResolved, resolution, resolvedBy, constants.ReportStatusOpen)
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve message reports: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (r *RoomRepository) RemoveMessageContent(ctx context.Context, messageID uuid.UUID) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Replacing the metadata also drops attachments, link previews and
+	// mention entities from the message.
+	result, err := tx.ExecContext(ctx, `
+		UPDATE messages
+		SET content = '', metadata = '{"removed": true}'::jsonb
+		WHERE id = $1
+	`, messageID)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove message content: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rows == 0 {
+		return false, nil
+	}
+
+	// Detached attachments are swept up with other orphans.
+	if _, err := tx.ExecContext(ctx, `UPDATE attachments SET message_id = NULL WHERE message_id = $1`, messageID); err != nil {
+		return false, fmt.Errorf("failed to detach attachments: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM message_reactions WHERE message_id = $1`, messageID); err != nil {
+		return false, fmt.Errorf("failed to remove reactions: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+func (r *RoomRepository) CreateModerationLogEntry(ctx context.Context, entry *ModerationLogEntry) error {
+	details := entry.Details
+	if len(details) == 0 {
+		details = []byte(`{}`)
+	}
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO moderation_log (room_id, moderator_id, moderator_username, action, target_user_id, target_username, message_id, report_id, details)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at
+	`,
+		entry.RoomID,
+		entry.ModeratorID,
+		entry.ModeratorUsername,
+		entry.Action,
+		entry.TargetUserID,
+		entry.TargetUsername,
+		entry.MessageID,
+		entry.ReportID,
+		string(details),
+	).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create moderation log entry: %w", err)
+	}
+	entry.Details = details
+	return nil
+}
+
+func (r *RoomRepository) GetModerationLog(ctx context.Context, roomID uuid.UUID, before *pagination.Cursor, limit int) ([]ModerationLogEntry, error) {
+	query := `
+		SELECT id, room_id, moderator_id, moderator_username, action, target_user_id, target_username,
+			message_id, report_id, details, created_at
+		FROM moderation_log
+		WHERE room_id = $1
+	`
+	args := []any{roomID}
+	if before != nil {
+		args = append(args, before.CreatedAt, before.ID)
+		query += fmt.Sprintf(` AND (created_at, id) < ($%d, $%d)`, len(args)-1, len(args))
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(` ORDER BY created_at DESC, id DESC LIMIT $%d`, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get moderation log: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []ModerationLogEntry
+	for rows.Next() {
+		var entry ModerationLogEntry
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.RoomID,
+			&entry.ModeratorID,
+			&entry.ModeratorUsername,
+			&entry.Action,
+			&entry.TargetUserID,
+			&entry.TargetUsername,
+			&entry.MessageID,
+			&entry.ReportID,
+			&entry.Details,
+			&entry.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan moderation log entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"chat-application/internal/constants"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// NotificationPreference controls which notifications a user receives. A nil
+// RoomID is the user's default; room preferences override it, and a nil
+// Level on a room preference inherits the default level.
+type NotificationPreference struct {
+	UserID     uuid.UUID
+	RoomID     *uuid.UUID
+	Level      *string
+	MutedUntil *time.Time
+	// EmailDigest is only meaningful on the default. SetNotificationPreference
+	// leaves it unchanged; use SetEmailDigest.
+	EmailDigest bool
+	UpdatedAt   time.Time
+}
+
+// mentionKinds are the notification kinds still delivered at the "mentions" level.
+var mentionKinds = map[string]bool{
+	"mention":       true,
+	"group_mention": true,
+	"reply":         true,
+}
+
+// acceptedLevels returns the preference levels that receive a notification kind.
+func acceptedLevels(kind string) []string {
+	if mentionKinds[kind] {
+		return []string{constants.NotificationLevelAll, constants.NotificationLevelMentions}
+	}
+	return []string{constants.NotificationLevelAll}
+}
+
+// acceptsNotificationSQL builds a predicate that holds when the user in userExpr
+// wants a notification in room roomExpr whose kind is accepted at the levels
+// bound to levelsParam. An active mute, for the room or overall, silences
+// everything; otherwise the room level wins over the default, which is "all".
+func acceptsNotificationSQL(userExpr, roomExpr, levelsParam string) string {
+	return fmt.Sprintf(`(
+		NOT EXISTS (
+			SELECT 1 FROM notification_preferences np
+			WHERE np.user_id = %[1]s
+				AND (np.room_id IS NULL OR np.room_id = %[2]s)
+				AND np.muted_until > NOW()
+		)
+		AND COALESCE((
+			SELECT np.level FROM notification_preferences np
+			WHERE np.user_id = %[1]s
+				AND (np.room_id IS NULL OR np.room_id = %[2]s)
+				AND np.level IS NOT NULL
+			ORDER BY np.room_id NULLS LAST
+			LIMIT 1
+		), 'all') = ANY(%[3]s)
+	)`, userExpr, roomExpr, levelsParam)
+}
+
+// notBlockedSQL builds a predicate that holds unless the user in userExpr has
+// blocked the sender in senderExpr. senderExpr is compared as text so it may
+// be NULL or come from a JSON payload.
+func notBlockedSQL(userExpr, senderExpr string) string {
+	return fmt.Sprintf(`NOT EXISTS (
+		SELECT 1 FROM user_blocks ub
+		WHERE ub.blocker_id = %s
+			AND ub.blocked_id::text = (%s)::text
+	)`, userExpr, senderExpr)
+}
+
+func (r *RoomRepository) MarkAllNotificationsRead(ctx context.Context, userID uuid.UUID, roomID *uuid.UUID) (int64, error) {
+	query := `UPDATE notifications SET is_read = TRUE WHERE user_id = $1 AND is_read = FALSE`
+	args := []any{userID}
+	if roomID != nil {
+		query += ` AND room_id = $2`
+		args = append(args, *roomID)
+	}
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// CreateNotificationIfAccepted stores a notification unless the recipient's
+// preferences silence its kind or, when the payload names a sender
+// ("from_user_id"), the recipient has blocked the sender or an unread
+// notification of the same kind about the same message from the same sender
+// is still waiting. It reports whether the notification was stored.
+func (r *RoomRepository) CreateNotificationIfAccepted(ctx context.Context, notification *Notification) (bool, error) {
+	payload := notification.Payload
+	if len(payload) == 0 {
+		payload = []byte(`{}`)
+	}
+
+	query := `
+		INSERT INTO notifications (user_id, room_id, message_id, kind, title, body, payload, is_read)
+		SELECT $1::uuid, $2::uuid, $3::uuid, $4::text, $5::text, $6::text, $7::jsonb, FALSE
+		WHERE ` + acceptsNotificationSQL("$1::uuid", "$2::uuid", "$8") + `
+			AND ` + notBlockedSQL("$1::uuid", "$7::jsonb->>'from_user_id'") + `
+			AND NOT EXISTS (
+				SELECT 1 FROM notifications n
+				WHERE $7::jsonb ? 'from_user_id'
+					AND n.user_id = $1::uuid
+					AND n.kind = $4::text
+					AND n.is_read = FALSE
+					AND n.message_id IS NOT DISTINCT FROM $3::uuid
+					AND n.payload->>'from_user_id' IS NOT DISTINCT FROM $7::jsonb->>'from_user_id'
+			)
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRowContext(ctx, query,
+		notification.UserID,
+		notification.RoomID,
+		notification.MessageID,
+		notification.Kind,
+		notification.Title,
+		notification.Body,
+		string(payload),
+		pq.Array(acceptedLevels(notification.Kind)),
+	).Scan(&notification.ID, &notification.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create notification: %w", err)
+	}
+	notification.Payload = payload
+	return true, nil
+}
+
+func (r *RoomRepository) CountUnreadNotifications(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND is_read = FALSE
+	`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+	return count, nil
+}
+
+func (r *RoomRepository) DeleteNotificationsBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM notifications WHERE created_at < $1`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old notifications: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (r *RoomRepository) GetNotificationPreferences(ctx context.Context, userID uuid.UUID) ([]NotificationPreference, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT user_id, room_id, level, muted_until, email_digest, updated_at
+		FROM notification_preferences
+		WHERE user_id = $1
+		ORDER BY room_id NULLS FIRST
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+	defer rows.Close()
+
+	var preferences []NotificationPreference
+	for rows.Next() {
+		var preference NotificationPreference
+		if err := rows.Scan(
+			&preference.UserID,
+			&preference.RoomID,
+			&preference.Level,
+			&preference.MutedUntil,
+			&preference.EmailDigest,
+			&preference.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan notification preference: %w", err)
+		}
+		preferences = append(preferences, preference)
+	}
+
+	return preferences, rows.Err()
+}
+
+func (r *RoomRepository) SetNotificationPreference(ctx context.Context, preference *NotificationPreference) error {
+	conflictTarget := `(user_id) WHERE room_id IS NULL`
+	if preference.RoomID != nil {
+		conflictTarget = `(user_id, room_id) WHERE room_id IS NOT NULL`
+	}
+
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO notification_preferences (user_id, room_id, level, muted_until)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT `+conflictTarget+` DO UPDATE
+		SET level = EXCLUDED.level,
+			muted_until = EXCLUDED.muted_until,
+			updated_at = NOW()
+		RETURNING email_digest, updated_at
+	`, preference.UserID, preference.RoomID, preference.Level, preference.MutedUntil).Scan(&preference.EmailDigest, &preference.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save notification preference: %w", err)
+	}
+	return nil
+}
+
+func (r *RoomRepository) DeleteNotificationPreference(ctx context.Context, userID uuid.UUID, roomID *uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `
+		DELETE FROM notification_preferences
+		WHERE user_id = $1 AND room_id IS NOT DISTINCT FROM $2
+	`, userID, roomID)
+	if err != nil {
+		return fmt.Errorf("failed to delete notification preference: %w", err)
+	}
+	return nil
+}
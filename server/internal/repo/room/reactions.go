@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"chat-application/internal/api/model"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type RoomEmoji struct {
+	ID           uuid.UUID
+	RoomID       uuid.UUID
+	Name         string
+	AttachmentID uuid.UUID
+	CreatedBy    *uuid.UUID
+	CreatedAt    time.Time
+}
+
+func (r *RoomRepository) ToggleReaction(ctx context.Context, reaction *model.MessageReaction) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		DELETE FROM message_reactions
+		WHERE message_id = $1 AND user_id = $2 AND emoji = $3
+	`, reaction.MessageID, reaction.UserID, reaction.Emoji)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove reaction: %w", err)
+	}
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	added := removed == 0
+	if added {
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO message_reactions (message_id, user_id, emoji)
+			VALUES ($1, $2, $3)
+			RETURNING id, created_at
+		`, reaction.MessageID, reaction.UserID, reaction.Emoji).Scan(&reaction.ID, &reaction.CreatedAt)
+		if err != nil {
+			return false, fmt.Errorf("failed to add reaction: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit reaction: %w", err)
+	}
+
+	return added, nil
+}
+
+func (r *RoomRepository) RemoveReaction(ctx context.Context, messageID, userID uuid.UUID, emoji string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM message_reactions
+		WHERE message_id = $1 AND user_id = $2 AND emoji = $3
+	`, messageID, userID, emoji)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove reaction: %w", err)
+	}
+
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return removed > 0, nil
+}
+
+func (r *RoomRepository) GetReactionCounts(ctx context.Context, messageIDs []uuid.UUID, viewerID *uuid.UUID) (map[uuid.UUID][]model.ReactionCountRes, error) {
+	counts := make(map[uuid.UUID][]model.ReactionCountRes)
+	if len(messageIDs) == 0 {
+		return counts, nil
+	}
+
+	ids := make([]string, 0, len(messageIDs))
+	for _, id := range messageIDs {
+		ids = append(ids, id.String())
+	}
+
+	query := `
+		SELECT message_id, emoji, COUNT(*), COALESCE(BOOL_OR(user_id = $2::uuid), FALSE)
+		FROM message_reactions
+		WHERE message_id = ANY($1)
+		GROUP BY message_id, emoji
+		ORDER BY MIN(created_at) ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids), viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reaction counts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var messageID uuid.UUID
+		var count model.ReactionCountRes
+		if err := rows.Scan(&messageID, &count.Emoji, &count.Count, &count.Reacted); err != nil {
+			return nil, fmt.Errorf("failed to scan reaction count: %w", err)
+		}
+		counts[messageID] = append(counts[messageID], count)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating reaction counts: %w", err)
+	}
+
+	return counts, nil
+}
+
+func (r *RoomRepository) CreateRoomEmoji(ctx context.Context, emoji *RoomEmoji) (*RoomEmoji, error) {
+	query := `
+		INSERT INTO room_emojis (room_id, name, attachment_id, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query, emoji.RoomID, emoji.Name, emoji.AttachmentID, emoji.CreatedBy).
+		Scan(&emoji.ID, &emoji.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create room emoji: %w", err)
+	}
+
+	return emoji, nil
+}
+
+func (r *RoomRepository) GetRoomEmojis(ctx context.Context, roomID uuid.UUID) ([]RoomEmoji, error) {
+	query := `
+		SELECT id, room_id, name, attachment_id, created_by, created_at
+		FROM room_emojis
+		WHERE room_id = $1
+		ORDER BY name ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get room emojis: %w", err)
+	}
+	defer rows.Close()
+
+	var emojis []RoomEmoji
+	for rows.Next() {
+		var emoji RoomEmoji
+		if err := rows.Scan(&emoji.ID, &emoji.RoomID, &emoji.Name, &emoji.AttachmentID, &emoji.CreatedBy, &emoji.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan room emoji: %w", err)
+		}
+		emojis = append(emojis, emoji)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating room emojis: %w", err)
+	}
+
+	return emojis, nil
+}
+
+func (r *RoomRepository) GetRoomEmoji(ctx context.Context, roomID uuid.UUID, name string) (*RoomEmoji, error) {
+	query := `
+		SELECT id, room_id, name, attachment_id, created_by, created_at
+		FROM room_emojis
+		WHERE room_id = $1 AND name = $2
+	`
+
+	var emoji RoomEmoji
+	err := r.db.QueryRowContext(ctx, query, roomID, name).
+		Scan(&emoji.ID, &emoji.RoomID, &emoji.Name, &emoji.AttachmentID, &emoji.CreatedBy, &emoji.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get room emoji: %w", err)
+	}
+
+	return &emoji, nil
+}
+
+func (r *RoomRepository) DeleteRoomEmoji(ctx context.Context, roomID uuid.UUID, name string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM room_emojis WHERE room_id = $1 AND name = $2`, roomID, name)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete room emoji: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return deleted > 0, nil
+}
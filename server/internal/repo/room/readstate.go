@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ChannelReadState is a user's read pointer in a channel together with the
+// number of unread messages and unread mentions after it. Channels the user
+// has never opened count messages since they joined the room.
+type ChannelReadState struct {
+	RoomID            uuid.UUID
+	ChannelID         uuid.UUID
+	LastReadMessageID *uuid.UUID
+	LastReadAt        *time.Time
+	UnreadCount       int
+	MentionCount      int
+}
+
+// ReadReceipt records that a user has read up to or past a message.
+type ReadReceipt struct {
+	UserID   uuid.UUID
+	Username string
+	ReadAt   time.Time
+}
+
+func (r *RoomRepository) MarkChannelRead(ctx context.Context, userID uuid.UUID, message *Message) (bool, error) {
+	if message.ChannelID == nil {
+		return false, nil
+	}
+
+	// The pointer only moves forward, so a stale client can't mark messages unread.
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO channel_read_states (user_id, channel_id, room_id, last_read_message_id, last_read_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, channel_id) DO UPDATE
+		SET last_read_message_id = EXCLUDED.last_read_message_id,
+			last_read_at = EXCLUDED.last_read_at,
+			updated_at = NOW()
+		WHERE (channel_read_states.last_read_at, channel_read_states.last_read_message_id)
+			< (EXCLUDED.last_read_at, EXCLUDED.last_read_message_id)
+	`, userID, *message.ChannelID, message.RoomID, message.ID, message.CreatedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark channel read: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to mark channel read: %w", err)
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	// Reading a channel also clears the notifications for what was read.
+	_, err = r.db.ExecContext(ctx, `
+		UPDATE notifications n
+		SET is_read = TRUE
+		FROM messages m
+		WHERE n.message_id = m.id
+			AND n.user_id = $1
+			AND n.is_read = FALSE
+			AND m.channel_id = $2
+			AND (m.created_at, m.id) <= ($3, $4)
+	`, userID, *message.ChannelID, message.CreatedAt, message.ID)
+	if err != nil {
+		return true, fmt.Errorf("failed to clear read notifications: %w", err)
+	}
+
+	return true, nil
+}
+
+func (r *RoomRepository) GetChannelReadStates(ctx context.Context, userID uuid.UUID, roomIDs []uuid.UUID) ([]ChannelReadState, error) {
+	if len(roomIDs) == 0 {
+		return nil, nil
+	}
+	ids := make([]string, 0, len(roomIDs))
+	for _, id := range roomIDs {
+		ids = append(ids, id.String())
+	}
+
+	query := `
+		SELECT c.room_id, c.id, rs.last_read_message_id, rs.last_read_at, unread.count, mentions.count
+		FROM room_members rm
+		JOIN room_channels c ON c.room_id = rm.room_id
+		LEFT JOIN channel_read_states rs ON rs.user_id = rm.user_id AND rs.channel_id = c.id
+		CROSS JOIN LATERAL (
+			SELECT COUNT(*) AS count
+			FROM messages m
+			WHERE m.channel_id = c.id
+				AND m.parent_message_id IS NULL
+				AND m.user_id IS DISTINCT FROM rm.user_id
+				AND CASE
+					WHEN rs.user_id IS NULL THEN m.created_at > rm.created_at
+					ELSE (m.created_at, m.id) > (rs.last_read_at, rs.last_read_message_id)
+				END
+		) unread
+		CROSS JOIN LATERAL (
+			SELECT COUNT(*) AS count
+			FROM notifications n
+			JOIN messages m ON m.id = n.message_id
+			WHERE n.user_id = rm.user_id
+				AND n.is_read = FALSE
+				AND n.kind IN ('mention', 'group_mention')
+				AND m.channel_id = c.id
+		) mentions
+		WHERE rm.user_id = $1
+			AND rm.room_id::text = ANY($2)
+			AND rm.banned_at IS NULL
+		ORDER BY c.room_id, c.position ASC, c.created_at ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get read states: %w", err)
+	}
+	defer rows.Close()
+
+	var states []ChannelReadState
+	for rows.Next() {
+		var state ChannelReadState
+		if err := rows.Scan(
+			&state.RoomID,
+			&state.ChannelID,
+			&state.LastReadMessageID,
+			&state.LastReadAt,
+			&state.UnreadCount,
+			&state.MentionCount,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan read state: %w", err)
+		}
+		states = append(states, state)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating read states: %w", err)
+	}
+
+	return states, nil
+}
+
+func (r *RoomRepository) GetMessageReadReceipts(ctx context.Context, message *Message) ([]ReadReceipt, error) {
+	if message.ChannelID == nil {
+		return nil, nil
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT rs.user_id, u.username, rs.updated_at
+		FROM channel_read_states rs
+		JOIN users u ON u.id = rs.user_id
+		WHERE rs.channel_id = $1
+			AND (rs.last_read_at, rs.last_read_message_id) >= ($2, $3)
+			AND rs.user_id IS DISTINCT FROM $4
+		ORDER BY rs.updated_at ASC
+	`, *message.ChannelID, message.CreatedAt, message.ID, message.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get read receipts: %w", err)
+	}
+	defer rows.Close()
+
+	var receipts []ReadReceipt
+	for rows.Next() {
+		var receipt ReadReceipt
+		if err := rows.Scan(&receipt.UserID, &receipt.Username, &receipt.ReadAt); err != nil {
+			return nil, fmt.Errorf("failed to scan read receipt: %w", err)
+		}
+		receipts = append(receipts, receipt)
+	}
+
+	return receipts, rows.Err()
+}
@@ -23,6 +23,33 @@ type Room struct {
 	TopicURL         *string    `json:"topic_url,omitempty"`
 	TopicSource      *string    `json:"topic_source,omitempty"`
 	TopicUpdatedAt   *time.Time `json:"topic_updated_at,omitempty"`
+	// Visibility is public, unlisted or invite_only.
+	Visibility string `json:"visibility"`
+}
+
+const roomColumns = `id, name, creator_id, created_at, expires_at, is_pinned,
+	topic_title, topic_description, topic_url, topic_source, topic_updated_at, visibility`
+
+func scanRoom(row rowScanner) (*Room, error) {
+	var room Room
+	err := row.Scan(
+		&room.ID,
+		&room.Name,
+		&room.CreatorID,
+		&room.CreatedAt,
+		&room.ExpiresAt,
+		&room.IsPinned,
+		&room.TopicTitle,
+		&room.TopicDescription,
+		&room.TopicURL,
+		&room.TopicSource,
+		&room.TopicUpdatedAt,
+		&room.Visibility,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &room, nil
 }
 
 type Message struct {
@@ -35,9 +62,41 @@ type Message struct {
 	Content         string     `json:"content"`
 	IsSystem        bool       `json:"is_system"`
 	Metadata        []byte     `json:"metadata,omitempty"`
+	ReplyCount      int        `json:"reply_count"`
+	LastReplyAt     *time.Time `json:"last_reply_at,omitempty"`
 	CreatedAt       time.Time  `json:"created_at"`
 }
 
+// messageColumns is the column list read by scanMessage; queries must alias messages as m.
+const messageColumns = `m.id, m.room_id, m.user_id, m.username, m.content, m.is_system, m.created_at,
+	m.channel_id, m.parent_message_id, m.metadata, m.reply_count, m.last_reply_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanMessage(row rowScanner) (*Message, error) {
+	var msg Message
+	err := row.Scan(
+		&msg.ID,
+		&msg.RoomID,
+		&msg.UserID,
+		&msg.Username,
+		&msg.Content,
+		&msg.IsSystem,
+		&msg.CreatedAt,
+		&msg.ChannelID,
+		&msg.ParentMessageID,
+		&msg.Metadata,
+		&msg.ReplyCount,
+		&msg.LastReplyAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
 type RoomRepository struct {
 	db *sql.DB
 }
@@ -58,9 +117,9 @@ func (r *RoomRepository) CreateRoom(ctx context.Context, room *Room) (*Room, err
 
 	if room.IsPinned {
 		query = `
-			INSERT INTO rooms (name, creator_id, is_pinned, topic_title, topic_description, topic_url, topic_source, topic_updated_at, expires_at)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-			RETURNING id, created_at, expires_at
+			INSERT INTO rooms (name, creator_id, is_pinned, topic_title, topic_description, topic_url, topic_source, topic_updated_at, expires_at, visibility)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, COALESCE(NULLIF($10, ''), 'public'))
+			RETURNING id, created_at, expires_at, visibility
 		`
 
 		err = r.db.QueryRowContext(ctx, query,
@@ -73,26 +132,30 @@ func (r *RoomRepository) CreateRoom(ctx context.Context, room *Room) (*Room, err
 			room.TopicSource,
 			room.TopicUpdatedAt,
 			room.ExpiresAt,
+			room.Visibility,
 		).Scan(
 			&room.ID,
 			&room.CreatedAt,
 			&room.ExpiresAt,
+			&room.Visibility,
 		)
 	} else {
 		query = `
-			INSERT INTO rooms (name, creator_id, expires_at)
-			VALUES ($1, $2, $3)
-			RETURNING id, created_at, expires_at
+			INSERT INTO rooms (name, creator_id, expires_at, visibility)
+			VALUES ($1, $2, $3, COALESCE(NULLIF($4, ''), 'public'))
+			RETURNING id, created_at, expires_at, visibility
 		`
 
 		err = r.db.QueryRowContext(ctx, query,
 			room.Name,
 			room.CreatorID,
 			room.ExpiresAt,
+			room.Visibility,
 		).Scan(
 			&room.ID,
 			&room.CreatedAt,
 			&room.ExpiresAt,
+			&room.Visibility,
 		)
 	}
 
@@ -105,26 +168,12 @@ func (r *RoomRepository) CreateRoom(ctx context.Context, room *Room) (*Room, err
 
 func (r *RoomRepository) GetRoomByID(ctx context.Context, id uuid.UUID) (*Room, error) {
 	query := `
-		SELECT id, name, creator_id, created_at, expires_at, is_pinned,
-			topic_title, topic_description, topic_url, topic_source, topic_updated_at
+		SELECT ` + roomColumns + `
 		FROM rooms
 		WHERE id = $1
 	`
 
-	var room Room
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&room.ID,
-		&room.Name,
-		&room.CreatorID,
-		&room.CreatedAt,
-		&room.ExpiresAt,
-		&room.IsPinned,
-		&room.TopicTitle,
-		&room.TopicDescription,
-		&room.TopicURL,
-		&room.TopicSource,
-		&room.TopicUpdatedAt,
-	)
+	room, err := scanRoom(r.db.QueryRowContext(ctx, query, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil // Room not found
@@ -132,7 +181,7 @@ func (r *RoomRepository) GetRoomByID(ctx context.Context, id uuid.UUID) (*Room,
 		return nil, fmt.Errorf("failed to get room by ID: %w", err)
 	}
 
-	return &room, nil
+	return room, nil
 }
 
 func (r *RoomRepository) CountActiveRooms(ctx context.Context) (int, error) {
@@ -152,8 +201,7 @@ func (r *RoomRepository) CountActiveRooms(ctx context.Context) (int, error) {
 
 func (r *RoomRepository) GetAllActiveRooms(ctx context.Context) ([]*Room, error) {
 	query := `
-		SELECT id, name, creator_id, created_at, expires_at, is_pinned,
-			topic_title, topic_description, topic_url, topic_source, topic_updated_at
+		SELECT ` + roomColumns + `
 		FROM rooms
 		WHERE expires_at > NOW()
 		ORDER BY is_pinned DESC, created_at DESC
@@ -165,26 +213,38 @@ func (r *RoomRepository) GetAllActiveRooms(ctx context.Context) ([]*Room, error)
 	}
 	defer rows.Close()
 
+	return scanRooms(rows)
+}
+
+func (r *RoomRepository) GetVisibleRooms(ctx context.Context, viewerID *uuid.UUID) ([]*Room, error) {
+	query := `
+		SELECT ` + roomColumns + `
+		FROM rooms
+		WHERE expires_at > NOW()
+			AND (visibility = 'public' OR EXISTS (
+				SELECT 1 FROM room_members rm
+				WHERE rm.room_id = rooms.id AND rm.user_id = $1 AND rm.banned_at IS NULL
+			))
+		ORDER BY is_pinned DESC, created_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get visible rooms: %w", err)
+	}
+	defer rows.Close()
+
+	return scanRooms(rows)
+}
+
+func scanRooms(rows *sql.Rows) ([]*Room, error) {
 	var rooms []*Room
 	for rows.Next() {
-		var room Room
-		err := rows.Scan(
-			&room.ID,
-			&room.Name,
-			&room.CreatorID,
-			&room.CreatedAt,
-			&room.ExpiresAt,
-			&room.IsPinned,
-			&room.TopicTitle,
-			&room.TopicDescription,
-			&room.TopicURL,
-			&room.TopicSource,
-			&room.TopicUpdatedAt,
-		)
+		room, err := scanRoom(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan room: %w", err)
 		}
-		rooms = append(rooms, &room)
+		rooms = append(rooms, room)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -196,13 +256,21 @@ func (r *RoomRepository) GetAllActiveRooms(ctx context.Context) ([]*Room, error)
 
 func (r *RoomRepository) CreateMessage(ctx context.Context, message *Message) (*Message, error) {
 	query := `
-		INSERT INTO messages (room_id, channel_id, parent_message_id, user_id, username, content, is_system, metadata)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO messages (id, room_id, channel_id, parent_message_id, user_id, username, content, is_system, metadata)
+		VALUES (COALESCE($1, gen_random_uuid()), $2, $3, $4, $5, $6, $7, $8, $9)
 		RETURNING id, created_at
 	`
 
+	// Callers may assign the ID up front so the message can be referenced
+	// before it is persisted.
+	var id *uuid.UUID
+	if message.ID != uuid.Nil {
+		id = &message.ID
+	}
+
 	err := r.db.QueryRowContext(
 		ctx, query,
+		id,
 		message.RoomID,
 		message.ChannelID,
 		message.ParentMessageID,
@@ -223,52 +291,36 @@ func (r *RoomRepository) CreateMessage(ctx context.Context, message *Message) (*
 	return message, nil
 }
 
-func (r *RoomRepository) GetRoomMessages(ctx context.Context, roomID uuid.UUID, limit int, offset int) ([]*Message, error) {
+func (r *RoomRepository) MergeMessageMetadata(ctx context.Context, messageID uuid.UUID, patch []byte) error {
 	query := `
-		SELECT m.id, m.room_id, m.user_id, m.username, m.content, m.is_system, m.created_at, m.channel_id, m.parent_message_id, m.metadata
-		FROM messages AS m
-		INNER JOIN rooms AS r ON m.room_id = r.id
-		WHERE r.id = $1
-		ORDER BY m.created_at DESC
-		LIMIT $2 OFFSET $3
+		UPDATE messages
+		SET metadata = COALESCE(metadata, '{}'::jsonb) || $2::jsonb
+		WHERE id = $1
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, roomID, limit, offset)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get room messages: %w", err)
+	if _, err := r.db.ExecContext(ctx, query, messageID, patch); err != nil {
+		return fmt.Errorf("failed to update message metadata: %w", err)
 	}
-	defer rows.Close()
 
-	var messages []*Message
-	for rows.Next() {
-		var msg Message
-		err := rows.Scan(
-			&msg.ID,
-			&msg.RoomID,
-			&msg.UserID,
-			&msg.Username,
-			&msg.Content,
-			&msg.IsSystem,
-			&msg.CreatedAt,
-			&msg.ChannelID,
-			&msg.ParentMessageID,
-			&msg.Metadata,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan message: %w", err)
-		}
-		messages = append(messages, &msg)
-	}
+	return nil
+}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating over messages: %w", err)
-	}
+func (r *RoomRepository) GetMessageByID(ctx context.Context, id uuid.UUID) (*Message, error) {
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages m
+		WHERE m.id = $1
+	`
 
-	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 { // Reverse the messages to get chronological order
-		messages[i], messages[j] = messages[j], messages[i]
+	msg, err := scanMessage(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get message: %w", err)
 	}
 
-	return messages, nil
+	return msg, nil
 }
 
 func (r *RoomRepository) CountPinnedRooms(ctx context.Context) (int, error) {
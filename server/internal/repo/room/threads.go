@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"chat-application/internal/pagination"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ThreadSummary is the reply state of a thread's root message.
+type ThreadSummary struct {
+	MessageID   uuid.UUID
+	RoomID      uuid.UUID
+	ChannelID   *uuid.UUID
+	ReplyCount  int
+	LastReplyAt *time.Time
+}
+
+func (r *RoomRepository) GetThreadReplies(ctx context.Context, parentID uuid.UUID, after *pagination.Cursor, limit int) ([]*Message, error) {
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages m
+		WHERE m.parent_message_id = $1
+	`
+	args := []any{parentID}
+	if after != nil {
+		query += ` AND (m.created_at, m.id) > ($2, $3)`
+		args = append(args, after.CreatedAt, after.ID)
+	}
+	query += fmt.Sprintf(` ORDER BY m.created_at ASC, m.id ASC LIMIT $%d`, len(args)+1)
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get thread replies: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []*Message
+	for rows.Next() {
+		msg, err := scanMessage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan thread reply: %w", err)
+		}
+		messages = append(messages, msg)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating thread replies: %w", err)
+	}
+
+	return messages, nil
+}
+
+func (r *RoomRepository) RecordThreadReply(ctx context.Context, parentID uuid.UUID, repliedAt time.Time) (*ThreadSummary, error) {
+	query := `
+		UPDATE messages
+		SET reply_count = reply_count + 1,
+			last_reply_at = GREATEST(COALESCE(last_reply_at, $2), $2)
+		WHERE id = $1
+		RETURNING id, room_id, channel_id, reply_count, last_reply_at
+	`
+
+	var summary ThreadSummary
+	err := r.db.QueryRowContext(ctx, query, parentID, repliedAt).Scan(
+		&summary.MessageID,
+		&summary.RoomID,
+		&summary.ChannelID,
+		&summary.ReplyCount,
+		&summary.LastReplyAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record thread reply: %w", err)
+	}
+
+	return &summary, nil
+}
+
+func (r *RoomRepository) FollowThread(ctx context.Context, messageID, userID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO thread_followers (message_id, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT (message_id, user_id) DO NOTHING
+	`, messageID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to follow thread: %w", err)
+	}
+	return nil
+}
+
+func (r *RoomRepository) UnfollowThread(ctx context.Context, messageID, userID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM thread_followers WHERE message_id = $1 AND user_id = $2`, messageID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to unfollow thread: %w", err)
+	}
+	return nil
+}
+
+func (r *RoomRepository) IsFollowingThread(ctx context.Context, messageID, userID uuid.UUID) (bool, error) {
+	var following bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS(SELECT 1 FROM thread_followers WHERE message_id = $1 AND user_id = $2)
+	`, messageID, userID).Scan(&following)
+	if err != nil {
+		return false, fmt.Errorf("failed to check thread follow: %w", err)
+	}
+	return following, nil
+}
+
+// CreateThreadReplyNotifications notifies the followers of a thread about a new
+// reply, skipping the author of the reply and any users in exclude (for example
+// those already notified of a mention in the same reply).
+func (r *RoomRepository) CreateThreadReplyNotifications(ctx context.Context, parent *Message, reply *Message, exclude []uuid.UUID) ([]Notification, error) {
+	excluded := make([]string, 0, len(exclude)+1)
+	for _, id := range exclude {
+		excluded = append(excluded, id.String())
+	}
+	if reply.UserID != nil {
+		excluded = append(excluded, reply.UserID.String())
+	}
+
+	payload := fmt.Sprintf(`{"room_id":%q,"message_id":%q,"thread_id":%q,"username":%q}`,
+		parent.RoomID.String(), reply.ID.String(), parent.ID.String(), reply.Username)
+
+	query := `
+		INSERT INTO notifications (user_id, room_id, message_id, kind, title, body, payload, is_read)
+		SELECT tf.user_id, $2, $3, 'thread_reply', $4, $5, $6, FALSE
+		FROM thread_followers tf
+		JOIN room_members rm ON rm.room_id = $2 AND rm.user_id = tf.user_id
+		WHERE tf.message_id = $1
+			AND rm.banned_at IS NULL
+			AND NOT (tf.user_id::text = ANY($7))
+			AND ` + acceptsNotificationSQL("tf.user_id", "$2", "$8") + `
+			AND ` + notBlockedSQL("tf.user_id", "$9::uuid") + `
+		RETURNING id, user_id, room_id, message_id, kind, title, body, payload, is_read, created_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query,
+		parent.ID,
+		parent.RoomID,
+		reply.ID,
+		"New reply in a thread you follow",
+		fmt.Sprintf("%s replied: %s", reply.Username, reply.Content),
+		[]byte(payload),
+		pq.Array(excluded),
+		pq.Array(acceptedLevels("thread_reply")),
+		reply.UserID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create thread reply notifications: %w", err)
+	}
+	defer rows.Close()
+
+	var notifications []Notification
+	for rows.Next() {
+		var notification Notification
+		if err := rows.Scan(
+			&notification.ID,
+			&notification.UserID,
+			&notification.RoomID,
+			&notification.MessageID,
+			&notification.Kind,
+			&notification.Title,
+			&notification.Body,
+			&notification.Payload,
+			&notification.IsRead,
+			&notification.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, rows.Err()
+}
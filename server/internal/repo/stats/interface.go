@@ -29,7 +29,8 @@ type StatsRepositoryInterface interface {
 	// CheckAwardsAndAchievements checks and awards new achievements.
 	CheckAwardsAndAchievements(ctx context.Context, userID uuid.UUID) ([]Achievement, error)
 
-	// CanUserUpvote checks if a user can give an upvote to another user.
+	// CanUserUpvote checks if a user can give an upvote to another user. Users
+	// who have blocked each other cannot.
 	CanUserUpvote(ctx context.Context, fromUserID, toUserID uuid.UUID) (bool, error)
 
 	// GiveUpvote records an upvote from one user to another.
@@ -40,6 +41,9 @@ type StatsRepositoryInterface interface {
 
 	// GetLeaderboard retrieves the top users by score.
 	GetLeaderboard(ctx context.Context, limit int) ([]LeaderboardEntry, error)
+
+	// GetUsername retrieves a user's display name.
+	GetUsername(ctx context.Context, userID uuid.UUID) (string, error)
 }
 
 // Ensure StatsRepository implements StatsRepositoryInterface
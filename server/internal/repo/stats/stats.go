@@ -325,9 +325,14 @@ func (r *StatsRepository) CanUserUpvote(ctx context.Context, fromUserID, toUserI
 		SELECT EXISTS (
 			SELECT 1 FROM upvotes
 			WHERE from_user_id = $1 AND to_user_id = $2
+		) OR EXISTS (
+			SELECT 1 FROM user_blocks
+			WHERE (blocker_id = $1 AND blocked_id = $2)
+				OR (blocker_id = $2 AND blocked_id = $1)
 		)
 	`
 
+	// A block in either direction counts as already upvoted.
 	var alreadyUpvoted bool
 	err := r.db.QueryRowContext(ctx, existsQuery, fromUserID, toUserID).Scan(&alreadyUpvoted)
 	if err != nil {
@@ -493,3 +498,16 @@ func (r *StatsRepository) GetLeaderboard(ctx context.Context, limit int) ([]Lead
 
 	return leaderboard, nil
 }
+
+// GetUsername returns the user's display name, or "" if the user does not exist.
+func (r *StatsRepository) GetUsername(ctx context.Context, userID uuid.UUID) (string, error) {
+	var username string
+	err := r.db.QueryRowContext(ctx, `SELECT username FROM users WHERE id = $1`, userID).Scan(&username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return username, nil
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BlockedUser is someone a user has blocked.
+type BlockedUser struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (r *UserRepository) BlockUser(ctx context.Context, blockerID, blockedID uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO user_blocks (blocker_id, blocked_id)
+		VALUES ($1, $2)
+		ON CONFLICT (blocker_id, blocked_id) DO NOTHING
+	`, blockerID, blockedID)
+	if err != nil {
+		return false, fmt.Errorf("failed to block user: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to check rows affected: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+func (r *UserRepository) UnblockUser(ctx context.Context, blockerID, blockedID uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM user_blocks
+		WHERE blocker_id = $1 AND blocked_id = $2
+	`, blockerID, blockedID)
+	if err != nil {
+		return false, fmt.Errorf("failed to unblock user: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to check rows affected: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+func (r *UserRepository) GetBlockedUsers(ctx context.Context, blockerID uuid.UUID) ([]BlockedUser, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT b.blocked_id, u.username, b.created_at
+		FROM user_blocks b
+		JOIN users u ON u.id = b.blocked_id
+		WHERE b.blocker_id = $1
+		ORDER BY b.created_at DESC
+	`, blockerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blocked users: %w", err)
+	}
+	defer rows.Close()
+
+	var blocked []BlockedUser
+	for rows.Next() {
+		var user BlockedUser
+		if err := rows.Scan(&user.UserID, &user.Username, &user.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan blocked user: %w", err)
+		}
+		blocked = append(blocked, user)
+	}
+	return blocked, rows.Err()
+}
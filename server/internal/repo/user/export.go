@@ -0,0 +1,284 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserExport is everything stored about a user, for a personal data export.
+type UserExport struct {
+	ExportedAt    time.Time            `json:"exported_at"`
+	Profile       ExportProfile        `json:"profile"`
+	Messages      []ExportMessage      `json:"messages"`
+	Reactions     []ExportReaction     `json:"reactions"`
+	Achievements  []ExportAchievement  `json:"achievements"`
+	Notifications []ExportNotification `json:"notifications"`
+	BlockedUsers  []BlockedUser        `json:"blocked_users"`
+}
+
+type ExportProfile struct {
+	ID               uuid.UUID    `json:"id"`
+	Username         string       `json:"username"`
+	Email            string       `json:"email"`
+	DisplayName      *string      `json:"display_name,omitempty"`
+	Bio              *string      `json:"bio,omitempty"`
+	Pronouns         *string      `json:"pronouns,omitempty"`
+	StatusText       *string      `json:"status_text,omitempty"`
+	StatusEmoji      *string      `json:"status_emoji,omitempty"`
+	EmailVerifiedAt  *time.Time   `json:"email_verified_at,omitempty"`
+	TwoFactorEnabled bool         `json:"two_factor_enabled"`
+	LinkedProviders  []string     `json:"linked_providers"`
+	Stats            *ExportStats `json:"stats,omitempty"`
+	CreatedAt        time.Time    `json:"created_at"`
+	UpdatedAt        time.Time    `json:"updated_at"`
+}
+
+type ExportStats struct {
+	DailyStreak          int `json:"daily_streak"`
+	TotalCheckins        int `json:"total_checkins"`
+	TotalMessages        int `json:"total_messages"`
+	TotalUpvotesGiven    int `json:"total_upvotes_given"`
+	TotalUpvotesReceived int `json:"total_upvotes_received"`
+}
+
+type ExportMessage struct {
+	ID              uuid.UUID  `json:"id"`
+	RoomID          uuid.UUID  `json:"room_id"`
+	RoomName        string     `json:"room_name"`
+	ChannelID       *uuid.UUID `json:"channel_id,omitempty"`
+	ParentMessageID *uuid.UUID `json:"parent_message_id,omitempty"`
+	Content         string     `json:"content"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+type ExportReaction struct {
+	MessageID uuid.UUID `json:"message_id"`
+	Emoji     string    `json:"emoji"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ExportAchievement struct {
+	Name        string    `json:"name"`
+	Description *string   `json:"description,omitempty"`
+	EarnedAt    time.Time `json:"earned_at"`
+}
+
+type ExportNotification struct {
+	ID        uuid.UUID  `json:"id"`
+	Kind      string     `json:"kind"`
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	RoomID    *uuid.UUID `json:"room_id,omitempty"`
+	MessageID *uuid.UUID `json:"message_id,omitempty"`
+	IsRead    bool       `json:"is_read"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (r *UserRepository) ExportUserData(ctx context.Context, userID uuid.UUID) (*UserExport, error) {
+	// One snapshot, so the sections agree with each other.
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	export := &UserExport{
+		ExportedAt:    time.Now().UTC(),
+		Messages:      []ExportMessage{},
+		Reactions:     []ExportReaction{},
+		Achievements:  []ExportAchievement{},
+		Notifications: []ExportNotification{},
+		BlockedUsers:  []BlockedUser{},
+	}
+
+	profile := &export.Profile
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, username, email, display_name, bio, pronouns, status_text, status_emoji,
+			email_verified_at, totp_enabled_at IS NOT NULL, created_at, updated_at
+		FROM users
+		WHERE id = $1
+	`, userID).Scan(
+		&profile.ID,
+		&profile.Username,
+		&profile.Email,
+		&profile.DisplayName,
+		&profile.Bio,
+		&profile.Pronouns,
+		&profile.StatusText,
+		&profile.StatusEmoji,
+		&profile.EmailVerifiedAt,
+		&profile.TwoFactorEnabled,
+		&profile.CreatedAt,
+		&profile.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // User not found
+		}
+		return nil, fmt.Errorf("failed to export profile: %w", err)
+	}
+
+	profile.LinkedProviders = []string{}
+	err = queryRows(ctx, tx, `
+		SELECT provider FROM linked_identities WHERE user_id = $1 ORDER BY provider
+	`, userID, func(rows *sql.Rows) error {
+		var provider string
+		if err := rows.Scan(&provider); err != nil {
+			return err
+		}
+		profile.LinkedProviders = append(profile.LinkedProviders, provider)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to export linked accounts: %w", err)
+	}
+
+	var stats ExportStats
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(daily_streak, 0), COALESCE(total_checkins, 0), COALESCE(total_messages, 0),
+			COALESCE(total_upvotes, 0), COALESCE(total_upvotes_received, 0)
+		FROM user_stats
+		WHERE user_id = $1
+	`, userID).Scan(
+		&stats.DailyStreak,
+		&stats.TotalCheckins,
+		&stats.TotalMessages,
+		&stats.TotalUpvotesGiven,
+		&stats.TotalUpvotesReceived,
+	)
+	switch {
+	case err == nil:
+		profile.Stats = &stats
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, fmt.Errorf("failed to export stats: %w", err)
+	}
+
+	err = queryRows(ctx, tx, `
+		SELECT m.id, m.room_id, r.name, m.channel_id, m.parent_message_id, m.content, m.created_at
+		FROM messages m
+		JOIN rooms r ON r.id = m.room_id
+		WHERE m.user_id = $1 AND m.is_system = FALSE
+		ORDER BY m.created_at, m.id
+	`, userID, func(rows *sql.Rows) error {
+		var message ExportMessage
+		if err := rows.Scan(
+			&message.ID,
+			&message.RoomID,
+			&message.RoomName,
+			&message.ChannelID,
+			&message.ParentMessageID,
+			&message.Content,
+			&message.CreatedAt,
+		); err != nil {
+			return err
+		}
+		export.Messages = append(export.Messages, message)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to export messages: %w", err)
+	}
+
+	err = queryRows(ctx, tx, `
+		SELECT message_id, emoji, created_at
+		FROM message_reactions
+		WHERE user_id = $1
+		ORDER BY created_at, id
+	`, userID, func(rows *sql.Rows) error {
+		var reaction ExportReaction
+		if err := rows.Scan(&reaction.MessageID, &reaction.Emoji, &reaction.CreatedAt); err != nil {
+			return err
+		}
+		export.Reactions = append(export.Reactions, reaction)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to export reactions: %w", err)
+	}
+
+	err = queryRows(ctx, tx, `
+		SELECT at.name, at.description, ua.earned_at
+		FROM user_achievements ua
+		JOIN achievement_types at ON at.id = ua.achievement_type_id
+		WHERE ua.user_id = $1
+		ORDER BY ua.earned_at
+	`, userID, func(rows *sql.Rows) error {
+		var achievement ExportAchievement
+		if err := rows.Scan(&achievement.Name, &achievement.Description, &achievement.EarnedAt); err != nil {
+			return err
+		}
+		export.Achievements = append(export.Achievements, achievement)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to export achievements: %w", err)
+	}
+
+	err = queryRows(ctx, tx, `
+		SELECT id, kind, title, body, room_id, message_id, is_read, created_at
+		FROM notifications
+		WHERE user_id = $1
+		ORDER BY created_at, id
+	`, userID, func(rows *sql.Rows) error {
+		var notification ExportNotification
+		if err := rows.Scan(
+			&notification.ID,
+			&notification.Kind,
+			&notification.Title,
+			&notification.Body,
+			&notification.RoomID,
+			&notification.MessageID,
+			&notification.IsRead,
+			&notification.CreatedAt,
+		); err != nil {
+			return err
+		}
+		export.Notifications = append(export.Notifications, notification)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to export notifications: %w", err)
+	}
+
+	err = queryRows(ctx, tx, `
+		SELECT b.blocked_id, u.username, b.created_at
+		FROM user_blocks b
+		JOIN users u ON u.id = b.blocked_id
+		WHERE b.blocker_id = $1
+		ORDER BY b.created_at
+	`, userID, func(rows *sql.Rows) error {
+		var blocked BlockedUser
+		if err := rows.Scan(&blocked.UserID, &blocked.Username, &blocked.CreatedAt); err != nil {
+			return err
+		}
+		export.BlockedUsers = append(export.BlockedUsers, blocked)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to export blocked users: %w", err)
+	}
+
+	return export, nil
+}
+
+// queryRows runs a query with a single user ID argument and calls scan for
+// each row.
+func queryRows(ctx context.Context, tx *sql.Tx, query string, userID uuid.UUID, scan func(*sql.Rows) error) error {
+	rows, err := tx.QueryContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ErrIdentityLinked is returned when a provider account is already linked to
+// a user, or the user already has an account from that provider.
+var ErrIdentityLinked = errors.New("identity is already linked")
+
+// LinkedIdentity is an account at an OAuth provider that can sign in as a user.
+type LinkedIdentity struct {
+	ID          uuid.UUID  `json:"id"`
+	UserID      uuid.UUID  `json:"user_id"`
+	Provider    string     `json:"provider"`
+	Subject     string     `json:"-"`
+	Email       *string    `json:"email,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+func (r *UserRepository) GetUserByIdentity(ctx context.Context, provider, subject string) (*User, error) {
+	query := `
+		UPDATE linked_identities li
+		SET last_login_at = NOW()
+		FROM users u
+		WHERE li.provider = $1 AND li.subject = $2 AND u.id = li.user_id
+		RETURNING u.id, u.username, u.email, u.password_hash, u.email_verified_at, u.created_at, u.updated_at
+	`
+
+	var user User
+	err := r.db.QueryRowContext(ctx, query, provider, subject).Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.PasswordHash,
+		&user.EmailVerifiedAt,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // No user has linked this identity
+		}
+		return nil, fmt.Errorf("failed to get user by identity: %w", err)
+	}
+
+	return &user, nil
+}
+
+func (r *UserRepository) CreateUserWithIdentity(ctx context.Context, user *User, identity *LinkedIdentity) (*User, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO users (username, email, password_hash, email_verified_at)
+		VALUES ($1, $2, NULL, $3)
+		RETURNING id, created_at, updated_at
+	`, user.Username, user.Email, user.EmailVerifiedAt).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
+			return nil, errors.New("username or email already exists")
+		}
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	identity.UserID = user.ID
+	if err := insertIdentity(ctx, tx, identity); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return user, nil
+}
+
+func (r *UserRepository) LinkIdentity(ctx context.Context, identity *LinkedIdentity, verifyEmail bool) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertIdentity(ctx, tx, identity); err != nil {
+		return err
+	}
+
+	if verifyEmail {
+		// Whoever set the password of an unverified account never proved they
+		// own the address, so the password does not survive the provider
+		// proving it for someone else.
+		_, err = tx.ExecContext(ctx, `
+			UPDATE users
+			SET password_hash = CASE WHEN email_verified_at IS NULL THEN NULL ELSE password_hash END,
+				email_verified_at = COALESCE(email_verified_at, NOW()),
+				updated_at = NOW()
+			WHERE id = $1
+		`, identity.UserID)
+		if err != nil {
+			return fmt.Errorf("failed to verify email: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func insertIdentity(ctx context.Context, tx *sql.Tx, identity *LinkedIdentity) error {
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO linked_identities (user_id, provider, subject, email, last_login_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		RETURNING id, created_at, last_login_at
+	`, identity.UserID, identity.Provider, identity.Subject, identity.Email).Scan(
+		&identity.ID,
+		&identity.CreatedAt,
+		&identity.LastLoginAt,
+	)
+	if err != nil {
+		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
+			return ErrIdentityLinked
+		}
+		return fmt.Errorf("failed to link identity: %w", err)
+	}
+	return nil
+}
+
+func (r *UserRepository) GetLinkedIdentities(ctx context.Context, userID uuid.UUID) ([]LinkedIdentity, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, user_id, provider, subject, email, created_at, last_login_at
+		FROM linked_identities
+		WHERE user_id = $1
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get linked identities: %w", err)
+	}
+	defer rows.Close()
+
+	var identities []LinkedIdentity
+	for rows.Next() {
+		var identity LinkedIdentity
+		if err := rows.Scan(
+			&identity.ID,
+			&identity.UserID,
+			&identity.Provider,
+			&identity.Subject,
+			&identity.Email,
+			&identity.CreatedAt,
+			&identity.LastLoginAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan linked identity: %w", err)
+		}
+		identities = append(identities, identity)
+	}
+	return identities, rows.Err()
+}
+
+func (r *UserRepository) UnlinkIdentity(ctx context.Context, userID uuid.UUID, provider string) (bool, error) {
+	// A user must keep a way to sign in: a password or another identity.
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM linked_identities li
+		WHERE li.user_id = $1 AND li.provider = $2
+		AND (
+			EXISTS (SELECT 1 FROM users u WHERE u.id = li.user_id AND u.password_hash IS NOT NULL)
+			OR EXISTS (SELECT 1 FROM linked_identities other WHERE other.user_id = li.user_id AND other.id <> li.id)
+		)
+	`, userID, provider)
+	if err != nil {
+		return false, fmt.Errorf("failed to unlink identity: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("get rows affected: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
@@ -2,6 +2,9 @@ package repository
 
 import (
 	"context"
+	"time"
+
+	"chat-application/internal/pagination"
 
 	"github.com/google/uuid"
 )
@@ -23,7 +26,124 @@ type UserRepositoryInterface interface {
 	// Returns the updated user or an error if the username is taken.
 	UpdateUsername(ctx context.Context, id uuid.UUID, username string) (*User, error)
 
-	// DeleteUser removes a user from the database by their ID.
+	// CreateUserToken stores the hash of a single-use token, replacing any
+	// unused token the user has for the same purpose.
+	CreateUserToken(ctx context.Context, userID uuid.UUID, purpose, tokenHash string, expiresAt time.Time) error
+
+	// ResetPassword consumes a password reset token and sets the new password hash.
+	// Returns nil, nil if the token is unknown, used or expired.
+	ResetPassword(ctx context.Context, tokenHash string, passwordHash string) (*uuid.UUID, error)
+
+	// VerifyEmail consumes an email verification token and marks the email verified.
+	// Returns nil, nil if the token is unknown, used or expired.
+	VerifyEmail(ctx context.Context, tokenHash string) (*uuid.UUID, error)
+
+	// GetUserByIdentity retrieves the user a provider account is linked to and
+	// records the sign-in. Returns nil, nil if the identity is not linked.
+	GetUserByIdentity(ctx context.Context, provider, subject string) (*User, error)
+
+	// CreateUserWithIdentity creates a passwordless user signed up through a
+	// provider, together with the linked identity.
+	CreateUserWithIdentity(ctx context.Context, user *User, identity *LinkedIdentity) (*User, error)
+
+	// LinkIdentity links a provider account to identity.UserID. With
+	// verifyEmail the user's email is marked verified, and the password of a
+	// previously unverified account is removed.
+	// Returns ErrIdentityLinked if either side is already linked.
+	LinkIdentity(ctx context.Context, identity *LinkedIdentity, verifyEmail bool) error
+
+	// GetLinkedIdentities returns the provider accounts linked to a user.
+	GetLinkedIdentities(ctx context.Context, userID uuid.UUID) ([]LinkedIdentity, error)
+
+	// UnlinkIdentity removes a linked provider account unless it is the
+	// user's only way to sign in. Returns false if nothing was removed.
+	UnlinkIdentity(ctx context.Context, userID uuid.UUID, provider string) (bool, error)
+
+	// GetTOTP returns the user's two-factor setup and how many unused
+	// recovery codes they have. Returns nil, nil if the user is not found.
+	GetTOTP(ctx context.Context, userID uuid.UUID) (*TOTPState, error)
+
+	// SetPendingTOTP stores an encrypted secret awaiting confirmation.
+	// Returns false if two-factor authentication is already enabled.
+	SetPendingTOTP(ctx context.Context, userID uuid.UUID, secret string) (bool, error)
+
+	// EnableTOTP turns on the pending secret, recording step as used, and
+	// replaces the user's recovery codes with the given hashes.
+	// Returns false if there is no pending secret.
+	EnableTOTP(ctx context.Context, userID uuid.UUID, step int64, recoveryCodeHashes []string) (bool, error)
+
+	// AdvanceTOTPStep records that a code for step was accepted.
+	// Returns false if that step or a later one was already used.
+	AdvanceTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+
+	// UseRecoveryCode consumes an unused recovery code.
+	// Returns false if the code is unknown or already used.
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error)
+
+	// DisableTOTP removes the user's secret and recovery codes.
+	DisableTOTP(ctx context.Context, userID uuid.UUID) error
+
+	// CreateLoginEvent records a sign-in attempt, dropping the user's events
+	// from before retainAfter.
+	CreateLoginEvent(ctx context.Context, event *LoginEvent, retainAfter time.Time) error
+
+	// GetLoginEvents returns a user's sign-in history, newest first, starting
+	// after the before cursor if given.
+	GetLoginEvents(ctx context.Context, userID uuid.UUID, before *pagination.Cursor, limit int) ([]LoginEvent, error)
+
+	// GetLoginLock returns when the user's sign-in lock ends.
+	// Returns nil, nil if sign-in is not locked.
+	GetLoginLock(ctx context.Context, userID uuid.UUID) (*time.Time, error)
+
+	// RecordFailedLogin counts a failed sign-in and returns the number of
+	// consecutive failures.
+	RecordFailedLogin(ctx context.Context, userID uuid.UUID) (int, error)
+
+	// LockLogin refuses sign-in for the user until the given time.
+	LockLogin(ctx context.Context, userID uuid.UUID, until time.Time) error
+
+	// ResetFailedLogins clears the failure count and any lock after a
+	// successful sign-in.
+	ResetFailedLogins(ctx context.Context, userID uuid.UUID) error
+
+	// GetProfile retrieves what a user shows to others.
+	// Returns nil, nil if the user is not found.
+	GetProfile(ctx context.Context, userID uuid.UUID) (*Profile, error)
+
+	// UpdateProfile replaces the user's display name, bio, pronouns and
+	// status. Returns nil, nil if the user is not found.
+	UpdateProfile(ctx context.Context, userID uuid.UUID, update *ProfileUpdate) (*Profile, error)
+
+	// SetAvatar sets or, with nil, removes the user's avatar and returns the
+	// updated profile with the previous avatar key, so its blob can be
+	// deleted. Returns nil, nil, nil if the user is not found.
+	SetAvatar(ctx context.Context, userID uuid.UUID, avatarKey *string) (*Profile, *string, error)
+
+	// BlockUser records that blocker no longer wants to hear from blocked.
+	// Returns false if the block already existed.
+	BlockUser(ctx context.Context, blockerID, blockedID uuid.UUID) (bool, error)
+
+	// UnblockUser removes a block. Returns false if there was none.
+	UnblockUser(ctx context.Context, blockerID, blockedID uuid.UUID) (bool, error)
+
+	// GetBlockedUsers returns the users a user has blocked, most recent first.
+	GetBlockedUsers(ctx context.Context, blockerID uuid.UUID) ([]BlockedUser, error)
+
+	// SearchUsers finds users by username or display name, best matches
+	// first, then by username.
+	SearchUsers(ctx context.Context, search UserSearch) ([]UserMatch, error)
+
+	// CanReadRoom reports whether the room exists and whether the user may
+	// read it: anyone may read a room that is not invite-only, otherwise only
+	// members who are not banned.
+	CanReadRoom(ctx context.Context, roomID, userID uuid.UUID) (exists bool, readable bool, err error)
+
+	// ExportUserData collects everything stored about a user for a personal
+	// data export. Returns nil, nil if the user is not found.
+	ExportUserData(ctx context.Context, userID uuid.UUID) (*UserExport, error)
+
+	// DeleteUser removes a user from the database by their ID. Their
+	// messages are kept under DeletedUsername.
 	DeleteUser(ctx context.Context, id uuid.UUID) error
 }
 
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Profile is what a user shows to others. The status fields are nil once
+// StatusExpiresAt has passed.
+type Profile struct {
+	UserID          uuid.UUID
+	Username        string
+	DisplayName     *string
+	Bio             *string
+	Pronouns        *string
+	AvatarKey       *string
+	StatusText      *string
+	StatusEmoji     *string
+	StatusExpiresAt *time.Time
+}
+
+// ProfileUpdate replaces a user's editable profile fields; nil clears one.
+type ProfileUpdate struct {
+	DisplayName     *string
+	Bio             *string
+	Pronouns        *string
+	StatusText      *string
+	StatusEmoji     *string
+	StatusExpiresAt *time.Time
+}
+
+const profileColumns = `
+	id, username, display_name, bio, pronouns, avatar_key,
+	CASE WHEN status_expires_at IS NULL OR status_expires_at > NOW() THEN status_text END,
+	CASE WHEN status_expires_at IS NULL OR status_expires_at > NOW() THEN status_emoji END,
+	CASE WHEN status_expires_at > NOW() THEN status_expires_at END
+`
+
+func scanProfile(row *sql.Row) (*Profile, error) {
+	var profile Profile
+	err := row.Scan(
+		&profile.UserID,
+		&profile.Username,
+		&profile.DisplayName,
+		&profile.Bio,
+		&profile.Pronouns,
+		&profile.AvatarKey,
+		&profile.StatusText,
+		&profile.StatusEmoji,
+		&profile.StatusExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func (r *UserRepository) GetProfile(ctx context.Context, userID uuid.UUID) (*Profile, error) {
+	query := `SELECT ` + profileColumns + ` FROM users WHERE id = $1`
+
+	profile, err := scanProfile(r.db.QueryRowContext(ctx, query, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // User not found
+		}
+		return nil, fmt.Errorf("failed to get profile: %w", err)
+	}
+
+	return profile, nil
+}
+
+func (r *UserRepository) UpdateProfile(ctx context.Context, userID uuid.UUID, update *ProfileUpdate) (*Profile, error) {
+	query := `
+		UPDATE users
+		SET display_name = $2, bio = $3, pronouns = $4,
+			status_text = $5, status_emoji = $6, status_expires_at = $7,
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING ` + profileColumns
+
+	profile, err := scanProfile(r.db.QueryRowContext(ctx, query,
+		userID,
+		update.DisplayName,
+		update.Bio,
+		update.Pronouns,
+		update.StatusText,
+		update.StatusEmoji,
+		update.StatusExpiresAt,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // User not found
+		}
+		return nil, fmt.Errorf("failed to update profile: %w", err)
+	}
+
+	return profile, nil
+}
+
+func (r *UserRepository) SetAvatar(ctx context.Context, userID uuid.UUID, avatarKey *string) (*Profile, *string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var previous *string
+	err = tx.QueryRowContext(ctx, `SELECT avatar_key FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&previous)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, nil // User not found
+		}
+		return nil, nil, fmt.Errorf("failed to get avatar: %w", err)
+	}
+
+	query := `
+		UPDATE users
+		SET avatar_key = $2, updated_at = NOW()
+		WHERE id = $1
+		RETURNING ` + profileColumns
+
+	profile, err := scanProfile(tx.QueryRowContext(ctx, query, userID, avatarKey))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to set avatar: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return profile, previous, nil
+}
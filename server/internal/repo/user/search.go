@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"chat-application/internal/constants"
+	"chat-application/internal/pagination"
+
+	"github.com/google/uuid"
+)
+
+// UserSearch describes a username lookup. Email addresses are never matched.
+type UserSearch struct {
+	// Query is matched case-insensitively; an empty query matches everyone
+	// in scope as a prefix.
+	Query string
+	// Fuzzy adds trigram matches on username and display name after the
+	// prefix matches.
+	Fuzzy bool
+	// RoomID limits results to members of the room who are not banned.
+	RoomID *uuid.UUID
+	// ViewerID is left out of results of users who have blocked them.
+	ViewerID uuid.UUID
+	After    *pagination.RankCursor
+	Limit    int
+}
+
+// UserMatch is a search result. Rank is 0 for the exact username, 1 for a
+// username prefix, 2 for a display name prefix and 3 for a fuzzy match.
+type UserMatch struct {
+	Profile
+	Rank int
+}
+
+// Cursor returns the cursor for the page after this match.
+func (m *UserMatch) Cursor() pagination.RankCursor {
+	return pagination.RankCursor{Rank: m.Rank, Name: strings.ToLower(m.Username), ID: m.UserID}
+}
+
+func (r *UserRepository) CanReadRoom(ctx context.Context, roomID, userID uuid.UUID) (bool, bool, error) {
+	var readable bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT r.visibility <> $3 OR EXISTS (
+			SELECT 1 FROM room_members rm
+			WHERE rm.room_id = r.id AND rm.user_id = $2 AND rm.banned_at IS NULL
+		)
+		FROM rooms r
+		WHERE r.id = $1`, roomID, userID, constants.RoomVisibilityInviteOnly).Scan(&readable)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, fmt.Errorf("failed to check room access: %w", err)
+	}
+	return true, readable, nil
+}
+
+func (r *UserRepository) SearchUsers(ctx context.Context, search UserSearch) ([]UserMatch, error) {
+	query := strings.ToLower(search.Query)
+	args := []any{query, escapeLike(query) + "%", search.Fuzzy, search.ViewerID}
+	scope := ""
+	if search.RoomID != nil {
+		args = append(args, *search.RoomID)
+		scope = fmt.Sprintf(` AND EXISTS (
+			SELECT 1 FROM room_members rm
+			WHERE rm.room_id = $%d AND rm.user_id = users.id AND rm.banned_at IS NULL
+		)`, len(args))
+	}
+	after := ""
+	if search.After != nil {
+		args = append(args, search.After.Rank, search.After.Name, search.After.ID)
+		after = fmt.Sprintf(` AND (match_rank, LOWER(username), id) > ($%d, $%d, $%d)`, len(args)-2, len(args)-1, len(args))
+	}
+	args = append(args, search.Limit)
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT match_rank, `+profileColumns+`
+		FROM (
+			SELECT users.*,
+				CASE
+					WHEN LOWER(username) = $1 THEN 0
+					WHEN LOWER(username) LIKE $2 THEN 1
+					WHEN LOWER(display_name) LIKE $2 THEN 2
+					WHEN $3 AND (LOWER(username) % $1 OR LOWER(display_name) % $1) THEN 3
+				END AS match_rank
+			FROM users
+			WHERE NOT EXISTS (
+				SELECT 1 FROM user_blocks ub
+				WHERE ub.blocker_id = users.id AND ub.blocked_id = $4
+			)`+scope+`
+		) matches
+		WHERE match_rank IS NOT NULL`+after+fmt.Sprintf(`
+		ORDER BY match_rank, LOWER(username), id
+		LIMIT $%d`, len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search users: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []UserMatch
+	for rows.Next() {
+		var match UserMatch
+		if err := rows.Scan(
+			&match.Rank,
+			&match.UserID,
+			&match.Username,
+			&match.DisplayName,
+			&match.Bio,
+			&match.Pronouns,
+			&match.AvatarKey,
+			&match.StatusText,
+			&match.StatusEmoji,
+			&match.StatusExpiresAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		matches = append(matches, match)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate users: %w", err)
+	}
+
+	return matches, nil
+}
+
+// escapeLike escapes the LIKE wildcards in a user-supplied pattern, using
+// the default backslash escape.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"chat-application/internal/pagination"
+
+	"github.com/google/uuid"
+)
+
+// Login event outcomes.
+const (
+	LoginOutcomeSuccess         = "success"
+	LoginOutcomeMFARequired     = "mfa_required"
+	LoginOutcomeInvalidPassword = "invalid_password"
+	LoginOutcomeInvalidCode     = "invalid_code"
+	LoginOutcomeLocked          = "locked"
+)
+
+// LoginEvent is an entry in a user's sign-in history. Method is "password",
+// "totp", "recovery_code" or the name of an OAuth provider.
+type LoginEvent struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Method    string    `json:"method"`
+	Outcome   string    `json:"outcome"`
+	IPAddress *string   `json:"ip_address,omitempty"`
+	UserAgent *string   `json:"user_agent,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (r *UserRepository) CreateLoginEvent(ctx context.Context, event *LoginEvent, retainAfter time.Time) error {
+	query := `
+		WITH pruned AS (
+			DELETE FROM login_events
+			WHERE user_id = $1 AND created_at < $6
+		)
+		INSERT INTO login_events (user_id, method, outcome, ip_address, user_agent)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		event.UserID,
+		event.Method,
+		event.Outcome,
+		event.IPAddress,
+		event.UserAgent,
+		retainAfter,
+	).Scan(&event.ID, &event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create login event: %w", err)
+	}
+
+	return nil
+}
+
+func (r *UserRepository) GetLoginEvents(ctx context.Context, userID uuid.UUID, before *pagination.Cursor, limit int) ([]LoginEvent, error) {
+	query := `
+		SELECT id, user_id, method, outcome, ip_address, user_agent, created_at
+		FROM login_events
+		WHERE user_id = $1
+	`
+	args := []any{userID}
+	if before != nil {
+		args = append(args, before.CreatedAt, before.ID)
+		query += fmt.Sprintf(` AND (created_at, id) < ($%d, $%d)`, len(args)-1, len(args))
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(` ORDER BY created_at DESC, id DESC LIMIT $%d`, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []LoginEvent
+	for rows.Next() {
+		var event LoginEvent
+		if err := rows.Scan(
+			&event.ID,
+			&event.UserID,
+			&event.Method,
+			&event.Outcome,
+			&event.IPAddress,
+			&event.UserAgent,
+			&event.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan login event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate login events: %w", err)
+	}
+
+	return events, nil
+}
+
+func (r *UserRepository) GetLoginLock(ctx context.Context, userID uuid.UUID) (*time.Time, error) {
+	query := `
+		SELECT login_locked_until
+		FROM users
+		WHERE id = $1 AND login_locked_until > NOW()
+	`
+
+	var until time.Time
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&until)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Not locked
+		}
+		return nil, fmt.Errorf("failed to get login lock: %w", err)
+	}
+
+	return &until, nil
+}
+
+func (r *UserRepository) RecordFailedLogin(ctx context.Context, userID uuid.UUID) (int, error) {
+	query := `
+		UPDATE users
+		SET failed_login_attempts = failed_login_attempts + 1
+		WHERE id = $1
+		RETURNING failed_login_attempts
+	`
+
+	var attempts int
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&attempts); err != nil {
+		return 0, fmt.Errorf("failed to record failed login: %w", err)
+	}
+
+	return attempts, nil
+}
+
+func (r *UserRepository) LockLogin(ctx context.Context, userID uuid.UUID, until time.Time) error {
+	query := `
+		UPDATE users
+		SET login_locked_until = $2
+		WHERE id = $1
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, until); err != nil {
+		return fmt.Errorf("failed to lock login: %w", err)
+	}
+
+	return nil
+}
+
+func (r *UserRepository) ResetFailedLogins(ctx context.Context, userID uuid.UUID) error {
+	query := `
+		UPDATE users
+		SET failed_login_attempts = 0, login_locked_until = NULL
+		WHERE id = $1 AND (failed_login_attempts > 0 OR login_locked_until IS NOT NULL)
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to reset failed logins: %w", err)
+	}
+
+	return nil
+}
@@ -12,14 +12,25 @@ import (
 )
 
 type User struct {
-	ID           uuid.UUID `json:"id"`
-	Username     string    `json:"username"`
-	Email        string    `json:"email"`
-	PasswordHash *string   `json:"-"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID              uuid.UUID  `json:"id"`
+	Username        string     `json:"username"`
+	Email           string     `json:"email"`
+	PasswordHash    *string    `json:"-"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
+// Purposes of single-use account tokens.
+const (
+	TokenPurposePasswordReset     = "password_reset"
+	TokenPurposeEmailVerification = "email_verification"
+)
+
+// DeletedUsername replaces the author name on a deleted user's messages. It
+// contains a space, so no account can take it.
+const DeletedUsername = "deleted user"
+
 type UserRepository struct {
 	db *sql.DB
 }
@@ -30,7 +41,7 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 
 func (r *UserRepository) GetUserByID(ctx context.Context, id uuid.UUID) (*User, error) {
 	query := `
-		SELECT id, username, email, password_hash, created_at, updated_at
+		SELECT id, username, email, password_hash, email_verified_at, created_at, updated_at
 		FROM users
 		WHERE id = $1	
 	`
@@ -41,6 +52,7 @@ func (r *UserRepository) GetUserByID(ctx context.Context, id uuid.UUID) (*User,
 		&user.Username,
 		&user.Email,
 		&user.PasswordHash,
+		&user.EmailVerifiedAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -57,7 +69,7 @@ func (r *UserRepository) GetUserByID(ctx context.Context, id uuid.UUID) (*User,
 
 func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*User, error) {
 	query := `
-		SELECT id, username, email, password_hash, created_at, updated_at
+		SELECT id, username, email, password_hash, email_verified_at, created_at, updated_at
 		FROM users
 		WHERE email = $1
 	`
@@ -68,6 +80,7 @@ func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*Use
 		&user.Username,
 		&user.Email,
 		&user.PasswordHash,
+		&user.EmailVerifiedAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -113,7 +126,7 @@ func (r *UserRepository) UpdateUsername(ctx context.Context, id uuid.UUID, usern
 		UPDATE users
 		SET username = $1, updated_at = NOW()
 		WHERE id = $2
-		RETURNING id, username, email, password_hash, created_at, updated_at
+		RETURNING id, username, email, password_hash, email_verified_at, created_at, updated_at
 	`
 
 	var user User
@@ -122,6 +135,7 @@ func (r *UserRepository) UpdateUsername(ctx context.Context, id uuid.UUID, usern
 		&user.Username,
 		&user.Email,
 		&user.PasswordHash,
+		&user.EmailVerifiedAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -140,12 +154,26 @@ func (r *UserRepository) UpdateUsername(ctx context.Context, id uuid.UUID, usern
 }
 
 func (r *UserRepository) DeleteUser(ctx context.Context, id uuid.UUID) error {
-	query := `
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Messages stay in their rooms for everyone else; the foreign key clears
+	// user_id, and the stored username is replaced here.
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE messages
+		SET username = $2
+		WHERE user_id = $1
+	`, id, DeletedUsername); err != nil {
+		return fmt.Errorf("failed to anonymise messages: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, `
 		DELETE FROM users
 		WHERE id = $1
-	`
-
-	result, err := r.db.ExecContext(ctx, query, id)
+	`, id)
 
 	if err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
@@ -160,5 +188,82 @@ func (r *UserRepository) DeleteUser(ctx context.Context, id uuid.UUID) error {
 		return fmt.Errorf("no user found with id: %s", id)
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (r *UserRepository) CreateUserToken(ctx context.Context, userID uuid.UUID, purpose, tokenHash string, expiresAt time.Time) error {
+	query := `
+		WITH superseded AS (
+			DELETE FROM user_tokens
+			WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+		)
+		INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, purpose, tokenHash, expiresAt); err != nil {
+		return fmt.Errorf("failed to create user token: %w", err)
+	}
+
 	return nil
 }
+
+func (r *UserRepository) ResetPassword(ctx context.Context, tokenHash string, passwordHash string) (*uuid.UUID, error) {
+	// The reset link was delivered to the user's inbox, so it also proves
+	// they own the address, and lifts any sign-in lock.
+	query := `
+		WITH consumed AS (
+			UPDATE user_tokens
+			SET used_at = NOW()
+			WHERE token_hash = $1 AND purpose = $3 AND used_at IS NULL AND expires_at > NOW()
+			RETURNING user_id
+		)
+		UPDATE users
+		SET password_hash = $2,
+			email_verified_at = COALESCE(email_verified_at, NOW()),
+			failed_login_attempts = 0,
+			login_locked_until = NULL,
+			updated_at = NOW()
+		FROM consumed
+		WHERE users.id = consumed.user_id
+		RETURNING users.id
+	`
+
+	return r.consumeToken(ctx, query, tokenHash, passwordHash, TokenPurposePasswordReset)
+}
+
+func (r *UserRepository) VerifyEmail(ctx context.Context, tokenHash string) (*uuid.UUID, error) {
+	query := `
+		WITH consumed AS (
+			UPDATE user_tokens
+			SET used_at = NOW()
+			WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+			RETURNING user_id
+		)
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, NOW()),
+			updated_at = NOW()
+		FROM consumed
+		WHERE users.id = consumed.user_id
+		RETURNING users.id
+	`
+
+	return r.consumeToken(ctx, query, tokenHash, TokenPurposeEmailVerification)
+}
+
+func (r *UserRepository) consumeToken(ctx context.Context, query string, args ...any) (*uuid.UUID, error) {
+	var userID uuid.UUID
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Token unknown, used or expired
+		}
+		return nil, fmt.Errorf("failed to consume user token: %w", err)
+	}
+
+	return &userID, nil
+}
@@ -0,0 +1,351 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"chat-application/internal/api/model"
+	"chat-application/internal/constants"
+	attachmentRepository "chat-application/internal/repo/attachment"
+	roomRepository "chat-application/internal/repo/room"
+	"chat-application/internal/storage"
+	"chat-application/util"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrEmptyFile           = errors.New("file is empty")
+	ErrFileTooLarge        = errors.New("file exceeds the maximum attachment size")
+	ErrUnsupportedFileType = errors.New("file type is not allowed")
+	ErrNotRoomMember       = errors.New("you must be a member of this room to upload files")
+	ErrAttachmentNotFound  = errors.New("attachment not found")
+	ErrAttachmentForbidden = errors.New("this attachment was posted in an invite-only room")
+)
+
+// UploadInput describes a file being uploaded to a room.
+type UploadInput struct {
+	RoomID     uuid.UUID
+	UploaderID uuid.UUID
+	Filename   string
+	Body       io.Reader
+}
+
+// AttachmentsService validates uploads, stores them in blob storage and keeps
+// attachment metadata in sync with the messages that reference it.
+type AttachmentsService struct {
+	attachmentRepo attachmentRepository.AttachmentRepositoryInterface
+	roomRepo       roomRepository.RoomRepositoryInterface
+	storage        storage.Storage
+	maxSize        int64
+}
+
+// NewAttachmentsService creates a new AttachmentsService instance.
+func NewAttachmentsService(
+	attachmentRepo attachmentRepository.AttachmentRepositoryInterface,
+	roomRepo roomRepository.RoomRepositoryInterface,
+	store storage.Storage,
+	maxSize int64,
+) *AttachmentsService {
+	if maxSize <= 0 {
+		maxSize = constants.MaxAttachmentSize
+	}
+
+	return &AttachmentsService{
+		attachmentRepo: attachmentRepo,
+		roomRepo:       roomRepo,
+		storage:        store,
+		maxSize:        maxSize,
+	}
+}
+
+// MaxSize returns the largest accepted upload in bytes.
+func (s *AttachmentsService) MaxSize() int64 {
+	return s.maxSize
+}
+
+func (s *AttachmentsService) Upload(ctx context.Context, in UploadInput) (*model.AttachmentRes, error) {
+	member, err := s.roomRepo.GetRoomMember(ctx, in.RoomID, in.UploaderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load room membership: %w", err)
+	}
+	if member == nil || member.BannedAt != nil || !member.CanPost {
+		return nil, ErrNotRoomMember
+	}
+
+	data, err := io.ReadAll(io.LimitReader(in.Body, s.maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, ErrEmptyFile
+	}
+	if int64(len(data)) > s.maxSize {
+		return nil, ErrFileTooLarge
+	}
+
+	contentType := detectContentType(data)
+	if !constants.IsAllowedAttachmentType(contentType) {
+		return nil, ErrUnsupportedFileType
+	}
+
+	attachmentID := uuid.New()
+	attachment := &attachmentRepository.Attachment{
+		ID:          attachmentID,
+		RoomID:      &in.RoomID,
+		UploaderID:  &in.UploaderID,
+		Filename:    sanitizeFilename(in.Filename),
+		ContentType: contentType,
+		SizeBytes:   int64(len(data)),
+		StorageKey:  fmt.Sprintf("rooms/%s/%s", in.RoomID, attachmentID),
+	}
+
+	var thumb *thumbnail
+	if isThumbnailable(contentType) {
+		width, height, err := imageDimensions(data)
+		if err != nil {
+			return nil, ErrUnsupportedFileType
+		}
+		attachment.Width = &width
+		attachment.Height = &height
+
+		thumb, err = generateThumbnail(data, constants.ThumbnailMaxDimension)
+		if err != nil {
+			// The original is still usable, so a failed thumbnail only degrades the preview.
+			log.Printf("AttachmentsService.Upload - Thumbnail generation failed for %s: %v", attachmentID, err)
+			thumb = nil
+		}
+	}
+
+	if err := s.storage.Put(ctx, attachment.StorageKey, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		return nil, fmt.Errorf("failed to store attachment: %w", err)
+	}
+
+	if thumb != nil {
+		thumbnailKey := attachment.StorageKey + "_thumb"
+		if err := s.storage.Put(ctx, thumbnailKey, bytes.NewReader(thumb.Data), int64(len(thumb.Data)), thumb.ContentType); err != nil {
+			log.Printf("AttachmentsService.Upload - Failed to store thumbnail for %s: %v", attachmentID, err)
+		} else {
+			attachment.ThumbnailKey = &thumbnailKey
+		}
+	}
+
+	created, err := s.attachmentRepo.CreateAttachment(ctx, attachment)
+	if err != nil {
+		s.deleteBlobs(ctx, *attachment)
+		return nil, err
+	}
+
+	res := ToResponse(created)
+	return &res, nil
+}
+
+// Open returns an attachment together with a reader for its original file or thumbnail.
+// viewerID is the signed-in user, or nil for guests. The caller must close the reader.
+func (s *AttachmentsService) Open(ctx context.Context, id uuid.UUID, viewerID *uuid.UUID, thumbnail bool) (*attachmentRepository.Attachment, io.ReadCloser, error) {
+	attachment, err := s.attachmentRepo.GetAttachmentByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if attachment == nil || attachment.RoomID == nil {
+		return nil, nil, ErrAttachmentNotFound
+	}
+	if err := s.checkAccess(ctx, attachment, viewerID); err != nil {
+		return nil, nil, err
+	}
+
+	key := attachment.StorageKey
+	if thumbnail {
+		if attachment.ThumbnailKey == nil {
+			return nil, nil, ErrAttachmentNotFound
+		}
+		key = *attachment.ThumbnailKey
+	}
+
+	reader, err := s.storage.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil, ErrAttachmentNotFound
+		}
+		return nil, nil, err
+	}
+
+	return attachment, reader, nil
+}
+
+// checkAccess applies the room's visibility to its attachments: files in an
+// invite-only room are only served to members who are not banned. Room emoji
+// are shown wherever their reactions are, so they are served to anyone.
+func (s *AttachmentsService) checkAccess(ctx context.Context, attachment *attachmentRepository.Attachment, viewerID *uuid.UUID) error {
+	room, err := s.roomRepo.GetRoomByID(ctx, *attachment.RoomID)
+	if err != nil {
+		return fmt.Errorf("failed to load room: %w", err)
+	}
+	if room == nil {
+		return ErrAttachmentNotFound
+	}
+	if room.Visibility != constants.RoomVisibilityInviteOnly {
+		return nil
+	}
+
+	emoji, err := s.attachmentRepo.IsRoomEmoji(ctx, attachment.ID)
+	if err != nil {
+		return err
+	}
+	if emoji {
+		return nil
+	}
+	if viewerID == nil {
+		return ErrAttachmentForbidden
+	}
+	member, err := s.roomRepo.GetRoomMember(ctx, room.ID, *viewerID)
+	if err != nil {
+		return fmt.Errorf("failed to load room membership: %w", err)
+	}
+	if member == nil || member.BannedAt != nil {
+		return ErrAttachmentForbidden
+	}
+	return nil
+}
+
+// ResolveForMessage returns the metadata for attachments a user uploaded to a room
+// and has not yet sent. Unknown or foreign IDs are silently dropped.
+func (s *AttachmentsService) ResolveForMessage(ctx context.Context, roomID, userID uuid.UUID, ids []uuid.UUID) ([]model.AttachmentRes, error) {
+	if len(ids) > constants.MaxAttachmentsPerMessage {
+		ids = ids[:constants.MaxAttachmentsPerMessage]
+	}
+
+	attachments, err := s.attachmentRepo.GetPendingAttachments(ctx, roomID, userID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]model.AttachmentRes, 0, len(attachments))
+	for i := range attachments {
+		res = append(res, ToResponse(&attachments[i]))
+	}
+	return res, nil
+}
+
+// AssignToMessage marks attachments as sent in a persisted message.
+func (s *AttachmentsService) AssignToMessage(ctx context.Context, messageID uuid.UUID, ids []uuid.UUID) error {
+	return s.attachmentRepo.AssignToMessage(ctx, messageID, ids)
+}
+
+// PurgeOrphaned removes blobs and rows for attachments whose room was deleted
+// (for example by DeleteExpiredRooms) or that were uploaded but never sent.
+func (s *AttachmentsService) PurgeOrphaned(ctx context.Context) (int, error) {
+	cutoff := time.Now().Add(-constants.AttachmentUnsentTTL)
+	purged := 0
+
+	for {
+		orphans, err := s.attachmentRepo.GetOrphanedAttachments(ctx, cutoff, 100)
+		if err != nil {
+			return purged, err
+		}
+		if len(orphans) == 0 {
+			return purged, nil
+		}
+
+		ids := make([]uuid.UUID, 0, len(orphans))
+		for _, orphan := range orphans {
+			if err := s.deleteBlobs(ctx, orphan); err != nil {
+				// Keep the row so the next sweep retries the blob deletion.
+				log.Printf("AttachmentsService.PurgeOrphaned - Failed to delete blobs for %s: %v", orphan.ID, err)
+				continue
+			}
+			ids = append(ids, orphan.ID)
+		}
+
+		if len(ids) == 0 {
+			return purged, nil
+		}
+		if err := s.attachmentRepo.DeleteAttachments(ctx, ids); err != nil {
+			return purged, err
+		}
+		purged += len(ids)
+
+		if len(orphans) < 100 {
+			return purged, nil
+		}
+	}
+}
+
+func (s *AttachmentsService) deleteBlobs(ctx context.Context, attachment attachmentRepository.Attachment) error {
+	if err := s.storage.Delete(ctx, attachment.StorageKey); err != nil {
+		return err
+	}
+	if attachment.ThumbnailKey != nil {
+		if err := s.storage.Delete(ctx, *attachment.ThumbnailKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ToResponse converts a stored attachment into its API representation.
+func ToResponse(attachment *attachmentRepository.Attachment) model.AttachmentRes {
+	res := model.AttachmentRes{
+		ID:          attachment.ID.String(),
+		Filename:    attachment.Filename,
+		ContentType: attachment.ContentType,
+		Size:        attachment.SizeBytes,
+		URL:         "/api/attachments/" + attachment.ID.String(),
+		CreatedAt:   attachment.CreatedAt,
+	}
+	if attachment.Width != nil {
+		res.Width = *attachment.Width
+	}
+	if attachment.Height != nil {
+		res.Height = *attachment.Height
+	}
+	if attachment.ThumbnailKey != nil {
+		res.ThumbnailURL = res.URL + "/thumbnail"
+	}
+	return res
+}
+
+// detectContentType sniffs the content type from the file itself rather than
+// trusting the client-supplied header or file extension.
+func detectContentType(data []byte) string {
+	contentType := http.DetectContentType(data)
+	if idx := strings.Index(contentType, ";"); idx >= 0 {
+		contentType = contentType[:idx]
+	}
+	return strings.TrimSpace(contentType)
+}
+
+func isThumbnailable(contentType string) bool {
+	switch contentType {
+	case "image/png", "image/jpeg", "image/gif":
+		return true
+	}
+	return false
+}
+
+func sanitizeFilename(name string) string {
+	name = util.SanitizeString(filepath.Base(strings.ReplaceAll(name, "\\", "/")))
+	name = strings.Map(func(r rune) rune {
+		if r == '"' || r == '\n' || r == '\t' {
+			return '_'
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == "/" {
+		name = "file"
+	}
+	for len(name) > 255 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
@@ -0,0 +1,322 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"chat-application/internal/constants"
+	attachmentRepository "chat-application/internal/repo/attachment"
+	roomRepository "chat-application/internal/repo/room"
+	"chat-application/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+type fakeAttachmentRepository struct {
+	created []*attachmentRepository.Attachment
+	emojis  map[uuid.UUID]bool
+}
+
+func (f *fakeAttachmentRepository) CreateAttachment(ctx context.Context, attachment *attachmentRepository.Attachment) (*attachmentRepository.Attachment, error) {
+	attachment.CreatedAt = time.Now()
+	f.created = append(f.created, attachment)
+	return attachment, nil
+}
+func (f *fakeAttachmentRepository) GetAttachmentByID(ctx context.Context, id uuid.UUID) (*attachmentRepository.Attachment, error) {
+	for _, attachment := range f.created {
+		if attachment.ID == id {
+			return attachment, nil
+		}
+	}
+	return nil, nil
+}
+func (f *fakeAttachmentRepository) IsRoomEmoji(ctx context.Context, id uuid.UUID) (bool, error) {
+	return f.emojis[id], nil
+}
+func (f *fakeAttachmentRepository) GetPendingAttachments(ctx context.Context, roomID, uploaderID uuid.UUID, ids []uuid.UUID) ([]attachmentRepository.Attachment, error) {
+	return nil, nil
+}
+func (f *fakeAttachmentRepository) AssignToMessage(ctx context.Context, messageID uuid.UUID, ids []uuid.UUID) error {
+	return nil
+}
+func (f *fakeAttachmentRepository) GetOrphanedAttachments(ctx context.Context, unsentBefore time.Time, limit int) ([]attachmentRepository.Attachment, error) {
+	var orphans []attachmentRepository.Attachment
+	for _, attachment := range f.created {
+		if attachment.RoomID == nil {
+			orphans = append(orphans, *attachment)
+		}
+	}
+	return orphans, nil
+}
+func (f *fakeAttachmentRepository) DeleteAttachments(ctx context.Context, ids []uuid.UUID) error {
+	remaining := f.created[:0]
+	for _, attachment := range f.created {
+		deleted := false
+		for _, id := range ids {
+			if attachment.ID == id {
+				deleted = true
+			}
+		}
+		if !deleted {
+			remaining = append(remaining, attachment)
+		}
+	}
+	f.created = remaining
+	return nil
+}
+
+// fakeRoomRepository only implements the room and membership lookups used
+// by uploads and downloads.
+type fakeRoomRepository struct {
+	roomRepository.RoomRepositoryInterface
+	room   *roomRepository.Room
+	member *roomRepository.RoomMember
+}
+
+func (f *fakeRoomRepository) GetRoomByID(ctx context.Context, roomID uuid.UUID) (*roomRepository.Room, error) {
+	return f.room, nil
+}
+
+func (f *fakeRoomRepository) GetRoomMember(ctx context.Context, roomID, userID uuid.UUID) (*roomRepository.RoomMember, error) {
+	return f.member, nil
+}
+
+func newTestService(t *testing.T, member *roomRepository.RoomMember, maxSize int64) (*AttachmentsService, *fakeAttachmentRepository, storage.Storage) {
+	t.Helper()
+
+	store, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	repo := &fakeAttachmentRepository{}
+	return NewAttachmentsService(repo, &fakeRoomRepository{member: member}, store, maxSize), repo, store
+}
+
+func encodeTestPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("failed to encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestUploadImageStoresOriginalAndThumbnail(t *testing.T) {
+	service, repo, store := newTestService(t, &roomRepository.RoomMember{CanPost: true}, 1<<20)
+
+	res, err := service.Upload(context.Background(), UploadInput{
+		RoomID:     uuid.New(),
+		UploaderID: uuid.New(),
+		Filename:   "../../holiday.png",
+		Body:       bytes.NewReader(encodeTestPNG(t, 800, 400)),
+	})
+	if err != nil {
+		t.Fatalf("expected upload to succeed, got %v", err)
+	}
+
+	if res.ContentType != "image/png" || res.Width != 800 || res.Height != 400 {
+		t.Fatalf("unexpected attachment metadata: %+v", res)
+	}
+	if res.Filename != "holiday.png" {
+		t.Fatalf("expected sanitised filename, got %q", res.Filename)
+	}
+	if res.ThumbnailURL == "" {
+		t.Fatal("expected thumbnail URL for image upload")
+	}
+
+	created := repo.created[0]
+	reader, err := store.Get(context.Background(), *created.ThumbnailKey)
+	if err != nil {
+		t.Fatalf("expected thumbnail to be stored: %v", err)
+	}
+	defer reader.Close()
+	data, _ := io.ReadAll(reader)
+
+	width, height, err := imageDimensions(data)
+	if err != nil {
+		t.Fatalf("failed to decode thumbnail: %v", err)
+	}
+	if width != 320 || height != 160 {
+		t.Fatalf("expected 320x160 thumbnail, got %dx%d", width, height)
+	}
+}
+
+func TestUploadRejectsInvalidFiles(t *testing.T) {
+	tests := []struct {
+		name   string
+		member *roomRepository.RoomMember
+		body   []byte
+		want   error
+	}{
+		{"too large", &roomRepository.RoomMember{CanPost: true}, bytes.Repeat([]byte("a"), 2048), ErrFileTooLarge},
+		{"disallowed type", &roomRepository.RoomMember{CanPost: true}, []byte("<html><script>alert(1)</script></html>"), ErrUnsupportedFileType},
+		{"empty", &roomRepository.RoomMember{CanPost: true}, nil, ErrEmptyFile},
+		{"not a member", nil, []byte("hello"), ErrNotRoomMember},
+		{"banned", &roomRepository.RoomMember{CanPost: true, BannedAt: &time.Time{}}, []byte("hello"), ErrNotRoomMember},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, repo, _ := newTestService(t, tt.member, 1024)
+
+			_, err := service.Upload(context.Background(), UploadInput{
+				RoomID:     uuid.New(),
+				UploaderID: uuid.New(),
+				Filename:   "file",
+				Body:       bytes.NewReader(tt.body),
+			})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+			if len(repo.created) != 0 {
+				t.Fatal("expected no attachment to be recorded")
+			}
+		})
+	}
+}
+
+func TestOpenRequiresMembershipOfInviteOnlyRooms(t *testing.T) {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	repo := &fakeAttachmentRepository{emojis: map[uuid.UUID]bool{}}
+	rooms := &fakeRoomRepository{member: &roomRepository.RoomMember{CanPost: true}}
+	service := NewAttachmentsService(repo, rooms, store, 1<<20)
+
+	roomID, memberID := uuid.New(), uuid.New()
+	upload := func(filename string) uuid.UUID {
+		res, err := service.Upload(context.Background(), UploadInput{RoomID: roomID, UploaderID: memberID, Filename: filename, Body: bytes.NewReader(encodeTestPNG(t, 8, 8))})
+		if err != nil {
+			t.Fatalf("upload failed: %v", err)
+		}
+		id, _ := uuid.Parse(res.ID)
+		return id
+	}
+	photoID, emojiID := upload("photo.png"), upload("party.png")
+	repo.emojis[emojiID] = true
+	rooms.room = &roomRepository.Room{ID: roomID, Visibility: constants.RoomVisibilityInviteOnly}
+
+	open := func(id uuid.UUID, viewerID *uuid.UUID) error {
+		_, reader, err := service.Open(context.Background(), id, viewerID, false)
+		if err == nil {
+			reader.Close()
+		}
+		return err
+	}
+
+	if err := open(photoID, &memberID); err != nil {
+		t.Fatalf("expected a member to open the file, got %v", err)
+	}
+	outsiderID := uuid.New()
+	rooms.member = nil
+	if err := open(photoID, &outsiderID); !errors.Is(err, ErrAttachmentForbidden) {
+		t.Fatalf("expected a non-member to be refused, got %v", err)
+	}
+	if err := open(photoID, nil); !errors.Is(err, ErrAttachmentForbidden) {
+		t.Fatalf("expected a guest to be refused, got %v", err)
+	}
+	if err := open(emojiID, nil); err != nil {
+		t.Fatalf("expected room emoji to stay public, got %v", err)
+	}
+
+	rooms.room.Visibility = constants.RoomVisibilityPublic
+	if err := open(photoID, nil); err != nil {
+		t.Fatalf("expected files in public rooms to be served to anyone, got %v", err)
+	}
+}
+
+func TestPurgeOrphanedDeletesBlobs(t *testing.T) {
+	service, repo, store := newTestService(t, &roomRepository.RoomMember{CanPost: true}, 1<<20)
+
+	if _, err := service.Upload(context.Background(), UploadInput{
+		RoomID:     uuid.New(),
+		UploaderID: uuid.New(),
+		Filename:   "notes.txt",
+		Body:       bytes.NewReader([]byte("meeting notes")),
+	}); err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+
+	// Simulate DeleteExpiredRooms detaching the attachment from its room.
+	key := repo.created[0].StorageKey
+	repo.created[0].RoomID = nil
+
+	purged, err := service.PurgeOrphaned(context.Background())
+	if err != nil {
+		t.Fatalf("purge failed: %v", err)
+	}
+	if purged != 1 || len(repo.created) != 0 {
+		t.Fatalf("expected one purged attachment, got %d (remaining %d)", purged, len(repo.created))
+	}
+	if _, err := store.Get(context.Background(), key); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected blob to be deleted, got %v", err)
+	}
+}
+
+func TestPutAvatarCropsAndResizes(t *testing.T) {
+	service, _, _ := newTestService(t, nil, 1<<20)
+	userID := uuid.New()
+
+	key, err := service.PutAvatar(context.Background(), userID, bytes.NewReader(encodeTestPNG(t, 800, 400)))
+	if err != nil {
+		t.Fatalf("expected avatar upload to succeed, got %v", err)
+	}
+	if !strings.HasPrefix(key, "avatars/"+userID.String()+"/") || !strings.HasSuffix(key, ".png") {
+		t.Fatalf("unexpected avatar key %q", key)
+	}
+
+	reader, contentType, err := service.OpenAvatar(context.Background(), key)
+	if err != nil {
+		t.Fatalf("expected avatar to be stored: %v", err)
+	}
+	defer reader.Close()
+	data, _ := io.ReadAll(reader)
+
+	if contentType != "image/png" {
+		t.Fatalf("expected image/png, got %q", contentType)
+	}
+	width, height, err := imageDimensions(data)
+	if err != nil {
+		t.Fatalf("failed to decode avatar: %v", err)
+	}
+	if width != 256 || height != 256 {
+		t.Fatalf("expected 256x256 avatar, got %dx%d", width, height)
+	}
+
+	if err := service.DeleteAvatar(context.Background(), key); err != nil {
+		t.Fatalf("failed to delete avatar: %v", err)
+	}
+	if _, _, err := service.OpenAvatar(context.Background(), key); !errors.Is(err, ErrAvatarNotFound) {
+		t.Fatalf("expected deleted avatar to be gone, got %v", err)
+	}
+}
+
+func TestPutAvatarRejectsNonImages(t *testing.T) {
+	service, _, _ := newTestService(t, nil, 1<<20)
+
+	for _, body := range [][]byte{nil, []byte("%PDF-1.4 not an image")} {
+		if _, err := service.PutAvatar(context.Background(), uuid.New(), bytes.NewReader(body)); err == nil {
+			t.Fatalf("expected %q to be rejected", body)
+		}
+	}
+	if _, _, err := service.OpenAvatar(context.Background(), "rooms/some/attachment"); !errors.Is(err, ErrAvatarNotFound) {
+		t.Fatalf("expected attachment keys to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strings"
+
+	"chat-application/internal/constants"
+	"chat-application/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+var ErrAvatarNotFound = errors.New("avatar not found")
+
+// PutAvatar crops an uploaded image to a square, scales it to
+// constants.AvatarDimension and stores it. It returns the storage key; each
+// upload gets a new key so cached copies of the old avatar are never served.
+func (s *AttachmentsService) PutAvatar(ctx context.Context, userID uuid.UUID, body io.Reader) (string, error) {
+	data, err := io.ReadAll(io.LimitReader(body, constants.MaxAvatarSize+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read upload: %w", err)
+	}
+	if len(data) == 0 {
+		return "", ErrEmptyFile
+	}
+	if len(data) > constants.MaxAvatarSize {
+		return "", ErrFileTooLarge
+	}
+	if !isThumbnailable(detectContentType(data)) {
+		return "", ErrUnsupportedFileType
+	}
+
+	avatar, err := generateAvatar(data, constants.AvatarDimension)
+	if err != nil {
+		return "", ErrUnsupportedFileType
+	}
+
+	extension := ".jpg"
+	if avatar.ContentType == "image/png" {
+		extension = ".png"
+	}
+	key := fmt.Sprintf("avatars/%s/%s%s", userID, uuid.New(), extension)
+	if err := s.storage.Put(ctx, key, bytes.NewReader(avatar.Data), int64(len(avatar.Data)), avatar.ContentType); err != nil {
+		return "", fmt.Errorf("failed to store avatar: %w", err)
+	}
+	return key, nil
+}
+
+// OpenAvatar returns a reader for a stored avatar and its content type. The
+// caller must close the reader.
+func (s *AttachmentsService) OpenAvatar(ctx context.Context, key string) (io.ReadCloser, string, error) {
+	if !strings.HasPrefix(key, "avatars/") {
+		return nil, "", ErrAvatarNotFound
+	}
+	reader, err := s.storage.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, "", ErrAvatarNotFound
+		}
+		return nil, "", err
+	}
+
+	contentType := "image/jpeg"
+	if strings.HasSuffix(key, ".png") {
+		contentType = "image/png"
+	}
+	return reader, contentType, nil
+}
+
+// DeleteAvatar removes a stored avatar.
+func (s *AttachmentsService) DeleteAvatar(ctx context.Context, key string) error {
+	if !strings.HasPrefix(key, "avatars/") {
+		return nil
+	}
+	return s.storage.Delete(ctx, key)
+}
+
+// generateAvatar crops the centre square of an image and scales it down to
+// size. Like thumbnails, avatars are always re-encoded.
+func generateAvatar(data []byte, size int) (*thumbnail, error) {
+	width, height, err := imageDimensions(data)
+	if err != nil {
+		return nil, err
+	}
+	if width <= 0 || height <= 0 || width*height > maxDecodePixels {
+		return nil, fmt.Errorf("image dimensions %dx%d are not supported", width, height)
+	}
+
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	bounds := src.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	x0 := bounds.Min.X + (bounds.Dx()-side)/2
+	y0 := bounds.Min.Y + (bounds.Dy()-side)/2
+	square := croppedImage{Image: src, rect: image.Rect(x0, y0, x0+side, y0+side)}
+
+	target := min(side, size)
+	scaled := scaleImage(square, target, target)
+
+	var out bytes.Buffer
+	contentType := "image/jpeg"
+	if format == "png" || format == "gif" {
+		contentType = "image/png"
+		err = png.Encode(&out, scaled)
+	} else {
+		err = jpeg.Encode(&out, scaled, &jpeg.Options{Quality: 85})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode avatar: %w", err)
+	}
+
+	return &thumbnail{
+		Data:        out.Bytes(),
+		ContentType: contentType,
+		Width:       target,
+		Height:      target,
+	}, nil
+}
+
+// croppedImage narrows the bounds of an image without copying its pixels.
+type croppedImage struct {
+	image.Image
+	rect image.Rectangle
+}
+
+func (c croppedImage) Bounds() image.Rectangle {
+	return c.rect
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+
+	_ "image/gif"
+)
+
+// maxDecodePixels guards against decompression bombs: images whose headers claim
+// dimensions that would need far more memory than the compressed upload suggests.
+const maxDecodePixels = 40_000_000
+
+type thumbnail struct {
+	Data        []byte
+	ContentType string
+	Width       int
+	Height      int
+}
+
+// imageDimensions reads the dimensions of an image without decoding its pixels.
+func imageDimensions(data []byte) (int, int, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read image header: %w", err)
+	}
+	return cfg.Width, cfg.Height, nil
+}
+
+// generateThumbnail scales an image down to fit within maxDimension on both sides.
+// Images that already fit are re-encoded at their original size so that the
+// thumbnail never carries metadata from the original upload.
+func generateThumbnail(data []byte, maxDimension int) (*thumbnail, error) {
+	width, height, err := imageDimensions(data)
+	if err != nil {
+		return nil, err
+	}
+	if width <= 0 || height <= 0 || width*height > maxDecodePixels {
+		return nil, fmt.Errorf("image dimensions %dx%d are not supported", width, height)
+	}
+
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	targetWidth, targetHeight := fitWithin(width, height, maxDimension)
+	scaled := scaleImage(src, targetWidth, targetHeight)
+
+	var out bytes.Buffer
+	contentType := "image/jpeg"
+	if format == "png" || format == "gif" {
+		// Keep transparency for formats that commonly use it.
+		contentType = "image/png"
+		err = png.Encode(&out, scaled)
+	} else {
+		err = jpeg.Encode(&out, scaled, &jpeg.Options{Quality: 80})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+
+	return &thumbnail{
+		Data:        out.Bytes(),
+		ContentType: contentType,
+		Width:       targetWidth,
+		Height:      targetHeight,
+	}, nil
+}
+
+func fitWithin(width, height, maxDimension int) (int, int) {
+	if width <= maxDimension && height <= maxDimension {
+		return width, height
+	}
+	if width >= height {
+		return maxDimension, max(1, height*maxDimension/width)
+	}
+	return max(1, width*maxDimension/height), maxDimension
+}
+
+// scaleImage resizes src using box filtering, averaging every source pixel that
+// falls inside each destination pixel. It is only used for downscaling.
+func scaleImage(src image.Image, width, height int) *image.RGBA {
+	bounds := src.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+
+	for y := 0; y < height; y++ {
+		y0 := y * srcHeight / height
+		y1 := max(y0+1, (y+1)*srcHeight/height)
+		for x := 0; x < width; x++ {
+			x0 := x * srcWidth / width
+			x1 := max(x0+1, (x+1)*srcWidth/width)
+
+			var r, g, b, a, count uint64
+			for sy := y0; sy < y1; sy++ {
+				offset := rgba.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint64(rgba.Pix[offset])
+					g += uint64(rgba.Pix[offset+1])
+					b += uint64(rgba.Pix[offset+2])
+					a += uint64(rgba.Pix[offset+3])
+					offset += 4
+					count++
+				}
+			}
+
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / count),
+				G: uint8(g / count),
+				B: uint8(b / count),
+				A: uint8(a / count),
+			})
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	htmlTemplate "html/template"
+	"log"
+	"net/url"
+	"strings"
+	textTemplate "text/template"
+	"time"
+
+	"chat-application/internal/constants"
+	"chat-application/internal/mailer"
+	roomRepository "chat-application/internal/repo/room"
+
+	"github.com/google/uuid"
+)
+
+//go:embed templates
+var templateFiles embed.FS
+
+var (
+	textDigest = textTemplate.Must(textTemplate.ParseFS(templateFiles, "templates/digest.txt"))
+	htmlDigest = htmlTemplate.Must(htmlTemplate.ParseFS(templateFiles, "templates/digest.html"))
+)
+
+// Repository is the storage the digest worker needs.
+type Repository interface {
+	GetPendingDigests(ctx context.Context, cutoff time.Time, perUser int) ([]roomRepository.DigestRecipient, error)
+	MarkNotificationsEmailed(ctx context.Context, userID uuid.UUID, cutoff time.Time) error
+	SetEmailDigest(ctx context.Context, userID uuid.UUID, enabled bool) error
+}
+
+// Presence reports whether a user currently has the app open.
+type Presence interface {
+	IsUserOnline(userID string) bool
+}
+
+// Config controls when digests are sent and where their links point.
+type Config struct {
+	// Interval between digest runs; zero disables the worker.
+	Interval time.Duration
+	// Delay is how old a notification must be before it is emailed.
+	Delay        time.Duration
+	MaxPerDigest int
+	// AppURL is the web client, used for room links; APIURL is this server,
+	// used for unsubscribe links.
+	AppURL string
+	APIURL string
+	// Secret signs unsubscribe links.
+	Secret []byte
+}
+
+// DefaultConfig returns the default schedule without URLs or a secret.
+func DefaultConfig() Config {
+	return Config{
+		Interval:     constants.DefaultDigestInterval,
+		Delay:        constants.DigestDelay,
+		MaxPerDigest: constants.DigestMaxNotifications,
+	}
+}
+
+// DigestService emails users a summary of the notifications they have not read.
+type DigestService struct {
+	repository Repository
+	mailer     mailer.Mailer
+	config     Config
+	now        func() time.Time
+	// Presence is optional; users who are online are skipped until a later run.
+	Presence Presence
+}
+
+func NewDigestService(repository Repository, mailer mailer.Mailer, config Config) *DigestService {
+	config.AppURL = strings.TrimRight(config.AppURL, "/")
+	config.APIURL = strings.TrimRight(config.APIURL, "/")
+	return &DigestService{
+		repository: repository,
+		mailer:     mailer,
+		config:     config,
+		now:        time.Now,
+	}
+}
+
+// Start sends digests every Interval until ctx is cancelled.
+func (s *DigestService) Start(ctx context.Context) {
+	if s.config.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sent, err := s.SendDigests(ctx)
+			if err != nil {
+				log.Printf("DigestService.Start - failed to send digests: %v", err)
+			} else if sent > 0 {
+				log.Printf("DigestService.Start - sent %d email digests", sent)
+			}
+		}
+	}
+}
+
+// SendDigests emails every user with pending notifications older than Delay
+// and returns how many digests were sent. A failed delivery is logged and
+// retried on the next run.
+func (s *DigestService) SendDigests(ctx context.Context) (int, error) {
+	cutoff := s.now().UTC().Add(-s.config.Delay)
+	recipients, err := s.repository.GetPendingDigests(ctx, cutoff, s.config.MaxPerDigest)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, recipient := range recipients {
+		if s.Presence != nil && s.Presence.IsUserOnline(recipient.UserID.String()) {
+			continue
+		}
+
+		msg, err := s.render(recipient)
+		if err != nil {
+			return sent, err
+		}
+
+		sendCtx, cancel := context.WithTimeout(ctx, constants.DigestSendTimeout)
+		err = s.mailer.Send(sendCtx, msg)
+		cancel()
+		if err != nil {
+			log.Printf("DigestService.SendDigests - failed to email user %s: %v", recipient.UserID, err)
+			continue
+		}
+
+		if err := s.repository.MarkNotificationsEmailed(ctx, recipient.UserID, cutoff); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+type digestItem struct {
+	Title string
+	Body  string
+	Link  string
+}
+
+type digestData struct {
+	Username       string
+	Pending        int
+	More           int
+	Items          []digestItem
+	AppURL         string
+	UnsubscribeURL string
+}
+
+func (s *DigestService) render(recipient roomRepository.DigestRecipient) (mailer.Message, error) {
+	data := digestData{
+		Username:       recipient.Username,
+		Pending:        recipient.Pending,
+		More:           recipient.Pending - len(recipient.Notifications),
+		AppURL:         s.config.AppURL,
+		UnsubscribeURL: s.UnsubscribeURL(recipient.UserID),
+	}
+	for _, notification := range recipient.Notifications {
+		item := digestItem{Title: notification.Title, Body: notification.Body}
+		if notification.RoomID != nil && s.config.AppURL != "" {
+			item.Link = s.config.AppURL + "/room/" + notification.RoomID.String()
+		}
+		data.Items = append(data.Items, item)
+	}
+
+	var text, html bytes.Buffer
+	if err := textDigest.Execute(&text, data); err != nil {
+		return mailer.Message{}, fmt.Errorf("failed to render digest: %w", err)
+	}
+	if err := htmlDigest.Execute(&html, data); err != nil {
+		return mailer.Message{}, fmt.Errorf("failed to render digest: %w", err)
+	}
+
+	subject := fmt.Sprintf("You have %d unread notifications", recipient.Pending)
+	if recipient.Pending == 1 {
+		subject = "You have 1 unread notification"
+	}
+
+	return mailer.Message{
+		To:      recipient.Email,
+		Subject: subject,
+		Text:    text.String(),
+		HTML:    html.String(),
+		Headers: map[string]string{
+			"List-Unsubscribe": "<" + data.UnsubscribeURL + ">",
+		},
+	}, nil
+}
+
+// UnsubscribeURL returns the signed link that turns off the user's digests.
+func (s *DigestService) UnsubscribeURL(userID uuid.UUID) string {
+	query := url.Values{"token": {SignUnsubscribeToken(s.config.Secret, userID)}}
+	return s.config.APIURL + "/api/websoc/notifications/unsubscribe?" + query.Encode()
+}
+
+// Unsubscribe turns off email digests for the user named by a signed token.
+func (s *DigestService) Unsubscribe(ctx context.Context, token string) error {
+	userID, err := VerifyUnsubscribeToken(s.config.Secret, token)
+	if err != nil {
+		return err
+	}
+	return s.repository.SetEmailDigest(ctx, userID, false)
+}
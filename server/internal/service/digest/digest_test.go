@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"chat-application/internal/mailer"
+	roomRepository "chat-application/internal/repo/room"
+
+	"github.com/google/uuid"
+)
+
+type fakeRepository struct {
+	recipients []roomRepository.DigestRecipient
+	emailed    []uuid.UUID
+	cutoff     time.Time
+	disabled   []uuid.UUID
+}
+
+func (f *fakeRepository) GetPendingDigests(ctx context.Context, cutoff time.Time, perUser int) ([]roomRepository.DigestRecipient, error) {
+	f.cutoff = cutoff
+	return f.recipients, nil
+}
+
+func (f *fakeRepository) MarkNotificationsEmailed(ctx context.Context, userID uuid.UUID, cutoff time.Time) error {
+	f.emailed = append(f.emailed, userID)
+	return nil
+}
+
+func (f *fakeRepository) SetEmailDigest(ctx context.Context, userID uuid.UUID, enabled bool) error {
+	if !enabled {
+		f.disabled = append(f.disabled, userID)
+	}
+	return nil
+}
+
+type fakePresence map[string]bool
+
+func (p fakePresence) IsUserOnline(userID string) bool {
+	return p[userID]
+}
+
+func TestSendDigestsEmailsOfflineUsersAndUnsubscribes(t *testing.T) {
+	roomID := uuid.New()
+	alice := uuid.New()
+	bob := uuid.New()
+	now := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+
+	repo := &fakeRepository{recipients: []roomRepository.DigestRecipient{
+		{
+			UserID:   alice,
+			Username: "alice",
+			Email:    "alice@example.com",
+			Pending:  3,
+			Notifications: []roomRepository.Notification{
+				{UserID: alice, RoomID: &roomID, Title: "You were mentioned", Body: "bob mentioned you in <b>hi</b>"},
+				{UserID: alice, Title: "New upvote", Body: "bob upvoted you"},
+			},
+		},
+		{UserID: bob, Username: "bob", Email: "bob@example.com", Pending: 1, Notifications: []roomRepository.Notification{{UserID: bob, Title: "New reply"}}},
+	}}
+	outbox := &mailer.MemoryMailer{}
+
+	config := DefaultConfig()
+	config.AppURL = "https://yappin.chat/"
+	config.APIURL = "https://api.yappin.chat"
+	config.Secret = []byte("secret")
+	digests := NewDigestService(repo, outbox, config)
+	digests.now = func() time.Time { return now }
+	digests.Presence = fakePresence{bob.String(): true}
+
+	sent, err := digests.SendDigests(context.Background())
+	if err != nil {
+		t.Fatalf("SendDigests: %v", err)
+	}
+	if sent != 1 || len(repo.emailed) != 1 || repo.emailed[0] != alice {
+		t.Fatalf("expected only offline alice to be emailed, sent=%d emailed=%v", sent, repo.emailed)
+	}
+	if !repo.cutoff.Equal(now.Add(-config.Delay)) {
+		t.Fatalf("expected cutoff %v, got %v", now.Add(-config.Delay), repo.cutoff)
+	}
+
+	messages := outbox.Sent()
+	if len(messages) != 1 {
+		t.Fatalf("expected one email, got %d", len(messages))
+	}
+	msg := messages[0]
+	if msg.To != "alice@example.com" || msg.Subject != "You have 3 unread notifications" {
+		t.Fatalf("unexpected email %q / %q", msg.To, msg.Subject)
+	}
+	for _, want := range []string{"https://yappin.chat/room/" + roomID.String(), "bob upvoted you", "and 1 more"} {
+		if !strings.Contains(msg.Text, want) {
+			t.Errorf("expected text body to contain %q:\n%s", want, msg.Text)
+		}
+	}
+	if !strings.Contains(msg.HTML, "&lt;b&gt;hi&lt;/b&gt;") {
+		t.Errorf("expected notification bodies to be escaped in HTML:\n%s", msg.HTML)
+	}
+
+	link := strings.Trim(msg.Headers["List-Unsubscribe"], "<>")
+	parsed, err := url.Parse(link)
+	if err != nil || !strings.HasPrefix(link, "https://api.yappin.chat/api/websoc/notifications/unsubscribe?") {
+		t.Fatalf("unexpected unsubscribe link %q", link)
+	}
+	if !strings.Contains(msg.Text, link) {
+		t.Errorf("expected the unsubscribe link in the text body")
+	}
+
+	if err := digests.Unsubscribe(context.Background(), parsed.Query().Get("token")); err != nil {
+		t.Fatalf("Unsubscribe: %v", err)
+	}
+	if len(repo.disabled) != 1 || repo.disabled[0] != alice {
+		t.Fatalf("expected alice to be unsubscribed, got %v", repo.disabled)
+	}
+}
+
+func TestVerifyUnsubscribeTokenRejectsForgeries(t *testing.T) {
+	userID := uuid.New()
+	token := SignUnsubscribeToken([]byte("secret"), userID)
+
+	got, err := VerifyUnsubscribeToken([]byte("secret"), token)
+	if err != nil || got != userID {
+		t.Fatalf("expected %s, got %s (%v)", userID, got, err)
+	}
+
+	for _, forged := range []string{
+		"",
+		"not-base64!",
+		SignUnsubscribeToken([]byte("other"), userID),
+		token[:len(token)-2] + "AA",
+	} {
+		if _, err := VerifyUnsubscribeToken([]byte("secret"), forged); err != ErrInvalidToken {
+			t.Errorf("expected %q to be rejected, got %v", forged, err)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidToken is returned for unsubscribe tokens that are malformed or
+// were not signed with the server's secret.
+var ErrInvalidToken = errors.New("invalid unsubscribe token")
+
+const tokenMACSize = 16
+
+// SignUnsubscribeToken returns a URL-safe token naming the user, signed with
+// secret. Tokens do not expire so that old emails keep working.
+func SignUnsubscribeToken(secret []byte, userID uuid.UUID) string {
+	payload := userID[:]
+	token := append(append([]byte{}, payload...), tokenMAC(secret, payload)...)
+	return base64.RawURLEncoding.EncodeToString(token)
+}
+
+// VerifyUnsubscribeToken returns the user named by a token from SignUnsubscribeToken.
+func VerifyUnsubscribeToken(secret []byte, token string) (uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) != len(uuid.UUID{})+tokenMACSize {
+		return uuid.Nil, ErrInvalidToken
+	}
+	payload, mac := raw[:len(uuid.UUID{})], raw[len(uuid.UUID{}):]
+	if !hmac.Equal(mac, tokenMAC(secret, payload)) {
+		return uuid.Nil, ErrInvalidToken
+	}
+	userID, err := uuid.FromBytes(payload)
+	if err != nil {
+		return uuid.Nil, ErrInvalidToken
+	}
+	return userID, nil
+}
+
+func tokenMAC(secret, payload []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("email-digest-unsubscribe:"))
+	mac.Write(payload)
+	return mac.Sum(nil)[:tokenMACSize]
+}
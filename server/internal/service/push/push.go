@@ -0,0 +1,303 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"chat-application/internal/constants"
+	"chat-application/internal/netguard"
+	pushRepository "chat-application/internal/repo/push"
+	roomRepository "chat-application/internal/repo/room"
+	"chat-application/internal/webpush"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidSubscription = errors.New("invalid push subscription")
+	ErrPushDisabled        = errors.New("push notifications are not configured")
+)
+
+// pushKinds are the notifications worth interrupting a user for when the app
+// is closed. There are no direct messages yet; mentions and replies are the
+// personal notifications.
+var pushKinds = map[string]bool{
+	"mention":       true,
+	"group_mention": true,
+	"reply":         true,
+}
+
+// maxBodyLength is in runes; it keeps the encoded payload well under
+// webpush.MaxPayloadSize.
+const maxBodyLength = 500
+
+// Sender delivers one encrypted push message.
+type Sender interface {
+	Send(ctx context.Context, subscription webpush.Subscription, payload []byte, opts webpush.Options) error
+}
+
+// Config controls delivery and retries.
+type Config struct {
+	Workers          int
+	QueueSize        int
+	MaxAttempts      int
+	RetryBaseDelay   time.Duration
+	RetryMaxDelay    time.Duration
+	TTL              time.Duration
+	SendTimeout      time.Duration
+	MaxSubscriptions int
+	// AppURL is the web client; notification clicks open rooms there.
+	AppURL string
+}
+
+// DefaultConfig returns the default delivery settings without an AppURL.
+func DefaultConfig() Config {
+	return Config{
+		Workers:          constants.PushWorkers,
+		QueueSize:        constants.PushQueueSize,
+		MaxAttempts:      constants.PushMaxAttempts,
+		RetryBaseDelay:   constants.PushRetryBaseDelay,
+		RetryMaxDelay:    constants.PushRetryMaxDelay,
+		TTL:              constants.PushTTL,
+		SendTimeout:      constants.PushSendTimeout,
+		MaxSubscriptions: constants.MaxPushSubscriptions,
+	}
+}
+
+// NewHTTPClient returns a client for reaching push services that refuses to
+// connect to private addresses, since endpoints are supplied by users.
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: netguard.DenyPrivateAddresses}
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   timeout,
+			ResponseHeaderTimeout: timeout,
+			MaxIdleConnsPerHost:   10,
+			IdleConnTimeout:       90 * time.Second,
+		},
+		Timeout: timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+type delivery struct {
+	subscription pushRepository.PushSubscription
+	payload      []byte
+	topic        string
+	attempt      int
+}
+
+// PushService sends browser push notifications to users who are offline.
+type PushService struct {
+	repository pushRepository.PushRepositoryInterface
+	sender     Sender
+	publicKey  string
+	config     Config
+	queue      chan roomRepository.Notification
+}
+
+// NewPushService creates a PushService. publicKey is the VAPID application
+// server key handed to browsers when they subscribe. With a nil sender push
+// is disabled: subscriptions are refused and notifications are ignored.
+func NewPushService(repository pushRepository.PushRepositoryInterface, sender Sender, publicKey string, config Config) *PushService {
+	config.AppURL = strings.TrimRight(config.AppURL, "/")
+	return &PushService{
+		repository: repository,
+		sender:     sender,
+		publicKey:  publicKey,
+		config:     config,
+		queue:      make(chan roomRepository.Notification, config.QueueSize),
+	}
+}
+
+// VAPIDPublicKey returns the application server key browsers subscribe with.
+func (s *PushService) VAPIDPublicKey() string {
+	return s.publicKey
+}
+
+// Subscribe registers a browser subscription for the user.
+func (s *PushService) Subscribe(ctx context.Context, userID uuid.UUID, subscription webpush.Subscription, userAgent string) error {
+	if s.sender == nil {
+		return ErrPushDisabled
+	}
+	if err := subscription.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSubscription, err)
+	}
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	return s.repository.SaveSubscription(ctx, &pushRepository.PushSubscription{
+		UserID:    userID,
+		Endpoint:  subscription.Endpoint,
+		P256dh:    subscription.P256dh,
+		Auth:      subscription.Auth,
+		UserAgent: userAgent,
+	}, s.config.MaxSubscriptions)
+}
+
+// Unsubscribe removes one of the user's subscriptions. It returns false if
+// the user had no subscription with that endpoint.
+func (s *PushService) Unsubscribe(ctx context.Context, userID uuid.UUID, endpoint string) (bool, error) {
+	return s.repository.DeleteSubscription(ctx, userID, endpoint)
+}
+
+// Enqueue queues a notification for push delivery. Kinds that are not pushed
+// are ignored, and notifications are dropped when the queue is full.
+func (s *PushService) Enqueue(notification roomRepository.Notification) {
+	if s.sender == nil || !pushKinds[notification.Kind] {
+		return
+	}
+	select {
+	case s.queue <- notification:
+	default:
+		log.Printf("PushService.Enqueue - queue full, dropping notification %s", notification.ID)
+	}
+}
+
+// Start runs the delivery workers until ctx is cancelled.
+func (s *PushService) Start(ctx context.Context) {
+	if s.sender == nil {
+		return
+	}
+	for i := 0; i < max(s.config.Workers, 1); i++ {
+		go s.work(ctx)
+	}
+}
+
+func (s *PushService) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case notification := <-s.queue:
+			s.deliverNotification(ctx, notification)
+		}
+	}
+}
+
+func (s *PushService) deliverNotification(ctx context.Context, notification roomRepository.Notification) {
+	subscriptions, err := s.repository.GetUserSubscriptions(ctx, notification.UserID)
+	if err != nil {
+		log.Printf("PushService.deliverNotification - failed to load subscriptions: %v", err)
+		return
+	}
+	if len(subscriptions) == 0 {
+		return
+	}
+
+	payload, err := s.buildPayload(notification)
+	if err != nil {
+		log.Printf("PushService.deliverNotification - %v", err)
+		return
+	}
+	topic := ""
+	if notification.RoomID != nil {
+		// Push services replace an undelivered message with the same topic,
+		// so an offline device receives one notification per room.
+		topic = strings.ReplaceAll(notification.RoomID.String(), "-", "")
+	}
+
+	for _, subscription := range subscriptions {
+		s.send(ctx, &delivery{subscription: subscription, payload: payload, topic: topic})
+	}
+}
+
+type pushPayload struct {
+	ID        string `json:"id"`
+	Kind      string `json:"kind"`
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+	URL       string `json:"url,omitempty"`
+	RoomID    string `json:"room_id,omitempty"`
+	MessageID string `json:"message_id,omitempty"`
+}
+
+func (s *PushService) buildPayload(notification roomRepository.Notification) ([]byte, error) {
+	payload := pushPayload{
+		ID:    notification.ID.String(),
+		Kind:  notification.Kind,
+		Title: notification.Title,
+		Body:  notification.Body,
+	}
+	if runes := []rune(payload.Body); len(runes) > maxBodyLength {
+		payload.Body = string(runes[:maxBodyLength]) + "…"
+	}
+	if notification.RoomID != nil {
+		payload.RoomID = notification.RoomID.String()
+		if s.config.AppURL != "" {
+			payload.URL = s.config.AppURL + "/room/" + payload.RoomID
+		}
+	}
+	if notification.MessageID != nil {
+		payload.MessageID = notification.MessageID.String()
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode push payload: %w", err)
+	}
+	return data, nil
+}
+
+// send makes one delivery attempt. Subscriptions the push service reports as
+// gone are deleted; temporary failures are retried with exponential backoff,
+// or after the push service's Retry-After.
+func (s *PushService) send(ctx context.Context, d *delivery) {
+	d.attempt++
+	sendCtx, cancel := context.WithTimeout(ctx, s.config.SendTimeout)
+	err := s.sender.Send(sendCtx, webpush.Subscription{
+		Endpoint: d.subscription.Endpoint,
+		P256dh:   d.subscription.P256dh,
+		Auth:     d.subscription.Auth,
+	}, d.payload, webpush.Options{
+		TTL:     s.config.TTL,
+		Urgency: "high",
+		Topic:   d.topic,
+	})
+	cancel()
+
+	if err == nil {
+		if err := s.repository.MarkSubscriptionUsed(ctx, d.subscription.ID); err != nil {
+			log.Printf("PushService.send - %v", err)
+		}
+		return
+	}
+
+	if errors.Is(err, webpush.ErrSubscriptionGone) {
+		if err := s.repository.DeleteSubscriptionByID(ctx, d.subscription.ID); err != nil {
+			log.Printf("PushService.send - %v", err)
+		}
+		return
+	}
+
+	var deliveryErr *webpush.DeliveryError
+	temporary := !errors.As(err, &deliveryErr) || deliveryErr.Temporary()
+	if !temporary || d.attempt >= s.config.MaxAttempts || ctx.Err() != nil {
+		log.Printf("PushService.send - giving up on subscription %s after %d attempts: %v", d.subscription.ID, d.attempt, err)
+		return
+	}
+
+	delay := s.config.RetryBaseDelay << (d.attempt - 1)
+	if deliveryErr != nil && deliveryErr.RetryAfter > delay {
+		delay = deliveryErr.RetryAfter
+	}
+	delay = min(delay, s.config.RetryMaxDelay)
+
+	// Retry off the worker so a slow push service does not hold up others.
+	time.AfterFunc(delay, func() {
+		if ctx.Err() == nil {
+			s.send(ctx, d)
+		}
+	})
+}
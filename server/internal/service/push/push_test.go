@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pushRepository "chat-application/internal/repo/push"
+	roomRepository "chat-application/internal/repo/room"
+	"chat-application/internal/webpush"
+
+	"github.com/google/uuid"
+)
+
+type fakePushRepository struct {
+	mu            sync.Mutex
+	subscriptions []pushRepository.PushSubscription
+	used          chan uuid.UUID
+	deleted       chan uuid.UUID
+}
+
+func newFakePushRepository(subscriptions ...pushRepository.PushSubscription) *fakePushRepository {
+	return &fakePushRepository{
+		subscriptions: subscriptions,
+		used:          make(chan uuid.UUID, 10),
+		deleted:       make(chan uuid.UUID, 10),
+	}
+}
+
+func (f *fakePushRepository) SaveSubscription(_ context.Context, subscription *pushRepository.PushSubscription, _ int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	subscription.ID = uuid.New()
+	f.subscriptions = append(f.subscriptions, *subscription)
+	return nil
+}
+
+func (f *fakePushRepository) GetUserSubscriptions(_ context.Context, userID uuid.UUID) ([]pushRepository.PushSubscription, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var subscriptions []pushRepository.PushSubscription
+	for _, subscription := range f.subscriptions {
+		if subscription.UserID == userID {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	return subscriptions, nil
+}
+
+func (f *fakePushRepository) DeleteSubscription(context.Context, uuid.UUID, string) (bool, error) {
+	return false, nil
+}
+
+func (f *fakePushRepository) DeleteSubscriptionByID(_ context.Context, id uuid.UUID) error {
+	f.deleted <- id
+	return nil
+}
+
+func (f *fakePushRepository) MarkSubscriptionUsed(_ context.Context, id uuid.UUID) error {
+	f.used <- id
+	return nil
+}
+
+func newSubscription(t *testing.T, userID uuid.UUID, endpoint string) pushRepository.PushSubscription {
+	t.Helper()
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	auth := make([]byte, 16)
+	_, _ = rand.Read(auth)
+	return pushRepository.PushSubscription{
+		ID:       uuid.New(),
+		UserID:   userID,
+		Endpoint: endpoint,
+		P256dh:   base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()),
+		Auth:     base64.RawURLEncoding.EncodeToString(auth),
+	}
+}
+
+func waitFor(t *testing.T, ch <-chan uuid.UUID, want uuid.UUID, what string) {
+	t.Helper()
+	select {
+	case got := <-ch:
+		if got != want {
+			t.Fatalf("expected %s for %s, got %s", what, want, got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+	}
+}
+
+func TestPushServiceRetriesAndPrunesSubscriptions(t *testing.T) {
+	var mu sync.Mutex
+	requests := map[string]int{}
+	stub := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		attempt := requests[r.URL.Path]
+		mu.Unlock()
+
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "vapid t=") || r.Header.Get("Topic") == "" {
+			t.Errorf("unexpected push headers %v", r.Header)
+		}
+		switch {
+		case r.URL.Path == "/gone":
+			w.WriteHeader(http.StatusGone)
+		case r.URL.Path == "/flaky" && attempt < 3:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer stub.Close()
+
+	keys, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatalf("GenerateVAPIDKeys: %v", err)
+	}
+	userID := uuid.New()
+	gone := newSubscription(t, userID, stub.URL+"/gone")
+	flaky := newSubscription(t, userID, stub.URL+"/flaky")
+	repository := newFakePushRepository(gone, flaky)
+
+	config := DefaultConfig()
+	config.RetryBaseDelay = 10 * time.Millisecond
+	config.AppURL = "https://yappin.chat/"
+	client := webpush.NewClient(stub.Client(), keys, "mailto:ops@yappin.chat", time.Hour)
+	service := NewPushService(repository, client, keys.PublicKey, config)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service.Start(ctx)
+
+	roomID := uuid.New()
+	service.Enqueue(roomRepository.Notification{ID: uuid.New(), UserID: userID, Kind: "reaction", RoomID: &roomID})
+	service.Enqueue(roomRepository.Notification{ID: uuid.New(), UserID: userID, Kind: "mention", RoomID: &roomID, Title: "alice mentioned you"})
+
+	waitFor(t, repository.deleted, gone.ID, "pruned subscription")
+	waitFor(t, repository.used, flaky.ID, "delivered subscription")
+
+	mu.Lock()
+	defer mu.Unlock()
+	if requests["/gone"] != 1 || requests["/flaky"] != 3 {
+		t.Fatalf("expected 1 request to the gone endpoint and 3 to the flaky one, got %v", requests)
+	}
+}
+
+func TestPushServiceRejectsInvalidSubscriptions(t *testing.T) {
+	keys, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatalf("GenerateVAPIDKeys: %v", err)
+	}
+	repository := newFakePushRepository()
+	service := NewPushService(repository, webpush.NewClient(http.DefaultClient, keys, "mailto:ops@yappin.chat", time.Hour), keys.PublicKey, DefaultConfig())
+
+	valid := newSubscription(t, uuid.New(), "https://push.example.com/abc")
+	cases := map[string]webpush.Subscription{
+		"plain http": {Endpoint: "http://push.example.com/abc", P256dh: valid.P256dh, Auth: valid.Auth},
+		"bad key":    {Endpoint: valid.Endpoint, P256dh: "not-a-key", Auth: valid.Auth},
+		"short auth": {Endpoint: valid.Endpoint, P256dh: valid.P256dh, Auth: "AAAA"},
+	}
+	for name, subscription := range cases {
+		if err := service.Subscribe(context.Background(), valid.UserID, subscription, ""); !errors.Is(err, ErrInvalidSubscription) {
+			t.Errorf("%s: expected ErrInvalidSubscription, got %v", name, err)
+		}
+	}
+
+	subscription := webpush.Subscription{Endpoint: valid.Endpoint, P256dh: valid.P256dh, Auth: valid.Auth}
+	if err := service.Subscribe(context.Background(), valid.UserID, subscription, "Firefox"); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	disabled := NewPushService(repository, nil, "", DefaultConfig())
+	if err := disabled.Subscribe(context.Background(), valid.UserID, subscription, ""); !errors.Is(err, ErrPushDisabled) {
+		t.Fatalf("expected ErrPushDisabled, got %v", err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 
+	"chat-application/internal/api/model"
 	stats "chat-application/internal/repo/stats"
 
 	"github.com/google/uuid"
@@ -12,6 +13,23 @@ import (
 
 type StatsService struct {
 	statsRepository *stats.StatsRepository
+	// Notifier is optional; when set, upvotes and new achievements are
+	// turned into notifications for the user concerned.
+	Notifier Notifier
+	// Profiles is optional; when set, GetUserProfile includes the user's
+	// display name, bio, avatar and status.
+	Profiles ProfileLoader
+}
+
+// ProfileLoader looks up what a user shows to others.
+type ProfileLoader interface {
+	GetProfile(ctx context.Context, userID uuid.UUID) (*model.UserProfileRes, error)
+}
+
+// Notifier delivers notifications for stats events.
+type Notifier interface {
+	NotifyUpvote(ctx context.Context, fromUserID, toUserID uuid.UUID)
+	NotifyAchievements(ctx context.Context, userID uuid.UUID, achievements []stats.Achievement)
 }
 
 type CheckinResult struct {
@@ -31,13 +49,19 @@ type Achievement struct {
 }
 
 type UserProfile struct {
-	UserID            string        `json:"user_id" db:"user_id"`
-	DailyStreak       int           `json:"daily_streak" db:"daily_streak"`
-	TotalCheckins     int           `json:"total_checkins" db:"total_checkins"`
-	TotalMessages     int           `json:"total_messages" db:"total_messages"`
-	TotalUpvotes      int           `json:"total_upvotes" db:"total_upvotes"`
-	CanReceiveUpvotes bool          `json:"can_receive_upvotes" db:"can_receive_upvotes"`
-	Achievements      []Achievement `json:"achievements" db:"achievements"`
+	UserID            string               `json:"user_id" db:"user_id"`
+	Username          string               `json:"username,omitempty"`
+	DisplayName       string               `json:"display_name,omitempty"`
+	Bio               string               `json:"bio,omitempty"`
+	Pronouns          string               `json:"pronouns,omitempty"`
+	AvatarURL         string               `json:"avatar_url,omitempty"`
+	Status            *model.UserStatusRes `json:"status,omitempty"`
+	DailyStreak       int                  `json:"daily_streak" db:"daily_streak"`
+	TotalCheckins     int                  `json:"total_checkins" db:"total_checkins"`
+	TotalMessages     int                  `json:"total_messages" db:"total_messages"`
+	TotalUpvotes      int                  `json:"total_upvotes" db:"total_upvotes"`
+	CanReceiveUpvotes bool                 `json:"can_receive_upvotes" db:"can_receive_upvotes"`
+	Achievements      []Achievement        `json:"achievements" db:"achievements"`
 }
 
 type LeaderboardEntry struct {
@@ -75,6 +99,9 @@ func (s *StatsService) ProcessDailyCheckin(ctx context.Context, userID uuid.UUID
 			return nil, fmt.Errorf("failed to check achievements: %w", err)
 		} else {
 			newAchievements = s.convertAchievement(achievements)
+			if s.Notifier != nil {
+				s.Notifier.NotifyAchievements(ctx, userID, achievements)
+			}
 		}
 	}
 
@@ -128,7 +155,7 @@ func (s *StatsService) GetUserProfile(ctx context.Context, userID, viewerID uuid
 		}
 	}
 
-	return &UserProfile{
+	profile := &UserProfile{
 		UserID:            userID.String(),
 		DailyStreak:       stats.DailyStreak,
 		TotalCheckins:     stats.TotalCheckins,
@@ -136,7 +163,25 @@ func (s *StatsService) GetUserProfile(ctx context.Context, userID, viewerID uuid
 		TotalUpvotes:      stats.TotalUpvotes,
 		CanReceiveUpvotes: canUpvote,
 		Achievements:      s.convertAchievement(achievements),
-	}, nil
+	}
+
+	if s.Profiles != nil {
+		details, err := s.Profiles.GetProfile(ctx, userID)
+		if err != nil {
+			log.Printf("GetUserProfile - Error retrieving profile details for user %s: %v", userID, err)
+			return nil, fmt.Errorf("failed to retrieve user profile: %w", err)
+		}
+		if details != nil {
+			profile.Username = details.Username
+			profile.DisplayName = details.DisplayName
+			profile.Bio = details.Bio
+			profile.Pronouns = details.Pronouns
+			profile.AvatarURL = details.AvatarURL
+			profile.Status = details.Status
+		}
+	}
+
+	return profile, nil
 }
 
 func (s *StatsService) GivenUpvote(ctx context.Context, fromUserID, toUserID uuid.UUID) error {
@@ -164,9 +209,18 @@ func (s *StatsService) GivenUpvote(ctx context.Context, fromUserID, toUserID uui
 	}
 
 	go func() {
-		_, err := s.statsRepository.CheckAwardsAndAchievements(context.Background(), toUserID)
+		ctx := context.Background()
+		if s.Notifier != nil {
+			s.Notifier.NotifyUpvote(ctx, fromUserID, toUserID)
+		}
+
+		achievements, err := s.statsRepository.CheckAwardsAndAchievements(ctx, toUserID)
 		if err != nil {
 			log.Printf("GivenUpvote - Error checking achievements after upvote for user %s: %v", toUserID, err)
+			return
+		}
+		if s.Notifier != nil {
+			s.Notifier.NotifyAchievements(ctx, toUserID, achievements)
 		}
 	}()
 
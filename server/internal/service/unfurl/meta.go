@@ -0,0 +1,135 @@
+package service
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxTitleLength       = 300
+	maxDescriptionLength = 1000
+)
+
+var (
+	metaTagRegex   = regexp.MustCompile(`(?is)<meta\s+([^>]*)>`)
+	attributeRegex = regexp.MustCompile(`(?is)([a-z_:.-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	titleTagRegex  = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	headEndRegex   = regexp.MustCompile(`(?i)</head\s*>`)
+	whitespace     = regexp.MustCompile(`\s+`)
+)
+
+type pageMeta struct {
+	Title       string
+	Description string
+	ImageURL    string
+	SiteName    string
+}
+
+// parseMeta reads Open Graph and Twitter card tags from a page, falling back to
+// <title> and the plain description meta tag. Only the document head is scanned.
+func parseMeta(document string, base *url.URL) *pageMeta {
+	if loc := headEndRegex.FindStringIndex(document); loc != nil {
+		document = document[:loc[0]]
+	}
+
+	tags := map[string]string{}
+	for _, match := range metaTagRegex.FindAllStringSubmatch(document, -1) {
+		attrs := parseAttributes(match[1])
+		key := attrs["property"]
+		if key == "" {
+			key = attrs["name"]
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		if key == "" {
+			continue
+		}
+		if _, exists := tags[key]; !exists {
+			tags[key] = attrs["content"]
+		}
+	}
+
+	meta := &pageMeta{
+		Title:       firstNonEmpty(tags["og:title"], tags["twitter:title"]),
+		Description: firstNonEmpty(tags["og:description"], tags["twitter:description"], tags["description"]),
+		SiteName:    firstNonEmpty(tags["og:site_name"], tags["application-name"]),
+	}
+	if meta.Title == "" {
+		if match := titleTagRegex.FindStringSubmatch(document); match != nil {
+			meta.Title = match[1]
+		}
+	}
+	if meta.SiteName == "" && base != nil {
+		meta.SiteName = base.Hostname()
+	}
+
+	meta.Title = truncate(cleanText(meta.Title), maxTitleLength)
+	meta.Description = truncate(cleanText(meta.Description), maxDescriptionLength)
+	meta.SiteName = truncate(cleanText(meta.SiteName), maxTitleLength)
+
+	image := firstNonEmpty(tags["og:image:secure_url"], tags["og:image"], tags["og:image:url"], tags["twitter:image"], tags["twitter:image:src"])
+	meta.ImageURL = resolveImageURL(cleanText(image), base)
+
+	return meta
+}
+
+func parseAttributes(raw string) map[string]string {
+	attrs := map[string]string{}
+	for _, match := range attributeRegex.FindAllStringSubmatch(raw, -1) {
+		name := strings.ToLower(match[1])
+		value := match[2]
+		if value == "" {
+			value = match[3]
+		}
+		if value == "" {
+			value = match[4]
+		}
+		attrs[name] = value
+	}
+	return attrs
+}
+
+func resolveImageURL(raw string, base *url.URL) string {
+	if raw == "" {
+		return ""
+	}
+	ref, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		ref = base.ResolveReference(ref)
+	}
+	if ref.Scheme != "http" && ref.Scheme != "https" {
+		return ""
+	}
+	return ref.String()
+}
+
+func cleanText(value string) string {
+	value = html.UnescapeString(value)
+	value = whitespace.ReplaceAllString(value, " ")
+	return strings.TrimSpace(value)
+}
+
+func truncate(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	value = value[:max]
+	for !utf8.ValidString(value) {
+		value = value[:len(value)-1]
+	}
+	return value + "…"
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"chat-application/internal/api/model"
+	"chat-application/internal/constants"
+	"chat-application/internal/markdown"
+	"chat-application/internal/netguard"
+	linkPreviewRepository "chat-application/internal/repo/linkpreview"
+)
+
+var (
+	ErrUnsupportedURL   = errors.New("unsupported URL")
+	ErrNotHTML          = errors.New("response is not HTML")
+	ErrNoPreviewContent = errors.New("page has no preview metadata")
+)
+
+// Config controls how pages are fetched. AllowPrivateNetworks disables the
+// SSRF guard and exists so tests can point the unfurler at a local server.
+type Config struct {
+	Timeout              time.Duration
+	MaxBodySize          int64
+	MaxRedirects         int
+	CacheTTL             time.Duration
+	FailureTTL           time.Duration
+	AllowPrivateNetworks bool
+	UserAgent            string
+}
+
+// DefaultConfig returns the production fetch limits.
+func DefaultConfig() Config {
+	return Config{
+		Timeout:      constants.LinkPreviewFetchTimeout,
+		MaxBodySize:  constants.LinkPreviewMaxBodySize,
+		MaxRedirects: constants.LinkPreviewMaxRedirects,
+		CacheTTL:     constants.LinkPreviewCacheTTL,
+		FailureTTL:   constants.LinkPreviewFailureTTL,
+		UserAgent:    "YappinBot/1.0 (+link preview)",
+	}
+}
+
+type UnfurlService struct {
+	cache  linkPreviewRepository.LinkPreviewRepositoryInterface
+	client *http.Client
+	config Config
+	now    func() time.Time
+}
+
+func NewUnfurlService(cache linkPreviewRepository.LinkPreviewRepositoryInterface, config Config) *UnfurlService {
+	dialer := &net.Dialer{Timeout: config.Timeout}
+	if !config.AllowPrivateNetworks {
+		dialer.Control = netguard.DenyPrivateAddresses
+	}
+
+	transport := &http.Transport{
+		// Never route through an environment proxy: the dial guard must see
+		// the real destination address.
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   config.Timeout,
+		ResponseHeaderTimeout: config.Timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   config.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > config.MaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", config.MaxRedirects)
+			}
+			return validateURL(req.URL)
+		},
+	}
+
+	return &UnfurlService{
+		cache:  cache,
+		client: client,
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// ExtractURLs returns the distinct http(s) links in a message, in order of
+// appearance. Links inside code spans are not previewed.
+func ExtractURLs(content string, limit int) []string {
+	urls := make([]string, 0, limit)
+	for _, link := range markdown.Links(content) {
+		if !strings.HasPrefix(link, "http://") && !strings.HasPrefix(link, "https://") {
+			continue
+		}
+		urls = append(urls, link)
+		if limit > 0 && len(urls) >= limit {
+			break
+		}
+	}
+	return urls
+}
+
+// Unfurl returns previews for the links in a message. Links that cannot be
+// previewed are skipped; failures are cached so they are not retried on every post.
+func (s *UnfurlService) Unfurl(ctx context.Context, content string) []model.LinkPreviewRes {
+	urls := ExtractURLs(content, constants.MaxLinkPreviewsPerMessage)
+	previews := make([]model.LinkPreviewRes, 0, len(urls))
+	for _, rawURL := range urls {
+		preview, err := s.Preview(ctx, rawURL)
+		if err != nil {
+			continue
+		}
+		previews = append(previews, *preview)
+	}
+	return previews
+}
+
+// Preview returns the preview for a single URL, using the cache when it is fresh.
+func (s *UnfurlService) Preview(ctx context.Context, rawURL string) (*model.LinkPreviewRes, error) {
+	cached, err := s.cache.GetLinkPreview(ctx, rawURL)
+	if err != nil {
+		log.Printf("UnfurlService.Preview - cache lookup failed: %v", err)
+	}
+	if cached != nil && s.isFresh(cached) {
+		if !cached.OK {
+			return nil, ErrNoPreviewContent
+		}
+		return toResponse(cached), nil
+	}
+
+	preview, fetchErr := s.fetch(ctx, rawURL)
+	record := &linkPreviewRepository.LinkPreview{URL: rawURL}
+	if fetchErr == nil {
+		record.OK = true
+		record.Title = preview.Title
+		record.Description = preview.Description
+		record.ImageURL = preview.ImageURL
+		record.SiteName = preview.SiteName
+	}
+
+	// A cancelled request says nothing about the link itself.
+	if ctx.Err() == nil {
+		if err := s.cache.UpsertLinkPreview(ctx, record); err != nil {
+			log.Printf("UnfurlService.Preview - cache store failed: %v", err)
+		}
+	}
+
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+	return toResponse(record), nil
+}
+
+func (s *UnfurlService) isFresh(preview *linkPreviewRepository.LinkPreview) bool {
+	ttl := s.config.CacheTTL
+	if !preview.OK {
+		ttl = s.config.FailureTTL
+	}
+	return s.now().Sub(preview.FetchedAt) < ttl
+}
+
+func (s *UnfurlService) fetch(ctx context.Context, rawURL string) (*pageMeta, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, ErrUnsupportedURL
+	}
+	if err := validateURL(target); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("User-Agent", s.config.UserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", target.Host, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, ErrNotHTML
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, s.config.MaxBodySize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	meta := parseMeta(string(body), resp.Request.URL)
+	if meta.Title == "" && meta.Description == "" && meta.ImageURL == "" {
+		return nil, ErrNoPreviewContent
+	}
+	return meta, nil
+}
+
+func validateURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ErrUnsupportedURL
+	}
+	if u.Hostname() == "" || u.User != nil {
+		return ErrUnsupportedURL
+	}
+	return nil
+}
+
+func toResponse(preview *linkPreviewRepository.LinkPreview) *model.LinkPreviewRes {
+	return &model.LinkPreviewRes{
+		URL:         preview.URL,
+		Title:       preview.Title,
+		Description: preview.Description,
+		ImageURL:    preview.ImageURL,
+		SiteName:    preview.SiteName,
+	}
+}
@@ -0,0 +1,259 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"embed"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"chat-application/internal/constants"
+	"chat-application/internal/mailer"
+	repository "chat-application/internal/repo/user"
+	"chat-application/util"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidToken         = errors.New("invalid or expired token")
+	ErrEmailAlreadyVerified = errors.New("email is already verified")
+	ErrEmailNotVerified     = errors.New("email verification required")
+)
+
+//go:embed templates
+var templateFiles embed.FS
+
+var (
+	passwordResetEmail = template.Must(template.ParseFS(templateFiles, "templates/password_reset.txt"))
+	verifyEmailEmail   = template.Must(template.ParseFS(templateFiles, "templates/verify_email.txt"))
+	accountLockedEmail = template.Must(template.ParseFS(templateFiles, "templates/account_locked.txt"))
+)
+
+type accountEmailData struct {
+	Username string
+	Link     string
+	Expiry   string
+}
+
+// newAccountToken returns a random token for an emailed link and the hash
+// that is stored in its place.
+func newAccountToken() (string, string, error) {
+	raw := make([]byte, constants.AccountTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	return token, hashAccountToken(token), nil
+}
+
+func hashAccountToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RequestPasswordReset emails a reset link if an account uses the address.
+// It returns nil whether or not one does, and sends the email in the
+// background so the response takes as long either way; callers cannot probe
+// for accounts.
+func (s *UserService) RequestPasswordReset(ctx context.Context, email string) error {
+	email = util.SanitizeString(email)
+	if err := util.ValidateEmail(email); err != nil {
+		return err
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	user, err := s.userRepo.GetUserByEmail(lookupCtx, email)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to request password reset: %w", err)
+	}
+	if user == nil {
+		log.Printf("UserService.RequestPasswordReset - no account for %s", email)
+		return nil
+	}
+
+	s.emails.Add(1)
+	go func() {
+		defer s.emails.Done()
+		if err := s.sendAccountEmail(context.WithoutCancel(ctx), user, repository.TokenPurposePasswordReset); err != nil {
+			log.Printf("UserService.RequestPasswordReset - %v", err)
+		}
+	}()
+	return nil
+}
+
+// ResetPassword sets a new password using the token from a reset email.
+func (s *UserService) ResetPassword(ctx context.Context, token, password string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	password = util.SanitizeString(password)
+	if err := util.ValidatePassword(password); err != nil {
+		return err
+	}
+	if token == "" {
+		return ErrInvalidToken
+	}
+
+	hashedPassword, err := util.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed to process password")
+	}
+
+	userID, err := s.userRepo.ResetPassword(ctx, hashAccountToken(token), hashedPassword)
+	if err != nil {
+		return err
+	}
+	if userID == nil {
+		return ErrInvalidToken
+	}
+
+	log.Printf("UserService.ResetPassword - password reset for user: %s", userID)
+	return nil
+}
+
+// RequestEmailVerification emails the user a new verification link.
+func (s *UserService) RequestEmailVerification(ctx context.Context, userID uuid.UUID) error {
+	lookupCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	user, err := s.userRepo.GetUserByID(lookupCtx, userID)
+	cancel()
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return fmt.Errorf("user not found")
+	}
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailAlreadyVerified
+	}
+
+	return s.sendAccountEmail(ctx, user, repository.TokenPurposeEmailVerification)
+}
+
+// VerifyEmail marks the address verified using the token from a verification email.
+func (s *UserService) VerifyEmail(ctx context.Context, token string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if token == "" {
+		return ErrInvalidToken
+	}
+
+	userID, err := s.userRepo.VerifyEmail(ctx, hashAccountToken(token))
+	if err != nil {
+		return err
+	}
+	if userID == nil {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// CheckEmailVerified returns ErrEmailNotVerified when RequireVerifiedEmail is
+// set and the user has not verified their address.
+func (s *UserService) CheckEmailVerified(ctx context.Context, userID uuid.UUID) error {
+	if !s.RequireVerifiedEmail {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil || user.EmailVerifiedAt == nil {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
+
+// sendAccountEmail creates a token for the purpose and emails its link.
+func (s *UserService) sendAccountEmail(ctx context.Context, user *repository.User, purpose string) error {
+	if s.Mailer == nil {
+		return fmt.Errorf("email is not configured")
+	}
+
+	var (
+		path    string
+		subject string
+		body    *template.Template
+		expiry  time.Duration
+	)
+	switch purpose {
+	case repository.TokenPurposePasswordReset:
+		path, subject, body, expiry = "/reset-password", "Reset your Yappin password", passwordResetEmail, constants.PasswordResetTokenExpiry
+	case repository.TokenPurposeEmailVerification:
+		path, subject, body, expiry = "/verify-email", "Confirm your email for Yappin", verifyEmailEmail, constants.EmailVerificationTokenExpiry
+	default:
+		return fmt.Errorf("unknown token purpose %q", purpose)
+	}
+
+	token, tokenHash, err := newAccountToken()
+	if err != nil {
+		return err
+	}
+
+	tokenCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	err = s.userRepo.CreateUserToken(tokenCtx, user.ID, purpose, tokenHash, time.Now().Add(expiry))
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	var text bytes.Buffer
+	err = body.Execute(&text, accountEmailData{
+		Username: user.Username,
+		Link:     strings.TrimRight(s.AppURL, "/") + path + "?token=" + url.QueryEscape(token),
+		Expiry:   formatExpiry(expiry),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to render email: %w", err)
+	}
+
+	return s.sendEmail(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: subject,
+		Text:    text.String(),
+	})
+}
+
+func (s *UserService) sendEmail(ctx context.Context, message mailer.Message) error {
+	if s.Mailer == nil {
+		return fmt.Errorf("email is not configured")
+	}
+
+	// SMTP can be slower than the service timeout callers hold ctx to.
+	sendCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), constants.AccountEmailTimeout)
+	defer cancel()
+	if err := s.Mailer.Send(sendCtx, message); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+func formatExpiry(d time.Duration) string {
+	if d < time.Hour {
+		minutes := int(d / time.Minute)
+		if minutes == 1 {
+			return "1 minute"
+		}
+		return fmt.Sprintf("%d minutes", minutes)
+	}
+	hours := int(d / time.Hour)
+	if hours == 1 {
+		return "1 hour"
+	}
+	return fmt.Sprintf("%d hours", hours)
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"chat-application/internal/mailer"
+	repository "chat-application/internal/repo/user"
+
+	"github.com/google/uuid"
+)
+
+// emailedToken returns the token from the link in the last email sent.
+func emailedToken(t *testing.T, m *mailer.MemoryMailer) string {
+	t.Helper()
+	sent := m.Sent()
+	if len(sent) == 0 {
+		t.Fatal("expected an email to be sent")
+	}
+	for _, field := range strings.Fields(sent[len(sent)-1].Text) {
+		if link, err := url.Parse(field); err == nil && link.Query().Get("token") != "" {
+			return link.Query().Get("token")
+		}
+	}
+	t.Fatalf("no token link in email: %s", sent[len(sent)-1].Text)
+	return ""
+}
+
+func TestPasswordResetTokenIsSingleUse(t *testing.T) {
+	user := &repository.User{ID: uuid.New(), Username: "alice", Email: "alice@example.com"}
+	repo := &fakeUserRepository{
+		getByEmailFn: func(ctx context.Context, email string) (*repository.User, error) {
+			if email == user.Email {
+				return user, nil
+			}
+			return nil, nil
+		},
+	}
+	sentMail := &mailer.MemoryMailer{}
+	service := NewUserService(repo)
+	service.Mailer = sentMail
+	service.AppURL = "https://yappin.chat/"
+
+	if err := service.RequestPasswordReset(context.Background(), "nobody@example.com"); err != nil {
+		t.Fatalf("expected unknown address to succeed quietly, got %v", err)
+	}
+	service.emails.Wait()
+	if len(sentMail.Sent()) != 0 {
+		t.Fatal("expected no email for an unknown address")
+	}
+
+	if err := service.RequestPasswordReset(context.Background(), user.Email); err != nil {
+		t.Fatalf("RequestPasswordReset: %v", err)
+	}
+	service.emails.Wait()
+	first := emailedToken(t, sentMail)
+	if !strings.Contains(sentMail.Sent()[0].Text, "https://yappin.chat/reset-password?token=") {
+		t.Fatalf("expected a reset link, got %s", sentMail.Sent()[0].Text)
+	}
+	for hash := range repo.tokens {
+		if hash == first || strings.Contains(hash, first) {
+			t.Fatal("expected only a hash of the token to be stored")
+		}
+	}
+
+	if err := service.RequestPasswordReset(context.Background(), user.Email); err != nil {
+		t.Fatalf("RequestPasswordReset: %v", err)
+	}
+	service.emails.Wait()
+	second := emailedToken(t, sentMail)
+
+	if err := service.ResetPassword(context.Background(), first, "New-password-123"); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected a superseded token to be rejected, got %v", err)
+	}
+	if err := service.ResetPassword(context.Background(), second, "short"); err == nil || errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected a weak password to be rejected before the token is used, got %v", err)
+	}
+	if err := service.ResetPassword(context.Background(), second, "New-password-123"); err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+	if err := service.ResetPassword(context.Background(), second, "New-password-123"); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected a used token to be rejected, got %v", err)
+	}
+}
+
+// blockingMailer holds every send until release is closed.
+type blockingMailer struct {
+	release chan struct{}
+}
+
+func (m *blockingMailer) Send(ctx context.Context, msg mailer.Message) error {
+	<-m.release
+	return nil
+}
+
+func TestPasswordResetDoesNotWaitForTheEmail(t *testing.T) {
+	user := &repository.User{ID: uuid.New(), Username: "alice", Email: "alice@example.com"}
+	repo := &fakeUserRepository{
+		getByEmailFn: func(ctx context.Context, email string) (*repository.User, error) {
+			return user, nil
+		},
+	}
+	slowMail := &blockingMailer{release: make(chan struct{})}
+	service := NewUserService(repo)
+	service.Mailer = slowMail
+
+	done := make(chan error, 1)
+	go func() { done <- service.RequestPasswordReset(context.Background(), user.Email) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("RequestPasswordReset: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the request to return before the email is sent")
+	}
+	close(slowMail.release)
+	service.emails.Wait()
+}
+
+func TestEmailVerificationRequiredWhenEnforced(t *testing.T) {
+	user := &repository.User{ID: uuid.New(), Username: "bob", Email: "bob@example.com"}
+	repo := &fakeUserRepository{
+		getByIDFn: func(ctx context.Context, id uuid.UUID) (*repository.User, error) {
+			return user, nil
+		},
+	}
+	sentMail := &mailer.MemoryMailer{}
+	service := NewUserService(repo)
+	service.Mailer = sentMail
+
+	if err := service.CheckEmailVerified(context.Background(), user.ID); err != nil {
+		t.Fatalf("expected no check without enforcement, got %v", err)
+	}
+	service.RequireVerifiedEmail = true
+	if err := service.CheckEmailVerified(context.Background(), user.ID); !errors.Is(err, ErrEmailNotVerified) {
+		t.Fatalf("expected ErrEmailNotVerified, got %v", err)
+	}
+
+	if err := service.RequestEmailVerification(context.Background(), user.ID); err != nil {
+		t.Fatalf("RequestEmailVerification: %v", err)
+	}
+	token := emailedToken(t, sentMail)
+	if err := service.VerifyEmail(context.Background(), "not-a-token"); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken, got %v", err)
+	}
+	if err := service.VerifyEmail(context.Background(), token); err != nil {
+		t.Fatalf("VerifyEmail: %v", err)
+	}
+}
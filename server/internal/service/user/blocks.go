@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	repository "chat-application/internal/repo/user"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrCannotBlockSelf = errors.New("you cannot block yourself")
+	ErrUserNotFound    = errors.New("user not found")
+)
+
+// BlockNotifier applies block changes to the blocker's open connections.
+type BlockNotifier interface {
+	NotifyBlockChanged(blockerID, blockedID uuid.UUID, blocked bool)
+}
+
+// BlockUser hides blocked's messages from blocker and stops blocked's
+// mentions, replies, reactions and upvotes reaching them.
+func (s *UserService) BlockUser(ctx context.Context, blockerID, blockedID uuid.UUID) error {
+	if blockerID == blockedID {
+		return ErrCannotBlockSelf
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	user, err := s.userRepo.GetUserByID(ctx, blockedID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+
+	created, err := s.userRepo.BlockUser(ctx, blockerID, blockedID)
+	if err != nil {
+		return err
+	}
+	if created {
+		log.Printf("UserService.BlockUser - User %s blocked %s", blockerID, blockedID)
+		if s.BlockNotifier != nil {
+			s.BlockNotifier.NotifyBlockChanged(blockerID, blockedID, true)
+		}
+	}
+	return nil
+}
+
+// UnblockUser removes a block. Removing a block that does not exist is not
+// an error.
+func (s *UserService) UnblockUser(ctx context.Context, blockerID, blockedID uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	removed, err := s.userRepo.UnblockUser(ctx, blockerID, blockedID)
+	if err != nil {
+		return err
+	}
+	if removed && s.BlockNotifier != nil {
+		s.BlockNotifier.NotifyBlockChanged(blockerID, blockedID, false)
+	}
+	return nil
+}
+
+// GetBlockedUsers returns the users the user has blocked, most recent first.
+func (s *UserService) GetBlockedUsers(ctx context.Context, blockerID uuid.UUID) ([]repository.BlockedUser, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.userRepo.GetBlockedUsers(ctx, blockerID)
+}
+
+// GetBlockedUserIDs returns the IDs of the users the user has blocked.
+func (s *UserService) GetBlockedUserIDs(ctx context.Context, blockerID uuid.UUID) ([]uuid.UUID, error) {
+	blocked, err := s.GetBlockedUsers(ctx, blockerID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uuid.UUID, 0, len(blocked))
+	for _, user := range blocked {
+		ids = append(ids, user.UserID)
+	}
+	return ids, nil
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	repository "chat-application/internal/repo/user"
+
+	"github.com/google/uuid"
+)
+
+type blockChange struct {
+	blockerID, blockedID uuid.UUID
+	blocked              bool
+}
+
+type fakeBlockNotifier struct {
+	changes []blockChange
+}
+
+func (f *fakeBlockNotifier) NotifyBlockChanged(blockerID, blockedID uuid.UUID, blocked bool) {
+	f.changes = append(f.changes, blockChange{blockerID, blockedID, blocked})
+}
+
+func TestBlockUserNotifiesOnlyOnChange(t *testing.T) {
+	blockerID, blockedID := uuid.New(), uuid.New()
+	repo := &fakeUserRepository{
+		getByIDFn: func(ctx context.Context, id uuid.UUID) (*repository.User, error) {
+			if id == blockedID {
+				return &repository.User{ID: id, Username: "mallory"}, nil
+			}
+			return nil, nil
+		},
+	}
+	notifier := &fakeBlockNotifier{}
+	service := NewUserService(repo)
+	service.BlockNotifier = notifier
+
+	if err := service.BlockUser(context.Background(), blockerID, blockerID); !errors.Is(err, ErrCannotBlockSelf) {
+		t.Fatalf("expected ErrCannotBlockSelf, got %v", err)
+	}
+	if err := service.BlockUser(context.Background(), blockerID, uuid.New()); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, got %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := service.BlockUser(context.Background(), blockerID, blockedID); err != nil {
+			t.Fatalf("BlockUser: %v", err)
+		}
+	}
+	ids, err := service.GetBlockedUserIDs(context.Background(), blockerID)
+	if err != nil {
+		t.Fatalf("GetBlockedUserIDs: %v", err)
+	}
+	if len(ids) != 1 || ids[0] != blockedID {
+		t.Fatalf("expected %s to be blocked, got %v", blockedID, ids)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := service.UnblockUser(context.Background(), blockerID, blockedID); err != nil {
+			t.Fatalf("UnblockUser: %v", err)
+		}
+	}
+
+	want := []blockChange{{blockerID, blockedID, true}, {blockerID, blockedID, false}}
+	if len(notifier.changes) != len(want) || notifier.changes[0] != want[0] || notifier.changes[1] != want[1] {
+		t.Fatalf("expected one block and one unblock, got %+v", notifier.changes)
+	}
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+
+	"chat-application/internal/api/model"
+	"chat-application/internal/constants"
+	repository "chat-application/internal/repo/user"
+	"chat-application/util"
+
+	"github.com/google/uuid"
+)
+
+var ErrDeleteNotConfirmed = errors.New("type your username to confirm")
+
+// ExportUserData returns everything stored about the user.
+func (s *UserService) ExportUserData(ctx context.Context, userID uuid.UUID) (*repository.UserExport, error) {
+	// Exports read several tables and can take longer than other requests.
+	ctx, cancel := context.WithTimeout(ctx, constants.AccountExportTimeout)
+	defer cancel()
+
+	export, err := s.userRepo.ExportUserData(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if export == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	return export, nil
+}
+
+// WriteExportArchive writes an export as a ZIP archive with one JSON file
+// per section.
+func WriteExportArchive(w io.Writer, export *repository.UserExport) error {
+	archive := zip.NewWriter(w)
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", export.Profile},
+		{"messages.json", export.Messages},
+		{"reactions.json", export.Reactions},
+		{"achievements.json", export.Achievements},
+		{"notifications.json", export.Notifications},
+	}
+
+	for _, file := range files {
+		f, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     file.name,
+			Method:   zip.Deflate,
+			Modified: export.ExportedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add %s: %w", file.name, err)
+		}
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.name, err)
+		}
+	}
+
+	return archive.Close()
+}
+
+// DeleteAccount deletes the user after they confirm with their password, or
+// by typing their username if they have none. Users with two-factor
+// authentication must also give a current code.
+func (s *UserService) DeleteAccount(ctx context.Context, userID uuid.UUID, req model.RequestDeleteAccount) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return fmt.Errorf("user not found")
+	}
+
+	if user.PasswordHash != nil {
+		if req.Password == "" || util.CheckPassword(*user.PasswordHash, req.Password) != nil {
+			return ErrInvalidPassword
+		}
+	} else if req.Confirm != user.Username {
+		return ErrDeleteNotConfirmed
+	}
+
+	state, err := s.userRepo.GetTOTP(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if state != nil && state.EnabledAt != nil {
+		if err := s.checkTOTPCode(ctx, userID, state, req.Code); err != nil {
+			return err
+		}
+	}
+
+	profile, err := s.userRepo.GetProfile(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userRepo.DeleteUser(ctx, userID); err != nil {
+		return err
+	}
+	if profile != nil && profile.AvatarKey != nil {
+		s.deleteAvatar(ctx, *profile.AvatarKey)
+	}
+	log.Printf("UserService.DeleteAccount - Deleted user: %s", userID.String())
+	return nil
+}
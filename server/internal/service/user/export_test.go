@@ -0,0 +1,96 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"chat-application/internal/api/model"
+	repository "chat-application/internal/repo/user"
+	"chat-application/internal/totp"
+
+	"github.com/google/uuid"
+)
+
+func TestWriteExportArchiveHasOneFilePerSection(t *testing.T) {
+	export := &repository.UserExport{
+		ExportedAt: time.Now().UTC(),
+		Profile:    repository.ExportProfile{ID: uuid.New(), Username: "alice"},
+		Messages:   []repository.ExportMessage{{ID: uuid.New(), Content: "hello"}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteExportArchive(&buf, export); err != nil {
+		t.Fatalf("WriteExportArchive: %v", err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("invalid zip: %v", err)
+	}
+
+	names := map[string]*zip.File{}
+	for _, f := range archive.File {
+		names[f.Name] = f
+	}
+	for _, name := range []string{"profile.json", "messages.json", "reactions.json", "achievements.json", "notifications.json"} {
+		if names[name] == nil {
+			t.Fatalf("expected %s in the archive", name)
+		}
+	}
+
+	f, err := names["messages.json"].Open()
+	if err != nil {
+		t.Fatalf("open messages.json: %v", err)
+	}
+	defer f.Close()
+	var messages []repository.ExportMessage
+	if err := json.NewDecoder(f).Decode(&messages); err != nil || len(messages) != 1 || messages[0].Content != "hello" {
+		t.Fatalf("unexpected messages.json %v %v", messages, err)
+	}
+}
+
+func TestDeleteAccountRequiresReauthentication(t *testing.T) {
+	passwordless := &repository.User{ID: uuid.New(), Username: "bob", Email: "bob@example.com"}
+	var deleted []uuid.UUID
+	repo := &fakeUserRepository{
+		getByIDFn: func(ctx context.Context, id uuid.UUID) (*repository.User, error) { return passwordless, nil },
+		deleteUserFn: func(ctx context.Context, id uuid.UUID) error {
+			deleted = append(deleted, id)
+			return nil
+		},
+	}
+	svc := NewUserService(repo)
+	ctx := context.Background()
+
+	if err := svc.DeleteAccount(ctx, passwordless.ID, model.RequestDeleteAccount{Confirm: "alice"}); !errors.Is(err, ErrDeleteNotConfirmed) {
+		t.Fatalf("expected the wrong username to be refused, got %v", err)
+	}
+
+	secret, _ := totp.GenerateSecret()
+	svc.TOTPEncryptionKey = "totp-key"
+	encrypted, err := svc.encryptTOTPSecret(secret)
+	if err != nil {
+		t.Fatalf("encryptTOTPSecret: %v", err)
+	}
+	enabledAt := time.Now()
+	repo.totp = repository.TOTPState{Secret: &encrypted, EnabledAt: &enabledAt}
+
+	if err := svc.DeleteAccount(ctx, passwordless.ID, model.RequestDeleteAccount{Confirm: "bob", Code: "000000"}); !errors.Is(err, ErrInvalidTOTPCode) {
+		t.Fatalf("expected a missing second factor to be refused, got %v", err)
+	}
+	if len(deleted) != 0 {
+		t.Fatal("expected nothing to be deleted yet")
+	}
+
+	code, _ := totp.Code(secret, totp.Counter(time.Now()))
+	if err := svc.DeleteAccount(ctx, passwordless.ID, model.RequestDeleteAccount{Confirm: "bob", Code: code}); err != nil {
+		t.Fatalf("DeleteAccount: %v", err)
+	}
+	if len(deleted) != 1 || deleted[0] != passwordless.ID {
+		t.Fatalf("expected the user to be deleted, got %v", deleted)
+	}
+}
@@ -0,0 +1,330 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"chat-application/internal/api/model"
+	"chat-application/internal/constants"
+	"chat-application/internal/oauth"
+	repository "chat-application/internal/repo/user"
+	"chat-application/util"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUnknownProvider    = errors.New("unknown login provider")
+	ErrInvalidOAuthState  = errors.New("login session expired, please try again")
+	ErrOAuthEmailRequired = errors.New("the provider did not share a verified email address")
+	ErrIdentityInUse      = errors.New("that account is already linked to another user")
+	ErrIdentityNotLinked  = errors.New("that provider is not linked")
+	ErrLastSignInMethod   = errors.New("cannot unlink your only way to sign in")
+)
+
+// oauthStateAudience keeps state tokens from being mistaken for sessions.
+const oauthStateAudience = "oauth-state"
+
+// oauthState travels in a short-lived cookie between BeginOAuth and
+// CompleteOAuth, so no server-side session is needed.
+type oauthState struct {
+	Provider   string `json:"provider"`
+	State      string `json:"state"`
+	Nonce      string `json:"nonce"`
+	Verifier   string `json:"verifier"`
+	LinkUserID string `json:"link_user_id,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// OAuthProviderNames returns the configured login providers, sorted.
+func (s *UserService) OAuthProviderNames() []string {
+	names := make([]string, 0, len(s.OAuthProviders))
+	for name := range s.OAuthProviders {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (s *UserService) oauthRedirectURI(provider string) string {
+	return strings.TrimRight(s.APIURL, "/") + "/api/users/oauth/" + provider + "/callback"
+}
+
+// BeginOAuth starts a login with the provider. It returns the provider URL to
+// send the user to and a signed state value for the caller to keep in a
+// cookie. With linkUserID the provider account is linked to that user
+// instead of signing in.
+func (s *UserService) BeginOAuth(ctx context.Context, providerName string, linkUserID *uuid.UUID) (string, string, error) {
+	provider, ok := s.OAuthProviders[providerName]
+	if !ok {
+		return "", "", ErrUnknownProvider
+	}
+	secretKey := util.GetEnv("JWT_SECRET_KEY", "")
+	if secretKey == "" {
+		return "", "", fmt.Errorf("server configuration error")
+	}
+
+	state := oauthState{
+		Provider: providerName,
+		State:    oauth.NewVerifier(),
+		Nonce:    oauth.NewVerifier(),
+		Verifier: oauth.NewVerifier(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{oauthStateAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(constants.OAuthStateExpiry)),
+		},
+	}
+	if linkUserID != nil {
+		state.LinkUserID = linkUserID.String()
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, state).SignedString([]byte(secretKey))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to sign login state: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, constants.OAuthRequestTimeout)
+	defer cancel()
+	authURL, err := provider.AuthCodeURL(ctx, s.oauthRedirectURI(providerName), state.State, state.Nonce, state.Verifier)
+	if err != nil {
+		return "", "", err
+	}
+	return authURL, signed, nil
+}
+
+// CompleteOAuth finishes a login from the provider's callback. The provider
+// account signs in as the user it is linked to; otherwise it is linked to the
+// account with the same verified email, or a new account is created.
+func (s *UserService) CompleteOAuth(ctx context.Context, providerName, code, state, stateCookie string, client LoginClient) (*model.ResponseLoginUser, error) {
+	provider, ok := s.OAuthProviders[providerName]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	saved, err := parseOAuthState(stateCookie)
+	if err != nil || saved.Provider != providerName || subtle.ConstantTimeCompare([]byte(saved.State), []byte(state)) != 1 {
+		return nil, ErrInvalidOAuthState
+	}
+
+	providerCtx, cancel := context.WithTimeout(ctx, constants.OAuthRequestTimeout)
+	defer cancel()
+	token, err := provider.Exchange(providerCtx, code, s.oauthRedirectURI(providerName), saved.Verifier)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := provider.Identity(providerCtx, token, saved.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel = context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	linked := &repository.LinkedIdentity{Provider: providerName, Subject: identity.Subject}
+	if identity.Email != "" {
+		linked.Email = &identity.Email
+	}
+
+	if saved.LinkUserID != "" {
+		userID, err := uuid.Parse(saved.LinkUserID)
+		if err != nil {
+			return nil, ErrInvalidOAuthState
+		}
+		return s.linkOAuthIdentity(ctx, userID, linked)
+	}
+
+	user, err := s.userRepo.GetUserByIdentity(ctx, providerName, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil {
+		return s.completeLogin(ctx, user, providerName, client)
+	}
+
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, ErrOAuthEmailRequired
+	}
+
+	user, err = s.userRepo.GetUserByEmail(ctx, identity.Email)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil {
+		linked.UserID = user.ID
+		if err := s.userRepo.LinkIdentity(ctx, linked, true); err != nil {
+			if errors.Is(err, repository.ErrIdentityLinked) {
+				return nil, ErrIdentityInUse
+			}
+			return nil, err
+		}
+		log.Printf("UserService.CompleteOAuth - linked %s to existing user %s by email", providerName, user.ID)
+		return s.completeLogin(ctx, user, providerName, client)
+	}
+
+	user, err = s.createOAuthUser(ctx, identity, linked)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("UserService.CompleteOAuth - created user %s from %s", user.ID, providerName)
+	return s.finishLogin(ctx, user, providerName, client)
+}
+
+func (s *UserService) linkOAuthIdentity(ctx context.Context, userID uuid.UUID, linked *repository.LinkedIdentity) (*model.ResponseLoginUser, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidOAuthState
+	}
+
+	linked.UserID = userID
+	if err := s.userRepo.LinkIdentity(ctx, linked, false); err != nil {
+		if !errors.Is(err, repository.ErrIdentityLinked) {
+			return nil, err
+		}
+		// Linking the same account twice is harmless.
+		owner, err := s.userRepo.GetUserByIdentity(ctx, linked.Provider, linked.Subject)
+		if err != nil {
+			return nil, err
+		}
+		if owner == nil || owner.ID != userID {
+			return nil, ErrIdentityInUse
+		}
+	}
+	return s.loginResponse(user)
+}
+
+// createOAuthUser signs up a passwordless user, picking a free username based
+// on the provider's handle, name or email.
+func (s *UserService) createOAuthUser(ctx context.Context, identity *oauth.Identity, linked *repository.LinkedIdentity) (*repository.User, error) {
+	base := oauthUsername(identity)
+	verifiedAt := time.Now()
+
+	var lastErr error
+	for attempt := 0; attempt < 5; attempt++ {
+		username := base
+		if attempt > 0 {
+			username = fmt.Sprintf("%s_%04d", base, rand.IntN(10000))
+		}
+		user, err := s.userRepo.CreateUserWithIdentity(ctx, &repository.User{
+			Username:        username,
+			Email:           identity.Email,
+			EmailVerifiedAt: &verifiedAt,
+		}, linked)
+		if err == nil {
+			return user, nil
+		}
+		if errors.Is(err, repository.ErrIdentityLinked) {
+			return nil, ErrIdentityInUse
+		}
+		if !strings.Contains(err.Error(), "already exists") {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("failed to find a free username: %w", lastErr)
+}
+
+// oauthUsername turns a provider handle into a valid username of at most 15
+// characters, leaving room for a numeric suffix.
+func oauthUsername(identity *oauth.Identity) string {
+	candidate := identity.Username
+	if candidate == "" {
+		candidate = identity.Name
+	}
+	if candidate == "" {
+		candidate, _, _ = strings.Cut(identity.Email, "@")
+	}
+
+	var b strings.Builder
+	for _, r := range candidate {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.':
+			b.WriteByte('_')
+		}
+		if b.Len() == 15 {
+			break
+		}
+	}
+	username := strings.Trim(b.String(), "_")
+	if len(username) < 3 {
+		username = "user"
+	}
+	return username
+}
+
+func parseOAuthState(value string) (*oauthState, error) {
+	secretKey := util.GetEnv("JWT_SECRET_KEY", "")
+	if secretKey == "" || value == "" {
+		return nil, ErrInvalidOAuthState
+	}
+
+	var state oauthState
+	_, err := jwt.ParseWithClaims(value, &state, func(*jwt.Token) (any, error) {
+		return []byte(secretKey), nil
+	},
+		jwt.WithValidMethods([]string{"HS256"}),
+		jwt.WithAudience(oauthStateAudience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, ErrInvalidOAuthState
+	}
+	return &state, nil
+}
+
+func (s *UserService) loginResponse(user *repository.User) (*model.ResponseLoginUser, error) {
+	ss, err := s.generateJWTToken(user.ID.String(), user.Username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate authentication token")
+	}
+	return &model.ResponseLoginUser{
+		AccessToken:   ss,
+		Username:      user.Username,
+		ID:            user.ID.String(),
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt != nil,
+	}, nil
+}
+
+// GetLinkedIdentities returns the provider accounts linked to the user.
+func (s *UserService) GetLinkedIdentities(ctx context.Context, userID uuid.UUID) ([]repository.LinkedIdentity, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.userRepo.GetLinkedIdentities(ctx, userID)
+}
+
+// UnlinkIdentity removes a linked provider account. It refuses to remove the
+// user's only way to sign in.
+func (s *UserService) UnlinkIdentity(ctx context.Context, userID uuid.UUID, provider string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	removed, err := s.userRepo.UnlinkIdentity(ctx, userID, provider)
+	if err != nil {
+		return err
+	}
+	if removed {
+		return nil
+	}
+
+	identities, err := s.userRepo.GetLinkedIdentities(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, identity := range identities {
+		if identity.Provider == provider {
+			return ErrLastSignInMethod
+		}
+	}
+	return ErrIdentityNotLinked
+}
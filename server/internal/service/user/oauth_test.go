@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"chat-application/internal/oauth"
+	"chat-application/internal/oauth/oauthtest"
+	repository "chat-application/internal/repo/user"
+	"chat-application/util"
+
+	"github.com/google/uuid"
+)
+
+func TestOAuthLoginLinksVerifiedEmailAndCreatesUsers(t *testing.T) {
+	t.Setenv("JWT_SECRET_KEY", "test-secret")
+
+	mock := oauthtest.NewProvider("yappin", "client-secret")
+	defer mock.Close()
+
+	password, _ := util.HashPassword("Super-secret-pass1")
+	alice := &repository.User{ID: uuid.New(), Username: "alice", Email: "alice@example.com", PasswordHash: &password}
+	repo := &fakeUserRepository{
+		getByEmailFn: func(ctx context.Context, email string) (*repository.User, error) {
+			if email == alice.Email {
+				return alice, nil
+			}
+			return nil, nil
+		},
+		getByIDFn: func(ctx context.Context, id uuid.UUID) (*repository.User, error) {
+			if id == alice.ID {
+				return alice, nil
+			}
+			return nil, nil
+		},
+	}
+	service := NewUserService(repo)
+	service.APIURL = "http://localhost:8080"
+	service.OAuthProviders = map[string]*oauth.Provider{
+		"mock": oauth.NewOIDCProvider("mock", mock.Issuer(), "yappin", "client-secret", mock.Client()),
+	}
+
+	login := func(user oauthtest.User, tamperState bool) (string, error) {
+		t.Helper()
+		mock.SignInAs(user)
+		authURL, stateCookie, err := service.BeginOAuth(context.Background(), "mock", nil)
+		if err != nil {
+			t.Fatalf("BeginOAuth: %v", err)
+		}
+		callback, err := mock.Authorize(authURL)
+		if err != nil {
+			t.Fatalf("Authorize: %v", err)
+		}
+		if callback.Path != "/api/users/oauth/mock/callback" {
+			t.Fatalf("unexpected callback %s", callback)
+		}
+		state := callback.Query().Get("state")
+		if tamperState {
+			state = oauth.NewVerifier()
+		}
+		res, err := service.CompleteOAuth(context.Background(), "mock", callback.Query().Get("code"), state, stateCookie, LoginClient{})
+		if err != nil {
+			return "", err
+		}
+		if res.AccessToken == "" {
+			t.Fatal("expected an access token")
+		}
+		return res.ID, nil
+	}
+
+	aliceAtProvider := oauthtest.User{Subject: "a-1", Email: alice.Email, EmailVerified: true, Username: "alice"}
+	if _, err := login(aliceAtProvider, true); !errors.Is(err, ErrInvalidOAuthState) {
+		t.Fatalf("expected a forged state to be rejected, got %v", err)
+	}
+
+	id, err := login(aliceAtProvider, false)
+	if err != nil || id != alice.ID.String() {
+		t.Fatalf("expected to sign in as the existing account, got %s (%v)", id, err)
+	}
+	if len(repo.emailsVerified) != 1 || repo.emailsVerified[0] != alice.ID {
+		t.Fatalf("expected linking by email to verify the account, got %v", repo.emailsVerified)
+	}
+	if id, err := login(aliceAtProvider, false); err != nil || id != alice.ID.String() {
+		t.Fatalf("expected the linked identity to sign in again, got %s (%v)", id, err)
+	}
+
+	if _, err := login(oauthtest.User{Subject: "b-1", Email: "bob@example.com"}, false); !errors.Is(err, ErrOAuthEmailRequired) {
+		t.Fatalf("expected an unverified email to be refused, got %v", err)
+	}
+
+	if _, err := login(oauthtest.User{Subject: "c-1", Email: "carol@example.com", EmailVerified: true, Name: "Carol Danvers"}, false); err != nil {
+		t.Fatalf("expected a new account, got %v", err)
+	}
+	if len(repo.created) != 1 || repo.created[0].Username != "Carol_Danvers" || repo.created[0].PasswordHash != nil || repo.created[0].EmailVerifiedAt == nil {
+		t.Fatalf("unexpected created user %+v", repo.created)
+	}
+}
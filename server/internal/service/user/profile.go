@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"chat-application/internal/api/model"
+	"chat-application/internal/constants"
+	repository "chat-application/internal/repo/user"
+	"chat-application/util"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidProfile     = errors.New("invalid profile")
+	ErrAvatarsUnavailable = errors.New("avatar uploads are not available")
+	ErrAvatarNotFound     = errors.New("avatar not found")
+)
+
+// AvatarStore resizes and stores avatar images.
+type AvatarStore interface {
+	PutAvatar(ctx context.Context, userID uuid.UUID, body io.Reader) (string, error)
+	OpenAvatar(ctx context.Context, key string) (io.ReadCloser, string, error)
+	DeleteAvatar(ctx context.Context, key string) error
+}
+
+// ProfileNotifier tells the rooms a user is in that their profile changed.
+type ProfileNotifier interface {
+	NotifyProfileUpdated(profile *model.UserProfileRes)
+}
+
+// GetProfile returns what the user shows to others.
+// Returns nil, nil if the user is not found.
+func (s *UserService) GetProfile(ctx context.Context, userID uuid.UUID) (*model.UserProfileRes, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	profile, err := s.userRepo.GetProfile(ctx, userID)
+	if err != nil || profile == nil {
+		return nil, err
+	}
+	return profileResponse(profile), nil
+}
+
+// UpdateProfile replaces the user's display name, bio, pronouns and status.
+func (s *UserService) UpdateProfile(ctx context.Context, userID uuid.UUID, req model.RequestUpdateProfile) (*model.UserProfileRes, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	update, err := profileUpdate(req, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := s.userRepo.UpdateProfile(ctx, userID, update)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	return s.profileChanged(profile), nil
+}
+
+// UploadAvatar resizes and stores a new avatar, replacing the previous one.
+func (s *UserService) UploadAvatar(ctx context.Context, userID uuid.UUID, body io.Reader) (*model.UserProfileRes, error) {
+	if s.Avatars == nil {
+		return nil, ErrAvatarsUnavailable
+	}
+
+	key, err := s.Avatars.PutAvatar(ctx, userID, body)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	profile, previous, err := s.userRepo.SetAvatar(ctx, userID, &key)
+	if err != nil || profile == nil {
+		s.deleteAvatar(ctx, key)
+		if err == nil {
+			err = fmt.Errorf("user not found")
+		}
+		return nil, err
+	}
+	if previous != nil {
+		s.deleteAvatar(ctx, *previous)
+	}
+	return s.profileChanged(profile), nil
+}
+
+// RemoveAvatar clears the user's avatar.
+func (s *UserService) RemoveAvatar(ctx context.Context, userID uuid.UUID) (*model.UserProfileRes, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	profile, previous, err := s.userRepo.SetAvatar(ctx, userID, nil)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	if previous != nil {
+		s.deleteAvatar(ctx, *previous)
+	}
+	return s.profileChanged(profile), nil
+}
+
+// OpenAvatar returns a reader for the user's avatar and its content type.
+// The caller must close the reader.
+func (s *UserService) OpenAvatar(ctx context.Context, userID uuid.UUID) (io.ReadCloser, string, error) {
+	if s.Avatars == nil {
+		return nil, "", ErrAvatarNotFound
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	profile, err := s.userRepo.GetProfile(lookupCtx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if profile == nil || profile.AvatarKey == nil {
+		return nil, "", ErrAvatarNotFound
+	}
+	return s.Avatars.OpenAvatar(ctx, *profile.AvatarKey)
+}
+
+// profileChanged announces an updated profile and returns its response.
+func (s *UserService) profileChanged(profile *repository.Profile) *model.UserProfileRes {
+	res := profileResponse(profile)
+	if s.ProfileNotifier != nil {
+		s.ProfileNotifier.NotifyProfileUpdated(res)
+	}
+	return res
+}
+
+// deleteAvatar removes a replaced avatar. A leftover blob only wastes
+// space, so failures are logged.
+func (s *UserService) deleteAvatar(ctx context.Context, key string) {
+	if s.Avatars == nil {
+		return
+	}
+	if err := s.Avatars.DeleteAvatar(ctx, key); err != nil {
+		log.Printf("UserService.deleteAvatar - Failed to delete avatar %s: %v", key, err)
+	}
+}
+
+// profileUpdate validates a profile request. Blank fields are cleared.
+func profileUpdate(req model.RequestUpdateProfile, now time.Time) (*repository.ProfileUpdate, error) {
+	update := &repository.ProfileUpdate{}
+	var err error
+	if update.DisplayName, err = profileField("display name", req.DisplayName, constants.MaxDisplayNameLength, false); err != nil {
+		return nil, err
+	}
+	if update.Bio, err = profileField("bio", req.Bio, constants.MaxBioLength, true); err != nil {
+		return nil, err
+	}
+	if update.Pronouns, err = profileField("pronouns", req.Pronouns, constants.MaxPronounsLength, false); err != nil {
+		return nil, err
+	}
+	if req.Status == nil {
+		return update, nil
+	}
+
+	if update.StatusText, err = profileField("status", req.Status.Text, constants.MaxStatusTextLength, false); err != nil {
+		return nil, err
+	}
+	if update.StatusEmoji, err = profileField("status emoji", req.Status.Emoji, constants.MaxStatusEmojiLength, false); err != nil {
+		return nil, err
+	}
+	if req.Status.ExpiresAt != nil && (update.StatusText != nil || update.StatusEmoji != nil) {
+		if !req.Status.ExpiresAt.After(now) {
+			return nil, fmt.Errorf("%w: status expiry must be in the future", ErrInvalidProfile)
+		}
+		expiresAt := req.Status.ExpiresAt.UTC()
+		update.StatusExpiresAt = &expiresAt
+	}
+	return update, nil
+}
+
+// profileField sanitises a profile field, returning nil for a blank value.
+// Only multiline fields keep their line breaks.
+func profileField(name, value string, maxLength int, multiline bool) (*string, error) {
+	value = util.SanitizeString(value)
+	if !multiline {
+		value = strings.Join(strings.Fields(value), " ")
+	}
+	if utf8.RuneCountInString(value) > maxLength {
+		return nil, fmt.Errorf("%w: %s must be at most %d characters", ErrInvalidProfile, name, maxLength)
+	}
+	if value == "" {
+		return nil, nil
+	}
+	return &value, nil
+}
+
+func profileResponse(profile *repository.Profile) *model.UserProfileRes {
+	res := &model.UserProfileRes{
+		UserID:   profile.UserID.String(),
+		Username: profile.Username,
+	}
+	if profile.DisplayName != nil {
+		res.DisplayName = *profile.DisplayName
+	}
+	if profile.Bio != nil {
+		res.Bio = *profile.Bio
+	}
+	if profile.Pronouns != nil {
+		res.Pronouns = *profile.Pronouns
+	}
+	if profile.AvatarKey != nil {
+		// The key changes with every upload, so it doubles as a cache buster.
+		version := strings.TrimSuffix(path.Base(*profile.AvatarKey), path.Ext(*profile.AvatarKey))
+		res.AvatarURL = "/api/users/" + profile.UserID.String() + "/avatar?v=" + version
+	}
+	if profile.StatusText != nil || profile.StatusEmoji != nil {
+		res.Status = &model.UserStatusRes{ExpiresAt: profile.StatusExpiresAt}
+		if profile.StatusText != nil {
+			res.Status.Text = *profile.StatusText
+		}
+		if profile.StatusEmoji != nil {
+			res.Status.Emoji = *profile.StatusEmoji
+		}
+	}
+	return res
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"chat-application/internal/api/model"
+	repository "chat-application/internal/repo/user"
+
+	"github.com/google/uuid"
+)
+
+type fakeAvatarStore struct {
+	stored  []string
+	deleted []string
+}
+
+func (f *fakeAvatarStore) PutAvatar(ctx context.Context, userID uuid.UUID, body io.Reader) (string, error) {
+	key := "avatars/" + userID.String() + "/" + uuid.NewString() + ".png"
+	f.stored = append(f.stored, key)
+	return key, nil
+}
+
+func (f *fakeAvatarStore) OpenAvatar(ctx context.Context, key string) (io.ReadCloser, string, error) {
+	return io.NopCloser(strings.NewReader(key)), "image/png", nil
+}
+
+func (f *fakeAvatarStore) DeleteAvatar(ctx context.Context, key string) error {
+	f.deleted = append(f.deleted, key)
+	return nil
+}
+
+type fakeProfileNotifier struct {
+	updates []*model.UserProfileRes
+}
+
+func (f *fakeProfileNotifier) NotifyProfileUpdated(profile *model.UserProfileRes) {
+	f.updates = append(f.updates, profile)
+}
+
+func TestUpdateProfileValidatesAndNotifies(t *testing.T) {
+	userID := uuid.New()
+	repo := &fakeUserRepository{profile: &repository.Profile{UserID: userID, Username: "alice"}}
+	notifier := &fakeProfileNotifier{}
+	service := NewUserService(repo)
+	service.ProfileNotifier = notifier
+
+	expiresAt := time.Now().Add(time.Hour)
+	profile, err := service.UpdateProfile(context.Background(), userID, model.RequestUpdateProfile{
+		DisplayName: "  Alice \n Liddell ",
+		Bio:         "Curiouser\nand curiouser",
+		Pronouns:    "she/her",
+		Status:      &model.RequestUpdateStatus{Text: "Down the rabbit hole", Emoji: "🐇", ExpiresAt: &expiresAt},
+	})
+	if err != nil {
+		t.Fatalf("UpdateProfile: %v", err)
+	}
+	if profile.DisplayName != "Alice Liddell" || profile.Bio != "Curiouser\nand curiouser" || profile.Pronouns != "she/her" {
+		t.Fatalf("unexpected profile %+v", profile)
+	}
+	if profile.Status == nil || profile.Status.Emoji != "🐇" || profile.Status.ExpiresAt == nil {
+		t.Fatalf("expected status with expiry, got %+v", profile.Status)
+	}
+	if len(notifier.updates) != 1 || notifier.updates[0].DisplayName != "Alice Liddell" {
+		t.Fatalf("expected rooms to be notified once, got %+v", notifier.updates)
+	}
+
+	invalid := []model.RequestUpdateProfile{
+		{DisplayName: strings.Repeat("a", 51)},
+		{Bio: strings.Repeat("é", 501)},
+		{Status: &model.RequestUpdateStatus{Text: "gone", ExpiresAt: &time.Time{}}},
+	}
+	for _, req := range invalid {
+		if _, err := service.UpdateProfile(context.Background(), userID, req); !errors.Is(err, ErrInvalidProfile) {
+			t.Fatalf("expected ErrInvalidProfile for %+v, got %v", req, err)
+		}
+	}
+	if len(notifier.updates) != 1 {
+		t.Fatal("expected rejected updates not to be announced")
+	}
+}
+
+func TestUploadAvatarReplacesPreviousAvatar(t *testing.T) {
+	userID := uuid.New()
+	repo := &fakeUserRepository{profile: &repository.Profile{UserID: userID, Username: "alice"}}
+	avatars := &fakeAvatarStore{}
+	service := NewUserService(repo)
+
+	if _, err := service.UploadAvatar(context.Background(), userID, bytes.NewReader(nil)); !errors.Is(err, ErrAvatarsUnavailable) {
+		t.Fatalf("expected uploads to be refused without a store, got %v", err)
+	}
+	service.Avatars = avatars
+
+	first, err := service.UploadAvatar(context.Background(), userID, bytes.NewReader(nil))
+	if err != nil {
+		t.Fatalf("UploadAvatar: %v", err)
+	}
+	second, err := service.UploadAvatar(context.Background(), userID, bytes.NewReader(nil))
+	if err != nil {
+		t.Fatalf("UploadAvatar: %v", err)
+	}
+	if first.AvatarURL == "" || first.AvatarURL == second.AvatarURL {
+		t.Fatalf("expected each upload to get a new avatar URL, got %q and %q", first.AvatarURL, second.AvatarURL)
+	}
+	if len(avatars.deleted) != 1 || avatars.deleted[0] != avatars.stored[0] {
+		t.Fatalf("expected the first avatar to be deleted, got %v", avatars.deleted)
+	}
+
+	if _, err := service.RemoveAvatar(context.Background(), userID); err != nil {
+		t.Fatalf("RemoveAvatar: %v", err)
+	}
+	if _, _, err := service.OpenAvatar(context.Background(), userID); !errors.Is(err, ErrAvatarNotFound) {
+		t.Fatalf("expected removed avatar to be gone, got %v", err)
+	}
+	if len(avatars.deleted) != 2 {
+		t.Fatalf("expected the second avatar to be deleted, got %v", avatars.deleted)
+	}
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"chat-application/internal/api/model"
+	"chat-application/internal/constants"
+	"chat-application/internal/pagination"
+	repository "chat-application/internal/repo/user"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidSearch = errors.New("search query is invalid")
+	ErrRoomNotFound  = errors.New("room not found")
+	ErrRoomForbidden = errors.New("this room is invite-only")
+)
+
+// SearchUsers looks users up by username or display name for the directory.
+// It returns the next page's cursor, or nil on the last page. Results carry
+// only public profile fields, never email addresses, and leave out users
+// who have blocked the viewer.
+func (s *UserService) SearchUsers(ctx context.Context, viewerID uuid.UUID, query string, after *pagination.RankCursor, limit int) ([]*model.UserProfileRes, *pagination.RankCursor, error) {
+	query, err := searchQuery(query)
+	if err != nil {
+		return nil, nil, err
+	}
+	if query == "" {
+		return nil, nil, ErrInvalidSearch
+	}
+
+	return s.searchUsers(ctx, repository.UserSearch{
+		Query:    query,
+		Fuzzy:    utf8.RuneCountInString(query) >= constants.MinFuzzySearchLength,
+		ViewerID: viewerID,
+		After:    after,
+		Limit:    limit,
+	})
+}
+
+// AutocompleteMembers suggests members of a room whose username or display
+// name starts with query, for the mention picker. An empty query lists
+// members alphabetically. Members of invite-only rooms are only listed to
+// other members.
+func (s *UserService) AutocompleteMembers(ctx context.Context, viewerID, roomID uuid.UUID, query string) ([]*model.UserProfileRes, error) {
+	query, err := searchQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	exists, readable, err := s.userRepo.CanReadRoom(ctx, roomID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrRoomNotFound
+	}
+	if !readable {
+		return nil, ErrRoomForbidden
+	}
+
+	results, _, err := s.searchUsers(ctx, repository.UserSearch{
+		Query:    query,
+		RoomID:   &roomID,
+		ViewerID: viewerID,
+		Limit:    constants.AutocompleteLimit,
+	})
+	return results, err
+}
+
+func (s *UserService) searchUsers(ctx context.Context, search repository.UserSearch) ([]*model.UserProfileRes, *pagination.RankCursor, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	// One extra row tells whether there is another page.
+	limit := search.Limit
+	search.Limit++
+	matches, err := s.userRepo.SearchUsers(ctx, search)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var next *pagination.RankCursor
+	if len(matches) > limit {
+		matches = matches[:limit]
+		cursor := matches[limit-1].Cursor()
+		next = &cursor
+	}
+
+	results := make([]*model.UserProfileRes, 0, len(matches))
+	for i := range matches {
+		results = append(results, profileResponse(&matches[i].Profile))
+	}
+	return results, next, nil
+}
+
+// searchQuery normalises a query, dropping the @ a mention starts with.
+func searchQuery(query string) (string, error) {
+	query = strings.TrimPrefix(strings.TrimSpace(query), "@")
+	if utf8.RuneCountInString(query) > constants.MaxUserSearchLength {
+		return "", ErrInvalidSearch
+	}
+	return query, nil
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	repository "chat-application/internal/repo/user"
+
+	"github.com/google/uuid"
+)
+
+func TestSearchUsersPagesAndNormalisesQuery(t *testing.T) {
+	avatarKey := "avatars/x/1234.png"
+	repo := &fakeUserRepository{}
+	for _, username := range []string{"Alice", "alicia", "malice"} {
+		repo.matches = append(repo.matches, repository.UserMatch{
+			Profile: repository.Profile{UserID: uuid.New(), Username: username, AvatarKey: &avatarKey},
+			Rank:    1,
+		})
+	}
+	service := NewUserService(repo)
+	viewerID := uuid.New()
+
+	results, next, err := service.SearchUsers(context.Background(), viewerID, "  @ali ", nil, 2)
+	if err != nil {
+		t.Fatalf("SearchUsers: %v", err)
+	}
+	if len(results) != 2 || results[0].Username != "Alice" || results[0].AvatarURL == "" {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	if next == nil || next.Name != "alicia" || next.ID != repo.matches[1].UserID {
+		t.Fatalf("expected a cursor after the second result, got %+v", next)
+	}
+	search := repo.searches[0]
+	if search.Query != "ali" || !search.Fuzzy || search.ViewerID != viewerID || search.Limit != 3 {
+		t.Fatalf("unexpected search %+v", search)
+	}
+
+	if _, next, _ := service.SearchUsers(context.Background(), viewerID, "al", nil, 5); next != nil {
+		t.Fatalf("expected no cursor on the last page, got %+v", next)
+	}
+	if repo.searches[1].Fuzzy {
+		t.Fatal("expected short queries to match prefixes only")
+	}
+
+	for _, query := range []string{" ", "@", strings.Repeat("a", 51)} {
+		if _, _, err := service.SearchUsers(context.Background(), viewerID, query, nil, 5); !errors.Is(err, ErrInvalidSearch) {
+			t.Fatalf("expected ErrInvalidSearch for %q, got %v", query, err)
+		}
+	}
+}
+
+func TestAutocompleteMembersIsScopedToTheRoom(t *testing.T) {
+	roomID, memberID := uuid.New(), uuid.New()
+	repo := &fakeUserRepository{roomReaders: map[uuid.UUID][]uuid.UUID{roomID: {memberID}}}
+	service := NewUserService(repo)
+
+	if _, err := service.AutocompleteMembers(context.Background(), memberID, roomID, "@"); err != nil {
+		t.Fatalf("AutocompleteMembers: %v", err)
+	}
+	search := repo.searches[0]
+	if search.RoomID == nil || *search.RoomID != roomID || search.Query != "" || search.Fuzzy {
+		t.Fatalf("unexpected search %+v", search)
+	}
+}
+
+func TestAutocompleteMembersRefusesOutsidersOfInviteOnlyRooms(t *testing.T) {
+	roomID, memberID := uuid.New(), uuid.New()
+	repo := &fakeUserRepository{roomReaders: map[uuid.UUID][]uuid.UUID{roomID: {memberID}}}
+	service := NewUserService(repo)
+
+	if _, err := service.AutocompleteMembers(context.Background(), uuid.New(), roomID, "a"); !errors.Is(err, ErrRoomForbidden) {
+		t.Fatalf("expected ErrRoomForbidden for a non-member, got %v", err)
+	}
+	if _, err := service.AutocompleteMembers(context.Background(), memberID, uuid.New(), "a"); !errors.Is(err, ErrRoomNotFound) {
+		t.Fatalf("expected ErrRoomNotFound for a missing room, got %v", err)
+	}
+	if len(repo.searches) != 0 {
+		t.Fatalf("expected no member search, got %+v", repo.searches)
+	}
+}
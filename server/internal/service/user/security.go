@@ -0,0 +1,166 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"chat-application/internal/api/model"
+	"chat-application/internal/constants"
+	"chat-application/internal/mailer"
+	"chat-application/internal/pagination"
+	repository "chat-application/internal/repo/user"
+
+	"github.com/google/uuid"
+)
+
+// maxUserAgentLength bounds the user agent kept in the sign-in history.
+const maxUserAgentLength = 512
+
+// LoginClient describes where a sign-in attempt came from.
+type LoginClient struct {
+	IPAddress string
+	UserAgent string
+}
+
+// AccountLockedError is returned while an account refuses sign-in after too
+// many failed attempts.
+type AccountLockedError struct {
+	Until time.Time
+}
+
+func (e *AccountLockedError) Error() string {
+	return "too many failed sign-in attempts, try again later"
+}
+
+// lockoutDuration is how long sign-in is locked after the given number of
+// consecutive failures, or zero below the threshold.
+func lockoutDuration(attempts int) time.Duration {
+	if attempts < constants.LoginLockoutThreshold {
+		return 0
+	}
+	d := constants.LoginLockoutBase
+	for i := constants.LoginLockoutThreshold; i < attempts && d < constants.LoginLockoutMax; i++ {
+		d *= 2
+	}
+	return min(d, constants.LoginLockoutMax)
+}
+
+// checkLoginLock refuses sign-in while the account is locked. It is checked
+// before the password, so a locked account gives nothing away.
+func (s *UserService) checkLoginLock(ctx context.Context, user *repository.User, method string, client LoginClient) error {
+	until, err := s.userRepo.GetLoginLock(ctx, user.ID)
+	if err != nil {
+		log.Printf("UserService.checkLoginLock - Failed to check lock for user %s: %v", user.ID, err)
+		return nil
+	}
+	if until == nil {
+		return nil
+	}
+
+	s.recordLoginEvent(ctx, user.ID, method, repository.LoginOutcomeLocked, client)
+	return &AccountLockedError{Until: *until}
+}
+
+// recordLoginFailure logs a failed attempt and locks the account once there
+// have been too many in a row. The owner is emailed when the first lock of a
+// run of failures starts.
+func (s *UserService) recordLoginFailure(ctx context.Context, user *repository.User, method, outcome string, client LoginClient) {
+	s.recordLoginEvent(ctx, user.ID, method, outcome, client)
+
+	attempts, err := s.userRepo.RecordFailedLogin(ctx, user.ID)
+	if err != nil {
+		log.Printf("UserService.recordLoginFailure - Failed to count failure for user %s: %v", user.ID, err)
+		return
+	}
+	lockFor := lockoutDuration(attempts)
+	if lockFor == 0 {
+		return
+	}
+
+	if err := s.userRepo.LockLogin(ctx, user.ID, time.Now().Add(lockFor)); err != nil {
+		log.Printf("UserService.recordLoginFailure - Failed to lock user %s: %v", user.ID, err)
+		return
+	}
+	log.Printf("UserService.recordLoginFailure - Locked sign-in for user %s for %s after %d failures", user.ID, lockFor, attempts)
+
+	if attempts == constants.LoginLockoutThreshold {
+		if err := s.sendLockoutEmail(ctx, user, attempts, lockFor, client); err != nil {
+			log.Printf("UserService.recordLoginFailure - Lockout email failed for user %s: %v", user.ID, err)
+		}
+	}
+}
+
+// finishLogin issues a session for a user who passed every factor.
+func (s *UserService) finishLogin(ctx context.Context, user *repository.User, method string, client LoginClient) (*model.ResponseLoginUser, error) {
+	if err := s.userRepo.ResetFailedLogins(ctx, user.ID); err != nil {
+		log.Printf("UserService.finishLogin - Failed to reset failures for user %s: %v", user.ID, err)
+	}
+	s.recordLoginEvent(ctx, user.ID, method, repository.LoginOutcomeSuccess, client)
+	return s.loginResponse(user)
+}
+
+// recordLoginEvent adds to the user's sign-in history. The history is best
+// effort and never fails a sign-in.
+func (s *UserService) recordLoginEvent(ctx context.Context, userID uuid.UUID, method, outcome string, client LoginClient) {
+	event := &repository.LoginEvent{
+		UserID:  userID,
+		Method:  method,
+		Outcome: outcome,
+	}
+	if client.IPAddress != "" {
+		event.IPAddress = &client.IPAddress
+	}
+	if client.UserAgent != "" {
+		userAgent := client.UserAgent
+		if len(userAgent) > maxUserAgentLength {
+			userAgent = strings.ToValidUTF8(userAgent[:maxUserAgentLength], "")
+		}
+		event.UserAgent = &userAgent
+	}
+
+	retainAfter := time.Now().Add(-constants.LoginEventRetention)
+	if err := s.userRepo.CreateLoginEvent(ctx, event, retainAfter); err != nil {
+		log.Printf("UserService.recordLoginEvent - Failed to record %s for user %s: %v", outcome, userID, err)
+	}
+}
+
+func (s *UserService) sendLockoutEmail(ctx context.Context, user *repository.User, attempts int, lockFor time.Duration, client LoginClient) error {
+	ipAddress := client.IPAddress
+	if ipAddress == "" {
+		ipAddress = "an unknown address"
+	}
+
+	var text bytes.Buffer
+	err := accountLockedEmail.Execute(&text, struct {
+		Username  string
+		Attempts  int
+		Duration  string
+		IPAddress string
+		Link      string
+	}{
+		Username:  user.Username,
+		Attempts:  attempts,
+		Duration:  formatExpiry(lockFor),
+		IPAddress: ipAddress,
+		Link:      strings.TrimRight(s.AppURL, "/") + "/login",
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.sendEmail(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Sign-in to your Yappin account was paused",
+		Text:    text.String(),
+	})
+}
+
+// GetLoginHistory returns the user's recent sign-in attempts, newest first.
+func (s *UserService) GetLoginHistory(ctx context.Context, userID uuid.UUID, before *pagination.Cursor, limit int) ([]repository.LoginEvent, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.userRepo.GetLoginEvents(ctx, userID, before, limit)
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"chat-application/internal/api/model"
+	"chat-application/internal/mailer"
+	repository "chat-application/internal/repo/user"
+	"chat-application/util"
+
+	"github.com/google/uuid"
+)
+
+func TestLockoutBackoffDoublesUpToTheCap(t *testing.T) {
+	cases := map[int]time.Duration{
+		4:  0,
+		5:  time.Minute,
+		6:  2 * time.Minute,
+		8:  8 * time.Minute,
+		11: time.Hour,
+		99: time.Hour,
+	}
+	for attempts, want := range cases {
+		if got := lockoutDuration(attempts); got != want {
+			t.Errorf("after %d failures expected %s, got %s", attempts, want, got)
+		}
+	}
+}
+
+func TestRepeatedFailuresLockTheAccountAndNotifyTheOwner(t *testing.T) {
+	t.Setenv("JWT_SECRET_KEY", "test-secret")
+
+	hashedPassword, err := util.HashPassword("super-secret-pass")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	user := &repository.User{ID: uuid.New(), Username: "alice", Email: "alice@example.com", PasswordHash: &hashedPassword}
+	repo := &fakeUserRepository{
+		getByEmailFn: func(ctx context.Context, email string) (*repository.User, error) { return user, nil },
+	}
+	memory := &mailer.MemoryMailer{}
+	svc := NewUserService(repo)
+	svc.Mailer = memory
+	client := LoginClient{IPAddress: "203.0.113.7", UserAgent: "curl/8.0"}
+	ctx := context.Background()
+
+	login := func(password string) error {
+		_, err := svc.Login(ctx, model.RequestLoginUser{Email: user.Email, Password: password}, client)
+		return err
+	}
+
+	for i := 0; i < 5; i++ {
+		if err := login("wrong-password"); err == nil || err.Error() != "invalid email or password" {
+			t.Fatalf("attempt %d: expected a wrong password to fail, got %v", i+1, err)
+		}
+	}
+
+	var locked *AccountLockedError
+	if err := login("super-secret-pass"); !errors.As(err, &locked) {
+		t.Fatalf("expected the right password to be refused while locked, got %v", err)
+	}
+	if until := time.Until(locked.Until); until <= 0 || until > time.Minute {
+		t.Fatalf("expected a one minute lock, got %s", until)
+	}
+	if repo.failedLogins != 5 {
+		t.Fatalf("expected attempts while locked not to count, got %d failures", repo.failedLogins)
+	}
+
+	sent := memory.Sent()
+	if len(sent) != 1 || sent[0].To != user.Email || !strings.Contains(sent[0].Text, "203.0.113.7") {
+		t.Fatalf("expected one lockout email naming the address, got %+v", sent)
+	}
+
+	outcomes := make([]string, 0, len(repo.loginEvents))
+	for _, event := range repo.loginEvents {
+		if event.UserAgent == nil || *event.UserAgent != "curl/8.0" {
+			t.Fatalf("expected the user agent to be recorded, got %+v", event)
+		}
+		outcomes = append(outcomes, event.Outcome)
+	}
+	if len(outcomes) != 6 || outcomes[0] != repository.LoginOutcomeInvalidPassword || outcomes[5] != repository.LoginOutcomeLocked {
+		t.Fatalf("unexpected login events %v", outcomes)
+	}
+
+	expired := time.Now().Add(-time.Second)
+	repo.lockedUntil = &expired
+	if err := login("super-secret-pass"); err != nil {
+		t.Fatalf("expected login to succeed once the lock expires, got %v", err)
+	}
+	if repo.failedLogins != 0 || repo.loginEvents[len(repo.loginEvents)-1].Outcome != repository.LoginOutcomeSuccess {
+		t.Fatalf("expected success to reset failures and be recorded, got %d failures", repo.failedLogins)
+	}
+}
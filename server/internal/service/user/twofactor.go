@@ -0,0 +1,378 @@
+package service
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"chat-application/internal/api/model"
+	"chat-application/internal/constants"
+	repository "chat-application/internal/repo/user"
+	"chat-application/internal/totp"
+	"chat-application/util"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrTwoFactorUnavailable = errors.New("two-factor authentication is not configured")
+	ErrTwoFactorEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled  = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorNotPending  = errors.New("start two-factor setup first")
+	ErrInvalidTOTPCode      = errors.New("invalid authentication code")
+	ErrInvalidMFAChallenge  = errors.New("login session expired, please sign in again")
+	ErrInvalidPassword      = errors.New("invalid password")
+)
+
+// mfaChallengeAudience keeps challenge tokens from being mistaken for
+// sessions; they also lack the id claim the auth middleware requires.
+const mfaChallengeAudience = "mfa-challenge"
+
+// recoveryCodeEncoding spells recovery codes in lowercase base32, which has
+// no easily confused characters.
+var recoveryCodeEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// completeLogin finishes a sign-in that proved the first factor. Users with
+// two-factor authentication get a challenge token instead of a session.
+func (s *UserService) completeLogin(ctx context.Context, user *repository.User, method string, client LoginClient) (*model.ResponseLoginUser, error) {
+	state, err := s.userRepo.GetTOTP(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate user")
+	}
+	if state == nil || state.EnabledAt == nil {
+		return s.finishLogin(ctx, user, method, client)
+	}
+
+	secretKey := util.GetEnv("JWT_SECRET_KEY", "")
+	if secretKey == "" {
+		return nil, fmt.Errorf("server configuration error")
+	}
+	challenge, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject:   user.ID.String(),
+		Audience:  jwt.ClaimStrings{mfaChallengeAudience},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(constants.MFAChallengeExpiry)),
+	}).SignedString([]byte(secretKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate authentication token")
+	}
+
+	log.Printf("UserService.completeLogin - Second factor required for user: %s", user.ID.String())
+	s.recordLoginEvent(ctx, user.ID, method, repository.LoginOutcomeMFARequired, client)
+	return &model.ResponseLoginUser{MFARequired: true, MFAToken: challenge}, nil
+}
+
+// CompleteMFALogin exchanges a challenge token and an authenticator or
+// recovery code for a session. Wrong codes count towards the account lockout
+// like wrong passwords.
+func (s *UserService) CompleteMFALogin(ctx context.Context, req model.RequestMFALogin, client LoginClient) (*model.ResponseLoginUser, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	userID, err := parseMFAChallenge(req.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate user")
+	}
+	state, err := s.userRepo.GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate user")
+	}
+	if user == nil || state == nil || state.EnabledAt == nil {
+		return nil, ErrInvalidMFAChallenge
+	}
+
+	method := "totp"
+	if req.RecoveryCode != "" {
+		method = "recovery_code"
+	}
+	if err := s.checkLoginLock(ctx, user, method, client); err != nil {
+		return nil, err
+	}
+
+	if req.RecoveryCode != "" {
+		used, err := s.userRepo.UseRecoveryCode(ctx, userID, hashRecoveryCode(req.RecoveryCode))
+		if err != nil {
+			return nil, fmt.Errorf("failed to authenticate user")
+		}
+		if !used {
+			s.recordLoginFailure(ctx, user, method, repository.LoginOutcomeInvalidCode, client)
+			return nil, ErrInvalidTOTPCode
+		}
+		log.Printf("UserService.CompleteMFALogin - Recovery code used by user: %s", userID.String())
+		return s.finishLogin(ctx, user, method, client)
+	}
+
+	if err := s.checkTOTPCode(ctx, userID, state, req.Code); err != nil {
+		if errors.Is(err, ErrInvalidTOTPCode) {
+			s.recordLoginFailure(ctx, user, method, repository.LoginOutcomeInvalidCode, client)
+		}
+		return nil, err
+	}
+	return s.finishLogin(ctx, user, method, client)
+}
+
+// checkTOTPCode accepts a code from the enabled authenticator, at most once
+// per time step.
+func (s *UserService) checkTOTPCode(ctx context.Context, userID uuid.UUID, state *repository.TOTPState, code string) error {
+	if state.Secret == nil {
+		return ErrTwoFactorNotEnabled
+	}
+	secret, err := s.decryptTOTPSecret(*state.Secret)
+	if err != nil {
+		return err
+	}
+
+	step, ok := totp.Validate(secret, code, time.Now(), constants.TOTPSkew)
+	if !ok {
+		return ErrInvalidTOTPCode
+	}
+	advanced, err := s.userRepo.AdvanceTOTPStep(ctx, userID, step)
+	if err != nil {
+		return err
+	}
+	if !advanced {
+		return ErrInvalidTOTPCode
+	}
+	return nil
+}
+
+// GetTwoFactorStatus reports whether the user has two-factor authentication
+// enabled and how many recovery codes remain.
+func (s *UserService) GetTwoFactorStatus(ctx context.Context, userID uuid.UUID) (*model.ResponseTwoFactorStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	state, err := s.userRepo.GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	return &model.ResponseTwoFactorStatus{
+		Enabled:                state.EnabledAt != nil,
+		RecoveryCodesRemaining: state.RecoveryCodes,
+	}, nil
+}
+
+// BeginTOTPEnrollment generates a new secret for the user to add to an
+// authenticator app. It does not take effect until ConfirmTOTPEnrollment.
+func (s *UserService) BeginTOTPEnrollment(ctx context.Context, userID uuid.UUID) (*model.ResponseTOTPSetup, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if s.TOTPEncryptionKey == "" {
+		return nil, ErrTwoFactorUnavailable
+	}
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := s.encryptTOTPSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := s.userRepo.SetPendingTOTP(ctx, userID, encrypted)
+	if err != nil {
+		return nil, err
+	}
+	if !stored {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	return &model.ResponseTOTPSetup{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(constants.TOTPIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTPEnrollment enables two-factor authentication once the user
+// proves their authenticator works, and returns their recovery codes. The
+// codes are only stored hashed, so this is the only time they are shown.
+func (s *UserService) ConfirmTOTPEnrollment(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	state, err := s.userRepo.GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil || state.Secret == nil {
+		return nil, ErrTwoFactorNotPending
+	}
+	if state.EnabledAt != nil {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret, err := s.decryptTOTPSecret(*state.Secret)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := totp.Validate(secret, code, time.Now(), constants.TOTPSkew)
+	if !ok {
+		return nil, ErrInvalidTOTPCode
+	}
+
+	codes := make([]string, constants.RecoveryCodeCount)
+	hashes := make([]string, constants.RecoveryCodeCount)
+	for i := range codes {
+		raw := make([]byte, 7)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		encoded := recoveryCodeEncoding.EncodeToString(raw)[:10]
+		codes[i] = encoded[:5] + "-" + encoded[5:]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	enabled, err := s.userRepo.EnableTOTP(ctx, userID, step, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, ErrTwoFactorNotPending
+	}
+
+	log.Printf("UserService.ConfirmTOTPEnrollment - Two-factor authentication enabled for user: %s", userID.String())
+	return codes, nil
+}
+
+// DisableTOTP turns off two-factor authentication after the user re-enters
+// their password. Users without a password confirm with a current code.
+func (s *UserService) DisableTOTP(ctx context.Context, userID uuid.UUID, req model.RequestDisableTOTP) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return fmt.Errorf("user not found")
+	}
+	state, err := s.userRepo.GetTOTP(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if state == nil || state.EnabledAt == nil {
+		return ErrTwoFactorNotEnabled
+	}
+
+	if user.PasswordHash != nil {
+		if req.Password == "" || util.CheckPassword(*user.PasswordHash, req.Password) != nil {
+			return ErrInvalidPassword
+		}
+	} else if err := s.checkTOTPCode(ctx, userID, state, req.Code); err != nil {
+		return err
+	}
+
+	if err := s.userRepo.DisableTOTP(ctx, userID); err != nil {
+		return err
+	}
+	log.Printf("UserService.DisableTOTP - Two-factor authentication disabled for user: %s", userID.String())
+	return nil
+}
+
+func parseMFAChallenge(value string) (uuid.UUID, error) {
+	secretKey := util.GetEnv("JWT_SECRET_KEY", "")
+	if secretKey == "" || value == "" {
+		return uuid.Nil, ErrInvalidMFAChallenge
+	}
+
+	var claims jwt.RegisteredClaims
+	_, err := jwt.ParseWithClaims(value, &claims, func(*jwt.Token) (any, error) {
+		return []byte(secretKey), nil
+	},
+		jwt.WithValidMethods([]string{"HS256"}),
+		jwt.WithAudience(mfaChallengeAudience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return uuid.Nil, ErrInvalidMFAChallenge
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, ErrInvalidMFAChallenge
+	}
+	return userID, nil
+}
+
+// hashRecoveryCode ignores case, spaces and dashes, which users tend to
+// change when typing a code back in.
+func hashRecoveryCode(code string) string {
+	normalized := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(code)))
+	return hashAccountToken(normalized)
+}
+
+func (s *UserService) totpCipher() (cipher.AEAD, error) {
+	if s.TOTPEncryptionKey == "" {
+		return nil, ErrTwoFactorUnavailable
+	}
+	key, err := hkdf.Key(sha256.New, []byte(s.TOTPEncryptionKey), nil, "yappin totp secret", 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive encryption key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+func (s *UserService) encryptTOTPSecret(secret string) (string, error) {
+	aead, err := s.totpCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(secret), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (s *UserService) decryptTOTPSecret(encrypted string) (string, error) {
+	aead, err := s.totpCipher()
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("invalid encrypted two-factor secret")
+	}
+	secret, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt two-factor secret: %w", err)
+	}
+	return string(secret), nil
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"chat-application/internal/api/model"
+	repository "chat-application/internal/repo/user"
+	"chat-application/internal/totp"
+	"chat-application/util"
+
+	"github.com/google/uuid"
+)
+
+func codeAt(t *testing.T, secret string, step int64) string {
+	t.Helper()
+	code, err := totp.Code(secret, step)
+	if err != nil {
+		t.Fatalf("Code: %v", err)
+	}
+	return code
+}
+
+func TestTwoFactorLoginRequiresCodeAndRejectsReplay(t *testing.T) {
+	t.Setenv("JWT_SECRET_KEY", "test-secret")
+
+	hashedPassword, err := util.HashPassword("super-secret-pass")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	user := &repository.User{ID: uuid.New(), Username: "alice", Email: "alice@example.com", PasswordHash: &hashedPassword}
+	repo := &fakeUserRepository{
+		getByEmailFn: func(ctx context.Context, email string) (*repository.User, error) { return user, nil },
+		getByIDFn:    func(ctx context.Context, id uuid.UUID) (*repository.User, error) { return user, nil },
+	}
+	svc := NewUserService(repo)
+	svc.TOTPEncryptionKey = "totp-key"
+	ctx := context.Background()
+	// Codes are taken relative to one step so the test holds across a step
+	// boundary.
+	step := totp.Counter(time.Now())
+
+	setup, err := svc.BeginTOTPEnrollment(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("BeginTOTPEnrollment: %v", err)
+	}
+	if !strings.HasPrefix(setup.ProvisioningURI, "otpauth://totp/") || *repo.totp.Secret == setup.Secret {
+		t.Fatalf("expected a provisioning URI and an encrypted secret, got %+v", setup)
+	}
+	if _, err := svc.ConfirmTOTPEnrollment(ctx, user.ID, "000000"); !errors.Is(err, ErrInvalidTOTPCode) {
+		t.Fatalf("expected a wrong code to be refused, got %v", err)
+	}
+	recoveryCodes, err := svc.ConfirmTOTPEnrollment(ctx, user.ID, codeAt(t, setup.Secret, step-1))
+	if err != nil {
+		t.Fatalf("ConfirmTOTPEnrollment: %v", err)
+	}
+	if len(recoveryCodes) != 10 || repo.recoveryCodes[recoveryCodes[0]] {
+		t.Fatalf("expected ten recovery codes stored hashed, got %v", recoveryCodes)
+	}
+
+	login := func() string {
+		t.Helper()
+		resp, err := svc.Login(ctx, model.RequestLoginUser{Email: user.Email, Password: "super-secret-pass"}, LoginClient{})
+		if err != nil {
+			t.Fatalf("Login: %v", err)
+		}
+		if !resp.MFARequired || resp.MFAToken == "" || resp.AccessToken != "" {
+			t.Fatalf("expected a challenge instead of a session, got %+v", resp)
+		}
+		return resp.MFAToken
+	}
+
+	challenge := login()
+	if _, err := svc.CompleteMFALogin(ctx, model.RequestMFALogin{MFAToken: challenge, Code: codeAt(t, setup.Secret, step-1)}, LoginClient{}); !errors.Is(err, ErrInvalidTOTPCode) {
+		t.Fatalf("expected the code used for enrollment to be refused, got %v", err)
+	}
+	resp, err := svc.CompleteMFALogin(ctx, model.RequestMFALogin{MFAToken: challenge, Code: codeAt(t, setup.Secret, step)}, LoginClient{})
+	if err != nil || resp.AccessToken == "" {
+		t.Fatalf("expected a session, got %+v %v", resp, err)
+	}
+	if _, err := svc.CompleteMFALogin(ctx, model.RequestMFALogin{MFAToken: resp.AccessToken, Code: codeAt(t, setup.Secret, step+1)}, LoginClient{}); !errors.Is(err, ErrInvalidMFAChallenge) {
+		t.Fatalf("expected a session token to be refused as a challenge, got %v", err)
+	}
+
+	recovery := model.RequestMFALogin{MFAToken: login(), RecoveryCode: strings.ToUpper(recoveryCodes[3])}
+	if _, err := svc.CompleteMFALogin(ctx, recovery, LoginClient{}); err != nil {
+		t.Fatalf("expected the recovery code to work, got %v", err)
+	}
+	if _, err := svc.CompleteMFALogin(ctx, recovery, LoginClient{}); !errors.Is(err, ErrInvalidTOTPCode) {
+		t.Fatalf("expected a recovery code to be single use, got %v", err)
+	}
+
+	if err := svc.DisableTOTP(ctx, user.ID, model.RequestDisableTOTP{Password: "wrong-pass"}); !errors.Is(err, ErrInvalidPassword) {
+		t.Fatalf("expected a wrong password to be refused, got %v", err)
+	}
+	if err := svc.DisableTOTP(ctx, user.ID, model.RequestDisableTOTP{Password: "super-secret-pass"}); err != nil {
+		t.Fatalf("DisableTOTP: %v", err)
+	}
+	resp, err = svc.Login(ctx, model.RequestLoginUser{Email: user.Email, Password: "super-secret-pass"}, LoginClient{})
+	if err != nil || resp.MFARequired || resp.AccessToken == "" {
+		t.Fatalf("expected a plain login after disabling, got %+v %v", resp, err)
+	}
+}
@@ -5,10 +5,13 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"sync"
 	"time"
 
 	"chat-application/internal/api/model"
 	"chat-application/internal/constants"
+	"chat-application/internal/mailer"
+	"chat-application/internal/oauth"
 	repository "chat-application/internal/repo/user"
 	"chat-application/util"
 
@@ -27,6 +30,30 @@ type JWTClaims struct {
 type UserService struct {
 	userRepo repository.UserRepositoryInterface
 	timeout  time.Duration
+	// Mailer sends password reset and verification emails; links in them
+	// point to AppURL.
+	Mailer mailer.Mailer
+	AppURL string
+	// RequireVerifiedEmail makes CheckEmailVerified refuse unverified users.
+	RequireVerifiedEmail bool
+	// OAuthProviders are the social login providers by name; their callbacks
+	// are served under APIURL.
+	OAuthProviders map[string]*oauth.Provider
+	APIURL         string
+	// TOTPEncryptionKey encrypts two-factor secrets at rest; without it
+	// users cannot enroll.
+	TOTPEncryptionKey string
+	// Avatars stores uploaded avatars; without it uploads are refused.
+	Avatars AvatarStore
+	// ProfileNotifier, if set, pushes profile changes to the rooms the
+	// user is in.
+	ProfileNotifier ProfileNotifier
+	// BlockNotifier, if set, applies blocks to the blocker's open sockets
+	// straight away.
+	BlockNotifier BlockNotifier
+
+	// emails tracks password reset emails still being sent.
+	emails sync.WaitGroup
 }
 
 // NewUserService creates a new UserService instance.
@@ -105,6 +132,12 @@ func (s *UserService) CreateUser(ctx context.Context, req model.RequestCreateUse
 
 	log.Printf("UserService.CreateUser - User created successfully in database: %s", user.ID.String())
 
+	// The account is usable without verification, so a failed email only
+	// means the user has to ask for another one.
+	if err := s.sendAccountEmail(ctx, user, repository.TokenPurposeEmailVerification); err != nil {
+		log.Printf("UserService.CreateUser - Verification email failed: %v", err)
+	}
+
 	ss, err := s.generateJWTToken(user.ID.String(), user.Username)
 	if err != nil {
 		log.Printf("UserService.CreateUser - JWT generation failed: %v", err)
@@ -112,14 +145,17 @@ func (s *UserService) CreateUser(ctx context.Context, req model.RequestCreateUse
 	}
 
 	return &model.ResponseLoginUser{
-		AccessToken: ss,
-		Username:    user.Username,
-		ID:          user.ID.String(),
-		Email:       user.Email,
+		AccessToken:   ss,
+		Username:      user.Username,
+		ID:            user.ID.String(),
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt != nil,
 	}, nil
 }
 
-func (s *UserService) Login(ctx context.Context, req model.RequestLoginUser) (*model.ResponseLoginUser, error) {
+// Login checks the user's password. Users with two-factor authentication get
+// a challenge to complete with CompleteMFALogin instead of a session.
+func (s *UserService) Login(ctx context.Context, req model.RequestLoginUser, client LoginClient) (*model.ResponseLoginUser, error) {
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
@@ -154,27 +190,30 @@ func (s *UserService) Login(ctx context.Context, req model.RequestLoginUser) (*m
 		return nil, fmt.Errorf("invalid user account")
 	}
 
+	if err := s.checkLoginLock(ctx, user, "password", client); err != nil {
+		log.Printf("UserService.Login - Sign-in is locked for user: %s", user.ID.String())
+		return nil, err
+	}
+
 	err = util.CheckPassword(*user.PasswordHash, req.Password)
 	if err != nil {
 		log.Printf("UserService.Login - Password check failed for user: %s", user.ID.String())
+		s.recordLoginFailure(ctx, user, "password", repository.LoginOutcomeInvalidPassword, client)
 		return nil, fmt.Errorf("invalid email or password")
 	}
 
 	log.Printf("UserService.Login - Password verified successfully for user: %s", user.ID.String())
 
-	ss, err := s.generateJWTToken(user.ID.String(), user.Username)
+	resp, err := s.completeLogin(ctx, user, "password", client)
 	if err != nil {
-		log.Printf("UserService.Login - JWT generation failed for user: %s, error: %v", user.ID.String(), err)
-		return nil, fmt.Errorf("failed to generate authentication token")
+		log.Printf("UserService.Login - Login failed for user: %s, error: %v", user.ID.String(), err)
+		return nil, err
 	}
 
-	log.Printf("UserService.Login - Login successful for user: %s (%s)", user.ID.String(), user.Username)
-	return &model.ResponseLoginUser{
-		AccessToken: ss,
-		Username:    user.Username,
-		ID:          user.ID.String(),
-		Email:       user.Email,
-	}, nil
+	if !resp.MFARequired {
+		log.Printf("UserService.Login - Login successful for user: %s (%s)", user.ID.String(), user.Username)
+	}
+	return resp, nil
 }
 
 func (s *UserService) GetUserByID(ctx context.Context, id uuid.UUID) (*repository.User, error) {
@@ -200,8 +239,9 @@ func (s *UserService) UpdateUsername(ctx context.Context, userID string, newUser
 	}
 
 	return &model.ResponseLoginUser{
-		ID:       user.ID.String(),
-		Username: user.Username,
-		Email:    user.Email,
+		ID:            user.ID.String(),
+		Username:      user.Username,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt != nil,
 	}, nil
 }
@@ -3,10 +3,12 @@ package service
 import (
 	"context"
 	"errors"
+	"slices"
 	"testing"
 	"time"
 
 	"chat-application/internal/api/model"
+	"chat-application/internal/pagination"
 	repository "chat-application/internal/repo/user"
 	"chat-application/util"
 
@@ -19,6 +21,30 @@ type fakeUserRepository struct {
 	getByIDFn        func(ctx context.Context, id uuid.UUID) (*repository.User, error)
 	updateUsernameFn func(ctx context.Context, id uuid.UUID, username string) (*repository.User, error)
 	deleteUserFn     func(ctx context.Context, id uuid.UUID) error
+	tokens           map[string]userToken
+	identities       []repository.LinkedIdentity
+	created          []*repository.User
+	emailsVerified   []uuid.UUID
+	totp             repository.TOTPState
+	recoveryCodes    map[string]bool
+	loginEvents      []repository.LoginEvent
+	failedLogins     int
+	lockedUntil      *time.Time
+	export           *repository.UserExport
+	profile          *repository.Profile
+	blocks           []repository.BlockedUser
+	matches          []repository.UserMatch
+	searches         []repository.UserSearch
+	// roomReaders maps rooms to the users who may read them; rooms not in
+	// it do not exist.
+	roomReaders map[uuid.UUID][]uuid.UUID
+}
+
+type userToken struct {
+	userID  uuid.UUID
+	purpose string
+	expires time.Time
+	used    bool
 }
 
 func (f *fakeUserRepository) GetUserByID(ctx context.Context, id uuid.UUID) (*repository.User, error) {
@@ -56,6 +82,249 @@ func (f *fakeUserRepository) DeleteUser(ctx context.Context, id uuid.UUID) error
 	return nil
 }
 
+func (f *fakeUserRepository) CreateUserToken(ctx context.Context, userID uuid.UUID, purpose, tokenHash string, expiresAt time.Time) error {
+	if f.tokens == nil {
+		f.tokens = map[string]userToken{}
+	}
+	for hash, token := range f.tokens {
+		if token.userID == userID && token.purpose == purpose && !token.used {
+			delete(f.tokens, hash)
+		}
+	}
+	f.tokens[tokenHash] = userToken{userID: userID, purpose: purpose, expires: expiresAt}
+	return nil
+}
+
+func (f *fakeUserRepository) consume(tokenHash, purpose string) *uuid.UUID {
+	token, ok := f.tokens[tokenHash]
+	if !ok || token.used || token.purpose != purpose || time.Now().After(token.expires) {
+		return nil
+	}
+	token.used = true
+	f.tokens[tokenHash] = token
+	return &token.userID
+}
+
+func (f *fakeUserRepository) ResetPassword(ctx context.Context, tokenHash string, passwordHash string) (*uuid.UUID, error) {
+	return f.consume(tokenHash, repository.TokenPurposePasswordReset), nil
+}
+
+func (f *fakeUserRepository) VerifyEmail(ctx context.Context, tokenHash string) (*uuid.UUID, error) {
+	return f.consume(tokenHash, repository.TokenPurposeEmailVerification), nil
+}
+
+func (f *fakeUserRepository) GetUserByIdentity(ctx context.Context, provider, subject string) (*repository.User, error) {
+	for _, identity := range f.identities {
+		if identity.Provider != provider || identity.Subject != subject {
+			continue
+		}
+		for _, user := range f.created {
+			if user.ID == identity.UserID {
+				return user, nil
+			}
+		}
+		return f.GetUserByID(ctx, identity.UserID)
+	}
+	return nil, nil
+}
+
+func (f *fakeUserRepository) CreateUserWithIdentity(ctx context.Context, user *repository.User, identity *repository.LinkedIdentity) (*repository.User, error) {
+	user.ID = uuid.New()
+	identity.UserID = user.ID
+	if err := f.LinkIdentity(ctx, identity, false); err != nil {
+		return nil, err
+	}
+	f.created = append(f.created, user)
+	return user, nil
+}
+
+func (f *fakeUserRepository) LinkIdentity(ctx context.Context, identity *repository.LinkedIdentity, verifyEmail bool) error {
+	for _, existing := range f.identities {
+		if (existing.Provider == identity.Provider && existing.Subject == identity.Subject) ||
+			(existing.UserID == identity.UserID && existing.Provider == identity.Provider) {
+			return repository.ErrIdentityLinked
+		}
+	}
+	identity.ID = uuid.New()
+	f.identities = append(f.identities, *identity)
+	if verifyEmail {
+		f.emailsVerified = append(f.emailsVerified, identity.UserID)
+	}
+	return nil
+}
+
+func (f *fakeUserRepository) GetLinkedIdentities(ctx context.Context, userID uuid.UUID) ([]repository.LinkedIdentity, error) {
+	var identities []repository.LinkedIdentity
+	for _, identity := range f.identities {
+		if identity.UserID == userID {
+			identities = append(identities, identity)
+		}
+	}
+	return identities, nil
+}
+
+func (f *fakeUserRepository) UnlinkIdentity(ctx context.Context, userID uuid.UUID, provider string) (bool, error) {
+	return false, nil
+}
+
+func (f *fakeUserRepository) GetTOTP(ctx context.Context, userID uuid.UUID) (*repository.TOTPState, error) {
+	state := f.totp
+	for _, used := range f.recoveryCodes {
+		if !used {
+			state.RecoveryCodes++
+		}
+	}
+	return &state, nil
+}
+
+func (f *fakeUserRepository) SetPendingTOTP(ctx context.Context, userID uuid.UUID, secret string) (bool, error) {
+	if f.totp.EnabledAt != nil {
+		return false, nil
+	}
+	f.totp = repository.TOTPState{Secret: &secret}
+	return true, nil
+}
+
+func (f *fakeUserRepository) EnableTOTP(ctx context.Context, userID uuid.UUID, step int64, recoveryCodeHashes []string) (bool, error) {
+	if f.totp.Secret == nil || f.totp.EnabledAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	f.totp.EnabledAt = &now
+	f.totp.LastStep = &step
+	f.recoveryCodes = map[string]bool{}
+	for _, hash := range recoveryCodeHashes {
+		f.recoveryCodes[hash] = false
+	}
+	return true, nil
+}
+
+func (f *fakeUserRepository) AdvanceTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	if f.totp.EnabledAt == nil || (f.totp.LastStep != nil && *f.totp.LastStep >= step) {
+		return false, nil
+	}
+	f.totp.LastStep = &step
+	return true, nil
+}
+
+func (f *fakeUserRepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	used, ok := f.recoveryCodes[codeHash]
+	if !ok || used {
+		return false, nil
+	}
+	f.recoveryCodes[codeHash] = true
+	return true, nil
+}
+
+func (f *fakeUserRepository) DisableTOTP(ctx context.Context, userID uuid.UUID) error {
+	f.totp = repository.TOTPState{}
+	f.recoveryCodes = nil
+	return nil
+}
+
+func (f *fakeUserRepository) CreateLoginEvent(ctx context.Context, event *repository.LoginEvent, retainAfter time.Time) error {
+	event.ID = uuid.New()
+	event.CreatedAt = time.Now()
+	f.loginEvents = append(f.loginEvents, *event)
+	return nil
+}
+
+func (f *fakeUserRepository) GetLoginEvents(ctx context.Context, userID uuid.UUID, before *pagination.Cursor, limit int) ([]repository.LoginEvent, error) {
+	return f.loginEvents, nil
+}
+
+func (f *fakeUserRepository) GetLoginLock(ctx context.Context, userID uuid.UUID) (*time.Time, error) {
+	if f.lockedUntil == nil || time.Now().After(*f.lockedUntil) {
+		return nil, nil
+	}
+	return f.lockedUntil, nil
+}
+
+func (f *fakeUserRepository) RecordFailedLogin(ctx context.Context, userID uuid.UUID) (int, error) {
+	f.failedLogins++
+	return f.failedLogins, nil
+}
+
+func (f *fakeUserRepository) LockLogin(ctx context.Context, userID uuid.UUID, until time.Time) error {
+	f.lockedUntil = &until
+	return nil
+}
+
+func (f *fakeUserRepository) ResetFailedLogins(ctx context.Context, userID uuid.UUID) error {
+	f.failedLogins = 0
+	f.lockedUntil = nil
+	return nil
+}
+
+func (f *fakeUserRepository) GetProfile(ctx context.Context, userID uuid.UUID) (*repository.Profile, error) {
+	if f.profile == nil {
+		return nil, nil
+	}
+	profile := *f.profile
+	return &profile, nil
+}
+
+func (f *fakeUserRepository) UpdateProfile(ctx context.Context, userID uuid.UUID, update *repository.ProfileUpdate) (*repository.Profile, error) {
+	if f.profile == nil {
+		return nil, nil
+	}
+	f.profile.DisplayName = update.DisplayName
+	f.profile.Bio = update.Bio
+	f.profile.Pronouns = update.Pronouns
+	f.profile.StatusText = update.StatusText
+	f.profile.StatusEmoji = update.StatusEmoji
+	f.profile.StatusExpiresAt = update.StatusExpiresAt
+	return f.GetProfile(ctx, userID)
+}
+
+func (f *fakeUserRepository) SetAvatar(ctx context.Context, userID uuid.UUID, avatarKey *string) (*repository.Profile, *string, error) {
+	if f.profile == nil {
+		return nil, nil, nil
+	}
+	previous := f.profile.AvatarKey
+	f.profile.AvatarKey = avatarKey
+	profile, err := f.GetProfile(ctx, userID)
+	return profile, previous, err
+}
+
+func (f *fakeUserRepository) BlockUser(ctx context.Context, blockerID, blockedID uuid.UUID) (bool, error) {
+	for _, block := range f.blocks {
+		if block.UserID == blockedID {
+			return false, nil
+		}
+	}
+	f.blocks = append([]repository.BlockedUser{{UserID: blockedID, CreatedAt: time.Now()}}, f.blocks...)
+	return true, nil
+}
+
+func (f *fakeUserRepository) UnblockUser(ctx context.Context, blockerID, blockedID uuid.UUID) (bool, error) {
+	for i, block := range f.blocks {
+		if block.UserID == blockedID {
+			f.blocks = append(f.blocks[:i], f.blocks[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeUserRepository) GetBlockedUsers(ctx context.Context, blockerID uuid.UUID) ([]repository.BlockedUser, error) {
+	return f.blocks, nil
+}
+
+func (f *fakeUserRepository) SearchUsers(ctx context.Context, search repository.UserSearch) ([]repository.UserMatch, error) {
+	f.searches = append(f.searches, search)
+	return f.matches[:min(len(f.matches), search.Limit)], nil
+}
+
+func (f *fakeUserRepository) CanReadRoom(ctx context.Context, roomID, userID uuid.UUID) (bool, bool, error) {
+	readers, ok := f.roomReaders[roomID]
+	return ok, slices.Contains(readers, userID), nil
+}
+
+func (f *fakeUserRepository) ExportUserData(ctx context.Context, userID uuid.UUID) (*repository.UserExport, error) {
+	return f.export, nil
+}
+
 func TestUserServiceLoginSuccess(t *testing.T) {
 	t.Setenv("JWT_SECRET_KEY", "test-secret")
 
@@ -83,7 +352,7 @@ func TestUserServiceLoginSuccess(t *testing.T) {
 	result, err := service.Login(context.Background(), model.RequestLoginUser{
 		Email:    "alice@example.com",
 		Password: "super-secret-pass",
-	})
+	}, LoginClient{})
 	if err != nil {
 		t.Fatalf("expected login to succeed, got error: %v", err)
 	}
@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// ErrNotFound is returned when a requested object does not exist in the store.
+var ErrNotFound = errors.New("object not found")
+
+// Storage persists binary blobs such as message attachments and thumbnails.
+type Storage interface {
+	// Put stores the object under key, replacing any existing object.
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+
+	// Get opens the object stored under key.
+	// Returns ErrNotFound if the object does not exist.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Delete removes the object stored under key.
+	// Deleting a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// cleanKey normalises an object key and rejects keys that could escape the store root.
+func cleanKey(key string) (string, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return "", fmt.Errorf("storage key is required")
+	}
+
+	cleaned := path.Clean("/" + strings.ReplaceAll(key, "\\", "/"))
+	cleaned = strings.TrimPrefix(cleaned, "/")
+	if cleaned == "" || cleaned == "." || strings.Contains(key, "..") {
+		return "", fmt.Errorf("invalid storage key: %q", key)
+	}
+
+	return cleaned, nil
+}
@@ -0,0 +1,55 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The SHA-1 vectors from RFC 6238 Appendix B, truncated to six digits.
+func TestCodeMatchesRFC6238Vectors(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range vectors {
+		got, err := Code(secret, Counter(time.Unix(unix, 0)))
+		if err != nil {
+			t.Fatalf("Code: %v", err)
+		}
+		if got != want {
+			t.Errorf("at %d expected %s, got %s", unix, want, got)
+		}
+	}
+}
+
+func TestValidateAllowsSkewAndReportsStep(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret: %v", err)
+	}
+	now := time.Unix(1_700_000_000, 0)
+	previous, _ := Code(secret, Counter(now)-1)
+
+	step, ok := Validate(secret, previous[:3]+" "+previous[3:], now, 1)
+	if !ok || step != Counter(now)-1 {
+		t.Fatalf("expected the previous step to validate, got %d %v", step, ok)
+	}
+	if _, ok := Validate(secret, previous, now, 0); ok {
+		t.Fatal("expected the previous step to fail without skew")
+	}
+	if _, ok := Validate(secret, "12345", now, 1); ok {
+		t.Fatal("expected a short code to fail")
+	}
+
+	uri := ProvisioningURI("Yappin", "alice@example.com", secret)
+	if !strings.HasPrefix(uri, "otpauth://totp/Yappin:alice@example.com?") || !strings.Contains(uri, "secret="+secret) {
+		t.Fatalf("unexpected provisioning URI %s", uri)
+	}
+}
@@ -0,0 +1,136 @@
+package webpush
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ErrSubscriptionGone is returned when the push service reports that a
+// subscription no longer exists (404 or 410); it should be deleted.
+var ErrSubscriptionGone = errors.New("push subscription is gone")
+
+// DeliveryError is a rejected push. Temporary errors (429 and 5xx) may be
+// retried, after RetryAfter if the push service gave one.
+type DeliveryError struct {
+	StatusCode int
+	RetryAfter time.Duration
+	Body       string
+}
+
+func (e *DeliveryError) Error() string {
+	return fmt.Sprintf("push service responded %d: %s", e.StatusCode, e.Body)
+}
+
+// Temporary reports whether retrying the push may succeed.
+func (e *DeliveryError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// Subscription is a browser's PushSubscription: the endpoint and its keys.
+type Subscription struct {
+	Endpoint string
+	P256dh   string
+	Auth     string
+}
+
+// Validate checks that the endpoint is an https URL and that the keys are a
+// P-256 public key and a 16-byte auth secret.
+func (s Subscription) Validate() error {
+	endpoint, err := url.Parse(s.Endpoint)
+	if err != nil || endpoint.Scheme != "https" || endpoint.Host == "" {
+		return fmt.Errorf("endpoint must be an https URL")
+	}
+	uaPublic, err := decodeKey(s.P256dh)
+	if err != nil {
+		return fmt.Errorf("invalid p256dh key: %w", err)
+	}
+	if _, err := ecdh.P256().NewPublicKey(uaPublic); err != nil {
+		return fmt.Errorf("invalid p256dh key: %w", err)
+	}
+	authSecret, err := decodeKey(s.Auth)
+	if err != nil || len(authSecret) != 16 {
+		return fmt.Errorf("auth secret must be 16 bytes")
+	}
+	return nil
+}
+
+// Options are the per-message push headers from RFC 8030.
+type Options struct {
+	TTL     time.Duration
+	Urgency string
+	Topic   string
+}
+
+// Client delivers encrypted push messages to push services.
+type Client struct {
+	httpClient  *http.Client
+	keys        *VAPIDKeys
+	subject     string
+	tokenExpiry time.Duration
+}
+
+// NewClient creates a Client. subject is a mailto: or https: contact for the
+// push service operator.
+func NewClient(httpClient *http.Client, keys *VAPIDKeys, subject string, tokenExpiry time.Duration) *Client {
+	return &Client{
+		httpClient:  httpClient,
+		keys:        keys,
+		subject:     subject,
+		tokenExpiry: tokenExpiry,
+	}
+}
+
+// Send encrypts payload for the subscription and posts it to its endpoint.
+func (c *Client) Send(ctx context.Context, sub Subscription, payload []byte, opts Options) error {
+	body, err := Encrypt(payload, sub.P256dh, sub.Auth)
+	if err != nil {
+		return err
+	}
+	authorization, err := c.keys.authorization(sub.Endpoint, c.subject, c.tokenExpiry)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build push request: %w", err)
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(int(opts.TTL/time.Second)))
+	if opts.Urgency != "" {
+		req.Header.Set("Urgency", opts.Urgency)
+	}
+	if opts.Topic != "" {
+		req.Header.Set("Topic", opts.Topic)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach push service: %w", err)
+	}
+	defer resp.Body.Close()
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrSubscriptionGone
+	}
+
+	deliveryErr := &DeliveryError{StatusCode: resp.StatusCode, Body: string(message)}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		deliveryErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return deliveryErr
+}
@@ -0,0 +1,116 @@
+package webpush
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// recordSize is the aes128gcm record size; a push message is a single record.
+	recordSize = 4096
+	saltSize   = 16
+	keySize    = 65
+	headerSize = saltSize + 4 + 1 + keySize
+	tagSize    = 16
+	// MaxPayloadSize is the largest plaintext that fits in one 4096-byte
+	// push message after the header, padding delimiter and GCM tag.
+	MaxPayloadSize = recordSize - headerSize - tagSize - 1
+)
+
+// ErrPayloadTooLarge is returned for payloads over MaxPayloadSize.
+var ErrPayloadTooLarge = errors.New("push payload too large")
+
+// Encrypt encrypts a push message for a subscription as described in RFC 8291,
+// using the aes128gcm content coding from RFC 8188. p256dh and auth are the
+// subscription keys reported by the browser.
+func Encrypt(payload []byte, p256dh, auth string) ([]byte, error) {
+	uaPublic, err := decodeKey(p256dh)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+	authSecret, err := decodeKey(auth)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth secret: %w", err)
+	}
+
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	return encrypt(payload, uaPublic, authSecret, asPrivate, salt)
+}
+
+func encrypt(payload, uaPublic, authSecret []byte, asPrivate *ecdh.PrivateKey, salt []byte) ([]byte, error) {
+	if len(payload) > MaxPayloadSize {
+		return nil, ErrPayloadTooLarge
+	}
+	if len(authSecret) != 16 {
+		return nil, fmt.Errorf("auth secret must be 16 bytes")
+	}
+
+	uaKey, err := ecdh.P256().NewPublicKey(uaPublic)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+	ecdhSecret, err := asPrivate.ECDH(uaKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive shared secret: %w", err)
+	}
+	asPublic := asPrivate.PublicKey().Bytes()
+
+	// RFC 8291 section 3.4: combine the shared secret with the auth secret.
+	keyInfo := "WebPush: info\x00" + string(uaPublic) + string(asPublic)
+	ikm, err := hkdf.Key(sha256.New, ecdhSecret, authSecret, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	// RFC 8188 section 2.2 and 2.3: content encryption key and nonce.
+	cek, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, headerSize, headerSize+len(payload)+1+tagSize)
+	copy(header, salt)
+	binary.BigEndian.PutUint32(header[saltSize:], recordSize)
+	header[saltSize+4] = keySize
+	copy(header[saltSize+5:], asPublic)
+
+	// A single, final record ends with the 0x02 padding delimiter.
+	record := append(append([]byte{}, payload...), 0x02)
+	return gcm.Seal(header, nonce, record, nil), nil
+}
+
+// decodeKey accepts the base64url keys browsers report, with or without padding.
+func decodeKey(value string) ([]byte, error) {
+	value = strings.TrimRight(value, "=")
+	return base64.RawURLEncoding.DecodeString(value)
+}
@@ -0,0 +1,82 @@
+package webpush
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/url"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// VAPIDKeys identify this server to push services (RFC 8292). PublicKey is
+// the base64url uncompressed P-256 point that browsers pass as
+// applicationServerKey when subscribing.
+type VAPIDKeys struct {
+	PublicKey  string
+	PrivateKey string
+	signer     *ecdsa.PrivateKey
+}
+
+// GenerateVAPIDKeys creates a new key pair.
+func GenerateVAPIDKeys() (*VAPIDKeys, error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate VAPID keys: %w", err)
+	}
+	return ParseVAPIDKeys(base64.RawURLEncoding.EncodeToString(key.Bytes()))
+}
+
+// ParseVAPIDKeys loads a key pair from its base64url private scalar; the
+// public key is derived from it.
+func ParseVAPIDKeys(privateKey string) (*VAPIDKeys, error) {
+	raw, err := decodeKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+	key, err := ecdh.P256().NewPrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+
+	public := key.PublicKey().Bytes()
+	signer := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(public[1:33]),
+			Y:     new(big.Int).SetBytes(public[33:]),
+		},
+		D: new(big.Int).SetBytes(raw),
+	}
+
+	return &VAPIDKeys{
+		PublicKey:  base64.RawURLEncoding.EncodeToString(public),
+		PrivateKey: base64.RawURLEncoding.EncodeToString(raw),
+		signer:     signer,
+	}, nil
+}
+
+// authorization returns the Authorization header for a request to endpoint.
+// The token is scoped to the push service's origin and expires after expiry.
+func (k *VAPIDKeys) authorization(endpoint, subject string, expiry time.Duration) (string, error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return "", fmt.Errorf("invalid push endpoint")
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": parsed.Scheme + "://" + parsed.Host,
+		"exp": time.Now().Add(expiry).Unix(),
+		"sub": subject,
+	})
+	signed, err := token.SignedString(k.signer)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign VAPID token: %w", err)
+	}
+	return fmt.Sprintf("vapid t=%s, k=%s", signed, k.PublicKey), nil
+}
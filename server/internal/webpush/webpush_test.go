@@ -0,0 +1,160 @@
+package webpush
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func mustDecode(t *testing.T, value string) []byte {
+	t.Helper()
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		t.Fatalf("decode %q: %v", value, err)
+	}
+	return decoded
+}
+
+// TestEncryptMatchesRFC8291Example checks the worked example in RFC 8291 appendix A.
+func TestEncryptMatchesRFC8291Example(t *testing.T) {
+	asPrivate, err := ecdh.P256().NewPrivateKey(mustDecode(t, "yfWPiYE-n46HLnH0KqZOF1fJJU3MYrct3AELtAQ-oRw"))
+	if err != nil {
+		t.Fatalf("NewPrivateKey: %v", err)
+	}
+	uaPublic := mustDecode(t, "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4")
+	authSecret := mustDecode(t, "BTBZMqHH6r4Tts7J_aSIgg")
+	salt := mustDecode(t, "DGv6ra1nlYgDCS1FRnbzlw")
+
+	body, err := encrypt([]byte("When I grow up, I want to be a watermelon"), uaPublic, authSecret, asPrivate, salt)
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+
+	want := "DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN"
+	if got := base64.RawURLEncoding.EncodeToString(body); got != want {
+		t.Fatalf("unexpected ciphertext\n got %s\nwant %s", got, want)
+	}
+}
+
+// decrypt reverses encrypt the way a browser would, using the subscription's private key.
+func decrypt(t *testing.T, body []byte, uaPrivate *ecdh.PrivateKey, authSecret []byte) string {
+	t.Helper()
+	salt, asPublic, ciphertext := body[:saltSize], body[saltSize+5:headerSize], body[headerSize:]
+
+	asKey, err := ecdh.P256().NewPublicKey(asPublic)
+	if err != nil {
+		t.Fatalf("invalid sender key: %v", err)
+	}
+	ecdhSecret, err := uaPrivate.ECDH(asKey)
+	if err != nil {
+		t.Fatalf("ECDH: %v", err)
+	}
+	keyInfo := "WebPush: info\x00" + string(uaPrivate.PublicKey().Bytes()) + string(asPublic)
+	ikm, _ := hkdf.Key(sha256.New, ecdhSecret, authSecret, keyInfo, 32)
+	cek, _ := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	nonce, _ := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+	record, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		t.Fatalf("failed to decrypt push message: %v", err)
+	}
+	return strings.TrimRight(string(record[:len(record)-1]), "\x00")
+}
+
+func TestClientSendsEncryptedPushToStub(t *testing.T) {
+	keys, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatalf("GenerateVAPIDKeys: %v", err)
+	}
+	reloaded, err := ParseVAPIDKeys(keys.PrivateKey)
+	if err != nil || reloaded.PublicKey != keys.PublicKey {
+		t.Fatalf("expected keys to round-trip, got %v (%v)", reloaded, err)
+	}
+
+	uaPrivate, _ := ecdh.P256().GenerateKey(rand.Reader)
+	authSecret := make([]byte, 16)
+	_, _ = rand.Read(authSecret)
+
+	status := http.StatusCreated
+	var received string
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "aes128gcm" || r.Header.Get("TTL") != "60" || r.Header.Get("Urgency") != "high" {
+			t.Errorf("unexpected push headers %v", r.Header)
+		}
+
+		authorization := r.Header.Get("Authorization")
+		var token, key string
+		for _, part := range strings.Split(strings.TrimPrefix(authorization, "vapid "), ", ") {
+			if value, ok := strings.CutPrefix(part, "t="); ok {
+				token = value
+			} else if value, ok := strings.CutPrefix(part, "k="); ok {
+				key = value
+			}
+		}
+		if key != keys.PublicKey {
+			t.Errorf("expected VAPID key %s, got %s", keys.PublicKey, key)
+		}
+		claims := jwt.MapClaims{}
+		_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (any, error) {
+			return &keys.signer.PublicKey, nil
+		}, jwt.WithValidMethods([]string{"ES256"}), jwt.WithAudience("http://"+r.Host))
+		if err != nil || claims["sub"] != "mailto:ops@yappin.chat" {
+			t.Errorf("invalid VAPID token: %v %v", err, claims)
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		received = decrypt(t, body, uaPrivate, authSecret)
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "30")
+		}
+		w.WriteHeader(status)
+	}))
+	defer stub.Close()
+
+	client := NewClient(stub.Client(), keys, "mailto:ops@yappin.chat", time.Hour)
+	sub := Subscription{
+		Endpoint: stub.URL + "/push/abc",
+		P256dh:   base64.RawURLEncoding.EncodeToString(uaPrivate.PublicKey().Bytes()),
+		Auth:     base64.URLEncoding.EncodeToString(authSecret),
+	}
+	opts := Options{TTL: time.Minute, Urgency: "high"}
+
+	if err := client.Send(context.Background(), sub, []byte(`{"title":"hi"}`), opts); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if received != `{"title":"hi"}` {
+		t.Fatalf("stub decrypted %q", received)
+	}
+
+	status = http.StatusGone
+	if err := client.Send(context.Background(), sub, []byte("x"), opts); !errors.Is(err, ErrSubscriptionGone) {
+		t.Fatalf("expected ErrSubscriptionGone, got %v", err)
+	}
+
+	status = http.StatusTooManyRequests
+	err = client.Send(context.Background(), sub, []byte("x"), opts)
+	var deliveryErr *DeliveryError
+	if !errors.As(err, &deliveryErr) || !deliveryErr.Temporary() || deliveryErr.RetryAfter != 30*time.Second {
+		t.Fatalf("expected a temporary error with Retry-After, got %v", err)
+	}
+
+	if _, err := Encrypt(make([]byte, MaxPayloadSize+1), sub.P256dh, sub.Auth); !errors.Is(err, ErrPayloadTooLarge) {
+		t.Fatalf("expected ErrPayloadTooLarge, got %v", err)
+	}
+}
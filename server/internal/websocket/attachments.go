@@ -0,0 +1,80 @@
+package websocket
+
+import (
+	"context"
+	"log"
+
+	"chat-application/internal/api/model"
+
+	"github.com/google/uuid"
+)
+
+// AttachmentResolver looks up uploaded files so they can be referenced from message metadata.
+type AttachmentResolver interface {
+	ResolveForMessage(ctx context.Context, roomID, userID uuid.UUID, ids []uuid.UUID) ([]model.AttachmentRes, error)
+	AssignToMessage(ctx context.Context, messageID uuid.UUID, ids []uuid.UUID) error
+}
+
+// attachFiles replaces the attachment IDs sent by a client with the metadata of
+// the files that client actually uploaded to the room. IDs that do not resolve
+// are dropped so a client cannot reference someone else's upload.
+func (c *Core) attachFiles(client *Client, msg *Message) {
+	requested := msg.AttachmentIDs
+	msg.AttachmentIDs = nil
+	if c.Attachments == nil || len(requested) == 0 {
+		return
+	}
+
+	roomID, err := uuid.Parse(client.RoomID)
+	if err != nil {
+		return
+	}
+	userID, err := uuid.Parse(client.UserID)
+	if err != nil {
+		return
+	}
+
+	ids := make([]uuid.UUID, 0, len(requested))
+	for _, raw := range requested {
+		if id, err := uuid.Parse(raw); err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), serviceTimeout)
+	defer cancel()
+
+	attachments, err := c.Attachments.ResolveForMessage(ctx, roomID, userID, ids)
+	if err != nil {
+		log.Printf("error resolving message attachments: %v", err)
+		return
+	}
+	if len(attachments) == 0 {
+		return
+	}
+
+	for _, attachment := range attachments {
+		msg.AttachmentIDs = append(msg.AttachmentIDs, attachment.ID)
+	}
+	if msg.Metadata == nil {
+		msg.Metadata = map[string]any{}
+	}
+	msg.Metadata["attachments"] = attachments
+}
+
+func (c *Core) assignAttachments(messageID uuid.UUID, msg *Message) {
+	if c.Attachments == nil || len(msg.AttachmentIDs) == 0 {
+		return
+	}
+
+	ids := make([]uuid.UUID, 0, len(msg.AttachmentIDs))
+	for _, raw := range msg.AttachmentIDs {
+		if id, err := uuid.Parse(raw); err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	if err := c.Attachments.AssignToMessage(context.Background(), messageID, ids); err != nil {
+		log.Printf("error assigning attachments to message: %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"chat-application/internal/automod"
+	"chat-application/internal/constants"
+	roomRepository "chat-application/internal/repo/room"
+
+	"github.com/google/uuid"
+)
+
+// applyAutoMod checks a new message against the room's AutoMod rules. Blocked
+// and muting messages are refused and logged; flagged ones are let through
+// with msg.AutoModFlags set. Members who can moderate or manage the room are
+// exempt.
+func (c *Core) applyAutoMod(member *roomRepository.RoomMember, msg *Message) string {
+	if member != nil && (member.CanModerate || member.CanManageRoom || member.Role == "owner") {
+		return ""
+	}
+	roomID, err := uuid.Parse(msg.RoomID)
+	if err != nil {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), serviceTimeout)
+	defer cancel()
+	settings, err := c.RoomRepository.GetAutoModSettings(ctx, roomID)
+	if err != nil {
+		log.Printf("error loading automod rules for room %s: %v", roomID, err)
+		return ""
+	}
+	if settings == nil {
+		return ""
+	}
+	var rules automod.Rules
+	if err := json.Unmarshal(settings.Rules, &rules); err != nil {
+		log.Printf("error decoding automod rules for room %s: %v", roomID, err)
+		return ""
+	}
+
+	violations := automod.Compile(rules).Check(msg.Content)
+	// The reason given is the rule that decided the outcome, not whichever
+	// rule happened to match first.
+	decisive := automod.Strictest(violations)
+	action := decisive.Action
+	switch action {
+	case "":
+		return ""
+	case automod.ActionFlag:
+		msg.AutoModFlags = violations
+		return ""
+	}
+
+	details := map[string]any{"content": msg.Content, "violations": violations}
+	reason := "Your message was blocked because " + decisive.Reason()
+	logAction := constants.ModerationAutoModBlock
+	// Guests have no membership to mute, so their messages are just blocked.
+	if action == automod.ActionMute && member != nil {
+		mutedUntil := time.Now().UTC().Add(rules.MuteDuration())
+		member.MutedUntil = &mutedUntil
+		if err := c.RoomRepository.UpdateRoomMember(ctx, *member); err != nil {
+			log.Printf("error muting user %s in room %s: %v", member.UserID, roomID, err)
+		} else {
+			logAction = constants.ModerationAutoModMute
+			details["muted_until"] = mutedUntil
+			reason += " and you are muted until " + mutedUntil.Format(time.RFC3339)
+		}
+	}
+
+	entry := &roomRepository.ModerationLogEntry{
+		RoomID:            roomID,
+		ModeratorUsername: constants.AutoModUsername,
+		Action:            logAction,
+		TargetUsername:    &msg.Username,
+	}
+	if member != nil {
+		entry.TargetUserID = &member.UserID
+	}
+	c.recordAutoMod(ctx, entry, details)
+	return reason
+}
+
+// flagForReview files an AutoMod report for a stored message that broke
+// rules with the flag action.
+func (c *Core) flagForReview(message *roomRepository.Message, violations []automod.Violation) {
+	if len(violations) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), serviceTimeout)
+	defer cancel()
+
+	details := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		details = append(details, violation.Rule+": "+violation.Detail)
+	}
+	summary := strings.Join(details, "; ")
+	if runes := []rune(summary); len(runes) > constants.MaxReportDetailsLength {
+		summary = string(runes[:constants.MaxReportDetailsLength])
+	}
+	report := &roomRepository.MessageReport{
+		RoomID:         message.RoomID,
+		MessageID:      &message.ID,
+		AuthorID:       message.UserID,
+		AuthorUsername: message.Username,
+		MessageContent: message.Content,
+		Reason:         constants.ReportReasonAutoMod,
+		Details:        &summary,
+	}
+	if _, err := c.RoomRepository.CreateMessageReport(ctx, report); err != nil {
+		log.Printf("error filing automod report for message %s: %v", message.ID, err)
+		return
+	}
+
+	c.recordAutoMod(ctx, &roomRepository.ModerationLogEntry{
+		RoomID:            message.RoomID,
+		ModeratorUsername: constants.AutoModUsername,
+		Action:            constants.ModerationAutoModFlag,
+		TargetUserID:      message.UserID,
+		TargetUsername:    &message.Username,
+		MessageID:         &message.ID,
+		ReportID:          &report.ID,
+	}, map[string]any{"violations": violations})
+}
+
+// recordAutoMod adds an AutoMod action to the room's moderation log.
+func (c *Core) recordAutoMod(ctx context.Context, entry *roomRepository.ModerationLogEntry, details map[string]any) {
+	encoded, err := json.Marshal(details)
+	if err != nil {
+		log.Printf("error encoding automod details: %v", err)
+		return
+	}
+	entry.Details = encoded
+	if err := c.RoomRepository.CreateModerationLogEntry(ctx, entry); err != nil {
+		log.Printf("error recording %s in room %s: %v", entry.Action, entry.RoomID, err)
+	}
+}
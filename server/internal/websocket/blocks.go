@@ -0,0 +1,99 @@
+package websocket
+
+import (
+	"context"
+	"log"
+
+	"github.com/google/uuid"
+)
+
+// BlockLister looks up the users a user has blocked.
+type BlockLister interface {
+	GetBlockedUserIDs(ctx context.Context, blockerID uuid.UUID) ([]uuid.UUID, error)
+}
+
+// loadBlocks attaches the signed-in user's blocks to a newly joined client.
+func (c *Core) loadBlocks(client *Client) {
+	if c.Blocks == nil {
+		return
+	}
+	userID, err := uuid.Parse(client.UserID)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), serviceTimeout)
+	defer cancel()
+	blockedIDs, err := c.Blocks.GetBlockedUserIDs(ctx, userID)
+	if err != nil {
+		log.Printf("error loading blocks for user %s: %v", userID, err)
+		return
+	}
+	if len(blockedIDs) == 0 {
+		return
+	}
+
+	blocked := make(map[string]struct{}, len(blockedIDs))
+	for _, id := range blockedIDs {
+		blocked[id.String()] = struct{}{}
+	}
+
+	room, ok := c.GetRoom(client.RoomID)
+	if !ok {
+		return
+	}
+	room.mu.Lock()
+	client.Blocked = blocked
+	room.mu.Unlock()
+}
+
+// NotifyBlockChanged adds or removes a block on every open socket of the
+// blocker, so live messages are filtered without reconnecting.
+func (c *Core) NotifyBlockChanged(blockerID, blockedID uuid.UUID, blocked bool) {
+	c.roomsMu.RLock()
+	rooms := make([]*Room, 0, len(c.Rooms))
+	for _, room := range c.Rooms {
+		rooms = append(rooms, room)
+	}
+	c.roomsMu.RUnlock()
+
+	blocker, target := blockerID.String(), blockedID.String()
+	for _, room := range rooms {
+		room.mu.Lock()
+		for _, client := range room.Clients {
+			if client.UserID != blocker {
+				continue
+			}
+			if blocked {
+				if client.Blocked == nil {
+					client.Blocked = make(map[string]struct{})
+				}
+				client.Blocked[target] = struct{}{}
+			} else {
+				delete(client.Blocked, target)
+			}
+		}
+		room.mu.Unlock()
+	}
+}
+
+// hides reports whether client has blocked the author of event. Reactions
+// are not hidden since they carry the message's running count. The caller
+// holds room.mu.
+func (client *Client) hides(event *Event) bool {
+	if len(client.Blocked) == 0 {
+		return false
+	}
+	var authorID string
+	switch {
+	case event.Message != nil:
+		authorID = event.Message.UserID
+	case event.Typing != nil:
+		authorID = event.Typing.UserID
+	}
+	if authorID == "" {
+		return false
+	}
+	_, blocked := client.Blocked[authorID]
+	return blocked
+}
@@ -6,6 +6,9 @@ import (
 	"strings"
 	"time"
 
+	"chat-application/internal/api/model"
+	"chat-application/internal/automod"
+
 	"github.com/gorilla/websocket"
 )
 
@@ -16,19 +19,66 @@ type Client struct {
 	RoomID   string `json:"room_id"`
 	Username string `json:"username"`
 	UserID   string `json:"user_id,omitempty"`
+	// Profile is loaded when a signed-in user joins and kept current by
+	// NotifyProfileUpdated.
+	Profile *model.UserProfileRes `json:"-"`
+	// Blocked holds the IDs of users whose messages and typing are not
+	// sent to this client.
+	Blocked map[string]struct{} `json:"-"`
 }
 
 type Message struct {
-	ID              string         `json:"id,omitempty"`
-	Content         string         `json:"content"`
-	RoomID          string         `json:"room_id"`
-	ChannelID       string         `json:"channel_id,omitempty"`
-	ParentMessageID string         `json:"parent_message_id,omitempty"`
-	Username        string         `json:"username"`
-	UserID          string         `json:"user_id,omitempty"`
-	System          bool           `json:"system"`
-	CreatedAt       string         `json:"created_at,omitempty"`
-	Metadata        map[string]any `json:"metadata,omitempty"`
+	ID              string                   `json:"id,omitempty"`
+	Content         string                   `json:"content"`
+	RoomID          string                   `json:"room_id"`
+	ChannelID       string                   `json:"channel_id,omitempty"`
+	ParentMessageID string                   `json:"parent_message_id,omitempty"`
+	Username        string                   `json:"username"`
+	UserID          string                   `json:"user_id,omitempty"`
+	System          bool                     `json:"system"`
+	CreatedAt       string                   `json:"created_at,omitempty"`
+	Metadata        map[string]any           `json:"metadata,omitempty"`
+	ReplyCount      int                      `json:"reply_count,omitempty"`
+	LastReplyAt     string                   `json:"last_reply_at,omitempty"`
+	Reactions       []model.ReactionCountRes `json:"reactions,omitempty"`
+	AttachmentIDs   []string                 `json:"-"`
+	// ReplyToUserID is the author of the message replied to, which may be a
+	// reply itself, before the parent is resolved to the thread root.
+	ReplyToUserID string `json:"-"`
+	// AutoModFlags are the AutoMod rules the message broke with the flag
+	// action; they are filed as a report once the message is stored.
+	AutoModFlags []automod.Violation `json:"-"`
+}
+
+type ThreadEvent struct {
+	RoomID            string `json:"room_id"`
+	ChannelID         string `json:"channel_id,omitempty"`
+	MessageID         string `json:"message_id"`
+	ReplyCount        int    `json:"reply_count"`
+	LastReplyAt       string `json:"last_reply_at,omitempty"`
+	LastReplyID       string `json:"last_reply_id,omitempty"`
+	LastReplyUsername string `json:"last_reply_username,omitempty"`
+}
+
+type ReactionEvent struct {
+	RoomID    string `json:"room_id"`
+	ChannelID string `json:"channel_id,omitempty"`
+	MessageID string `json:"message_id"`
+	UserID    string `json:"user_id"`
+	Username  string `json:"username"`
+	Emoji     string `json:"emoji"`
+	Count     int    `json:"count"`
+}
+
+// ReceiptEvent is both the inbound "read" request and the outbound "receipt"
+// announcing how far a user has read in a channel.
+type ReceiptEvent struct {
+	RoomID    string `json:"room_id"`
+	ChannelID string `json:"channel_id,omitempty"`
+	MessageID string `json:"message_id"`
+	UserID    string `json:"user_id"`
+	Username  string `json:"username"`
+	ReadAt    string `json:"read_at,omitempty"`
 }
 
 type TypingEvent struct {
@@ -45,12 +95,16 @@ type PresenceEvent struct {
 }
 
 type PresenceUser struct {
-	UserID   string `json:"user_id,omitempty"`
-	Username string `json:"username"`
+	UserID      string               `json:"user_id,omitempty"`
+	Username    string               `json:"username"`
+	DisplayName string               `json:"display_name,omitempty"`
+	AvatarURL   string               `json:"avatar_url,omitempty"`
+	Status      *model.UserStatusRes `json:"status,omitempty"`
 }
 
 type NotificationEvent struct {
 	ID        string         `json:"id,omitempty"`
+	UserID    string         `json:"user_id,omitempty"`
 	Kind      string         `json:"kind"`
 	Title     string         `json:"title"`
 	Body      string         `json:"body"`
@@ -66,14 +120,22 @@ type Event struct {
 	Typing       *TypingEvent       `json:"typing,omitempty"`
 	Presence     *PresenceEvent     `json:"presence,omitempty"`
 	Notification *NotificationEvent `json:"notification,omitempty"`
+	Reaction     *ReactionEvent     `json:"reaction,omitempty"`
+	Thread       *ThreadEvent       `json:"thread,omitempty"`
+	Receipt      *ReceiptEvent      `json:"receipt,omitempty"`
+	Profile      *PresenceUser      `json:"profile,omitempty"`
+	// Error is sent with type "error" to the client whose event was refused.
+	Error string `json:"error,omitempty"`
 }
 
 type inboundEvent struct {
-	Type            string `json:"type"`
-	Content         string `json:"content"`
-	ChannelID       string `json:"channel_id"`
-	ParentMessageID string `json:"parent_message_id"`
-	IsTyping        bool   `json:"is_typing"`
+	Type            string   `json:"type"`
+	Content         string   `json:"content"`
+	ChannelID       string   `json:"channel_id"`
+	MessageID       string   `json:"message_id"`
+	ParentMessageID string   `json:"parent_message_id"`
+	IsTyping        bool     `json:"is_typing"`
+	AttachmentIDs   []string `json:"attachment_ids"`
 }
 
 func (c *Client) ReadMessage(core *Core) {
@@ -92,6 +154,25 @@ func (c *Client) ReadMessage(core *Core) {
 		}
 
 		event := parseInboundEvent(c, payload)
+		if event.Message != nil {
+			reason, disconnect := core.admitMessage(c, event.Message)
+			if disconnect {
+				c.disconnect(reason)
+				break
+			}
+			if reason == "" {
+				reason = core.screenMessage(c, event.Message)
+			}
+			if reason != "" {
+				c.refuse(reason)
+				continue
+			}
+			core.resolveThreadParent(event.Message)
+			core.attachFiles(c, event.Message)
+			addEntities(event.Message)
+		} else if !core.flood.admitEvent(c.floodKey(), time.Now()) {
+			continue
+		}
 		log.Printf("Received websocket event %s from %s in room %s", event.Type, c.Username, c.RoomID)
 		core.Broadcast <- event
 	}
@@ -124,6 +205,16 @@ func parseInboundEvent(client *Client, payload []byte) *Event {
 				IsTyping:  inbound.IsTyping,
 			},
 		}
+	case "read":
+		return &Event{
+			Type: "read",
+			Receipt: &ReceiptEvent{
+				RoomID:    client.RoomID,
+				MessageID: inbound.MessageID,
+				UserID:    client.UserID,
+				Username:  client.Username,
+			},
+		}
 	default:
 		return &Event{
 			Type: "message.created",
@@ -135,6 +226,7 @@ func parseInboundEvent(client *Client, payload []byte) *Event {
 				Username:        client.Username,
 				UserID:          client.UserID,
 				CreatedAt:       time.Now().UTC().Format(time.RFC3339),
+				AttachmentIDs:   inbound.AttachmentIDs,
 			},
 		}
 	}
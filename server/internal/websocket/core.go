@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"chat-application/internal/constants"
 	roomRepository "chat-application/internal/repo/room"
 	statsRepository "chat-application/internal/repo/stats"
 
@@ -36,6 +37,8 @@ func (r *Room) AddMessage(msg *Message) {
 	r.History = append(r.History, msg)
 }
 
+const serviceTimeout = constants.DefaultServiceTimeout
+
 type Core struct {
 	Rooms           map[string]*Room
 	roomsMu         sync.RWMutex
@@ -44,6 +47,12 @@ type Core struct {
 	Broadcast       chan *Event
 	RoomRepository  roomRepository.RoomRepositoryInterface
 	StatsRepository statsRepository.StatsRepositoryInterface
+	Attachments     AttachmentResolver
+	Unfurler        LinkUnfurler
+	Push            PushNotifier
+	Profiles        ProfileLoader
+	Blocks          BlockLister
+	flood           *floodGuard
 	db              *sql.DB
 }
 
@@ -67,6 +76,7 @@ func NewCoreWithDependencies(
 		Broadcast:       make(chan *Event, 16),
 		RoomRepository:  roomRepo,
 		StatsRepository: statsRepo,
+		flood:           newFloodGuard(),
 		db:              db,
 	}
 }
@@ -120,6 +130,9 @@ func (c *Core) registerClient(client *Client) {
 	room.mu.Unlock()
 
 	go func() {
+		c.loadProfile(client)
+		c.loadBlocks(client)
+
 		roomUUID, err := uuid.Parse(client.RoomID)
 		if err != nil {
 			log.Printf("error parsing room ID: %v", err)
@@ -137,15 +150,39 @@ func (c *Core) registerClient(client *Client) {
 			channelID = &defaultChannel.ID
 		}
 
-		messages, err := c.RoomRepository.GetRoomMessagesByChannel(context.Background(), roomUUID, channelID, 100, 0)
+		messages, err := c.RoomRepository.GetMessagesBefore(context.Background(), roomUUID, channelID, nil, 100)
 		if err != nil {
 			log.Printf("error fetching room messages: %v", err)
 			return
 		}
 
+		messageIDs := make([]uuid.UUID, 0, len(messages))
+		for _, msg := range messages {
+			messageIDs = append(messageIDs, msg.ID)
+		}
+		var viewerID *uuid.UUID
+		if parsedUserID, err := uuid.Parse(client.UserID); err == nil {
+			viewerID = &parsedUserID
+		}
+		reactionCounts, err := c.RoomRepository.GetReactionCounts(context.Background(), messageIDs, viewerID)
+		if err != nil {
+			log.Printf("error fetching reaction counts: %v", err)
+		}
+
+		room.mu.RLock()
+		blocked := client.Blocked
+		room.mu.RUnlock()
+
 		history := make([]*Message, 0, len(messages))
 		for _, msg := range messages {
-			history = append(history, mapRepositoryMessage(msg))
+			if msg.UserID != nil {
+				if _, ok := blocked[msg.UserID.String()]; ok {
+					continue
+				}
+			}
+			message := mapRepositoryMessage(msg)
+			message.Reactions = reactionCounts[msg.ID]
+			history = append(history, message)
 		}
 
 		client.Message <- &Event{
@@ -186,11 +223,32 @@ func (c *Core) handleEvent(event *Event) {
 			c.fanout(event.Typing.RoomID, event, "")
 		}
 	case "notification":
-		if event.Notification != nil {
+		if event.Notification == nil {
+			return
+		}
+		if event.Notification.UserID != "" {
+			c.sendToUser(event.Notification.UserID, event)
+		} else {
 			c.fanout(event.Notification.RoomID, event, "")
 		}
+	case "read":
+		if event.Receipt != nil {
+			go c.handleRead(event.Receipt)
+		}
 	case "message.created":
 		c.handleMessageCreated(event)
+	case "message.updated":
+		if event.Message != nil {
+			c.fanout(event.Message.RoomID, event, "")
+		}
+	case "thread.updated":
+		if event.Thread != nil {
+			c.fanout(event.Thread.RoomID, event, "")
+		}
+	case "reaction.added", "reaction.removed":
+		if event.Reaction != nil {
+			c.fanout(event.Reaction.RoomID, event, "")
+		}
 	}
 }
 
@@ -208,11 +266,15 @@ func (c *Core) handleMessageCreated(event *Event) {
 	if message.CreatedAt == "" {
 		message.CreatedAt = time.Now().UTC().Format(time.RFC3339)
 	}
+	// Assign the ID before fanout so clients can react to or reply to the
+	// message straight away.
+	messageID := uuid.New()
+	message.ID = messageID.String()
 
 	room.AddMessage(message)
 	c.fanout(message.RoomID, &Event{Type: "message.created", Message: message}, "")
 
-	go func(msg *Message) {
+	go func(messageID uuid.UUID, msg *Message) {
 		roomUUID, err := uuid.Parse(msg.RoomID)
 		if err != nil {
 			log.Printf("error parsing room ID: %v", err)
@@ -248,6 +310,7 @@ func (c *Core) handleMessageCreated(event *Event) {
 		}
 
 		dbMessage := &roomRepository.Message{
+			ID:              messageID,
 			RoomID:          roomUUID,
 			ChannelID:       channelID,
 			ParentMessageID: parentMessageID,
@@ -264,45 +327,48 @@ func (c *Core) handleMessageCreated(event *Event) {
 			return
 		}
 
-		msg.ID = createdMessage.ID.String()
+		c.assignAttachments(createdMessage.ID, msg)
+		c.flagForReview(createdMessage, msg.AutoModFlags)
+		go c.unfurlLinks(createdMessage.ID, msg)
 
 		if userID != nil {
 			if err := c.StatsRepository.IncrementMessageCount(context.Background(), *userID); err != nil {
 				log.Printf("error incrementing message count: %v", err)
 			} else {
 				go func() {
-					_, err := c.StatsRepository.CheckAwardsAndAchievements(context.Background(), *userID)
+					achievements, err := c.StatsRepository.CheckAwardsAndAchievements(context.Background(), *userID)
 					if err != nil {
 						log.Printf("error checking awards and achievements: %v", err)
+						return
 					}
+					c.NotifyAchievements(context.Background(), *userID, achievements)
 				}()
 			}
 		}
 
-		notifications, err := c.RoomRepository.CreateMentionNotifications(context.Background(), roomUUID, createdMessage)
+		// Posting in a channel marks everything up to the new message as read.
+		if userID != nil && parentMessageID == nil {
+			if _, err := c.RoomRepository.MarkChannelRead(context.Background(), *userID, createdMessage); err != nil {
+				log.Printf("error marking channel read: %v", err)
+			}
+		}
+
+		groups := c.resolveMentionGroups(context.Background(), createdMessage)
+		notifications, err := c.RoomRepository.CreateMentionNotifications(context.Background(), roomUUID, createdMessage, groups)
 		if err != nil {
 			log.Printf("error creating mention notifications: %v", err)
-			return
 		}
+		c.publishNotifications(notifications)
+
+		mentioned := make([]uuid.UUID, 0, len(notifications)+1)
 		for _, notification := range notifications {
-			payload := map[string]any{}
-			if len(notification.Payload) > 0 {
-				_ = json.Unmarshal(notification.Payload, &payload)
-			}
-			c.Broadcast <- &Event{
-				Type: "notification",
-				Notification: &NotificationEvent{
-					ID:        notification.ID.String(),
-					Kind:      notification.Kind,
-					Title:     notification.Title,
-					Body:      notification.Body,
-					RoomID:    roomUUID.String(),
-					MessageID: createdMessage.ID.String(),
-					Payload:   payload,
-				},
-			}
+			mentioned = append(mentioned, notification.UserID)
+		}
+		if replyTo := c.notifyReply(createdMessage, msg.ReplyToUserID, mentioned); replyTo != nil {
+			mentioned = append(mentioned, *replyTo)
 		}
-	}(message)
+		c.recordThreadReply(createdMessage, mentioned)
+	}(messageID, message)
 }
 
 func (c *Core) fanout(roomID string, event *Event, excludeClientID string) {
@@ -317,6 +383,9 @@ func (c *Core) fanout(roomID string, event *Event, excludeClientID string) {
 		if excludeClientID != "" && client.ID == excludeClientID {
 			continue
 		}
+		if client.hides(event) {
+			continue
+		}
 		select {
 		case client.Message <- event:
 		default:
@@ -345,12 +414,10 @@ func (c *Core) buildPresenceSnapshot(roomID string) *PresenceEvent {
 	room.mu.RLock()
 	defer room.mu.RUnlock()
 
+	now := time.Now()
 	users := make([]PresenceUser, 0, len(room.Clients))
 	for _, client := range room.Clients {
-		users = append(users, PresenceUser{
-			UserID:   client.UserID,
-			Username: client.Username,
-		})
+		users = append(users, *presenceUser(client, now))
 	}
 
 	return &PresenceEvent{
@@ -377,6 +444,10 @@ func mapRepositoryMessage(msg *roomRepository.Message) *Message {
 	if msg.ParentMessageID != nil {
 		message.ParentMessageID = msg.ParentMessageID.String()
 	}
+	message.ReplyCount = msg.ReplyCount
+	if msg.LastReplyAt != nil {
+		message.LastReplyAt = msg.LastReplyAt.UTC().Format(time.RFC3339)
+	}
 	if len(msg.Metadata) > 0 {
 		var metadata map[string]any
 		if err := json.Unmarshal(msg.Metadata, &metadata); err == nil {
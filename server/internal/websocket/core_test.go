@@ -3,10 +3,18 @@ package websocket
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"chat-application/internal/api/model"
+	"chat-application/internal/automod"
+	"chat-application/internal/constants"
+	"chat-application/internal/pagination"
 	roomRepository "chat-application/internal/repo/room"
 	statsRepository "chat-application/internal/repo/stats"
 
@@ -14,8 +22,21 @@ import (
 )
 
 type fakeRoomRepository struct {
-	getMessagesFn   func(ctx context.Context, roomID uuid.UUID, limit int, offset int) ([]*roomRepository.Message, error)
+	getMessagesFn   func(ctx context.Context, roomID uuid.UUID, before *pagination.Cursor, limit int) ([]*roomRepository.Message, error)
 	createMessageFn func(ctx context.Context, message *roomRepository.Message) (*roomRepository.Message, error)
+	mergeMetadataFn func(ctx context.Context, messageID uuid.UUID, patch []byte) error
+	messages        map[uuid.UUID]*roomRepository.Message
+	threadNotifyFn  func(ctx context.Context, parent *roomRepository.Message, reply *roomRepository.Message, exclude []uuid.UUID) ([]roomRepository.Notification, error)
+	notifyFn        func(ctx context.Context, notification *roomRepository.Notification) (bool, error)
+	members         []roomRepository.RoomMember
+	channels        []roomRepository.RoomChannel
+	automod         *roomRepository.AutoModSettings
+	updatedMembers  []roomRepository.RoomMember
+	reports         []*roomRepository.MessageReport
+	moderationLog   []roomRepository.ModerationLogEntry
+
+	readMu       sync.Mutex
+	readPointers map[uuid.UUID]time.Time
 }
 
 func (f *fakeRoomRepository) GetDB() *sql.DB { return nil }
@@ -29,30 +50,104 @@ func (f *fakeRoomRepository) CountActiveRooms(ctx context.Context) (int, error)
 func (f *fakeRoomRepository) GetAllActiveRooms(ctx context.Context) ([]*roomRepository.Room, error) {
 	return nil, nil
 }
+func (f *fakeRoomRepository) GetVisibleRooms(ctx context.Context, viewerID *uuid.UUID) ([]*roomRepository.Room, error) {
+	return nil, nil
+}
+func (f *fakeRoomRepository) SetRoomVisibility(ctx context.Context, roomID uuid.UUID, visibility string) (bool, error) {
+	return false, nil
+}
+func (f *fakeRoomRepository) CreateRoomInvite(ctx context.Context, invite *roomRepository.RoomInvite) (*roomRepository.RoomInvite, error) {
+	return invite, nil
+}
+func (f *fakeRoomRepository) GetRoomInvites(ctx context.Context, roomID uuid.UUID) ([]roomRepository.RoomInvite, error) {
+	return nil, nil
+}
+func (f *fakeRoomRepository) GetRoomInviteByCode(ctx context.Context, code string) (*roomRepository.RoomInvite, error) {
+	return nil, nil
+}
+func (f *fakeRoomRepository) RevokeRoomInvite(ctx context.Context, roomID, inviteID uuid.UUID) (bool, error) {
+	return false, nil
+}
+func (f *fakeRoomRepository) UseRoomInvite(ctx context.Context, inviteID uuid.UUID) (bool, error) {
+	return false, nil
+}
+func (f *fakeRoomRepository) SetMemberInvite(ctx context.Context, roomID, userID uuid.UUID, invite *roomRepository.RoomInvite) error {
+	return nil
+}
 func (f *fakeRoomRepository) CreateMessage(ctx context.Context, message *roomRepository.Message) (*roomRepository.Message, error) {
 	if f.createMessageFn != nil {
 		return f.createMessageFn(ctx, message)
 	}
 	return message, nil
 }
-func (f *fakeRoomRepository) GetRoomMessages(ctx context.Context, roomID uuid.UUID, limit int, offset int) ([]*roomRepository.Message, error) {
+func (f *fakeRoomRepository) MergeMessageMetadata(ctx context.Context, messageID uuid.UUID, patch []byte) error {
+	if f.mergeMetadataFn != nil {
+		return f.mergeMetadataFn(ctx, messageID, patch)
+	}
+	return nil
+}
+func (f *fakeRoomRepository) GetMessagesBefore(ctx context.Context, roomID uuid.UUID, channelID *uuid.UUID, before *pagination.Cursor, limit int) ([]*roomRepository.Message, error) {
 	if f.getMessagesFn != nil {
-		return f.getMessagesFn(ctx, roomID, limit, offset)
+		return f.getMessagesFn(ctx, roomID, before, limit)
 	}
 	return nil, nil
 }
+func (f *fakeRoomRepository) GetMessagesAfter(ctx context.Context, roomID uuid.UUID, channelID *uuid.UUID, after pagination.Cursor, limit int) ([]*roomRepository.Message, error) {
+	return nil, nil
+}
 func (f *fakeRoomRepository) CountPinnedRooms(ctx context.Context) (int, error)   { return 0, nil }
 func (f *fakeRoomRepository) DeleteExpiredRooms(ctx context.Context) (int, error) { return 0, nil }
 func (f *fakeRoomRepository) EnsureRoomMembership(ctx context.Context, roomID, userID uuid.UUID) error {
 	return nil
 }
 func (f *fakeRoomRepository) GetRoomMember(ctx context.Context, roomID, userID uuid.UUID) (*roomRepository.RoomMember, error) {
+	for i := range f.members {
+		if f.members[i].RoomID == roomID && f.members[i].UserID == userID {
+			member := f.members[i]
+			return &member, nil
+		}
+	}
 	return nil, nil
 }
 func (f *fakeRoomRepository) GetRoomMembers(ctx context.Context, roomID uuid.UUID) ([]roomRepository.RoomMember, error) {
-	return nil, nil
+	return f.members, nil
 }
 func (f *fakeRoomRepository) UpdateRoomMember(ctx context.Context, member roomRepository.RoomMember) error {
+	f.updatedMembers = append(f.updatedMembers, member)
+	return nil
+}
+func (f *fakeRoomRepository) CreateMessageReport(ctx context.Context, report *roomRepository.MessageReport) (bool, error) {
+	report.ID = uuid.New()
+	f.reports = append(f.reports, report)
+	return true, nil
+}
+func (f *fakeRoomRepository) GetMessageReport(ctx context.Context, id uuid.UUID) (*roomRepository.MessageReport, error) {
+	return nil, nil
+}
+func (f *fakeRoomRepository) GetMessageReports(ctx context.Context, roomID uuid.UUID, status string, after *pagination.Cursor, limit int) ([]roomRepository.MessageReport, error) {
+	return nil, nil
+}
+func (f *fakeRoomRepository) ResolveMessageReports(ctx context.Context, report *roomRepository.MessageReport, resolution string, resolvedBy uuid.UUID) (int64, error) {
+	return 0, nil
+}
+func (f *fakeRoomRepository) RemoveMessageContent(ctx context.Context, messageID uuid.UUID) (bool, error) {
+	return false, nil
+}
+func (f *fakeRoomRepository) CreateModerationLogEntry(ctx context.Context, entry *roomRepository.ModerationLogEntry) error {
+	f.moderationLog = append(f.moderationLog, *entry)
+	return nil
+}
+func (f *fakeRoomRepository) GetModerationLog(ctx context.Context, roomID uuid.UUID, before *pagination.Cursor, limit int) ([]roomRepository.ModerationLogEntry, error) {
+	return nil, nil
+}
+func (f *fakeRoomRepository) GetAutoModSettings(ctx context.Context, roomID uuid.UUID) (*roomRepository.AutoModSettings, error) {
+	if f.automod != nil && f.automod.RoomID == roomID {
+		return f.automod, nil
+	}
+	return nil, nil
+}
+func (f *fakeRoomRepository) SaveAutoModSettings(ctx context.Context, settings *roomRepository.AutoModSettings) error {
+	f.automod = settings
 	return nil
 }
 func (f *fakeRoomRepository) CreateCategory(ctx context.Context, category *roomRepository.RoomCategory) (*roomRepository.RoomCategory, error) {
@@ -67,11 +162,26 @@ func (f *fakeRoomRepository) GetRoomCategories(ctx context.Context, roomID uuid.
 func (f *fakeRoomRepository) GetRoomChannels(ctx context.Context, roomID uuid.UUID) ([]roomRepository.RoomChannel, error) {
 	return nil, nil
 }
-func (f *fakeRoomRepository) GetDefaultChannel(ctx context.Context, roomID uuid.UUID) (*roomRepository.RoomChannel, error) {
+func (f *fakeRoomRepository) GetRoomChannel(ctx context.Context, roomID, channelID uuid.UUID) (*roomRepository.RoomChannel, error) {
+	for i := range f.channels {
+		if f.channels[i].RoomID == roomID && f.channels[i].ID == channelID {
+			channel := f.channels[i]
+			return &channel, nil
+		}
+	}
 	return nil, nil
 }
-func (f *fakeRoomRepository) GetRoomMessagesByChannel(ctx context.Context, roomID uuid.UUID, channelID *uuid.UUID, limit int, offset int) ([]*roomRepository.Message, error) {
-	return f.GetRoomMessages(ctx, roomID, limit, offset)
+func (f *fakeRoomRepository) SetChannelSlowMode(ctx context.Context, roomID, channelID uuid.UUID, seconds int) (bool, error) {
+	for i := range f.channels {
+		if f.channels[i].RoomID == roomID && f.channels[i].ID == channelID {
+			f.channels[i].SlowModeSeconds = seconds
+			return true, nil
+		}
+	}
+	return false, nil
+}
+func (f *fakeRoomRepository) GetDefaultChannel(ctx context.Context, roomID uuid.UUID) (*roomRepository.RoomChannel, error) {
+	return nil, nil
 }
 func (f *fakeRoomRepository) SearchMessages(ctx context.Context, roomID uuid.UUID, queryText string, channelID *uuid.UUID, username string, limit int) ([]roomRepository.Message, error) {
 	return nil, nil
@@ -79,13 +189,46 @@ func (f *fakeRoomRepository) SearchMessages(ctx context.Context, roomID uuid.UUI
 func (f *fakeRoomRepository) CreateNotification(ctx context.Context, notification *roomRepository.Notification) error {
 	return nil
 }
-func (f *fakeRoomRepository) GetNotifications(ctx context.Context, userID uuid.UUID, limit int) ([]roomRepository.Notification, error) {
+func (f *fakeRoomRepository) GetNotifications(ctx context.Context, userID uuid.UUID, before *pagination.Cursor, unreadOnly bool, limit int) ([]roomRepository.Notification, error) {
+	return nil, nil
+}
+func (f *fakeRoomRepository) MarkAllNotificationsRead(ctx context.Context, userID uuid.UUID, roomID *uuid.UUID) (int64, error) {
+	return 0, nil
+}
+func (f *fakeRoomRepository) CountUnreadNotifications(ctx context.Context, userID uuid.UUID) (int, error) {
+	return 0, nil
+}
+func (f *fakeRoomRepository) DeleteNotificationsBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	return 0, nil
+}
+func (f *fakeRoomRepository) GetNotificationPreferences(ctx context.Context, userID uuid.UUID) ([]roomRepository.NotificationPreference, error) {
 	return nil, nil
 }
+func (f *fakeRoomRepository) SetNotificationPreference(ctx context.Context, preference *roomRepository.NotificationPreference) error {
+	return nil
+}
+func (f *fakeRoomRepository) DeleteNotificationPreference(ctx context.Context, userID uuid.UUID, roomID *uuid.UUID) error {
+	return nil
+}
 func (f *fakeRoomRepository) MarkNotificationRead(ctx context.Context, notificationID, userID uuid.UUID) error {
 	return nil
 }
-func (f *fakeRoomRepository) CreateMentionNotifications(ctx context.Context, roomID uuid.UUID, message *roomRepository.Message) ([]roomRepository.Notification, error) {
+func (f *fakeRoomRepository) CreateNotificationIfAccepted(ctx context.Context, notification *roomRepository.Notification) (bool, error) {
+	if f.notifyFn != nil {
+		return f.notifyFn(ctx, notification)
+	}
+	return false, nil
+}
+func (f *fakeRoomRepository) GetPendingDigests(ctx context.Context, cutoff time.Time, perUser int) ([]roomRepository.DigestRecipient, error) {
+	return nil, nil
+}
+func (f *fakeRoomRepository) MarkNotificationsEmailed(ctx context.Context, userID uuid.UUID, cutoff time.Time) error {
+	return nil
+}
+func (f *fakeRoomRepository) SetEmailDigest(ctx context.Context, userID uuid.UUID, enabled bool) error {
+	return nil
+}
+func (f *fakeRoomRepository) CreateMentionNotifications(ctx context.Context, roomID uuid.UUID, message *roomRepository.Message, groups *roomRepository.MentionGroups) ([]roomRepository.Notification, error) {
 	return nil, nil
 }
 func (f *fakeRoomRepository) AddReaction(ctx context.Context, reaction *model.MessageReaction) error {
@@ -94,6 +237,78 @@ func (f *fakeRoomRepository) AddReaction(ctx context.Context, reaction *model.Me
 func (f *fakeRoomRepository) GetReactions(ctx context.Context, messageID string) ([]model.MessageReaction, error) {
 	return nil, nil
 }
+func (f *fakeRoomRepository) ToggleReaction(ctx context.Context, reaction *model.MessageReaction) (bool, error) {
+	return true, nil
+}
+func (f *fakeRoomRepository) RemoveReaction(ctx context.Context, messageID, userID uuid.UUID, emoji string) (bool, error) {
+	return false, nil
+}
+func (f *fakeRoomRepository) GetReactionCounts(ctx context.Context, messageIDs []uuid.UUID, viewerID *uuid.UUID) (map[uuid.UUID][]model.ReactionCountRes, error) {
+	return nil, nil
+}
+func (f *fakeRoomRepository) GetMessageByID(ctx context.Context, id uuid.UUID) (*roomRepository.Message, error) {
+	return f.messages[id], nil
+}
+func (f *fakeRoomRepository) GetThreadReplies(ctx context.Context, parentID uuid.UUID, after *pagination.Cursor, limit int) ([]*roomRepository.Message, error) {
+	return nil, nil
+}
+func (f *fakeRoomRepository) RecordThreadReply(ctx context.Context, parentID uuid.UUID, repliedAt time.Time) (*roomRepository.ThreadSummary, error) {
+	parent := f.messages[parentID]
+	parent.ReplyCount++
+	parent.LastReplyAt = &repliedAt
+	return &roomRepository.ThreadSummary{
+		MessageID:   parentID,
+		RoomID:      parent.RoomID,
+		ChannelID:   parent.ChannelID,
+		ReplyCount:  parent.ReplyCount,
+		LastReplyAt: parent.LastReplyAt,
+	}, nil
+}
+func (f *fakeRoomRepository) FollowThread(ctx context.Context, messageID, userID uuid.UUID) error {
+	return nil
+}
+func (f *fakeRoomRepository) UnfollowThread(ctx context.Context, messageID, userID uuid.UUID) error {
+	return nil
+}
+func (f *fakeRoomRepository) IsFollowingThread(ctx context.Context, messageID, userID uuid.UUID) (bool, error) {
+	return false, nil
+}
+func (f *fakeRoomRepository) CreateThreadReplyNotifications(ctx context.Context, parent *roomRepository.Message, reply *roomRepository.Message, exclude []uuid.UUID) ([]roomRepository.Notification, error) {
+	if f.threadNotifyFn != nil {
+		return f.threadNotifyFn(ctx, parent, reply, exclude)
+	}
+	return nil, nil
+}
+func (f *fakeRoomRepository) MarkChannelRead(ctx context.Context, userID uuid.UUID, message *roomRepository.Message) (bool, error) {
+	f.readMu.Lock()
+	defer f.readMu.Unlock()
+	if f.readPointers == nil {
+		f.readPointers = make(map[uuid.UUID]time.Time)
+	}
+	if last, ok := f.readPointers[userID]; ok && !message.CreatedAt.After(last) {
+		return false, nil
+	}
+	f.readPointers[userID] = message.CreatedAt
+	return true, nil
+}
+func (f *fakeRoomRepository) GetChannelReadStates(ctx context.Context, userID uuid.UUID, roomIDs []uuid.UUID) ([]roomRepository.ChannelReadState, error) {
+	return nil, nil
+}
+func (f *fakeRoomRepository) GetMessageReadReceipts(ctx context.Context, message *roomRepository.Message) ([]roomRepository.ReadReceipt, error) {
+	return nil, nil
+}
+func (f *fakeRoomRepository) CreateRoomEmoji(ctx context.Context, emoji *roomRepository.RoomEmoji) (*roomRepository.RoomEmoji, error) {
+	return emoji, nil
+}
+func (f *fakeRoomRepository) GetRoomEmojis(ctx context.Context, roomID uuid.UUID) ([]roomRepository.RoomEmoji, error) {
+	return nil, nil
+}
+func (f *fakeRoomRepository) GetRoomEmoji(ctx context.Context, roomID uuid.UUID, name string) (*roomRepository.RoomEmoji, error) {
+	return nil, nil
+}
+func (f *fakeRoomRepository) DeleteRoomEmoji(ctx context.Context, roomID uuid.UUID, name string) (bool, error) {
+	return false, nil
+}
 
 type fakeStatsRepository struct {
 	incremented []uuid.UUID
@@ -133,11 +348,14 @@ func (f *fakeStatsRepository) IncrementMessageCount(ctx context.Context, userID
 func (f *fakeStatsRepository) GetLeaderboard(ctx context.Context, limit int) ([]statsRepository.LeaderboardEntry, error) {
 	return nil, nil
 }
+func (f *fakeStatsRepository) GetUsername(ctx context.Context, userID uuid.UUID) (string, error) {
+	return "", nil
+}
 
 func TestCoreRegisterLoadsRoomHistory(t *testing.T) {
 	roomID := uuid.New()
 	repo := &fakeRoomRepository{
-		getMessagesFn: func(ctx context.Context, gotRoomID uuid.UUID, limit int, offset int) ([]*roomRepository.Message, error) {
+		getMessagesFn: func(ctx context.Context, gotRoomID uuid.UUID, before *pagination.Cursor, limit int) ([]*roomRepository.Message, error) {
 			if gotRoomID != roomID {
 				t.Fatalf("expected room id %s, got %s", roomID, gotRoomID)
 			}
@@ -220,6 +438,7 @@ func TestCoreBroadcastPersistsAndFanoutsMessage(t *testing.T) {
 		},
 	}
 
+	var broadcastID string
 	select {
 	case event := <-client.Message:
 		if event.Type != "message.created" || event.Message == nil {
@@ -228,6 +447,10 @@ func TestCoreBroadcastPersistsAndFanoutsMessage(t *testing.T) {
 		if event.Message.Content != "hello world" {
 			t.Fatalf("expected broadcast content, got %q", event.Message.Content)
 		}
+		if event.Message.ID == "" {
+			t.Fatal("expected broadcast message to carry its ID")
+		}
+		broadcastID = event.Message.ID
 	case <-time.After(2 * time.Second):
 		t.Fatal("timed out waiting for broadcast")
 	}
@@ -244,6 +467,9 @@ func TestCoreBroadcastPersistsAndFanoutsMessage(t *testing.T) {
 	if persisted.Content != "hello world" {
 		t.Fatalf("expected persisted content %q, got %q", "hello world", persisted.Content)
 	}
+	if persisted.ID.String() != broadcastID {
+		t.Fatalf("expected persisted ID %s to match broadcast, got %s", broadcastID, persisted.ID)
+	}
 	if len(statsRepo.incremented) != 1 || statsRepo.incremented[0] != userID {
 		deadline := time.Now().Add(2 * time.Second)
 		for len(statsRepo.incremented) != 1 && time.Now().Before(deadline) {
@@ -254,3 +480,652 @@ func TestCoreBroadcastPersistsAndFanoutsMessage(t *testing.T) {
 		t.Fatalf("expected stats increment for user %s, got %+v", userID, statsRepo.incremented)
 	}
 }
+
+type fakeUnfurler struct {
+	previews []model.LinkPreviewRes
+}
+
+func (f *fakeUnfurler) Unfurl(ctx context.Context, content string) []model.LinkPreviewRes {
+	return f.previews
+}
+
+func TestCoreUnfurlsLinksAfterPersisting(t *testing.T) {
+	roomID := uuid.New()
+	messageID := uuid.New()
+	patches := make(chan string, 1)
+
+	repo := &fakeRoomRepository{
+		createMessageFn: func(ctx context.Context, message *roomRepository.Message) (*roomRepository.Message, error) {
+			message.ID = messageID
+			return message, nil
+		},
+		mergeMetadataFn: func(ctx context.Context, gotID uuid.UUID, patch []byte) error {
+			if gotID != messageID {
+				t.Errorf("expected metadata update for %s, got %s", messageID, gotID)
+			}
+			patches <- string(patch)
+			return nil
+		},
+	}
+	core := NewCoreWithDependencies(nil, repo, &fakeStatsRepository{})
+	core.Unfurler = &fakeUnfurler{previews: []model.LinkPreviewRes{{URL: "https://go.dev", Title: "Go"}}}
+	client := &Client{
+		ID:      "client-1",
+		RoomID:  roomID.String(),
+		Message: make(chan *Event, 4),
+	}
+	core.AddRoom(&Room{
+		ID:      roomID.String(),
+		Name:    "General",
+		Clients: map[string]*Client{client.ID: client},
+	})
+
+	go core.Start()
+
+	core.Broadcast <- &Event{
+		Type: "message.created",
+		Message: &Message{
+			Content:  "read https://go.dev",
+			RoomID:   roomID.String(),
+			Username: "alice",
+			Metadata: map[string]any{"kind": "text"},
+		},
+	}
+
+	var updated *Message
+	deadline := time.After(2 * time.Second)
+	for updated == nil {
+		select {
+		case event := <-client.Message:
+			if event.Type == "message.updated" {
+				updated = event.Message
+			}
+		case <-deadline:
+			t.Fatal("timed out waiting for message.updated")
+		}
+	}
+
+	if updated.ID != messageID.String() {
+		t.Fatalf("expected updated message ID %s, got %q", messageID, updated.ID)
+	}
+	previews, ok := updated.Metadata["previews"].([]model.LinkPreviewRes)
+	if !ok || len(previews) != 1 || previews[0].Title != "Go" {
+		t.Fatalf("expected previews in metadata, got %+v", updated.Metadata)
+	}
+	if updated.Metadata["kind"] != "text" {
+		t.Fatalf("expected existing metadata to be kept, got %+v", updated.Metadata)
+	}
+
+	select {
+	case patch := <-patches:
+		if patch != `{"previews":[{"url":"https://go.dev","title":"Go"}]}` {
+			t.Fatalf("unexpected metadata patch %s", patch)
+		}
+	default:
+		t.Fatal("expected previews to be persisted")
+	}
+}
+
+func TestResolveThreadParentJoinsRootThread(t *testing.T) {
+	roomID := uuid.New()
+	channelID := uuid.New()
+	rootID := uuid.New()
+	replyID := uuid.New()
+	repo := &fakeRoomRepository{
+		messages: map[uuid.UUID]*roomRepository.Message{
+			rootID:  {ID: rootID, RoomID: roomID, ChannelID: &channelID},
+			replyID: {ID: replyID, RoomID: roomID, ChannelID: &channelID, ParentMessageID: &rootID},
+		},
+	}
+	core := NewCoreWithDependencies(nil, repo, &fakeStatsRepository{})
+
+	msg := &Message{RoomID: roomID.String(), ParentMessageID: replyID.String()}
+	core.resolveThreadParent(msg)
+	if msg.ParentMessageID != rootID.String() || msg.ChannelID != channelID.String() {
+		t.Fatalf("expected reply to join root thread in its channel, got %+v", msg)
+	}
+
+	other := &Message{RoomID: uuid.New().String(), ParentMessageID: rootID.String()}
+	core.resolveThreadParent(other)
+	if other.ParentMessageID != "" {
+		t.Fatalf("expected parent from another room to be dropped, got %q", other.ParentMessageID)
+	}
+}
+
+func TestThreadReplyUpdatesRootAndNotifiesOnlyFollowers(t *testing.T) {
+	roomID := uuid.New()
+	rootID := uuid.New()
+	authorID := uuid.New()
+	replierID := uuid.New()
+
+	repo := &fakeRoomRepository{
+		messages: map[uuid.UUID]*roomRepository.Message{
+			rootID: {ID: rootID, RoomID: roomID, UserID: &authorID, Username: "author"},
+		},
+		threadNotifyFn: func(ctx context.Context, parent *roomRepository.Message, reply *roomRepository.Message, exclude []uuid.UUID) ([]roomRepository.Notification, error) {
+			return []roomRepository.Notification{{
+				ID:        uuid.New(),
+				UserID:    authorID,
+				RoomID:    &roomID,
+				MessageID: &reply.ID,
+				Kind:      "thread_reply",
+			}}, nil
+		},
+	}
+	core := NewCoreWithDependencies(nil, repo, &fakeStatsRepository{})
+	author := &Client{ID: "author", RoomID: roomID.String(), UserID: authorID.String(), Message: make(chan *Event, 8)}
+	replier := &Client{ID: "replier", RoomID: roomID.String(), UserID: replierID.String(), Message: make(chan *Event, 8)}
+	core.AddRoom(&Room{
+		ID:      roomID.String(),
+		Name:    "General",
+		Clients: map[string]*Client{author.ID: author, replier.ID: replier},
+	})
+
+	go core.Start()
+
+	core.Broadcast <- &Event{
+		Type: "message.created",
+		Message: &Message{
+			Content:         "agreed",
+			RoomID:          roomID.String(),
+			ParentMessageID: rootID.String(),
+			Username:        "replier",
+			UserID:          replierID.String(),
+		},
+	}
+
+	collect := func(client *Client, want int) []string {
+		var types []string
+		deadline := time.After(2 * time.Second)
+		for len(types) < want {
+			select {
+			case event := <-client.Message:
+				types = append(types, event.Type)
+				if event.Type == "thread.updated" && (event.Thread.MessageID != rootID.String() || event.Thread.ReplyCount != 1) {
+					t.Errorf("unexpected thread event %+v", event.Thread)
+				}
+			case <-deadline:
+				return types
+			}
+		}
+		return types
+	}
+
+	authorEvents := collect(author, 3)
+	if len(authorEvents) != 3 || authorEvents[2] != "notification" {
+		t.Fatalf("expected author to get message, thread update and notification, got %v", authorEvents)
+	}
+
+	replierEvents := collect(replier, 2)
+	if len(replierEvents) != 2 || replierEvents[1] != "thread.updated" {
+		t.Fatalf("expected replier to get message and thread update, got %v", replierEvents)
+	}
+	select {
+	case event := <-replier.Message:
+		t.Fatalf("expected no further events for replier, got %s", event.Type)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestReadEventSharesReceiptInSmallRooms(t *testing.T) {
+	roomID := uuid.New()
+	channelID := uuid.New()
+	messageID := uuid.New()
+	aliceID := uuid.New()
+	bobID := uuid.New()
+
+	repo := &fakeRoomRepository{
+		messages: map[uuid.UUID]*roomRepository.Message{
+			messageID: {ID: messageID, RoomID: roomID, ChannelID: &channelID, UserID: &bobID, CreatedAt: time.Now()},
+		},
+		members: []roomRepository.RoomMember{
+			{RoomID: roomID, UserID: aliceID, Username: "alice"},
+			{RoomID: roomID, UserID: bobID, Username: "bob"},
+		},
+	}
+	core := NewCoreWithDependencies(nil, repo, &fakeStatsRepository{})
+	alice := &Client{ID: "alice", RoomID: roomID.String(), UserID: aliceID.String(), Username: "alice", Message: make(chan *Event, 8)}
+	bob := &Client{ID: "bob", RoomID: roomID.String(), UserID: bobID.String(), Username: "bob", Message: make(chan *Event, 8)}
+	core.AddRoom(&Room{
+		ID:      roomID.String(),
+		Name:    "General",
+		Clients: map[string]*Client{alice.ID: alice, bob.ID: bob},
+	})
+
+	go core.Start()
+
+	payload := []byte(`{"type":"read","message_id":"` + messageID.String() + `"}`)
+	core.Broadcast <- parseInboundEvent(alice, payload)
+
+	for _, client := range []*Client{alice, bob} {
+		select {
+		case event := <-client.Message:
+			if event.Type != "receipt" || event.Receipt.UserID != aliceID.String() || event.Receipt.ChannelID != channelID.String() {
+				t.Fatalf("expected receipt from alice, got %+v", event)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for receipt on %s", client.ID)
+		}
+	}
+
+	// Reading the same message again does not move the pointer or announce anything.
+	core.Broadcast <- parseInboundEvent(alice, payload)
+	select {
+	case event := <-bob.Message:
+		t.Fatalf("expected no second receipt, got %+v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestResolveMentionGroupsRequiresPermission(t *testing.T) {
+	roomID := uuid.New()
+	aliceID := uuid.New()
+	bobID := uuid.New()
+
+	repo := &fakeRoomRepository{
+		members: []roomRepository.RoomMember{
+			{RoomID: roomID, UserID: aliceID, Username: "alice", Role: "member", CanMentionEveryone: true},
+			{RoomID: roomID, UserID: bobID, Username: "bob", Role: "member"},
+		},
+	}
+	core := NewCoreWithDependencies(nil, repo, &fakeStatsRepository{})
+	core.AddRoom(&Room{
+		ID:   roomID.String(),
+		Name: "General",
+		Clients: map[string]*Client{
+			"alice-1": {ID: "alice-1", RoomID: roomID.String(), UserID: aliceID.String(), Username: "alice"},
+			"alice-2": {ID: "alice-2", RoomID: roomID.String(), UserID: aliceID.String(), Username: "alice"},
+			"bob":     {ID: "bob", RoomID: roomID.String(), UserID: bobID.String(), Username: "bob"},
+		},
+	})
+
+	message := &roomRepository.Message{RoomID: roomID, UserID: &aliceID, Content: "@here @room @moderators @admins"}
+	groups := core.resolveMentionGroups(context.Background(), message)
+	if groups == nil {
+		t.Fatal("expected groups for a member allowed to mention everyone")
+	}
+	if len(groups.Online) != 2 || groups.Everyone != "@room" || !groups.Moderators {
+		t.Fatalf("unexpected groups: %+v", groups)
+	}
+	if strings.Join(groups.Roles, ",") != "admins,admin" {
+		t.Fatalf("expected admins and admin as role candidates, got %v", groups.Roles)
+	}
+
+	message.UserID = &bobID
+	if groups := core.resolveMentionGroups(context.Background(), message); groups != nil {
+		t.Fatalf("expected no groups without permission, got %+v", groups)
+	}
+}
+
+func TestReplyToReplyNotifiesItsAuthorOnce(t *testing.T) {
+	roomID := uuid.New()
+	rootID := uuid.New()
+	answerID := uuid.New()
+	rootAuthorID := uuid.New()
+	answerAuthorID := uuid.New()
+	replierID := uuid.New()
+
+	excluded := make(chan []uuid.UUID, 1)
+	repo := &fakeRoomRepository{
+		messages: map[uuid.UUID]*roomRepository.Message{
+			rootID:   {ID: rootID, RoomID: roomID, UserID: &rootAuthorID, Username: "root"},
+			answerID: {ID: answerID, RoomID: roomID, ParentMessageID: &rootID, UserID: &answerAuthorID, Username: "answer"},
+		},
+		notifyFn: func(ctx context.Context, notification *roomRepository.Notification) (bool, error) {
+			notification.ID = uuid.New()
+			return true, nil
+		},
+		threadNotifyFn: func(ctx context.Context, parent *roomRepository.Message, reply *roomRepository.Message, exclude []uuid.UUID) ([]roomRepository.Notification, error) {
+			excluded <- exclude
+			return nil, nil
+		},
+	}
+	core := NewCoreWithDependencies(nil, repo, &fakeStatsRepository{})
+	answerAuthor := &Client{ID: "answer", RoomID: roomID.String(), UserID: answerAuthorID.String(), Message: make(chan *Event, 8)}
+	replier := &Client{ID: "replier", RoomID: roomID.String(), UserID: replierID.String(), Username: "replier", Message: make(chan *Event, 8)}
+	core.AddRoom(&Room{
+		ID:      roomID.String(),
+		Name:    "General",
+		Clients: map[string]*Client{answerAuthor.ID: answerAuthor, replier.ID: replier},
+	})
+
+	go core.Start()
+
+	event := parseInboundEvent(replier, []byte(`{"type":"message","content":"thanks","parent_message_id":"`+answerID.String()+`"}`))
+	core.resolveThreadParent(event.Message)
+	if event.Message.ParentMessageID != rootID.String() || event.Message.ReplyToUserID != answerAuthorID.String() {
+		t.Fatalf("expected reply to join the root thread and remember the answer's author, got %+v", event.Message)
+	}
+	core.Broadcast <- event
+
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case event := <-answerAuthor.Message:
+			if event.Type != "notification" {
+				continue
+			}
+			if event.Notification.Kind != "reply" || event.Notification.UserID != answerAuthorID.String() {
+				t.Fatalf("expected a reply notification for the answer's author, got %+v", event.Notification)
+			}
+		case <-deadline:
+			t.Fatal("timed out waiting for reply notification")
+		}
+		break
+	}
+
+	select {
+	case exclude := <-excluded:
+		if len(exclude) != 1 || exclude[0] != answerAuthorID {
+			t.Fatalf("expected thread notifications to skip the answer's author, got %v", exclude)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for thread notifications")
+	}
+}
+
+func TestProfileUpdateReachesEveryRoomOfTheUser(t *testing.T) {
+	userID := uuid.New()
+	core := NewCoreWithDependencies(nil, &fakeRoomRepository{}, &fakeStatsRepository{})
+
+	var clients []*Client
+	for _, name := range []string{"General", "Random"} {
+		roomID := uuid.New().String()
+		own := &Client{ID: name + "-alice", RoomID: roomID, Username: "alice", UserID: userID.String(), Message: make(chan *Event, 1)}
+		other := &Client{ID: name + "-bob", RoomID: roomID, Username: "bob", UserID: uuid.New().String(), Message: make(chan *Event, 1)}
+		core.AddRoom(&Room{ID: roomID, Name: name, Clients: map[string]*Client{own.ID: own, other.ID: other}})
+		clients = append(clients, own, other)
+	}
+	quiet := &Client{ID: "elsewhere", RoomID: uuid.New().String(), Username: "carol", Message: make(chan *Event, 1)}
+	core.AddRoom(&Room{ID: quiet.RoomID, Clients: map[string]*Client{quiet.ID: quiet}})
+
+	expired := time.Now().Add(-time.Minute)
+	core.NotifyProfileUpdated(&model.UserProfileRes{
+		UserID:      userID.String(),
+		Username:    "alice",
+		DisplayName: "Alice",
+		AvatarURL:   "/api/users/" + userID.String() + "/avatar?v=1",
+		Status:      &model.UserStatusRes{Text: "lunch", ExpiresAt: &expired},
+	})
+
+	for _, client := range clients {
+		select {
+		case event := <-client.Message:
+			if event.Type != "profile" || event.Profile == nil || event.Profile.DisplayName != "Alice" || event.Profile.AvatarURL == "" {
+				t.Fatalf("expected profile event for %s, got %+v", client.ID, event)
+			}
+			if event.Profile.Status != nil {
+				t.Fatalf("expected expired status to be dropped, got %+v", event.Profile.Status)
+			}
+		default:
+			t.Fatalf("expected %s to receive the profile update", client.ID)
+		}
+	}
+	if len(quiet.Message) != 0 {
+		t.Fatal("expected rooms without the user to be left alone")
+	}
+
+	snapshot := core.buildPresenceSnapshot(clients[0].RoomID)
+	for _, user := range snapshot.OnlineUsers {
+		if user.UserID == userID.String() && user.DisplayName != "Alice" {
+			t.Fatalf("expected presence to show the new display name, got %+v", user)
+		}
+	}
+}
+
+func TestFanoutSkipsBlockedAuthorsForTheBlocker(t *testing.T) {
+	aliceID, malloryID := uuid.New(), uuid.New()
+	core := NewCoreWithDependencies(nil, &fakeRoomRepository{}, &fakeStatsRepository{})
+
+	roomID := uuid.New().String()
+	alice := &Client{ID: "alice", RoomID: roomID, Username: "alice", UserID: aliceID.String(), Message: make(chan *Event, 4)}
+	bob := &Client{ID: "bob", RoomID: roomID, Username: "bob", UserID: uuid.New().String(), Message: make(chan *Event, 4)}
+	core.AddRoom(&Room{ID: roomID, Clients: map[string]*Client{alice.ID: alice, bob.ID: bob}})
+
+	core.NotifyBlockChanged(aliceID, malloryID, true)
+	core.fanout(roomID, &Event{Type: "message.created", Message: &Message{RoomID: roomID, UserID: malloryID.String(), Content: "hi"}}, "")
+	core.fanout(roomID, &Event{Type: "typing", Typing: &TypingEvent{RoomID: roomID, UserID: malloryID.String(), IsTyping: true}}, "")
+
+	if len(alice.Message) != 0 {
+		t.Fatalf("expected the blocker to receive nothing, got %d events", len(alice.Message))
+	}
+	if len(bob.Message) != 2 {
+		t.Fatalf("expected other members to receive both events, got %d", len(bob.Message))
+	}
+
+	core.NotifyBlockChanged(aliceID, malloryID, false)
+	core.fanout(roomID, &Event{Type: "message.created", Message: &Message{RoomID: roomID, UserID: malloryID.String(), Content: "hi again"}}, "")
+	if len(alice.Message) != 1 {
+		t.Fatal("expected messages to reach the user again after unblocking")
+	}
+}
+
+func TestPostingRefusedForMutedAndBannedMembers(t *testing.T) {
+	roomID := uuid.New()
+	mutedID, bannedID, memberID := uuid.New(), uuid.New(), uuid.New()
+	mutedUntil := time.Now().Add(time.Hour)
+	bannedAt := time.Now()
+	repo := &fakeRoomRepository{members: []roomRepository.RoomMember{
+		{RoomID: roomID, UserID: mutedID, CanPost: true, MutedUntil: &mutedUntil},
+		{RoomID: roomID, UserID: bannedID, CanPost: true, BannedAt: &bannedAt},
+		{RoomID: roomID, UserID: memberID, CanPost: true},
+	}}
+	core := NewCoreWithDependencies(nil, repo, &fakeStatsRepository{})
+
+	for userID, refused := range map[uuid.UUID]bool{mutedID: true, bannedID: true, memberID: false, uuid.New(): false} {
+		client := &Client{ID: userID.String(), RoomID: roomID.String(), UserID: userID.String()}
+		if reason := postingRefusal(core.clientMember(client)); (reason != "") != refused {
+			t.Fatalf("expected refused=%v for %s, got %q", refused, userID, reason)
+		}
+	}
+	guest := &Client{ID: "guest", RoomID: roomID.String(), Username: "guest"}
+	if reason := postingRefusal(core.clientMember(guest)); reason != "" {
+		t.Fatalf("expected guests to be allowed, got %q", reason)
+	}
+}
+
+func TestAutoModBlocksFlagsAndMutes(t *testing.T) {
+	roomID := uuid.New()
+	memberID, moderatorID := uuid.New(), uuid.New()
+	rules, _ := json.Marshal(automod.Rules{
+		Enabled:      true,
+		BlockedWords: automod.WordFilter{Words: []string{"scam*"}},
+		Links:        automod.LinkFilter{Deny: []string{"bad.example"}, Action: automod.ActionFlag},
+		Invites:      automod.InviteFilter{Enabled: true, Action: automod.ActionMute},
+	})
+	repo := &fakeRoomRepository{
+		automod: &roomRepository.AutoModSettings{RoomID: roomID, Rules: rules},
+		members: []roomRepository.RoomMember{
+			{RoomID: roomID, UserID: memberID, Username: "member", CanPost: true},
+			{RoomID: roomID, UserID: moderatorID, Username: "mod", CanPost: true, CanModerate: true},
+		},
+	}
+	core := NewCoreWithDependencies(nil, repo, &fakeStatsRepository{})
+	member := &Client{ID: "member", RoomID: roomID.String(), Username: "member", UserID: memberID.String()}
+	moderator := &Client{ID: "mod", RoomID: roomID.String(), Username: "mod", UserID: moderatorID.String()}
+	guest := &Client{ID: "guest", RoomID: roomID.String(), Username: "guest"}
+	message := func(client *Client, content string) *Message {
+		return &Message{RoomID: roomID.String(), Username: client.Username, UserID: client.UserID, Content: content}
+	}
+
+	if reason := core.screenMessage(member, message(member, "hello everyone")); reason != "" {
+		t.Fatalf("expected clean message to pass, got %q", reason)
+	}
+	if reason := core.screenMessage(guest, message(guest, "total scammers here")); !strings.Contains(reason, "blocked word") {
+		t.Fatalf("expected blocked word to be refused, got %q", reason)
+	}
+	if reason := core.screenMessage(moderator, message(moderator, "scam warning")); reason != "" {
+		t.Fatalf("expected moderators to be exempt, got %q", reason)
+	}
+
+	flagged := message(member, "look at https://bad.example/x")
+	if reason := core.screenMessage(member, flagged); reason != "" || len(flagged.AutoModFlags) != 1 {
+		t.Fatalf("expected flagged message to pass with flags, got %q %+v", reason, flagged.AutoModFlags)
+	}
+	stored := &roomRepository.Message{ID: uuid.New(), RoomID: roomID, UserID: &memberID, Username: "member", Content: flagged.Content}
+	core.flagForReview(stored, flagged.AutoModFlags)
+	if len(repo.reports) != 1 || repo.reports[0].Reason != constants.ReportReasonAutoMod || repo.reports[0].ReporterID != nil || *repo.reports[0].MessageID != stored.ID {
+		t.Fatalf("expected an AutoMod report for the stored message, got %+v", repo.reports)
+	}
+
+	if reason := core.screenMessage(member, message(member, "join discord.gg/abc")); !strings.Contains(reason, "muted until") {
+		t.Fatalf("expected invite link to mute, got %q", reason)
+	}
+	if len(repo.updatedMembers) != 1 || repo.updatedMembers[0].MutedUntil == nil {
+		t.Fatalf("expected the author to be muted, got %+v", repo.updatedMembers)
+	}
+
+	var actions []string
+	for _, entry := range repo.moderationLog {
+		if entry.ModeratorID != nil || entry.ModeratorUsername != constants.AutoModUsername {
+			t.Fatalf("expected entries to be logged as AutoMod, got %+v", entry)
+		}
+		actions = append(actions, entry.Action)
+	}
+	want := []string{constants.ModerationAutoModBlock, constants.ModerationAutoModFlag, constants.ModerationAutoModMute}
+	if !reflect.DeepEqual(actions, want) {
+		t.Fatalf("expected log %v, got %v", want, actions)
+	}
+}
+
+func TestAutoModRefusalNamesTheDecidingRule(t *testing.T) {
+	roomID := uuid.New()
+	rules, _ := json.Marshal(automod.Rules{
+		Enabled: true,
+		Links:   automod.LinkFilter{Deny: []string{"bad.example"}, Action: automod.ActionFlag},
+		Invites: automod.InviteFilter{Enabled: true, Action: automod.ActionBlock},
+	})
+	repo := &fakeRoomRepository{automod: &roomRepository.AutoModSettings{RoomID: roomID, Rules: rules}}
+	core := NewCoreWithDependencies(nil, repo, &fakeStatsRepository{})
+	guest := &Client{ID: "guest", RoomID: roomID.String(), Username: "guest"}
+
+	// The flagged link matches first, but the invite link is what blocks it.
+	msg := &Message{RoomID: roomID.String(), Username: guest.Username, Content: "see https://bad.example/x or discord.gg/abc"}
+	reason := core.screenMessage(guest, msg)
+	if !strings.Contains(reason, "invite link") || strings.Contains(reason, "domain") {
+		t.Fatalf("expected the invite rule to be named, got %q", reason)
+	}
+}
+
+func TestFloodGuardEscalatesFromWarningToDisconnect(t *testing.T) {
+	guard := newFloodGuard()
+	now := time.Now()
+
+	for i := 0; i < constants.WebSocketMessageBurst; i++ {
+		if verdict := guard.admitMessage("user:a", fmt.Sprintf("message %d", i), now); verdict.Reason != "" {
+			t.Fatalf("expected message %d of the burst to be accepted, got %+v", i, verdict)
+		}
+	}
+
+	var penalties []int
+	for i := 0; i < constants.FloodStrikesBeforeDisconnect; i++ {
+		penalties = append(penalties, guard.admitMessage("user:a", fmt.Sprintf("flood %d", i), now).Penalty)
+	}
+	want := []int{floodWarn, floodWarn, floodMute, floodMute, floodDisconnect}
+	if !reflect.DeepEqual(penalties, want) {
+		t.Fatalf("expected penalties %v, got %v", want, penalties)
+	}
+
+	later := now.Add(time.Minute)
+	if verdict := guard.admitMessage("user:a", "calm now", later); verdict.Reason == "" || verdict.Penalty != floodNone {
+		t.Fatalf("expected the mute to refuse without a new strike, got %+v", verdict)
+	}
+	if verdict := guard.admitMessage("user:b", "hello", now); verdict.Reason != "" {
+		t.Fatalf("expected other senders to have their own budget, got %+v", verdict)
+	}
+}
+
+func TestFloodGuardRefusesDuplicates(t *testing.T) {
+	guard := newFloodGuard()
+	now := time.Now()
+
+	guard.admitMessage("user:a", "Buy now", now)
+	guard.recordMessage("user:a", "Buy now", now)
+	if verdict := guard.admitMessage("user:a", "buy   NOW", now.Add(time.Second)); verdict.Penalty != floodWarn {
+		t.Fatalf("expected a repeat to be refused with a warning, got %+v", verdict)
+	}
+	if verdict := guard.admitMessage("user:a", "buy now", now.Add(constants.DuplicateMessageWindow+2*time.Second)); verdict.Reason != "" {
+		t.Fatalf("expected a repeat outside the window to be accepted, got %+v", verdict)
+	}
+}
+
+func TestFloodGuardIgnoresRefusedMessagesForDuplicates(t *testing.T) {
+	guard := newFloodGuard()
+	now := time.Now()
+	interval := 10 * time.Second
+
+	guard.admitMessage("user:a", "first", now)
+	guard.slowModeWait("user:a", "channel", interval, now, true)
+	guard.recordMessage("user:a", "first", now)
+
+	// Slow mode refuses the next message, so it is never recorded.
+	later := now.Add(time.Second)
+	if verdict := guard.admitMessage("user:a", "second", later); verdict.Reason != "" {
+		t.Fatalf("expected the flood check to pass, got %+v", verdict)
+	}
+	if wait := guard.slowModeWait("user:a", "channel", interval, later, false); wait <= 0 {
+		t.Fatal("expected slow mode to refuse the second message")
+	}
+
+	resent := now.Add(interval + time.Second)
+	if verdict := guard.admitMessage("user:a", "second", resent); verdict.Reason != "" || verdict.Penalty != floodNone {
+		t.Fatalf("expected the refused message to be resendable without a strike, got %+v", verdict)
+	}
+}
+
+func TestSlowModeRefusesUntilTheIntervalPasses(t *testing.T) {
+	roomID, channelID := uuid.New(), uuid.New()
+	memberID, moderatorID := uuid.New(), uuid.New()
+	repo := &fakeRoomRepository{
+		channels: []roomRepository.RoomChannel{{ID: channelID, RoomID: roomID, SlowModeSeconds: 30}},
+		members: []roomRepository.RoomMember{
+			{RoomID: roomID, UserID: memberID, CanPost: true},
+			{RoomID: roomID, UserID: moderatorID, CanPost: true, CanModerate: true},
+		},
+	}
+	core := NewCoreWithDependencies(nil, repo, &fakeStatsRepository{})
+	post := func(userID uuid.UUID, content string) string {
+		client := &Client{ID: userID.String(), RoomID: roomID.String(), UserID: userID.String()}
+		return core.screenMessage(client, &Message{RoomID: roomID.String(), ChannelID: channelID.String(), Content: content})
+	}
+
+	if reason := post(memberID, "first"); reason != "" {
+		t.Fatalf("expected the first message to pass, got %q", reason)
+	}
+	if reason := post(memberID, "second"); !strings.Contains(reason, "Slow mode") {
+		t.Fatalf("expected slow mode to refuse the second message, got %q", reason)
+	}
+	for _, content := range []string{"first", "second"} {
+		if reason := post(moderatorID, content); reason != "" {
+			t.Fatalf("expected moderators to be exempt, got %q", reason)
+		}
+	}
+}
+
+func TestSlowModeIgnoresMessagesAutoModBlocks(t *testing.T) {
+	roomID, channelID, memberID := uuid.New(), uuid.New(), uuid.New()
+	rules, _ := json.Marshal(automod.Rules{
+		Enabled:      true,
+		BlockedWords: automod.WordFilter{Words: []string{"scam*"}},
+	})
+	repo := &fakeRoomRepository{
+		automod:  &roomRepository.AutoModSettings{RoomID: roomID, Rules: rules},
+		channels: []roomRepository.RoomChannel{{ID: channelID, RoomID: roomID, SlowModeSeconds: 30}},
+		members:  []roomRepository.RoomMember{{RoomID: roomID, UserID: memberID, Username: "member", CanPost: true}},
+	}
+	core := NewCoreWithDependencies(nil, repo, &fakeStatsRepository{})
+	member := &Client{ID: "member", RoomID: roomID.String(), Username: "member", UserID: memberID.String()}
+	post := func(content string) string {
+		return core.screenMessage(member, &Message{RoomID: roomID.String(), ChannelID: channelID.String(), Username: member.Username, Content: content})
+	}
+
+	if reason := post("total scammer"); reason == "" || strings.Contains(reason, "Slow mode") {
+		t.Fatalf("expected AutoMod to block the message, got %q", reason)
+	}
+	if reason := post("total gentleman"); reason != "" {
+		t.Fatalf("expected the corrected message to pass, got %q", reason)
+	}
+	if reason := post("again"); !strings.Contains(reason, "Slow mode") {
+		t.Fatalf("expected the accepted message to start slow mode, got %q", reason)
+	}
+}
@@ -0,0 +1,16 @@
+package websocket
+
+import "chat-application/internal/markdown"
+
+// addEntities stores the parsed Markdown entities of a message in its metadata
+// so clients share the server's interpretation of the content.
+func addEntities(msg *Message) {
+	entities := markdown.Parse(msg.Content)
+	if len(entities) == 0 {
+		return
+	}
+	if msg.Metadata == nil {
+		msg.Metadata = map[string]any{}
+	}
+	msg.Metadata["entities"] = entities
+}
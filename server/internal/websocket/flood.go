@@ -0,0 +1,319 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"chat-application/internal/constants"
+	roomRepository "chat-application/internal/repo/room"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// Flood penalties, in escalating order.
+const (
+	floodNone = iota
+	floodWarn
+	floodMute
+	floodDisconnect
+)
+
+// floodVerdict is the outcome of a flood check. Reason is empty when the
+// event is accepted.
+type floodVerdict struct {
+	Reason     string
+	Penalty    int
+	MutedUntil time.Time
+}
+
+// floodGuard rate limits inbound websocket events per sender. Signed-in
+// users share one budget across their connections; guests are keyed by
+// address.
+type floodGuard struct {
+	mu        sync.Mutex
+	senders   map[string]*floodState
+	lastSweep time.Time
+}
+
+type floodState struct {
+	messages    tokenBucket
+	events      tokenBucket
+	lastContent string
+	lastSentAt  time.Time
+	strikes     int
+	lastStrike  time.Time
+	mutedUntil  time.Time
+	// lastPosts holds the last accepted message time per slow-mode channel.
+	lastPosts map[string]time.Time
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// take refills the bucket for the time passed and spends a token if one is
+// left.
+func (b *tokenBucket) take(now time.Time, burst int, rate float64) bool {
+	if b.updated.IsZero() {
+		b.tokens = float64(burst)
+	} else {
+		b.tokens = min(float64(burst), b.tokens+now.Sub(b.updated).Seconds()*rate)
+	}
+	b.updated = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func newFloodGuard() *floodGuard {
+	return &floodGuard{senders: make(map[string]*floodState)}
+}
+
+// state returns the sender's state, dropping idle senders every so often.
+// Callers hold g.mu.
+func (g *floodGuard) state(key string, now time.Time) *floodState {
+	if now.Sub(g.lastSweep) > constants.FloodStrikeDecay {
+		for senderKey, state := range g.senders {
+			if state.idle(now) {
+				delete(g.senders, senderKey)
+			}
+		}
+		g.lastSweep = now
+	}
+
+	state, ok := g.senders[key]
+	if !ok {
+		state = &floodState{}
+		g.senders[key] = state
+	}
+	return state
+}
+
+// idle reports whether forgetting the sender changes nothing.
+func (s *floodState) idle(now time.Time) bool {
+	lastActive := s.messages.updated
+	if s.events.updated.After(lastActive) {
+		lastActive = s.events.updated
+	}
+	for _, postedAt := range s.lastPosts {
+		if postedAt.After(lastActive) {
+			lastActive = postedAt
+		}
+	}
+	return now.Sub(lastActive) > time.Duration(constants.MaxSlowModeSeconds)*time.Second &&
+		now.Sub(s.lastStrike) > constants.FloodStrikeDecay &&
+		now.After(s.mutedUntil)
+}
+
+// admitMessage checks a message against the sender's rate limit and their
+// previous accepted message. Each refusal is a strike; strikes escalate from
+// a warning to a temporary mute and then a disconnect.
+func (g *floodGuard) admitMessage(key, content string, now time.Time) floodVerdict {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	state := g.state(key, now)
+
+	normalized := normalizeContent(content)
+	var reason string
+	switch {
+	case !state.messages.take(now, constants.WebSocketMessageBurst, constants.WebSocketMessageRate):
+		reason = "You are sending messages too quickly"
+	case normalized != "" && normalized == state.lastContent && now.Sub(state.lastSentAt) < constants.DuplicateMessageWindow:
+		reason = "You already sent that message"
+	}
+
+	if reason == "" {
+		if now.Before(state.mutedUntil) {
+			return floodVerdict{Reason: "You are muted for flooding until " + state.mutedUntil.UTC().Format(time.RFC3339)}
+		}
+		return floodVerdict{}
+	}
+
+	if now.Sub(state.lastStrike) > constants.FloodStrikeDecay {
+		state.strikes = 0
+	}
+	state.strikes++
+	state.lastStrike = now
+
+	switch {
+	case state.strikes >= constants.FloodStrikesBeforeDisconnect:
+		return floodVerdict{Reason: reason + "; you have been disconnected", Penalty: floodDisconnect}
+	case state.strikes >= constants.FloodStrikesBeforeMute:
+		state.mutedUntil = now.Add(constants.FloodMuteDuration)
+		return floodVerdict{
+			Reason:     reason + "; you are muted until " + state.mutedUntil.UTC().Format(time.RFC3339),
+			Penalty:    floodMute,
+			MutedUntil: state.mutedUntil,
+		}
+	}
+	return floodVerdict{Reason: reason + "; slow down or you will be muted", Penalty: floodWarn}
+}
+
+// recordMessage remembers an accepted message so that sending it again is
+// refused as a duplicate. Messages refused later on, by slow mode or AutoMod,
+// are not recorded and can be resent as they are.
+func (g *floodGuard) recordMessage(key, content string, now time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	state := g.state(key, now)
+	state.lastContent = normalizeContent(content)
+	state.lastSentAt = now
+}
+
+// normalizeContent folds case and whitespace for duplicate detection.
+func normalizeContent(content string) string {
+	return strings.Join(strings.Fields(strings.ToLower(content)), " ")
+}
+
+// admitEvent spends from the sender's budget for typing and read events,
+// which are dropped without a strike when it runs out.
+func (g *floodGuard) admitEvent(key string, now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.state(key, now).events.take(now, constants.WebSocketEventBurst, constants.WebSocketEventRate)
+}
+
+// slowModeWait returns how long the sender must still wait to post in the
+// channel. When the wait is over and claim is set, it records the post.
+func (g *floodGuard) slowModeWait(key, channelID string, interval time.Duration, now time.Time, claim bool) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	state := g.state(key, now)
+
+	if wait := state.lastPosts[channelID].Add(interval).Sub(now); wait > 0 {
+		return wait
+	}
+	if !claim {
+		return 0
+	}
+	if state.lastPosts == nil {
+		state.lastPosts = make(map[string]time.Time)
+	}
+	state.lastPosts[channelID] = now
+	return 0
+}
+
+// floodKey identifies the sender for flood control.
+func (client *Client) floodKey() string {
+	if client.UserID != "" {
+		return "user:" + client.UserID
+	}
+	if client.Conn != nil {
+		address := client.Conn.RemoteAddr().String()
+		if host, _, err := net.SplitHostPort(address); err == nil {
+			address = host
+		}
+		return "guest:" + address
+	}
+	return "client:" + client.ID
+}
+
+// admitMessage applies flood control to a new message. It returns why the
+// message was refused, or "", and whether the client should be disconnected.
+// A flood mute is also stored on a member's membership so it shows up to
+// moderators and survives reconnecting.
+func (c *Core) admitMessage(client *Client, msg *Message) (string, bool) {
+	verdict := c.flood.admitMessage(client.floodKey(), msg.Content, time.Now())
+	switch verdict.Penalty {
+	case floodMute:
+		c.recordFloodMute(client, verdict.MutedUntil)
+	case floodDisconnect:
+		log.Printf("disconnecting %s from room %s for flooding", client.Username, client.RoomID)
+		return verdict.Reason, true
+	}
+	return verdict.Reason, false
+}
+
+func (c *Core) recordFloodMute(client *Client, mutedUntil time.Time) {
+	member := c.clientMember(client)
+	if member == nil {
+		return
+	}
+	if member.MutedUntil != nil && member.MutedUntil.After(mutedUntil) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), serviceTimeout)
+	defer cancel()
+	member.MutedUntil = &mutedUntil
+	if err := c.RoomRepository.UpdateRoomMember(ctx, *member); err != nil {
+		log.Printf("error muting user %s in room %s for flooding: %v", member.UserID, member.RoomID, err)
+		return
+	}
+	c.recordAutoMod(ctx, &roomRepository.ModerationLogEntry{
+		RoomID:            member.RoomID,
+		ModeratorUsername: constants.AutoModUsername,
+		Action:            constants.ModerationFloodMute,
+		TargetUserID:      &member.UserID,
+		TargetUsername:    &member.Username,
+	}, map[string]any{"muted_until": mutedUntil})
+}
+
+// slowModeInterval returns the slow mode of the message's channel, the
+// room's default channel when none is given. It returns zero when slow mode
+// is off or the member is a moderator or room manager, who are exempt.
+func (c *Core) slowModeInterval(member *roomRepository.RoomMember, msg *Message) (string, time.Duration) {
+	if member != nil && (member.CanModerate || member.CanManageRoom || member.Role == "owner") {
+		return "", 0
+	}
+	roomID, err := uuid.Parse(msg.RoomID)
+	if err != nil {
+		return "", 0
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), serviceTimeout)
+	defer cancel()
+	var channel *roomRepository.RoomChannel
+	if msg.ChannelID != "" {
+		channelID, err := uuid.Parse(msg.ChannelID)
+		if err != nil {
+			return "", 0
+		}
+		channel, err = c.RoomRepository.GetRoomChannel(ctx, roomID, channelID)
+		if err != nil {
+			log.Printf("error loading channel %s: %v", channelID, err)
+			return "", 0
+		}
+	} else {
+		channel, err = c.RoomRepository.GetDefaultChannel(ctx, roomID)
+		if err != nil {
+			log.Printf("error loading default channel of room %s: %v", roomID, err)
+			return "", 0
+		}
+	}
+	if channel == nil || channel.SlowModeSeconds <= 0 {
+		return "", 0
+	}
+	return channel.ID.String(), time.Duration(channel.SlowModeSeconds) * time.Second
+}
+
+// slowModeRefusal checks the sender's slow mode slot in the channel. With
+// claim set, an open slot is spent on the message.
+func (c *Core) slowModeRefusal(client *Client, channelID string, interval time.Duration, claim bool) string {
+	if interval <= 0 {
+		return ""
+	}
+	wait := c.flood.slowModeWait(client.floodKey(), channelID, interval, time.Now(), claim)
+	if wait <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("Slow mode is on; you can post again in %d seconds", int(math.Ceil(wait.Seconds())))
+}
+
+// disconnect tells the client why and closes its connection.
+func (client *Client) disconnect(reason string) {
+	message := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason)
+	if err := client.Conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(time.Second)); err != nil {
+		log.Printf("error sending close to client %s: %v", client.ID, err)
+	}
+}
@@ -0,0 +1,80 @@
+package websocket
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"chat-application/internal/markdown"
+	roomRepository "chat-application/internal/repo/room"
+
+	"github.com/google/uuid"
+)
+
+// resolveMentionGroups works out which groups a message mentions: @here (members
+// online in the room), @channel or @room (every member), @moderators, and
+// member roles by name, singular or plural ("@admin", "@admins"). Group
+// mentions need the can_mention_everyone permission, which owners and room
+// managers always have; otherwise they are left as plain text and nil is returned.
+func (c *Core) resolveMentionGroups(ctx context.Context, message *roomRepository.Message) *roomRepository.MentionGroups {
+	names := markdown.Mentions(message.Content)
+	if len(names) == 0 || message.UserID == nil {
+		return nil
+	}
+
+	groups := &roomRepository.MentionGroups{}
+	var here bool
+	for _, name := range names {
+		switch name {
+		case "here":
+			here = true
+		case "channel", "room":
+			if groups.Everyone == "" {
+				groups.Everyone = "@" + name
+			}
+		case "moderators":
+			groups.Moderators = true
+		default:
+			// Names that are usernames are also tried as roles; the direct
+			// mention takes precedence when both match.
+			groups.Roles = append(groups.Roles, name)
+			if role := strings.TrimSuffix(name, "s"); role != name {
+				groups.Roles = append(groups.Roles, role)
+			}
+		}
+	}
+
+	member, err := c.RoomRepository.GetRoomMember(ctx, message.RoomID, *message.UserID)
+	if err != nil {
+		log.Printf("error loading mention author: %v", err)
+		return nil
+	}
+	if member == nil || !(member.CanMentionEveryone || member.CanManageRoom || member.Role == "owner") {
+		return nil
+	}
+
+	if here {
+		groups.Online = c.onlineUserIDs(message.RoomID.String())
+	}
+	return groups
+}
+
+// onlineUserIDs returns the distinct users in the room's presence snapshot.
+func (c *Core) onlineUserIDs(roomID string) []uuid.UUID {
+	snapshot := c.buildPresenceSnapshot(roomID)
+	if snapshot == nil {
+		return nil
+	}
+
+	seen := make(map[uuid.UUID]bool, len(snapshot.OnlineUsers))
+	userIDs := make([]uuid.UUID, 0, len(snapshot.OnlineUsers))
+	for _, user := range snapshot.OnlineUsers {
+		userID, err := uuid.Parse(user.UserID)
+		if err != nil || seen[userID] {
+			continue
+		}
+		seen[userID] = true
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs
+}
@@ -0,0 +1,90 @@
+package websocket
+
+import (
+	"context"
+	"log"
+	"time"
+
+	roomRepository "chat-application/internal/repo/room"
+
+	"github.com/google/uuid"
+)
+
+// clientMember loads the membership of a signed-in client, or returns nil
+// for guests, users without a membership row and on errors.
+func (c *Core) clientMember(client *Client) *roomRepository.RoomMember {
+	userID, err := uuid.Parse(client.UserID)
+	if err != nil {
+		return nil
+	}
+	roomID, err := uuid.Parse(client.RoomID)
+	if err != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), serviceTimeout)
+	defer cancel()
+	member, err := c.RoomRepository.GetRoomMember(ctx, roomID, userID)
+	if err != nil {
+		log.Printf("error loading room membership for user %s: %v", userID, err)
+		return nil
+	}
+	return member
+}
+
+// postingRefusal explains why a member may not post to the room, or returns
+// "" if they may. Guests and users without a membership row (nil member) are
+// not restricted here.
+func postingRefusal(member *roomRepository.RoomMember) string {
+	switch {
+	case member == nil:
+		return ""
+	case member.BannedAt != nil:
+		return "You are banned from this room"
+	case member.MutedUntil != nil && member.MutedUntil.After(time.Now()):
+		return "You are muted in this room until " + member.MutedUntil.UTC().Format(time.RFC3339)
+	case !member.CanPost:
+		return "You cannot post in this room"
+	}
+	return ""
+}
+
+// screenMessage runs a new message past the author's posting restrictions,
+// the channel's slow mode and the room's AutoMod rules, and returns why it
+// was refused, or "". Accepted messages are recorded for duplicate checks.
+func (c *Core) screenMessage(client *Client, msg *Message) string {
+	member := c.clientMember(client)
+	if reason := postingRefusal(member); reason != "" {
+		return reason
+	}
+	// Slow mode is checked before AutoMod but the slot is only spent once
+	// the message is accepted, so a blocked message can be corrected at once.
+	channelID, interval := c.slowModeInterval(member, msg)
+	if reason := c.slowModeRefusal(client, channelID, interval, false); reason != "" {
+		return reason
+	}
+	if reason := c.applyAutoMod(member, msg); reason != "" {
+		return reason
+	}
+	if reason := c.slowModeRefusal(client, channelID, interval, true); reason != "" {
+		return reason
+	}
+	c.flood.recordMessage(client.floodKey(), msg.Content, time.Now())
+	return ""
+}
+
+// refuse tells a client its event was not accepted.
+func (client *Client) refuse(reason string) {
+	select {
+	case client.Message <- &Event{Type: "error", Error: reason}:
+	default:
+	}
+}
+
+// PublishMessageRemoved tells the room a moderator removed a message.
+func (c *Core) PublishMessageRemoved(message *roomRepository.Message) {
+	removed := mapRepositoryMessage(message)
+	removed.Content = ""
+	removed.Metadata = map[string]any{"removed": true}
+	c.Broadcast <- &Event{Type: "message.updated", Message: removed}
+}
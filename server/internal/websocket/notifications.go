@@ -0,0 +1,136 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+
+	roomRepository "chat-application/internal/repo/room"
+	statsRepository "chat-application/internal/repo/stats"
+
+	"github.com/google/uuid"
+)
+
+// PushNotifier sends browser push notifications to users with no open socket.
+type PushNotifier interface {
+	Enqueue(notification roomRepository.Notification)
+}
+
+// notify stores a notification the recipient's preferences accept and delivers
+// it to their open sockets. It reports whether the notification was sent.
+func (c *Core) notify(ctx context.Context, notification *roomRepository.Notification, payload map[string]any) bool {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("error encoding notification payload: %v", err)
+		return false
+	}
+	notification.Payload = encoded
+
+	created, err := c.RoomRepository.CreateNotificationIfAccepted(ctx, notification)
+	if err != nil {
+		log.Printf("error creating %s notification: %v", notification.Kind, err)
+		return false
+	}
+	if created {
+		c.publishNotifications([]roomRepository.Notification{*notification})
+	}
+	return created
+}
+
+// notifyReply tells the author of the message replied to about the reply,
+// unless they wrote it or were already notified of it. It returns the user
+// notified, if any.
+func (c *Core) notifyReply(reply *roomRepository.Message, replyToUserID string, alreadyNotified []uuid.UUID) *uuid.UUID {
+	if reply.ParentMessageID == nil || reply.UserID == nil || replyToUserID == "" {
+		return nil
+	}
+	recipient, err := uuid.Parse(replyToUserID)
+	if err != nil || recipient == *reply.UserID || slices.Contains(alreadyNotified, recipient) {
+		return nil
+	}
+
+	notification := &roomRepository.Notification{
+		UserID:    recipient,
+		RoomID:    &reply.RoomID,
+		MessageID: &reply.ID,
+		Kind:      "reply",
+		Title:     "New reply",
+		Body:      fmt.Sprintf("%s replied to you: %s", reply.Username, reply.Content),
+	}
+	payload := map[string]any{
+		"room_id":           reply.RoomID.String(),
+		"message_id":        reply.ID.String(),
+		"parent_message_id": reply.ParentMessageID.String(),
+		"username":          reply.Username,
+		"from_user_id":      reply.UserID.String(),
+	}
+	if !c.notify(context.Background(), notification, payload) {
+		return nil
+	}
+	return &recipient
+}
+
+// NotifyReaction tells a message's author that someone reacted to it. Repeated
+// reactions from the same member collapse into one unread notification.
+func (c *Core) NotifyReaction(ctx context.Context, message *roomRepository.Message, reactor *roomRepository.RoomMember, emoji string) {
+	if message.UserID == nil || *message.UserID == reactor.UserID {
+		return
+	}
+
+	notification := &roomRepository.Notification{
+		UserID:    *message.UserID,
+		RoomID:    &message.RoomID,
+		MessageID: &message.ID,
+		Kind:      "reaction",
+		Title:     "New reaction",
+		Body:      fmt.Sprintf("%s reacted %s to your message", reactor.Username, emoji),
+	}
+	c.notify(ctx, notification, map[string]any{
+		"room_id":      message.RoomID.String(),
+		"message_id":   message.ID.String(),
+		"username":     reactor.Username,
+		"from_user_id": reactor.UserID.String(),
+		"emoji":        emoji,
+	})
+}
+
+// NotifyUpvote tells a user they received an upvote.
+func (c *Core) NotifyUpvote(ctx context.Context, fromUserID, toUserID uuid.UUID) {
+	username, err := c.StatsRepository.GetUsername(ctx, fromUserID)
+	if err != nil {
+		log.Printf("error loading upvote sender: %v", err)
+	}
+	if username == "" {
+		username = "Someone"
+	}
+
+	notification := &roomRepository.Notification{
+		UserID: toUserID,
+		Kind:   "upvote",
+		Title:  "New upvote",
+		Body:   fmt.Sprintf("%s upvoted you", username),
+	}
+	c.notify(ctx, notification, map[string]any{
+		"username":     username,
+		"from_user_id": fromUserID.String(),
+	})
+}
+
+// NotifyAchievements tells a user about achievements they just unlocked.
+func (c *Core) NotifyAchievements(ctx context.Context, userID uuid.UUID, achievements []statsRepository.Achievement) {
+	for _, achievement := range achievements {
+		notification := &roomRepository.Notification{
+			UserID: userID,
+			Kind:   "achievement",
+			Title:  "Achievement unlocked",
+			Body:   fmt.Sprintf("You earned %s: %s", achievement.Name, achievement.Description),
+		}
+		c.notify(ctx, notification, map[string]any{
+			"achievement_id": achievement.ID.String(),
+			"name":           achievement.Name,
+			"icon":           achievement.Icon,
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package websocket
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"chat-application/internal/api/model"
+
+	"github.com/google/uuid"
+)
+
+// ProfileLoader looks up the profile shown next to a user in presence lists.
+type ProfileLoader interface {
+	GetProfile(ctx context.Context, userID uuid.UUID) (*model.UserProfileRes, error)
+}
+
+// loadProfile attaches the signed-in user's profile to a newly joined client.
+func (c *Core) loadProfile(client *Client) {
+	if c.Profiles == nil {
+		return
+	}
+	userID, err := uuid.Parse(client.UserID)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), serviceTimeout)
+	defer cancel()
+	profile, err := c.Profiles.GetProfile(ctx, userID)
+	if err != nil {
+		log.Printf("error loading profile for user %s: %v", userID, err)
+		return
+	}
+	if profile == nil {
+		return
+	}
+
+	room, ok := c.GetRoom(client.RoomID)
+	if !ok {
+		return
+	}
+	room.mu.Lock()
+	client.Profile = profile
+	room.mu.Unlock()
+}
+
+// NotifyProfileUpdated refreshes the profile on every open socket of the user
+// and sends a profile event to each room they are in.
+func (c *Core) NotifyProfileUpdated(profile *model.UserProfileRes) {
+	c.roomsMu.RLock()
+	rooms := make([]*Room, 0, len(c.Rooms))
+	for _, room := range c.Rooms {
+		rooms = append(rooms, room)
+	}
+	c.roomsMu.RUnlock()
+
+	now := time.Now()
+	for _, room := range rooms {
+		var user *PresenceUser
+		room.mu.Lock()
+		for _, client := range room.Clients {
+			if client.UserID == profile.UserID {
+				client.Profile = profile
+				user = presenceUser(client, now)
+			}
+		}
+		room.mu.Unlock()
+
+		if user != nil {
+			c.fanout(room.ID, &Event{Type: "profile", Profile: user}, "")
+		}
+	}
+}
+
+// presenceUser describes a connected client. A status past its expiry is
+// left out; clients hide a live status themselves once ExpiresAt passes.
+func presenceUser(client *Client, now time.Time) *PresenceUser {
+	user := &PresenceUser{
+		UserID:   client.UserID,
+		Username: client.Username,
+	}
+	if client.Profile == nil {
+		return user
+	}
+
+	user.DisplayName = client.Profile.DisplayName
+	user.AvatarURL = client.Profile.AvatarURL
+	if status := client.Profile.Status; status != nil && (status.ExpiresAt == nil || status.ExpiresAt.After(now)) {
+		user.Status = status
+	}
+	return user
+}
@@ -0,0 +1,89 @@
+package websocket
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"chat-application/internal/constants"
+	roomRepository "chat-application/internal/repo/room"
+
+	"github.com/google/uuid"
+)
+
+// handleRead applies an inbound "read" event from a signed-in client.
+func (c *Core) handleRead(read *ReceiptEvent) {
+	userID, err := uuid.Parse(read.UserID)
+	if err != nil {
+		return
+	}
+	messageID, err := uuid.Parse(read.MessageID)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), serviceTimeout)
+	defer cancel()
+
+	message, err := c.RoomRepository.GetMessageByID(ctx, messageID)
+	if err != nil {
+		log.Printf("error loading read message: %v", err)
+		return
+	}
+	if message == nil || message.RoomID.String() != read.RoomID {
+		return
+	}
+
+	member, err := c.RoomRepository.GetRoomMember(ctx, message.RoomID, userID)
+	if err != nil {
+		log.Printf("error loading room member: %v", err)
+		return
+	}
+	if member == nil || member.BannedAt != nil {
+		return
+	}
+
+	if _, err := c.MarkRead(ctx, member, message); err != nil {
+		log.Printf("error marking message read: %v", err)
+	}
+}
+
+// MarkRead moves the member's read pointer up to the message and sends a
+// "receipt" event. Rooms with at most constants.ReadReceiptMaxMembers members
+// share receipts with everyone; otherwise only the reader's own sockets get
+// it, so their other sessions can clear unread badges.
+func (c *Core) MarkRead(ctx context.Context, member *roomRepository.RoomMember, message *roomRepository.Message) (bool, error) {
+	advanced, err := c.RoomRepository.MarkChannelRead(ctx, member.UserID, message)
+	if err != nil || !advanced {
+		return advanced, err
+	}
+
+	receipt := &ReceiptEvent{
+		RoomID:    message.RoomID.String(),
+		MessageID: message.ID.String(),
+		UserID:    member.UserID.String(),
+		Username:  member.Username,
+		ReadAt:    time.Now().UTC().Format(time.RFC3339),
+	}
+	if message.ChannelID != nil {
+		receipt.ChannelID = message.ChannelID.String()
+	}
+	event := &Event{Type: "receipt", Receipt: receipt}
+
+	if c.ReadReceiptsEnabled(ctx, message.RoomID) {
+		c.fanout(receipt.RoomID, event, "")
+	} else {
+		c.sendToUser(receipt.UserID, event)
+	}
+	return true, nil
+}
+
+// ReadReceiptsEnabled reports whether the room is small enough to share read receipts.
+func (c *Core) ReadReceiptsEnabled(ctx context.Context, roomID uuid.UUID) bool {
+	members, err := c.RoomRepository.GetRoomMembers(ctx, roomID)
+	if err != nil {
+		log.Printf("error loading room members: %v", err)
+		return false
+	}
+	return len(members) <= constants.ReadReceiptMaxMembers
+}
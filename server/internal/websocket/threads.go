@@ -0,0 +1,183 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	roomRepository "chat-application/internal/repo/room"
+
+	"github.com/google/uuid"
+)
+
+// resolveThreadParent checks that a reply targets a message in the same room.
+// Threads are one level deep, so replying to a reply joins the root's thread,
+// and replies always live in the channel of their root.
+func (c *Core) resolveThreadParent(msg *Message) {
+	if msg.ParentMessageID == "" {
+		return
+	}
+
+	parentID, err := uuid.Parse(msg.ParentMessageID)
+	if err != nil {
+		msg.ParentMessageID = ""
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), serviceTimeout)
+	defer cancel()
+
+	parent, err := c.RoomRepository.GetMessageByID(ctx, parentID)
+	if err != nil {
+		log.Printf("error loading thread parent: %v", err)
+	}
+	if parent == nil || parent.RoomID.String() != msg.RoomID {
+		msg.ParentMessageID = ""
+		return
+	}
+
+	if parent.UserID != nil {
+		msg.ReplyToUserID = parent.UserID.String()
+	}
+	if parent.ParentMessageID != nil {
+		msg.ParentMessageID = parent.ParentMessageID.String()
+	}
+	if parent.ChannelID != nil {
+		msg.ChannelID = parent.ChannelID.String()
+	}
+}
+
+// recordThreadReply updates the thread root after a reply is persisted, makes
+// the participants follow the thread and notifies its other followers.
+// alreadyNotified lists users who got a mention notification for the same reply.
+func (c *Core) recordThreadReply(reply *roomRepository.Message, alreadyNotified []uuid.UUID) {
+	if reply.ParentMessageID == nil {
+		return
+	}
+	ctx := context.Background()
+
+	summary, err := c.RoomRepository.RecordThreadReply(ctx, *reply.ParentMessageID, reply.CreatedAt)
+	if err != nil {
+		log.Printf("error recording thread reply: %v", err)
+		return
+	}
+
+	threadEvent := &ThreadEvent{
+		RoomID:            summary.RoomID.String(),
+		MessageID:         summary.MessageID.String(),
+		ReplyCount:        summary.ReplyCount,
+		LastReplyID:       reply.ID.String(),
+		LastReplyUsername: reply.Username,
+	}
+	if summary.ChannelID != nil {
+		threadEvent.ChannelID = summary.ChannelID.String()
+	}
+	if summary.LastReplyAt != nil {
+		threadEvent.LastReplyAt = summary.LastReplyAt.UTC().Format(time.RFC3339)
+	}
+	c.Broadcast <- &Event{Type: "thread.updated", Thread: threadEvent}
+
+	parent, err := c.RoomRepository.GetMessageByID(ctx, *reply.ParentMessageID)
+	if err != nil || parent == nil {
+		log.Printf("error loading thread root: %v", err)
+		return
+	}
+
+	// The author of the root follows from the first reply on; after that an
+	// explicit unfollow is respected.
+	if summary.ReplyCount == 1 && parent.UserID != nil {
+		if err := c.RoomRepository.FollowThread(ctx, parent.ID, *parent.UserID); err != nil {
+			log.Printf("error following thread: %v", err)
+		}
+	}
+	if reply.UserID != nil {
+		if err := c.RoomRepository.FollowThread(ctx, parent.ID, *reply.UserID); err != nil {
+			log.Printf("error following thread: %v", err)
+		}
+	}
+
+	notifications, err := c.RoomRepository.CreateThreadReplyNotifications(ctx, parent, reply, alreadyNotified)
+	if err != nil {
+		log.Printf("error creating thread reply notifications: %v", err)
+		return
+	}
+	c.publishNotifications(notifications)
+}
+
+// publishNotifications delivers stored notifications to their recipients' open
+// sockets, and queues a browser push for recipients who are offline.
+func (c *Core) publishNotifications(notifications []roomRepository.Notification) {
+	for _, notification := range notifications {
+		payload := map[string]any{}
+		if len(notification.Payload) > 0 {
+			_ = json.Unmarshal(notification.Payload, &payload)
+		}
+		event := &NotificationEvent{
+			ID:      notification.ID.String(),
+			UserID:  notification.UserID.String(),
+			Kind:    notification.Kind,
+			Title:   notification.Title,
+			Body:    notification.Body,
+			Payload: payload,
+		}
+		if notification.RoomID != nil {
+			event.RoomID = notification.RoomID.String()
+		}
+		if notification.MessageID != nil {
+			event.MessageID = notification.MessageID.String()
+		}
+		c.Broadcast <- &Event{Type: "notification", Notification: event}
+
+		if c.Push != nil && !c.IsUserOnline(event.UserID) {
+			c.Push.Enqueue(notification)
+		}
+	}
+}
+
+// sendToUser delivers an event to every socket the user has open, in any room.
+func (c *Core) sendToUser(userID string, event *Event) {
+	c.roomsMu.RLock()
+	rooms := make([]*Room, 0, len(c.Rooms))
+	for _, room := range c.Rooms {
+		rooms = append(rooms, room)
+	}
+	c.roomsMu.RUnlock()
+
+	for _, room := range rooms {
+		room.mu.RLock()
+		for _, client := range room.Clients {
+			if client.UserID != userID {
+				continue
+			}
+			select {
+			case client.Message <- event:
+			default:
+				log.Printf("dropping websocket event %s for client %s due to full channel", event.Type, client.ID)
+			}
+		}
+		room.mu.RUnlock()
+	}
+}
+
+// IsUserOnline reports whether the user has a socket open in any room.
+func (c *Core) IsUserOnline(userID string) bool {
+	c.roomsMu.RLock()
+	rooms := make([]*Room, 0, len(c.Rooms))
+	for _, room := range c.Rooms {
+		rooms = append(rooms, room)
+	}
+	c.roomsMu.RUnlock()
+
+	for _, room := range rooms {
+		room.mu.RLock()
+		for _, client := range room.Clients {
+			if client.UserID == userID {
+				room.mu.RUnlock()
+				return true
+			}
+		}
+		room.mu.RUnlock()
+	}
+	return false
+}
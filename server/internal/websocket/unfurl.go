@@ -0,0 +1,58 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"chat-application/internal/api/model"
+
+	"github.com/google/uuid"
+)
+
+// unfurlTimeout bounds the whole preview pass for one message.
+const unfurlTimeout = 15 * time.Second
+
+// LinkUnfurler builds link previews for the URLs in a message.
+type LinkUnfurler interface {
+	Unfurl(ctx context.Context, content string) []model.LinkPreviewRes
+}
+
+// unfurlLinks fetches previews for a persisted message, stores them in the
+// message metadata and pushes a message.updated event to the room.
+func (c *Core) unfurlLinks(messageID uuid.UUID, msg *Message) {
+	if c.Unfurler == nil || msg.System {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), unfurlTimeout)
+	defer cancel()
+
+	previews := c.Unfurler.Unfurl(ctx, msg.Content)
+	if len(previews) == 0 {
+		return
+	}
+
+	patch, err := json.Marshal(map[string]any{"previews": previews})
+	if err != nil {
+		log.Printf("error encoding link previews: %v", err)
+		return
+	}
+	if err := c.RoomRepository.MergeMessageMetadata(ctx, messageID, patch); err != nil {
+		log.Printf("error storing link previews: %v", err)
+		return
+	}
+
+	// The original message may still be in flight to clients, so send a copy
+	// rather than mutating the shared one.
+	updated := *msg
+	updated.ID = messageID.String()
+	updated.Metadata = make(map[string]any, len(msg.Metadata)+1)
+	for key, value := range msg.Metadata {
+		updated.Metadata[key] = value
+	}
+	updated.Metadata["previews"] = previews
+
+	c.Broadcast <- &Event{Type: "message.updated", Message: &updated}
+}
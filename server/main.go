@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"log"
 	"net/http"
 	"os"
@@ -29,8 +30,26 @@ import (
 
 	coreHandler "chat-application/internal/api/handler/core"
 	"chat-application/internal/middleware"
+	"chat-application/internal/ratelimit"
 	pinnedRooms "chat-application/internal/service/pinnedrooms"
 	"chat-application/router"
+
+	attachmentsHandler "chat-application/internal/api/handler/attachments"
+	attachmentRepo "chat-application/internal/repo/attachment"
+	linkPreviewRepo "chat-application/internal/repo/linkpreview"
+	attachmentsService "chat-application/internal/service/attachments"
+	unfurlService "chat-application/internal/service/unfurl"
+	"chat-application/internal/storage"
+
+	digestHandler "chat-application/internal/api/handler/digest"
+	"chat-application/internal/mailer"
+	"chat-application/internal/oauth"
+	digestService "chat-application/internal/service/digest"
+
+	pushHandler "chat-application/internal/api/handler/push"
+	pushRepo "chat-application/internal/repo/push"
+	pushService "chat-application/internal/service/push"
+	"chat-application/internal/webpush"
 )
 
 func main() {
@@ -60,16 +79,73 @@ func main() {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
 
+	blobStorage, err := newBlobStorage(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialise attachment storage: %v", err)
+	}
+
 	userRepo := userRepo.NewUserRepository(dbConn)
 	statsRepository := statsRepo.NewStatsRepository(dbConn)
+	attachmentRepository := attachmentRepo.NewAttachmentRepository(dbConn)
+
+	appMailer, err := newMailer(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialise mailer: %v", err)
+	}
 
 	userService := userService.NewUserService(userRepo)
+	userService.Mailer = appMailer
+	userService.AppURL = cfg.AppURL
+	userService.RequireVerifiedEmail = cfg.RequireVerifiedEmail
+	userService.OAuthProviders = newOAuthProviders(cfg)
+	userService.APIURL = cfg.APIURL
+	userService.TOTPEncryptionKey = cfg.TOTPEncryptionKey
 	statsService := statsService.NewStatsService(statsRepository)
 	webService := websoc.NewCore(dbConn)
+	statsService.Notifier = webService
+	attachmentService := attachmentsService.NewAttachmentsService(
+		attachmentRepository,
+		webService.RoomRepository,
+		blobStorage,
+		cfg.MaxAttachmentSize,
+	)
+	webService.Attachments = attachmentService
+	userService.Avatars = attachmentService
+	userService.ProfileNotifier = webService
+	webService.Profiles = userService
+	userService.BlockNotifier = webService
+	webService.Blocks = userService
+	statsService.Profiles = userService
+	if cfg.LinkPreviewsEnabled {
+		webService.Unfurler = unfurlService.NewUnfurlService(
+			linkPreviewRepo.NewLinkPreviewRepository(dbConn),
+			unfurlService.DefaultConfig(),
+		)
+	}
+
+	digestConfig := digestService.DefaultConfig()
+	digestConfig.Interval = cfg.DigestInterval
+	digestConfig.AppURL = cfg.AppURL
+	digestConfig.APIURL = cfg.APIURL
+	digestConfig.Secret = []byte(cfg.EmailSigningSecret)
+	digestService := digestService.NewDigestService(webService.RoomRepository, appMailer, digestConfig)
+	digestService.Presence = webService
+
+	pushConfig := pushService.DefaultConfig()
+	pushConfig.AppURL = cfg.AppURL
+	pushSender, vapidPublicKey, err := newPushSender(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialise web push: %v", err)
+	}
+	pushService := pushService.NewPushService(pushRepo.NewPushRepository(dbConn), pushSender, vapidPublicKey, pushConfig)
+	webService.Push = pushService
 
 	userHandler := userHandler.NewUserHandler(userService)
 	coreHandler := coreHandler.NewCoreHandler(webService)
 	statsHandler := statsHandler.NewStatsHandler(statsService)
+	attachmentsHandler := attachmentsHandler.NewAttachmentsHandler(attachmentService)
+	digestHandler := digestHandler.NewDigestHandler(digestService)
+	pushHandler := pushHandler.NewPushHandler(pushService)
 
 	pinnedRoomService := pinnedRooms.NewPinnedRoomsService(dbConn, webService)
 	if err := pinnedRoomService.CheckAndRefreshPinnedRooms(context.Background()); err != nil {
@@ -80,15 +156,20 @@ func main() {
 	log.Println("Starting WebSocket core...")
 	go webService.Start()
 
-	go startRoomCleanup(dbConn, webService)
+	go startRoomCleanup(dbConn, webService, attachmentService, cfg.NotificationRetention)
+	go digestService.Start(context.Background())
+	pushService.Start(context.Background())
 
-	rateLimiter := middleware.NewRateLimiter(constants.DefaultRateLimit, constants.RateLimitWindow)
-	routerWithLimiter := rateLimiter.Middleware(router.SetupRoutes(userHandler, coreHandler, statsHandler))
+	if cfg.RateLimitStore == "postgres" {
+		rateLimitStore := ratelimit.NewPostgresStore(dbConn)
+		middleware.SetRateLimitStore(rateLimitStore)
+		go rateLimitStore.Start(context.Background(), constants.RateLimitSweepInterval)
+	}
 
 	// Create server with graceful shutdown support
 	srv := &http.Server{
 		Addr:         ":" + cfg.ServerPort,
-		Handler:      routerWithLimiter,
+		Handler:      router.SetupRoutes(userHandler, coreHandler, statsHandler, attachmentsHandler, digestHandler, pushHandler),
 		ReadTimeout:  constants.HTTPServerTimeout,
 		WriteTimeout: constants.HTTPServerTimeout,
 		IdleTimeout:  constants.HTTPServerTimeout * 2,
@@ -119,20 +200,91 @@ func main() {
 	log.Println("Server exited gracefully")
 }
 
-func startRoomCleanup(db *sql.DB, websocketCore *websoc.Core) {
+func newBlobStorage(cfg *config.Config) (storage.Storage, error) {
+	if cfg.StorageDriver == "s3" {
+		return storage.NewS3Storage(storage.S3Config{
+			Endpoint:        cfg.S3Endpoint,
+			Region:          cfg.S3Region,
+			Bucket:          cfg.S3Bucket,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+			UsePathStyle:    cfg.S3UsePathStyle,
+		})
+	}
+	return storage.NewLocalStorage(cfg.StorageLocalPath)
+}
+
+func newMailer(cfg *config.Config) (mailer.Mailer, error) {
+	if cfg.SMTPHost == "" {
+		if !cfg.IsDevelopment() {
+			return nil, errors.New("SMTP_HOST is required outside development")
+		}
+		return mailer.LogMailer{}, nil
+	}
+	return mailer.NewSMTPMailer(mailer.SMTPConfig{
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		From:     cfg.SMTPFrom,
+	})
+}
+
+func newOAuthProviders(cfg *config.Config) map[string]*oauth.Provider {
+	httpClient := &http.Client{Timeout: constants.OAuthRequestTimeout}
+	providers := map[string]*oauth.Provider{}
+	if cfg.GitHubClientID != "" {
+		providers["github"] = oauth.NewGitHubProvider(cfg.GitHubClientID, cfg.GitHubClientSecret, "https://github.com", "https://api.github.com", httpClient)
+	}
+	if cfg.GoogleClientID != "" {
+		providers["google"] = oauth.NewGoogleProvider(cfg.GoogleClientID, cfg.GoogleClientSecret, httpClient)
+	}
+	if cfg.OIDCClientID != "" {
+		providers[cfg.OIDCProviderName] = oauth.NewOIDCProvider(cfg.OIDCProviderName, cfg.OIDCIssuer, cfg.OIDCClientID, cfg.OIDCClientSecret, httpClient)
+	}
+	return providers
+}
+
+// newPushSender returns nil when push is disabled: in production without
+// VAPID_PRIVATE_KEY. Development falls back to a temporary key pair.
+func newPushSender(cfg *config.Config) (pushService.Sender, string, error) {
+	var keys *webpush.VAPIDKeys
+	var err error
+	switch {
+	case cfg.VAPIDPrivateKey != "":
+		keys, err = webpush.ParseVAPIDKeys(cfg.VAPIDPrivateKey)
+	case cfg.IsProduction():
+		log.Println("VAPID_PRIVATE_KEY is not set; web push is disabled")
+		return nil, "", nil
+	default:
+		keys, err = webpush.GenerateVAPIDKeys()
+		if err == nil {
+			log.Println("VAPID_PRIVATE_KEY is not set; using a temporary key for this run")
+		}
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	httpClient := pushService.NewHTTPClient(constants.PushSendTimeout)
+	client := webpush.NewClient(httpClient, keys, cfg.VAPIDSubject, constants.VAPIDTokenExpiry)
+	return client, keys.PublicKey, nil
+}
+
+func startRoomCleanup(db *sql.DB, websocketCore *websoc.Core, attachmentService *attachmentsService.AttachmentsService, notificationRetention time.Duration) {
 	roomRepository := roomRepository.NewRoomRepository(db)
 	pinnedRoomsService := pinnedRooms.NewPinnedRoomsService(db, websocketCore)
 	ticker := time.NewTicker(constants.RoomCleanupInterval)
 	defer ticker.Stop()
 
-	cleanupRooms(roomRepository, pinnedRoomsService)
+	cleanupRooms(roomRepository, pinnedRoomsService, attachmentService, notificationRetention)
 
 	for range ticker.C {
-		cleanupRooms(roomRepository, pinnedRoomsService)
+		cleanupRooms(roomRepository, pinnedRoomsService, attachmentService, notificationRetention)
 	}
 }
 
-func cleanupRooms(roomRepository *roomRepository.RoomRepository, pinnedRoomsService *pinnedRooms.PinnedRoomsService) {
+func cleanupRooms(roomRepository *roomRepository.RoomRepository, pinnedRoomsService *pinnedRooms.PinnedRoomsService, attachmentService *attachmentsService.AttachmentsService, notificationRetention time.Duration) {
 	ctx := context.Background()
 
 	deletedCount, err := roomRepository.DeleteExpiredRooms(ctx)
@@ -145,6 +297,23 @@ func cleanupRooms(roomRepository *roomRepository.RoomRepository, pinnedRoomsServ
 		log.Printf("Deleted %d expired rooms", deletedCount)
 	}
 
+	// Deleting a room detaches its attachments; remove their blobs from storage.
+	purgedCount, err := attachmentService.PurgeOrphaned(ctx)
+	if err != nil {
+		log.Printf("Failed to purge orphaned attachments: %v", err)
+	} else if purgedCount > 0 {
+		log.Printf("Purged %d orphaned attachments", purgedCount)
+	}
+
+	if notificationRetention > 0 {
+		expiredCount, err := roomRepository.DeleteNotificationsBefore(ctx, time.Now().Add(-notificationRetention))
+		if err != nil {
+			log.Printf("Failed to delete old notifications: %v", err)
+		} else if expiredCount > 0 {
+			log.Printf("Deleted %d notifications past retention", expiredCount)
+		}
+	}
+
 	if err := pinnedRoomsService.RefreshPinnedRooms(ctx); err != nil {
 		log.Printf("Failed to refresh pinned rooms: %v", err)
 	}
@@ -8,14 +8,25 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 
+	attachmentsHandler "chat-application/internal/api/handler/attachments"
 	coreHandler "chat-application/internal/api/handler/core"
+	digestHandler "chat-application/internal/api/handler/digest"
+	pushHandler "chat-application/internal/api/handler/push"
 	statsHandler "chat-application/internal/api/handler/stats"
 	userHandler "chat-application/internal/api/handler/user"
+	"chat-application/internal/constants"
 	authMiddleware "chat-application/internal/middleware"
 	"chat-application/util"
 )
 
-func SetupRoutes(userHandler *userHandler.UserHandler, coreHandler *coreHandler.CoreHandler, statsHandler *statsHandler.StatsHandler) http.Handler {
+func SetupRoutes(
+	userHandler *userHandler.UserHandler,
+	coreHandler *coreHandler.CoreHandler,
+	statsHandler *statsHandler.StatsHandler,
+	attachmentsHandler *attachmentsHandler.AttachmentsHandler,
+	digestHandler *digestHandler.DigestHandler,
+	pushHandler *pushHandler.PushHandler,
+) http.Handler {
 	r := chi.NewRouter()
 	allowedOrigins := util.GetEnvList("ALLOWED_ORIGINS", []string{
 		"http://localhost:3000",
@@ -25,7 +36,7 @@ func SetupRoutes(userHandler *userHandler.UserHandler, coreHandler *coreHandler.
 	})
 
 	r.Use(authMiddleware.SecurityHeaders)
-	r.Use(authMiddleware.RequestSizeLimit(1 << 20))
+	r.Use(authMiddleware.RequestSizeLimitWithUploads(1<<20, attachmentsHandler.MaxRequestSize()))
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.RequestID)
@@ -36,28 +47,56 @@ func SetupRoutes(userHandler *userHandler.UserHandler, coreHandler *coreHandler.
 		AllowedOrigins:   allowedOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		ExposedHeaders:   []string{"Link", "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
 
 	r.Route("/api", func(api chi.Router) {
-		api.Use(authMiddleware.GetRateLimiter(100))
+		api.Use(authMiddleware.GetRateLimiter("api", constants.DefaultRateLimit))
 		api.Use(authMiddleware.ContentTypeJSON)
 
 		api.Route("/users", func(u chi.Router) {
 			u.Group(func(r chi.Router) {
-				r.Use(authMiddleware.GetRateLimiter(10))
+				r.Use(authMiddleware.GetRateLimiter("auth", 10))
 				r.Post("/sign-up", userHandler.CreateUser)
 				r.Post("/login", userHandler.Login)
+				r.Post("/login/mfa", userHandler.CompleteMFALogin)
+				r.Post("/password-reset", userHandler.RequestPasswordReset)
+				r.Post("/password-reset/confirm", userHandler.ConfirmPasswordReset)
+				r.Post("/verify-email/confirm", userHandler.VerifyEmail)
+				r.With(authMiddleware.OptionalJWTAuth).Get("/oauth/{provider}", userHandler.StartOAuth)
+				r.Get("/oauth/{provider}/callback", userHandler.OAuthCallback)
 			})
 
+			u.Get("/oauth/providers", userHandler.GetOAuthProviders)
+			u.Get("/{userID}/avatar", userHandler.GetAvatar)
+
 			r.Post("/logout", userHandler.Logout)
 
 			u.Group(func(r chi.Router) {
 				r.Use(authMiddleware.JWTAuth)
 				r.Get("/me", userHandler.GetCurrentUser)
+				r.With(authMiddleware.GetRateLimiter("delete-account", 10)).Delete("/me", userHandler.DeleteAccount)
+				r.With(authMiddleware.GetRateLimiter("export", 10)).Get("/me/export", userHandler.ExportData)
 				r.Put("/username", userHandler.UpdateUsername)
+				r.Get("/me/profile", userHandler.GetMyProfile)
+				r.Put("/me/profile", userHandler.UpdateProfile)
+				r.With(authMiddleware.GetRateLimiter("avatar", 10)).Post("/me/avatar", userHandler.UploadAvatar)
+				r.Delete("/me/avatar", userHandler.RemoveAvatar)
+				r.Get("/search", userHandler.SearchUsers)
+				r.Get("/autocomplete", userHandler.AutocompleteMembers)
+				r.Get("/me/blocks", userHandler.GetBlockedUsers)
+				r.Put("/me/blocks/{userID}", userHandler.BlockUser)
+				r.Delete("/me/blocks/{userID}", userHandler.UnblockUser)
+				r.With(authMiddleware.GetRateLimiter("verify-email", 10)).Post("/verify-email", userHandler.RequestEmailVerification)
+				r.Get("/me/identities", userHandler.GetLinkedIdentities)
+				r.Delete("/me/identities/{provider}", userHandler.UnlinkIdentity)
+				r.Get("/me/login-history", userHandler.GetLoginHistory)
+				r.Get("/me/2fa", userHandler.GetTwoFactorStatus)
+				r.Post("/me/2fa/setup", userHandler.SetupTOTP)
+				r.Post("/me/2fa/enable", userHandler.EnableTOTP)
+				r.With(authMiddleware.GetRateLimiter("2fa-disable", 10)).Post("/me/2fa/disable", userHandler.DisableTOTP)
 			})
 		})
 
@@ -72,27 +111,66 @@ func SetupRoutes(userHandler *userHandler.UserHandler, coreHandler *coreHandler.
 			s.Get("/leaderboard", statsHandler.GetLeaderboard)
 		})
 
+		api.Route("/attachments", func(a chi.Router) {
+			a.With(authMiddleware.JWTAuth, authMiddleware.GetRateLimiter("attachments", 30)).Post("/", attachmentsHandler.Upload)
+			a.With(authMiddleware.OptionalJWTAuth).Get("/{attachmentId}", attachmentsHandler.Download)
+			a.With(authMiddleware.OptionalJWTAuth).Get("/{attachmentId}/thumbnail", attachmentsHandler.Thumbnail)
+		})
+
 		api.Route("/websoc", func(u chi.Router) {
 			u.Group(func(r chi.Router) {
 				r.Use(authMiddleware.OptionalJWTAuth)
 				// Apply a stricter per-route rate limiter to prevent room-creation spam
-				r.Use(authMiddleware.GetRateLimiter(10)) // 10 requests per minute for creating rooms
+				r.Use(authMiddleware.GetRateLimiter("create-room", 10)) // 10 requests per minute for creating rooms
+				r.Use(userHandler.RequireVerifiedEmail)
 				r.Post("/create-room", coreHandler.CreateRoom)
 			})
 
-			u.With(authMiddleware.JWTAuth).Post("/reactions", coreHandler.AddReaction)
+			u.With(authMiddleware.JWTAuth).Post("/reactions", coreHandler.ToggleReaction)
+			u.With(authMiddleware.JWTAuth).Delete("/reactions/{messageID}", coreHandler.RemoveReaction)
 			u.With(authMiddleware.JWTAuth).Get("/notifications", coreHandler.GetNotifications)
+			u.With(authMiddleware.JWTAuth).Get("/notifications/unread-count", coreHandler.GetUnreadNotificationCount)
+			u.With(authMiddleware.JWTAuth).Put("/notifications/read-all", coreHandler.MarkAllNotificationsRead)
+			u.With(authMiddleware.JWTAuth).Get("/notifications/preferences", coreHandler.GetNotificationPreferences)
+			u.With(authMiddleware.JWTAuth).Put("/notifications/preferences", coreHandler.UpdateNotificationPreferences)
 			u.With(authMiddleware.JWTAuth).Put("/notifications/{notificationId}/read", coreHandler.MarkNotificationRead)
-			u.Get("/reactions/{messageID}", coreHandler.GetReactions)
+			u.Get("/notifications/unsubscribe", digestHandler.Unsubscribe)
+			u.Get("/push/vapid-public-key", pushHandler.GetVAPIDPublicKey)
+			u.With(authMiddleware.JWTAuth).Post("/push/subscriptions", pushHandler.Subscribe)
+			u.With(authMiddleware.JWTAuth).Delete("/push/subscriptions", pushHandler.Unsubscribe)
+			u.With(authMiddleware.OptionalJWTAuth).Get("/reactions/{messageID}", coreHandler.GetReactions)
 
 			u.With(authMiddleware.OptionalJWTAuth).Get("/join-room/{roomId}", coreHandler.JoinRoom)
-			u.Get("/get-rooms", coreHandler.GetRooms)
-			u.Get("/rooms/{roomId}", coreHandler.GetRoomDetail)
-			u.Get("/rooms/{roomId}/search", coreHandler.SearchMessages)
+			u.With(authMiddleware.OptionalJWTAuth).Get("/get-rooms", coreHandler.GetRooms)
+			u.With(authMiddleware.OptionalJWTAuth).Get("/rooms/{roomId}", coreHandler.GetRoomDetail)
+			u.With(authMiddleware.OptionalJWTAuth).Get("/rooms/{roomId}/search", coreHandler.SearchMessages)
 			u.With(authMiddleware.JWTAuth).Post("/rooms/{roomId}/categories", coreHandler.CreateCategory)
 			u.With(authMiddleware.JWTAuth).Post("/rooms/{roomId}/channels", coreHandler.CreateChannel)
+			u.With(authMiddleware.JWTAuth).Put("/rooms/{roomId}/channels/{channelId}/slow-mode", coreHandler.SetSlowMode)
+			u.With(authMiddleware.JWTAuth).Put("/rooms/{roomId}/visibility", coreHandler.SetRoomVisibility)
+			u.With(authMiddleware.JWTAuth).Get("/rooms/{roomId}/invites", coreHandler.GetInvites)
+			u.With(authMiddleware.JWTAuth).Post("/rooms/{roomId}/invites", coreHandler.CreateInvite)
+			u.With(authMiddleware.JWTAuth).Delete("/rooms/{roomId}/invites/{inviteId}", coreHandler.RevokeInvite)
+			u.With(authMiddleware.JWTAuth, authMiddleware.GetRateLimiter("redeem-invite", 20)).Post("/invites/{code}", coreHandler.RedeemInvite)
 			u.With(authMiddleware.JWTAuth).Put("/rooms/{roomId}/members/{userId}", coreHandler.UpdateMemberRole)
-			u.Get("/clients/{room_id}", coreHandler.GetClients)
+			u.With(authMiddleware.JWTAuth, authMiddleware.GetRateLimiter("report", 20)).Post("/rooms/{roomId}/messages/{messageId}/report", coreHandler.ReportMessage)
+			u.With(authMiddleware.JWTAuth).Get("/rooms/{roomId}/reports", coreHandler.GetReports)
+			u.With(authMiddleware.JWTAuth).Post("/rooms/{roomId}/reports/{reportId}/actions", coreHandler.ResolveReport)
+			u.With(authMiddleware.JWTAuth).Get("/rooms/{roomId}/moderation-log", coreHandler.GetModerationLog)
+			u.With(authMiddleware.JWTAuth).Get("/rooms/{roomId}/automod", coreHandler.GetAutoModRules)
+			u.With(authMiddleware.JWTAuth).Put("/rooms/{roomId}/automod", coreHandler.UpdateAutoModRules)
+			u.With(authMiddleware.OptionalJWTAuth).Get("/rooms/{roomId}/messages", coreHandler.GetMessages)
+			u.With(authMiddleware.JWTAuth).Post("/rooms/{roomId}/read", coreHandler.MarkRead)
+			u.With(authMiddleware.JWTAuth).Put("/rooms/{roomId}/notification-preferences", coreHandler.UpdateRoomNotificationPreferences)
+			u.With(authMiddleware.JWTAuth).Delete("/rooms/{roomId}/notification-preferences", coreHandler.DeleteRoomNotificationPreferences)
+			u.With(authMiddleware.JWTAuth).Get("/rooms/{roomId}/messages/{messageId}/receipts", coreHandler.GetReadReceipts)
+			u.With(authMiddleware.OptionalJWTAuth).Get("/rooms/{roomId}/messages/{messageId}/thread", coreHandler.GetThread)
+			u.With(authMiddleware.JWTAuth).Put("/rooms/{roomId}/messages/{messageId}/thread/follow", coreHandler.FollowThread)
+			u.With(authMiddleware.JWTAuth).Delete("/rooms/{roomId}/messages/{messageId}/thread/follow", coreHandler.UnfollowThread)
+			u.With(authMiddleware.OptionalJWTAuth).Get("/rooms/{roomId}/emojis", coreHandler.GetRoomEmojis)
+			u.With(authMiddleware.JWTAuth).Post("/rooms/{roomId}/emojis", coreHandler.CreateRoomEmoji)
+			u.With(authMiddleware.JWTAuth).Delete("/rooms/{roomId}/emojis/{name}", coreHandler.DeleteRoomEmoji)
+			u.With(authMiddleware.OptionalJWTAuth).Get("/clients/{room_id}", coreHandler.GetClients)
 		})
 	})
 